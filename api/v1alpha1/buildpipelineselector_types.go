@@ -73,6 +73,48 @@ type PipelineParam struct {
 	Value string `json:"value"`
 }
 
+// PipelineReferenceType defines how the build pipeline is referenced from generated Pipelines as Code PipelineRuns.
+// +kubebuilder:validation:Enum=inline;bundles;git;resolver
+type PipelineReferenceType string
+
+const (
+	// PipelineReferenceInline embeds the pipeline definition from the bundle as pipelineSpec.
+	PipelineReferenceInline PipelineReferenceType = "inline"
+	// PipelineReferenceBundlesResolver references the pipeline in the bundle via Tekton bundles resolver.
+	PipelineReferenceBundlesResolver PipelineReferenceType = "bundles"
+	// PipelineReferenceGitResolver references the pipeline definition in a git repository via Tekton git resolver.
+	PipelineReferenceGitResolver PipelineReferenceType = "git"
+	// PipelineReferenceResolver references the pipeline with the same resolver as the selector pipelineRef, e.g. git, hub or cluster.
+	PipelineReferenceResolver PipelineReferenceType = "resolver"
+)
+
+// GitPipelineLocation defines location of a pipeline definition in a git repository.
+type GitPipelineLocation struct {
+	// URL of the git repository, e.g. 'https://github.com/my-org/build-definitions.git'.
+	// +kubebuilder:validation:Required
+	URL string `json:"url"`
+
+	// Git revision to take the pipeline definition from, e.g. branch, tag or commit SHA.
+	// +kubebuilder:validation:Required
+	Revision string `json:"revision"`
+
+	// Path to the pipeline definition file in the repository, e.g. 'pipelines/docker-build.yaml'.
+	// +kubebuilder:validation:Required
+	PathInRepo string `json:"pathInRepo"`
+}
+
+// PipelineReference defines how the build pipeline is referenced from generated Pipelines as Code PipelineRuns.
+type PipelineReference struct {
+	// Type of the reference. Defaults to 'inline'.
+	// +kubebuilder:validation:Optional
+	Type PipelineReferenceType `json:"type,omitempty"`
+
+	// Location of the pipeline definition for 'git' type.
+	// The definition must match the pipeline in the bundle, which is used to compute the PipelineRun workspaces.
+	// +kubebuilder:validation:Optional
+	Git *GitPipelineLocation `json:"git,omitempty"`
+}
+
 // PipelineSelector defines allowed build pipeline and conditions when it should be used.
 type PipelineSelector struct {
 	// Name of the selector item. Optional.
@@ -80,6 +122,7 @@ type PipelineSelector struct {
 	Name string `json:"name,omitempty"`
 
 	// Build Pipeline to use if the selector conditions are met.
+	// Either bundle and name or a resolver (bundles, git, hub or cluster) with its params must be specified.
 	// +kubebuilder:validation:Required
 	PipelineRef tektonapi.PipelineRef `json:"pipelineRef"`
 
@@ -88,6 +131,11 @@ type PipelineSelector struct {
 	// +listType=atomic
 	PipelineParams []PipelineParam `json:"pipelineParams,omitempty"`
 
+	// Defines how the build pipeline is referenced from generated Pipelines as Code PipelineRuns.
+	// If omitted, the pipeline definition is inlined.
+	// +kubebuilder:validation:Optional
+	PipelineReference *PipelineReference `json:"pipelineReference,omitempty"`
+
 	// Defines the selector conditions when given build pipeline should be used.
 	// All conditions are connected via AND, whereas cases within any condition connected via OR.
 	// If the section is omitted, then the condition is considered true (usually used for fallback condition).
@@ -105,6 +105,21 @@ func (in *BuildPipelineSelectorSpec) DeepCopy() *BuildPipelineSelectorSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitPipelineLocation) DeepCopyInto(out *GitPipelineLocation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitPipelineLocation.
+func (in *GitPipelineLocation) DeepCopy() *GitPipelineLocation {
+	if in == nil {
+		return nil
+	}
+	out := new(GitPipelineLocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PipelineParam) DeepCopyInto(out *PipelineParam) {
 	*out = *in
@@ -120,6 +135,26 @@ func (in *PipelineParam) DeepCopy() *PipelineParam {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineReference) DeepCopyInto(out *PipelineReference) {
+	*out = *in
+	if in.Git != nil {
+		in, out := &in.Git, &out.Git
+		*out = new(GitPipelineLocation)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineReference.
+func (in *PipelineReference) DeepCopy() *PipelineReference {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PipelineSelector) DeepCopyInto(out *PipelineSelector) {
 	*out = *in
@@ -129,6 +164,11 @@ func (in *PipelineSelector) DeepCopyInto(out *PipelineSelector) {
 		*out = make([]PipelineParam, len(*in))
 		copy(*out, *in)
 	}
+	if in.PipelineReference != nil {
+		in, out := &in.PipelineReference, &out.PipelineReference
+		*out = new(PipelineReference)
+		(*in).DeepCopyInto(*out)
+	}
 	in.WhenConditions.DeepCopyInto(&out.WhenConditions)
 }
 
@@ -19,6 +19,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"os"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -37,7 +38,10 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/github"
 	l "github.com/redhat-appstudio/build-service/pkg/logs"
+	pipelineresolver "github.com/redhat-appstudio/build-service/pkg/pipeline-resolver"
+	registrymirrors "github.com/redhat-appstudio/build-service/pkg/registry-mirrors"
 )
 
 const (
@@ -52,6 +56,44 @@ const (
 	PaCProvisionErrorAnnotationValue       = "error"
 	PaCProvisionErrorDetailsAnnotationName = "appstudio.openshift.io/pac-provision-error"
 
+	// PaC webhook settings of the component, override the global configuration
+	PaCWebhookSSLVerificationAnnotationName = "appstudio.openshift.io/pac-webhook-ssl-verification"
+	PaCWebhookEventsAnnotationName          = "appstudio.openshift.io/pac-webhook-events"
+
+	// Comma separated lists of labels, reviewers (user names), team reviewers (team slugs) and assignees (user names)
+	// to add to the onboarding merge request. Override 'pac-merge-request-options' config map in the component namespace.
+	PaCMergeRequestLabelsAnnotationName        = "appstudio.openshift.io/pac-pr-labels"
+	PaCMergeRequestReviewersAnnotationName     = "appstudio.openshift.io/pac-pr-reviewers"
+	PaCMergeRequestTeamReviewersAnnotationName = "appstudio.openshift.io/pac-pr-team-reviewers"
+	PaCMergeRequestAssigneesAnnotationName     = "appstudio.openshift.io/pac-pr-assignees"
+	// PaCMergeRequestDraftAnnotationName makes new onboarding merge request draft if set to true
+	PaCMergeRequestDraftAnnotationName = "appstudio.openshift.io/pac-pr-draft"
+	// PaCMergeRequestAutoMergeAnnotationName enables auto-merge of the onboarding merge request if set to true
+	PaCMergeRequestAutoMergeAnnotationName = "appstudio.openshift.io/pac-pr-auto-merge"
+	// PaCMergeRequestMergeMethodAnnotationName is the auto-merge method: merge (default), squash or rebase
+	PaCMergeRequestMergeMethodAnnotationName = "appstudio.openshift.io/pac-pr-merge-method"
+	// PaCMergeRequestBatchAnnotationName puts PaC configuration of the component into one onboarding merge request
+	// with other components in the same repository and target branch if set to true
+	PaCMergeRequestBatchAnnotationName = "appstudio.openshift.io/pac-pr-batch"
+
+	// Onboarding merge request created on PaC provision and its state, see PaCMergeRequestState*AnnotationValue
+	PaCMergeRequestUrlAnnotationName                  = "appstudio.openshift.io/pac-pr-url"
+	PaCMergeRequestNumberAnnotationName               = "appstudio.openshift.io/pac-pr-number"
+	PaCMergeRequestStateAnnotationName                = "appstudio.openshift.io/pac-pr-state"
+	PaCMergeRequestStateOpenAnnotationValue           = "pr-open"
+	PaCMergeRequestStateMergedAnnotationValue         = "merged"
+	PaCMergeRequestStateClosedUnmergedAnnotationValue = "closed-unmerged"
+	// PaCMergeRequestCheckedAtAnnotationName is the time the state of the open onboarding merge request was last checked
+	PaCMergeRequestCheckedAtAnnotationName = "appstudio.openshift.io/pac-pr-checked-at"
+	// PaCMergeRequestClosedAtAnnotationName is the time the onboarding merge request was found closed without merging
+	PaCMergeRequestClosedAtAnnotationName = "appstudio.openshift.io/pac-pr-closed-at"
+	// PaCMergeRequestClosedActionAnnotationName overrides what to do when the onboarding merge request is closed without merging:
+	// reopen, recreate (after cooldown) or error.
+	PaCMergeRequestClosedActionAnnotationName = "appstudio.openshift.io/pac-pr-closed-action"
+
+	PaCWebhookSecretRotateAnnotationName           = "appstudio.openshift.io/pac-webhook-secret-rotate"
+	PaCWebhookSecretRotateRequestedAnnotationValue = "request"
+
 	ApplicationNameLabelName  = "appstudio.openshift.io/application"
 	ComponentNameLabelName    = "appstudio.openshift.io/component"
 	PartOfLabelName           = "app.kubernetes.io/part-of"
@@ -61,6 +103,9 @@ const (
 	gitRepoAtShaAnnotationName    = "build.appstudio.openshift.io/repo"
 	gitTargetBranchAnnotationName = "build.appstudio.redhat.com/target_branch"
 
+	// pipelineBundleDigestAnnotationName holds digest of the bundle the build pipeline definition was taken from
+	pipelineBundleDigestAnnotationName = "build.appstudio.redhat.com/bundle_digest"
+
 	ImageRepoAnnotationName         = "image.redhat.com/image"
 	ImageRepoGenerateAnnotationName = "image.redhat.com/generate"
 	buildPipelineServiceAccountName = "appstudio-pipeline"
@@ -68,6 +113,10 @@ const (
 	buildServiceNamespaceName         = "build-service"
 	buildPipelineSelectorResourceName = "build-pipeline-selector"
 
+	// pipelineBundlePullSecretEnvVar is the name of an image pull secret in build-service namespace
+	// used to pull build pipeline bundles for all namespaces, in addition to the pipeline service account pull secrets
+	pipelineBundlePullSecretEnvVar = "PIPELINE_BUNDLE_PULL_SECRET"
+
 	metricsNamespace = "redhat_appstudio"
 	metricsSubsystem = "buildservice"
 )
@@ -101,6 +150,12 @@ func initMetrics() error {
 	if err := metrics.Registry.Register(pipelinesAsCodeComponentProvisionTimeMetric); err != nil {
 		return fmt.Errorf("failed to register the PaC_configuration_time metric: %w", err)
 	}
+	if err := metrics.Registry.Register(github.AppCacheHitsMetric); err != nil {
+		return fmt.Errorf("failed to register the github_app_cache_hits_total metric: %w", err)
+	}
+	if err := metrics.Registry.Register(github.AppCacheMissesMetric); err != nil {
+		return fmt.Errorf("failed to register the github_app_cache_misses_total metric: %w", err)
+	}
 
 	return nil
 }
@@ -116,6 +171,8 @@ type ComponentBuildReconciler struct {
 	Client        client.Client
 	Scheme        *runtime.Scheme
 	EventRecorder record.EventRecorder
+	// PipelineResolverClient fetches build pipeline definitions, created in SetupWithManager if not set
+	PipelineResolverClient *pipelineresolver.ResolverClient
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -123,6 +180,19 @@ func (r *ComponentBuildReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if err := initMetrics(); err != nil {
 		return err
 	}
+	if r.PipelineResolverClient == nil {
+		// Do not cache Pipeline objects and pull secrets, they are read rarely
+		keychainProvider := &pipelineresolver.PullSecretsKeychainProvider{
+			Reader:             mgr.GetAPIReader(),
+			ServiceAccountName: buildPipelineServiceAccountName,
+			GlobalPullSecret:   types.NamespacedName{Namespace: buildServiceNamespaceName, Name: os.Getenv(pipelineBundlePullSecretEnvVar)},
+		}
+		mirrorsProvider := &registrymirrors.ConfigMapProvider{
+			Reader:    mgr.GetClient(),
+			ConfigMap: types.NamespacedName{Namespace: buildServiceNamespaceName, Name: registrymirrors.ConfigMapName},
+		}
+		r.PipelineResolverClient = pipelineresolver.NewResolverClient(mgr.GetAPIReader(), []string{buildServiceNamespaceName}, keychainProvider, mirrorsProvider)
+	}
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&appstudiov1alpha1.Component{}, builder.WithPredicates(predicate.Funcs{
@@ -146,6 +216,7 @@ func (r *ComponentBuildReconciler) SetupWithManager(mgr ctrl.Manager) error {
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=components/status,verbs=get;list;watch
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=buildpipelineselectors,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups=tekton.dev,resources=pipelineruns,verbs=create
+//+kubebuilder:rbac:groups=tekton.dev,resources=pipelines,verbs=get
 //+kubebuilder:rbac:groups=pipelinesascode.tekton.dev,resources=repositories,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;patch;update
@@ -288,7 +359,19 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	// Check if Pipelines as Code workflow enabled
 	if val, exists := component.Annotations[PaCProvisionAnnotationName]; exists {
 		if val != PaCProvisionRequestedAnnotationValue && !isSwitchedImageRegistry {
-			if !(val == PaCProvisionDoneAnnotationValue || val == PaCProvisionErrorAnnotationValue) {
+			if val == PaCProvisionDoneAnnotationValue {
+				// Pipelines as Code is provisioned, track the onboarding merge request and rotate the webhook secret if needed
+				mrStateResult, err := r.reconcilePaCMergeRequestState(ctx, &component)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				rotationResult, err := r.reconcilePaCWebhookSecretRotation(ctx, &component)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				return getEarliestRequeueResult(mrStateResult, rotationResult), nil
+			}
+			if val != PaCProvisionErrorAnnotationValue {
 				message := fmt.Sprintf(
 					"Unexpected value \"%s\" for \"%s\" annotation. Use \"%s\" value to do Pipeline as Code provision for the Component",
 					val, PaCProvisionAnnotationName, PaCProvisionRequestedAnnotationValue)
@@ -302,8 +385,14 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 
 		var pacAnnotationValue string
 		var pacPersistentErrorMessage string
-		err := r.ProvisionPaCForComponent(ctx, &component)
+		mr, err := r.ProvisionPaCForComponent(ctx, &component)
 		if err != nil {
+			if retryAt, isRateLimited := boerrors.GetRetryAt(err); isRateLimited {
+				// Git provider API quota is exhausted, it's not a failure of the provision
+				requeueAfter := getRateLimitRequeueAfter(retryAt)
+				log.Info(fmt.Sprintf("Git provider rate limit reached, Pipelines as Code provision is postponed for %s", requeueAfter), "error", err.Error())
+				return ctrl.Result{RequeueAfter: requeueAfter}, nil
+			}
 			if boErr, ok := err.(*boerrors.BuildOpError); ok && boErr.IsPersistent() {
 				log.Error(err, "Pipelines as Code provision for the Component failed")
 				pacAnnotationValue = PaCProvisionErrorAnnotationValue
@@ -334,6 +423,10 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		} else {
 			delete(component.Annotations, PaCProvisionErrorDetailsAnnotationName)
 		}
+		isMergeRequestStateChanged := false
+		if mr != nil {
+			isMergeRequestStateChanged = setPaCMergeRequestAnnotations(&component, mr)
+		}
 
 		// Add finalizer to clean up Pipelines as Code configuration on component deletion
 		if component.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -348,6 +441,9 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		} else {
 			log.Info("PaC finalizer added", l.Action, l.ActionUpdate)
 		}
+		if isMergeRequestStateChanged {
+			r.reportPaCMergeRequestState(&component)
+		}
 
 		return ctrl.Result{}, nil
 	}
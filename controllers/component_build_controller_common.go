@@ -20,14 +20,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/go-logr/logr"
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
 	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
 	l "github.com/redhat-appstudio/build-service/pkg/logs"
+	pipelineresolver "github.com/redhat-appstudio/build-service/pkg/pipeline-resolver"
 	pipelineselector "github.com/redhat-appstudio/build-service/pkg/pipeline-selector"
 	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	corev1 "k8s.io/api/core/v1"
@@ -39,7 +43,8 @@ import (
 )
 
 // GetPipelineForComponent searches for the build pipeline to use on the component.
-func (r *ComponentBuildReconciler) GetPipelineForComponent(ctx context.Context, component *appstudiov1alpha1.Component) (*tektonapi.PipelineRef, []tektonapi.Param, error) {
+// Also returns how the pipeline should be referenced from PaC PipelineRuns, nil means inlined.
+func (r *ComponentBuildReconciler) GetPipelineForComponent(ctx context.Context, component *appstudiov1alpha1.Component) (*tektonapi.PipelineRef, []tektonapi.Param, *buildappstudiov1alpha1.PipelineReference, error) {
 	var pipelineSelectors []buildappstudiov1alpha1.BuildPipelineSelector
 	pipelineSelector := &buildappstudiov1alpha1.BuildPipelineSelector{}
 
@@ -55,7 +60,7 @@ func (r *ComponentBuildReconciler) GetPipelineForComponent(ctx context.Context,
 	for _, pipelineSelectorKey := range pipelineSelectorKeys {
 		if err := r.Client.Get(ctx, pipelineSelectorKey, pipelineSelector); err != nil {
 			if !errors.IsNotFound(err) {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 			// The config is not found, try the next one in the hierarchy
 		} else {
@@ -64,12 +69,12 @@ func (r *ComponentBuildReconciler) GetPipelineForComponent(ctx context.Context,
 	}
 
 	if len(pipelineSelectors) > 0 {
-		pipelineRef, pipelineParams, err := pipelineselector.SelectPipelineForComponent(component, pipelineSelectors)
+		pipelineRef, pipelineParams, pipelineReference, err := pipelineselector.SelectPipelineForComponent(component, pipelineSelectors)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		if pipelineRef != nil {
-			return pipelineRef, pipelineParams, nil
+			return pipelineRef, pipelineParams, pipelineReference, nil
 		}
 	}
 
@@ -77,7 +82,76 @@ func (r *ComponentBuildReconciler) GetPipelineForComponent(ctx context.Context,
 	return &tektonapi.PipelineRef{
 		Name:   defaultPipelineName,
 		Bundle: defaultPipelineBundle,
-	}, nil, nil
+	}, nil, nil, nil
+}
+
+// describePipelineRef returns human readable location of the given pipeline to use in logs.
+func describePipelineRef(pipelineRef *tektonapi.PipelineRef) string {
+	if pipelineRef.Bundle != "" {
+		return fmt.Sprintf("%s pipeline from %s bundle", pipelineRef.Name, pipelineRef.Bundle)
+	}
+	if pipelineRef.Resolver != "" {
+		_, params, err := pipelineresolver.GetResolverParams(pipelineRef)
+		if err == nil {
+			return fmt.Sprintf("pipeline from %s resolver with %v params", pipelineRef.Resolver, params)
+		}
+	}
+	return fmt.Sprintf("%s pipeline", pipelineRef.Name)
+}
+
+// getPipelineNameAndBundle returns name of the given pipeline and its bundle.
+// The bundle is empty if the pipeline is not in a bundle.
+func getPipelineNameAndBundle(pipelineRef *tektonapi.PipelineRef) (string, string) {
+	resolverName, params, err := pipelineresolver.GetResolverParams(pipelineRef)
+	if err != nil {
+		return pipelineRef.Name, pipelineRef.Bundle
+	}
+	name := params[pipelineresolver.NameParam]
+	if name == "" && resolverName == pipelineresolver.GitResolverName {
+		name = strings.TrimSuffix(path.Base(params["pathInRepo"]), path.Ext(params["pathInRepo"]))
+	}
+	if resolverName != pipelineresolver.BundlesResolverName {
+		return name, ""
+	}
+	return name, params["bundle"]
+}
+
+// setPipelineBundle returns copy of the given bundle pipeline reference which points to the given bundle.
+// Other pipeline references are returned as is.
+func setPipelineBundle(pipelineRef *tektonapi.PipelineRef, bundle string) *tektonapi.PipelineRef {
+	if pipelineRef.Bundle != "" {
+		pipelineRefCopy := pipelineRef.DeepCopy()
+		pipelineRefCopy.Bundle = bundle
+		return pipelineRefCopy
+	}
+	if pipelineRef.Resolver != pipelineresolver.BundlesResolverName {
+		return pipelineRef
+	}
+	pipelineRefCopy := pipelineRef.DeepCopy()
+	for i := range pipelineRefCopy.Params {
+		if pipelineRefCopy.Params[i].Name == "bundle" {
+			pipelineRefCopy.Params[i].Value = *tektonapi.NewArrayOrString(bundle)
+		}
+	}
+	return pipelineRefCopy
+}
+
+// filterPipelineParams returns the given params which are declared by the pipeline.
+// Dropped params are logged, so a pipeline which silently ignores e.g. the dockerfile can be diagnosed.
+func filterPipelineParams(params []tektonapi.Param, pipelineSpec *tektonapi.PipelineSpec, log logr.Logger) []tektonapi.Param {
+	declaredParams := make(map[string]bool, len(pipelineSpec.Params))
+	for _, paramSpec := range pipelineSpec.Params {
+		declaredParams[paramSpec.Name] = true
+	}
+	filteredParams := []tektonapi.Param{}
+	for _, param := range params {
+		if declaredParams[param.Name] {
+			filteredParams = append(filteredParams, param)
+		} else {
+			log.Info(fmt.Sprintf("'%s' param is not passed to the build pipeline, because the pipeline doesn't declare it", param.Name))
+		}
+	}
+	return filteredParams
 }
 
 func (r *ComponentBuildReconciler) ensurePipelineServiceAccount(ctx context.Context, namespace string) (*corev1.ServiceAccount, error) {
@@ -304,3 +378,15 @@ func getPathContext(gitContext, dockerfileContext string) string {
 	path = strings.TrimPrefix(path, separator)
 	return path
 }
+
+// minRateLimitRequeueAfter prevents busy requeue if the git provider rate limit reset time is in the past already
+const minRateLimitRequeueAfter = 10 * time.Second
+
+// getRateLimitRequeueAfter returns delay after which the reconcile should be retried when git provider rate limit is reached.
+func getRateLimitRequeueAfter(retryAt time.Time) time.Duration {
+	requeueAfter := time.Until(retryAt)
+	if requeueAfter < minRateLimitRequeueAfter {
+		return minRateLimitRequeueAfter
+	}
+	return requeueAfter
+}
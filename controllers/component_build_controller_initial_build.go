@@ -21,20 +21,21 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
-	"net/url"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-logr/logr"
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
 	"github.com/redhat-appstudio/application-service/gitops"
 	gitopsprepare "github.com/redhat-appstudio/application-service/gitops/prepare"
 	"github.com/redhat-appstudio/application-service/pkg/devfile"
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
-	"github.com/redhat-appstudio/build-service/pkg/github"
-	"github.com/redhat-appstudio/build-service/pkg/gitlab"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitproviderfactory"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitrepourl"
 	l "github.com/redhat-appstudio/build-service/pkg/logs"
+	pipelineresolver "github.com/redhat-appstudio/build-service/pkg/pipeline-resolver"
 	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -68,7 +69,7 @@ func (r *ComponentBuildReconciler) SubmitNewBuild(ctx context.Context, component
 		if gitSecret.Annotations == nil {
 			gitSecret.Annotations = map[string]string{}
 		}
-		gitHost, _ := getGitProviderUrl(component.Spec.Source.GitSource.URL)
+		gitHost, _ := gitrepourl.GetGitProviderUrl(component.Spec.Source.GitSource.URL)
 		// Doesn't matter if the annotation was present, we will always override because we clone from one repository only
 		if gitSecret.Annotations["tekton.dev/git-0"] != gitHost {
 			gitSecret.Annotations["tekton.dev/git-0"] = gitHost
@@ -88,10 +89,16 @@ func (r *ComponentBuildReconciler) SubmitNewBuild(ctx context.Context, component
 
 	// Create initial build pipeline
 
-	pipelineRef, additionalPipelineParams, err := r.GetPipelineForComponent(ctx, component)
+	pipelineRef, additionalPipelineParams, _, err := r.GetPipelineForComponent(ctx, component)
 	if err != nil {
 		return err
 	}
+	// The pipeline definition is needed to bind workspaces and pass params it declares
+	resolvedPipeline, err := r.PipelineResolverClient.GetPipeline(ctx, pipelineRef, component.Namespace)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("Failed to get %s", describePipelineRef(pipelineRef)), l.Action, l.ActionView)
+		return err
+	}
 
 	// Find out source commit SHA to build from.
 	// This is optional for the build itself, but needed for UI to correctly display build pipeline.
@@ -108,122 +115,96 @@ func (r *ComponentBuildReconciler) SubmitNewBuild(ctx context.Context, component
 			gitSourceSHA = revision
 		}
 	}
-	if gitSourceSHA == "" {
-		pacSecret := corev1.Secret{}
-		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: buildServiceNamespaceName, Name: gitopsprepare.PipelinesAsCodeSecretName}, &pacSecret); err == nil {
-			gitSourceSHA, err = getGitSourceShaForComponent(component, pacSecret.Data)
-			if err != nil {
-				log.Error(err, "Failed to retrieve git source commit SHA", l.Action, l.ActionView, l.Audit, "true")
+
+	gitRepoAtShaLink := ""
+	pacSecret := corev1.Secret{}
+	gitProvider, _ := gitops.GetGitProvider(*component)
+	if !isGlobalPaCSecretAllowed(component.Spec.Source.GitSource.URL, gitProvider) {
+		log.Info("Global git provider credentials are not used for self-hosted instances, skipping git source commit SHA resolution")
+	} else if err := r.Client.Get(ctx, types.NamespacedName{Namespace: buildServiceNamespaceName, Name: gitopsprepare.PipelinesAsCodeSecretName}, &pacSecret); err == nil {
+		gitClient, err := getGitClientForSimpleBuild(component, pacSecret.Data)
+		if err == nil {
+			if gitSourceSHA == "" {
+				gitSourceSHA, err = getGitSourceShaForComponent(component, gitClient)
+				if err != nil {
+					log.Error(err, "Failed to retrieve git source commit SHA", l.Action, l.ActionView, l.Audit, "true")
+				}
+			}
+			if gitSourceSHA != "" {
+				gitRepoAtShaLink = gitClient.GetBrowseRepositoryAtShaLink(component.Spec.Source.GitSource.URL, gitSourceSHA)
 			}
 		} else {
-			log.Error(err, "error getting git provider credentials secret", l.Action, l.ActionView)
+			log.Error(err, "Failed to create git client", l.Action, l.ActionView, l.Audit, "true")
 		}
+	} else {
+		log.Error(err, "error getting git provider credentials secret", l.Action, l.ActionView)
 	}
 
-	initialBuildPipelineRun, err := generateInitialPipelineRunForComponent(component, pipelineRef, additionalPipelineParams, gitSourceSHA)
+	initialBuildPipelineRun, err := generateInitialPipelineRunForComponent(component, pipelineRef, resolvedPipeline, additionalPipelineParams, gitSourceSHA, gitRepoAtShaLink, log)
 	if err != nil {
 		log.Error(err, fmt.Sprintf("Failed to generate PipelineRun to build %s component in %s namespace", component.Name, component.Namespace))
 		return err
 	}
 
-	err = controllerutil.SetOwnerReference(component, initialBuildPipelineRun, r.Scheme)
+	pipelineRunObject, err := convertPipelineRun(initialBuildPipelineRun, getPipelineRunApiVersion(log))
+	if err != nil {
+		log.Error(err, fmt.Sprintf("Failed to convert PipelineRun to build %s component in %s namespace", component.Name, component.Namespace))
+		return err
+	}
+
+	err = controllerutil.SetOwnerReference(component, pipelineRunObject, r.Scheme)
 	if err != nil {
-		log.Error(err, fmt.Sprintf("Unable to set owner reference for %v", initialBuildPipelineRun), l.Action, l.ActionUpdate)
+		log.Error(err, fmt.Sprintf("Unable to set owner reference for %v", pipelineRunObject), l.Action, l.ActionUpdate)
 	}
 
-	err = r.Client.Create(ctx, initialBuildPipelineRun)
+	err = r.Client.Create(ctx, pipelineRunObject)
 	if err != nil {
-		log.Error(err, fmt.Sprintf("Unable to create the build PipelineRun %v", initialBuildPipelineRun), l.Action, l.ActionAdd)
+		log.Error(err, fmt.Sprintf("Unable to create the build PipelineRun %v", pipelineRunObject), l.Action, l.ActionAdd)
 		return err
 	}
 
 	initialBuildPipelineCreationTimeMetric.Observe(time.Since(component.CreationTimestamp.Time).Seconds())
 
-	log.Info(fmt.Sprintf("Build pipeline %s created for component %s in %s namespace using %s pipeline from %s bundle",
-		initialBuildPipelineRun.Name, component.Name, component.Namespace, pipelineRef.Name, pipelineRef.Bundle),
+	log.Info(fmt.Sprintf("Build pipeline %s created for component %s in %s namespace using %s",
+		initialBuildPipelineRun.Name, component.Name, component.Namespace, describePipelineRef(pipelineRef)),
 		l.Action, l.ActionAdd, l.Audit, "true")
 
 	return nil
 }
 
-func getGitSourceShaForComponent(component *appstudiov1alpha1.Component, pacConfig map[string][]byte) (string, error) {
+// getGitClientForSimpleBuild creates git client to read the component repository.
+// In case of GitHub Application, any installation of the application is used.
+func getGitClientForSimpleBuild(component *appstudiov1alpha1.Component, pacConfig map[string][]byte) (gitprovider.GitProvider, error) {
 	gitProvider, err := gitops.GetGitProvider(*component)
 	if err != nil {
 		// There is no point to continue if git provider is not known
-		return "", fmt.Errorf("error detecting git provider: %w", err)
-	}
-
-	isAppUsed := gitops.IsPaCApplicationConfigured(gitProvider, pacConfig)
-
-	var accessToken string
-	if !isAppUsed {
-		accessToken = strings.TrimSpace(string(pacConfig[gitops.GetProviderTokenKey(gitProvider)]))
+		return nil, fmt.Errorf("error detecting git provider: %w", err)
 	}
 
-	// https://github.com/owner/repository
-	gitSourceUrlParts := strings.Split(strings.TrimSuffix(component.Spec.Source.GitSource.URL, ".git"), "/")
-
-	switch gitProvider {
-	case "github":
-		owner := gitSourceUrlParts[3]
-		repository := gitSourceUrlParts[4]
-
-		var ghclient *github.GithubClient
-		if isAppUsed {
-			githubAppIdStr := string(pacConfig[gitops.PipelinesAsCode_githubAppIdKey])
-			githubAppId, err := strconv.ParseInt(githubAppIdStr, 10, 64)
-			if err != nil {
-				return "", fmt.Errorf("failed to convert %s to int: %w", githubAppIdStr, err)
-			}
-
-			privateKey := pacConfig[gitops.PipelinesAsCode_githubPrivateKey]
-			ghclient, err = github.NewGithubClientForSimpleBuildByApp(githubAppId, privateKey)
-			if err != nil {
-				return "", fmt.Errorf("failed to create GitHub client for simple build: %w", err)
-			}
-		} else {
-			ghclient = github.NewGithubClient(accessToken)
-		}
-
-		branchName := component.Spec.Source.GitSource.Revision
-		if branchName == "" {
-			branchName, err = github.GetDefaultBranch(ghclient, owner, repository)
-			if err != nil {
-				return "", nil
-			}
-		}
+	return gitproviderfactory.CreateGitClient(gitproviderfactory.GitClientConfig{
+		PacSecretData:             pacConfig,
+		GitProvider:               gitProvider,
+		RepoUrl:                   component.Spec.Source.GitSource.URL,
+		IsAppInstallationExpected: false,
+	})
+}
 
-		return github.GetBranchSHA(ghclient, owner, repository, branchName)
+func getGitSourceShaForComponent(component *appstudiov1alpha1.Component, gitClient gitprovider.GitProvider) (string, error) {
+	repoUrl := component.Spec.Source.GitSource.URL
 
-	case "gitlab":
-		glclient, err := gitlab.NewGitlabClient(accessToken)
+	branchName := component.Spec.Source.GitSource.Revision
+	if branchName == "" {
+		var err error
+		branchName, err = gitClient.GetDefaultBranch(repoUrl)
 		if err != nil {
 			return "", err
 		}
-
-		gitlabNamespace := gitSourceUrlParts[3]
-		gitlabProjectName := gitSourceUrlParts[4]
-		projectPath := gitlabNamespace + "/" + gitlabProjectName
-
-		branchName := component.Spec.Source.GitSource.Revision
-		if branchName == "" {
-			branchName, err = gitlab.GetDefaultBranch(glclient, projectPath)
-			if err != nil {
-				return "", nil
-			}
-		}
-
-		return gitlab.GetBranchSHA(glclient, projectPath, branchName)
-
-	case "bitbucket":
-		// TODO implement
-		return "", fmt.Errorf("git provider %s is not supported", gitProvider)
-	default:
-		return "", fmt.Errorf("git provider %s is not supported", gitProvider)
 	}
+
+	return gitClient.GetBranchSha(repoUrl, branchName)
 }
 
-func generateInitialPipelineRunForComponent(component *appstudiov1alpha1.Component, pipelineRef *tektonapi.PipelineRef, additionalPipelineParams []tektonapi.Param, gitSourceSHA string) (*tektonapi.PipelineRun, error) {
+func generateInitialPipelineRunForComponent(component *appstudiov1alpha1.Component, pipelineRef *tektonapi.PipelineRef, resolvedPipeline *pipelineresolver.ResolvedPipeline, additionalPipelineParams []tektonapi.Param, gitSourceSHA, gitRepoAtShaLink string, log logr.Logger) (*tektonapi.PipelineRun, error) {
 	timestamp := time.Now().Unix()
 	pipelineGenerateName := fmt.Sprintf("%s-", component.Name)
 	revision := ""
@@ -231,9 +212,13 @@ func generateInitialPipelineRunForComponent(component *appstudiov1alpha1.Compone
 		revision = component.Spec.Source.GitSource.Revision
 	}
 
+	pipelineName, pipelineBundle := getPipelineNameAndBundle(pipelineRef)
 	annotations := map[string]string{
-		"build.appstudio.redhat.com/pipeline_name": pipelineRef.Name,
-		"build.appstudio.redhat.com/bundle":        pipelineRef.Bundle,
+		"build.appstudio.redhat.com/pipeline_name": pipelineName,
+		"build.appstudio.redhat.com/bundle":        pipelineBundle,
+	}
+	if resolvedPipeline.Digest != "" {
+		annotations[pipelineBundleDigestAnnotationName] = resolvedPipeline.Digest
 	}
 	if revision != "" {
 		annotations[gitTargetBranchAnnotationName] = revision
@@ -267,8 +252,20 @@ func generateInitialPipelineRunForComponent(component *appstudiov1alpha1.Compone
 		}
 	}
 
+	// Do not pass generated params the pipeline doesn't support
+	params = filterPipelineParams(params, resolvedPipeline.Spec, log)
 	params = mergeAndSortTektonParams(params, additionalPipelineParams)
 
+	workspaces := []tektonapi.WorkspaceBinding{}
+	for _, workspace := range resolvedPipeline.Spec.Workspaces {
+		if workspace.Name == "workspace" {
+			workspaces = append(workspaces, tektonapi.WorkspaceBinding{
+				Name:                workspace.Name,
+				VolumeClaimTemplate: generateVolumeClaimTemplate(),
+			})
+		}
+	}
+
 	pipelineRun := &tektonapi.PipelineRun{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "PipelineRun",
@@ -287,49 +284,22 @@ func generateInitialPipelineRunForComponent(component *appstudiov1alpha1.Compone
 		Spec: tektonapi.PipelineRunSpec{
 			PipelineRef: pipelineRef,
 			Params:      params,
-			Workspaces: []tektonapi.WorkspaceBinding{
-				{
-					Name:                "workspace",
-					VolumeClaimTemplate: generateVolumeClaimTemplate(),
-				},
-			},
+			Workspaces:  workspaces,
 		},
 	}
+	if resolvedPipeline.Bundle != "" {
+		// Let the cluster pull the bundle from the registry mirror it was found in, annotations keep the canonical reference
+		pipelineRun.Spec.PipelineRef = setPipelineBundle(pipelineRef, resolvedPipeline.Bundle)
+	}
 
 	if gitSourceSHA != "" {
 		pipelineRun.Annotations[gitCommitShaAnnotationName] = gitSourceSHA
-
-		gitProvider, _ := gitops.GetGitProvider(*component)
-		switch gitProvider {
-		case "github":
-			pipelineRun.Annotations[gitRepoAtShaAnnotationName] = github.GetBrowseRepositoryAtShaLink(component.Spec.Source.GitSource.URL, gitSourceSHA)
-		case "gitlab":
-			pipelineRun.Annotations[gitRepoAtShaAnnotationName] = gitlab.GetBrowseRepositoryAtShaLink(component.Spec.Source.GitSource.URL, gitSourceSHA)
-		}
 	}
-
-	return pipelineRun, nil
-}
-
-// getGitProviderUrl takes a Git URL and returns git provider host.
-// Examples:
-//
-//	For https://github.com/foo/bar returns https://github.com
-//	For git@github.com:foo/bar returns https://github.com
-func getGitProviderUrl(gitURL string) (string, error) {
-	if strings.HasPrefix(gitURL, "git@") {
-		host := strings.Split(strings.TrimPrefix(gitURL, "git@"), ":")[0]
-		return "https://" + host, nil
+	if gitRepoAtShaLink != "" {
+		pipelineRun.Annotations[gitRepoAtShaAnnotationName] = gitRepoAtShaLink
 	}
 
-	u, err := url.Parse(gitURL)
-
-	// We really need the format of the string to be correct.
-	// We'll not do any autocorrection.
-	if err != nil || u.Scheme == "" {
-		return "", fmt.Errorf("failed to parse string into a URL: %v or scheme is empty", err)
-	}
-	return u.Scheme + "://" + u.Host, nil
+	return pipelineRun, nil
 }
 
 func getRandomString(length int) string {
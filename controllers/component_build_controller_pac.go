@@ -23,48 +23,53 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
-	"github.com/google/go-containerregistry/pkg/authn"
 	pacv1alpha1 "github.com/openshift-pipelines/pipelines-as-code/pkg/apis/pipelinesascode/v1alpha1"
 	routev1 "github.com/openshift/api/route/v1"
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
 	"github.com/redhat-appstudio/application-service/gitops"
 	gitopsprepare "github.com/redhat-appstudio/application-service/gitops/prepare"
 	"github.com/redhat-appstudio/application-service/pkg/devfile"
+	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitproviderfactory"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitrepourl"
 	"github.com/redhat-appstudio/build-service/pkg/github"
 	"github.com/redhat-appstudio/build-service/pkg/gitlab"
 	l "github.com/redhat-appstudio/build-service/pkg/logs"
+	pipelineresolver "github.com/redhat-appstudio/build-service/pkg/pipeline-resolver"
 	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
-	oci "github.com/tektoncd/pipeline/pkg/remote/oci"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
-	"sigs.k8s.io/yaml"
-
-	gogithub "github.com/google/go-github/v45/github"
-	gogitlab "github.com/xanzy/go-gitlab"
 )
 
 const (
-	pipelineRunOnPushSuffix          = "-on-push"
-	pipelineRunOnPRSuffix            = "-on-pull-request"
-	pipelineRunOnPushFilename        = "push.yaml"
-	pipelineRunOnPRFilename          = "pull-request.yaml"
+	pipelineRunOnPushSuffix = "-on-push"
+	pipelineRunOnPRSuffix   = "-on-pull-request"
+	// pacConfigDirectory is where Pipelines as Code looks for PipelineRun definitions in the repository
+	pacConfigDirectory               = ".tekton/"
+	pipelineRunOnPushEvent           = "push"
+	pipelineRunOnPREvent             = "pull-request"
 	pipelineRunOnPRExpirationEnvVar  = "IMAGE_TAG_ON_PR_EXPIRATION"
 	pipelineRunOnPRExpirationDefault = "5d"
 	pipelinesAsCodeNamespace         = "openshift-pipelines"
 	pipelinesAsCodeNamespaceFallback = "pipelines-as-code"
 	pipelinesAsCodeRouteName         = "pipelines-as-code-controller"
 	pipelinesAsCodeRouteEnvVar       = "PAC_WEBHOOK_URL"
+	// pacWebhookSSLVerificationEnvVar enables or disables TLS verification of PaC webhook, enabled by default
+	pacWebhookSSLVerificationEnvVar = "PAC_WEBHOOK_SSL_VERIFICATION"
+	// pacWebhookEventsEnvVar is comma separated list of events to subscribe PaC webhook to: pull_request, push, tag_push, comment
+	pacWebhookEventsEnvVar = "PAC_WEBHOOK_EVENTS"
 
 	pacMergeRequestSourceBranchPrefix = "appstudio-"
 
@@ -85,26 +90,27 @@ To customize the proposed PipelineRuns after merge, please refer to [Build Pipel
 // ProvisionPaCForComponent does Pipelines as Code provision for the given component.
 // Mainly, it creates PaC configuration merge request into the component source repositotiry.
 // If GitHub PaC application is not configured, creates a webhook for PaC.
-func (r *ComponentBuildReconciler) ProvisionPaCForComponent(ctx context.Context, component *appstudiov1alpha1.Component) error {
+// Returns the onboarding merge request or nil if the configuration is already up to date.
+func (r *ComponentBuildReconciler) ProvisionPaCForComponent(ctx context.Context, component *appstudiov1alpha1.Component) (*gitprovider.MergeRequest, error) {
 	log := ctrllog.FromContext(ctx).WithName("PaC-setup")
 	ctx = ctrllog.IntoContext(ctx, log)
 
 	gitProvider, err := gitops.GetGitProvider(*component)
 	if err != nil {
 		// Do not reconcile, because configuration must be fixed before it is possible to proceed.
-		return boerrors.NewBuildOpError(boerrors.EUnknownGitProvider,
+		return nil, boerrors.NewBuildOpError(boerrors.EUnknownGitProvider,
 			fmt.Errorf("error detecting git provider: %w", err))
 	}
 
 	pacSecret, err := r.ensurePaCSecret(ctx, component, gitProvider)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := validatePaCConfiguration(gitProvider, pacSecret.Data); err != nil {
 		r.EventRecorder.Event(pacSecret, "Warning", "ErrorValidatingPaCSecret", err.Error())
 		// Do not reconcile, because configuration must be fixed before it is possible to proceed.
-		return boerrors.NewBuildOpError(boerrors.EPaCSecretInvalid,
+		return nil, boerrors.NewBuildOpError(boerrors.EPaCSecretInvalid,
 			fmt.Errorf("invalid configuration in Pipelines as Code secret: %w", err))
 	}
 
@@ -114,41 +120,41 @@ func (r *ComponentBuildReconciler) ProvisionPaCForComponent(ctx context.Context,
 		// and stores it in the corresponding k8s secret.
 		webhookSecretString, err = r.ensureWebhookSecret(ctx, component)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// Obtain Pipelines as Code callback URL
 		webhookTargetUrl, err = r.getPaCWebhookTargetUrl(ctx)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	if err := r.ensurePaCRepository(ctx, component, pacSecret.Data); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Manage merge request for Pipelines as Code configuration
-	mrUrl, err := r.ConfigureRepositoryForPaC(ctx, component, pacSecret.Data, webhookTargetUrl, webhookSecretString)
+	mr, err := r.ConfigureRepositoryForPaC(ctx, component, pacSecret.Data, webhookTargetUrl, webhookSecretString)
 	if err != nil {
 		r.EventRecorder.Event(component, "Warning", "ErrorConfiguringPaCForComponentRepository", err.Error())
-		return err
+		return nil, err
 	}
 	var mrMessage string
-	if mrUrl != "" {
-		mrMessage = fmt.Sprintf("Pipelines as Code configuration merge request: %s", mrUrl)
+	if mr != nil {
+		mrMessage = fmt.Sprintf("Pipelines as Code configuration merge request: %s", mr.WebUrl)
 	} else {
 		mrMessage = "Pipelines as Code configuration is up to date"
 	}
 	log.Info(mrMessage)
 	r.EventRecorder.Event(component, "Normal", "PipelinesAsCodeConfiguration", mrMessage)
 
-	if mrUrl != "" {
+	if mr != nil {
 		// PaC PR has been just created
 		pipelinesAsCodeComponentProvisionTimeMetric.Observe(time.Since(component.CreationTimestamp.Time).Seconds())
 	}
 
-	return nil
+	return mr, nil
 }
 
 // UndoPaCProvisionForComponent creates merge request that removes Pipelines as Code configuration from component source repository.
@@ -164,6 +170,10 @@ func (r *ComponentBuildReconciler) UndoPaCProvisionForComponent(ctx context.Cont
 		// There is no point to continue if git provider is not known.
 		return
 	}
+	if !isGlobalPaCSecretAllowed(component.Spec.Source.GitSource.URL, gitProvider) {
+		log.Info("Skipping Pipelines as Code cleanup, global git provider credentials are not used for self-hosted instances")
+		return
+	}
 
 	pacSecret := corev1.Secret{}
 	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: buildServiceNamespaceName, Name: gitopsprepare.PipelinesAsCodeSecretName}, &pacSecret); err != nil {
@@ -195,6 +205,8 @@ func (r *ComponentBuildReconciler) UndoPaCProvisionForComponent(ctx context.Cont
 		}
 	} else if action == "close" {
 		log.Info(fmt.Sprintf("Pipelines as Code configuration merge request has been closed: %s", mrUrl))
+	} else if action == "keep" {
+		log.Info(fmt.Sprintf("Pipelines as Code configuration merge request is kept for other components: %s", mrUrl))
 	}
 }
 
@@ -212,6 +224,11 @@ func (r *ComponentBuildReconciler) ensurePaCSecret(ctx context.Context, componen
 		}
 
 		// Fallback to the global configuration
+		if !isGlobalPaCSecretAllowed(component.Spec.Source.GitSource.URL, gitProvider) {
+			// Do not trigger a new reconcile. The PaC secret must be created first.
+			return nil, boerrors.NewBuildOpError(boerrors.EPaCSecretNotFound,
+				fmt.Errorf(" Pipelines as Code secret not found in %s namespace, global secret is not used for self-hosted git provider instances", pacSecretKey.Namespace))
+		}
 		globalPaCSecretKey := types.NamespacedName{Namespace: buildServiceNamespaceName, Name: gitopsprepare.PipelinesAsCodeSecretName}
 		if err := r.Client.Get(ctx, globalPaCSecretKey, &pacSecret); err != nil {
 			if !errors.IsNotFound(err) {
@@ -248,6 +265,25 @@ func (r *ComponentBuildReconciler) ensurePaCSecret(ctx context.Context, componen
 	return &pacSecret, nil
 }
 
+// publicGitProviderHosts maps git providers to hosts of their SaaS instances.
+var publicGitProviderHosts = map[string]string{
+	"github":    "github.com",
+	"gitlab":    "gitlab.com",
+	"bitbucket": "bitbucket.org",
+}
+
+// isGlobalPaCSecretAllowed checks if the global Pipelines as Code secret may be used for the given repository.
+// Credentials from the global secret are sent only to SaaS instances of git providers,
+// because a self-hosted instance could be controlled by anyone who creates a component.
+func isGlobalPaCSecretAllowed(repoUrl, gitProvider string) bool {
+	gitRepoUrl, err := gitrepourl.ParseGitRepoUrl(repoUrl)
+	if err != nil {
+		return false
+	}
+	publicHost, exists := publicGitProviderHosts[gitProvider]
+	return exists && strings.EqualFold(gitRepoUrl.Host, publicHost)
+}
+
 // Returns webhook secret for given component.
 // Generates the webhook secret and saves it the k8s secret if doesn't exist.
 func (r *ComponentBuildReconciler) ensureWebhookSecret(ctx context.Context, component *appstudiov1alpha1.Component) (string, error) {
@@ -288,6 +324,7 @@ func (r *ComponentBuildReconciler) ensureWebhookSecret(ctx context.Context, comp
 		webhookSecretsSecret.Data = make(map[string][]byte)
 	}
 	webhookSecretsSecret.Data[componentWebhookSecretKey] = []byte(webhookSecretString)
+	setWebhookSecretRotationTime(webhookSecretsSecret, componentWebhookSecretKey, time.Now())
 	if err := r.Client.Update(ctx, webhookSecretsSecret); err != nil {
 		log.Error(err, "failed to update webhook secrets secret", l.Action, l.ActionUpdate)
 		return "", err
@@ -321,6 +358,73 @@ func (r *ComponentBuildReconciler) getPaCWebhookTargetUrl(ctx context.Context) (
 	return webhookTargetUrl, nil
 }
 
+// getPaCWebhookOptions returns PaC webhook settings for the given component.
+// Global configuration is taken from the operator environment and could be overridden by the component annotations.
+// Invalid values are ignored.
+func getPaCWebhookOptions(ctx context.Context, component *appstudiov1alpha1.Component) *gitprovider.WebhookOptions {
+	log := ctrllog.FromContext(ctx)
+
+	webhookOptions := &gitprovider.WebhookOptions{
+		SSLVerification: true,
+		Events:          gitprovider.DefaultWebhookEvents,
+	}
+	configSources := []struct {
+		sslVerificationSource string
+		sslVerification       string
+		eventsSource          string
+		events                string
+	}{
+		{
+			sslVerificationSource: pacWebhookSSLVerificationEnvVar + " envVar",
+			sslVerification:       os.Getenv(pacWebhookSSLVerificationEnvVar),
+			eventsSource:          pacWebhookEventsEnvVar + " envVar",
+			events:                os.Getenv(pacWebhookEventsEnvVar),
+		},
+		{
+			sslVerificationSource: PaCWebhookSSLVerificationAnnotationName + " annotation",
+			sslVerification:       component.Annotations[PaCWebhookSSLVerificationAnnotationName],
+			eventsSource:          PaCWebhookEventsAnnotationName + " annotation",
+			events:                component.Annotations[PaCWebhookEventsAnnotationName],
+		},
+	}
+	for _, config := range configSources {
+		if config.sslVerification != "" {
+			if sslVerification, err := strconv.ParseBool(config.sslVerification); err == nil {
+				webhookOptions.SSLVerification = sslVerification
+			} else {
+				log.Info(fmt.Sprintf("invalid webhook SSL verification '%s' in %s, ignoring", config.sslVerification, config.sslVerificationSource))
+			}
+		}
+		if config.events != "" {
+			if events, err := parseWebhookEvents(config.events); err == nil {
+				webhookOptions.Events = events
+			} else {
+				log.Info(fmt.Sprintf("invalid webhook events '%s' in %s, ignoring: %s", config.events, config.eventsSource, err.Error()))
+			}
+		}
+	}
+	return webhookOptions
+}
+
+// parseWebhookEvents parses comma separated list of git provider independent webhook events.
+func parseWebhookEvents(eventsList string) ([]string, error) {
+	var events []string
+	for _, event := range strings.Split(eventsList, ",") {
+		event = strings.TrimSpace(event)
+		if event == "" {
+			continue
+		}
+		if !gitprovider.IsValidWebhookEvent(event) {
+			return nil, fmt.Errorf("unknown event %s", event)
+		}
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no events given")
+	}
+	return events, nil
+}
+
 // getPaCRoutePublicUrl returns Pipelines as Code public route that recieves events to trigger new pipeline runs.
 func (r *ComponentBuildReconciler) getPaCRoutePublicUrl(ctx context.Context) (string, error) {
 	pacWebhookRoute := &routev1.Route{}
@@ -414,6 +518,30 @@ func (r *ComponentBuildReconciler) ensurePaCRepository(ctx context.Context, comp
 	if err != nil {
 		return err
 	}
+	if repository.Spec.GitProvider != nil {
+		gitProvider, _ := gitops.GetGitProvider(*component)
+		switch gitProvider {
+		case "github":
+			// Pipelines as Code needs to know API URL of GitHub Enterprise Server instance
+			githubUrl, err := gitrepourl.GetGitProviderUrl(component.Spec.Source.GitSource.URL)
+			if err != nil {
+				return err
+			}
+			if githubUrl != github.GithubComUrl {
+				repository.Spec.GitProvider.URL = github.GetApiUrl(githubUrl)
+			}
+		case "gitlab":
+			// Point Pipelines as Code to the GitLab instance that hosts the repository, it might be self-hosted one
+			baseUrl, _, err := gitlab.GetBaseUrlAndProjectPath(component.Spec.Source.GitSource.URL)
+			if err != nil {
+				return err
+			}
+			repository.Spec.GitProvider.URL = baseUrl
+		case "bitbucket":
+			// Bitbucket Cloud authenticates API calls by the user name and app password pair
+			repository.Spec.GitProvider.User = string(config["username"])
+		}
+	}
 
 	existingRepository := &pacv1alpha1.Repository{}
 	if err := r.Client.Get(ctx, types.NamespacedName{Name: repository.Name, Namespace: repository.Namespace}, existingRepository); err != nil {
@@ -435,40 +563,62 @@ func (r *ComponentBuildReconciler) ensurePaCRepository(ctx context.Context, comp
 
 // generatePaCPipelineRunConfigs generates PipelineRun YAML configs for given component.
 // The generated PipelineRun Yaml content are returned in byte string and in the order of push and pull request.
-func (r *ComponentBuildReconciler) generatePaCPipelineRunConfigs(ctx context.Context, component *appstudiov1alpha1.Component, pacTargetBranch string) ([]byte, []byte, error) {
+func (r *ComponentBuildReconciler) generatePaCPipelineRunConfigs(ctx context.Context, component *appstudiov1alpha1.Component, gitClient gitprovider.GitProvider, pacTargetBranch string) ([]byte, []byte, error) {
 	log := ctrllog.FromContext(ctx)
 
-	pipelineRef, additionalPipelineParams, err := r.GetPipelineForComponent(ctx, component)
+	pipelineRef, additionalPipelineParams, pipelineReference, err := r.GetPipelineForComponent(ctx, component)
 	if err != nil {
 		return nil, nil, err
 	}
-	log.Info(fmt.Sprintf("Selected %s pipeline from %s bundle for %s component",
-		pipelineRef.Name, pipelineRef.Bundle, component.Name),
+	log.Info(fmt.Sprintf("Selected %s for %s component", describePipelineRef(pipelineRef), component.Name),
 		l.Audit, "true")
 
-	// Get pipeline from the bundle to be expanded to the PipelineRun
-	pipelineSpec, err := retrievePipelineSpec(pipelineRef.Bundle, pipelineRef.Name)
+	pacPipelineRef, err := getPaCPipelineRef(pipelineRef, pipelineReference)
+	if err != nil {
+		r.EventRecorder.Event(component, "Warning", "ErrorGettingPipelineReference", err.Error())
+		return nil, nil, err
+	}
+
+	// Get pipeline definition to be expanded to the PipelineRun.
+	// The definition is needed even if the pipeline is referenced, to bind workspaces and pass params it declares.
+	// A referenced pipeline is resolved from the same location the PipelineRun references,
+	// e.g. from the git repository configured in the selector, which may differ from the selected pipeline.
+	pipelineRefToResolve := pipelineRef
+	if pacPipelineRef != nil {
+		pipelineRefToResolve = pacPipelineRef
+	}
+	resolvedPipeline, err := r.PipelineResolverClient.GetPipeline(ctx, pipelineRefToResolve, component.Namespace)
 	if err != nil {
 		r.EventRecorder.Event(component, "Warning", "ErrorGettingPipelineFromBundle", err.Error())
 		return nil, nil, err
 	}
+	if pacPipelineRef != nil && resolvedPipeline.Digest != "" {
+		// Pin the referenced bundle to the digest the definition was taken from,
+		// so the PipelineRun doesn't run a different pipeline if the tag is moved.
+		pacPipelineRef, err = pinPaCPipelineBundle(pacPipelineRef, resolvedPipeline.Digest)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	apiVersion := getPipelineRunApiVersion(log)
 
 	pipelineRunOnPush, err := generatePaCPipelineRunForComponent(
-		component, pipelineSpec, additionalPipelineParams, false, pacTargetBranch, log)
+		component, resolvedPipeline, pacPipelineRef, additionalPipelineParams, false, pacTargetBranch, gitClient, log)
 	if err != nil {
 		return nil, nil, err
 	}
-	pipelineRunOnPushYaml, err := yaml.Marshal(pipelineRunOnPush)
+	pipelineRunOnPushYaml, err := marshalPipelineRun(pipelineRunOnPush, apiVersion)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	pipelineRunOnPR, err := generatePaCPipelineRunForComponent(
-		component, pipelineSpec, additionalPipelineParams, true, pacTargetBranch, log)
+		component, resolvedPipeline, pacPipelineRef, additionalPipelineParams, true, pacTargetBranch, gitClient, log)
 	if err != nil {
 		return nil, nil, err
 	}
-	pipelineRunOnPRYaml, err := yaml.Marshal(pipelineRunOnPR)
+	pipelineRunOnPRYaml, err := marshalPipelineRun(pipelineRunOnPR, apiVersion)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -476,184 +626,172 @@ func (r *ComponentBuildReconciler) generatePaCPipelineRunConfigs(ctx context.Con
 	return pipelineRunOnPushYaml, pipelineRunOnPRYaml, nil
 }
 
-func generateMergeRequestSourceBranch(component *appstudiov1alpha1.Component) string {
-	return fmt.Sprintf("%s%s", pacMergeRequestSourceBranchPrefix, component.Name)
+// generatePaCFilesForComponent returns .tekton files with PipelineRun definitions of the given component.
+// User modifications of the definitions which already exist in the base branch are preserved.
+func (r *ComponentBuildReconciler) generatePaCFilesForComponent(ctx context.Context, component *appstudiov1alpha1.Component, gitClient gitprovider.GitProvider, baseBranch string) ([]gitprovider.RepositoryFile, error) {
+	pipelineRunOnPushPath, pipelineRunOnPRPath, err := r.getPipelineRunPaths(ctx, component, baseBranch)
+	if err != nil {
+		return nil, err
+	}
+	pipelineRunOnPushYaml, pipelineRunOnPRYaml, err := r.generatePaCPipelineRunConfigs(ctx, component, gitClient, baseBranch)
+	if err != nil {
+		return nil, err
+	}
+	files := []gitprovider.RepositoryFile{
+		{FullPath: pipelineRunOnPushPath, Content: pipelineRunOnPushYaml},
+		{FullPath: pipelineRunOnPRPath, Content: pipelineRunOnPRYaml},
+	}
+	for i := range files {
+		// Do not overwrite user modifications of the PipelineRun definitions
+		files[i].Content, err = preservePipelineRunCustomizations(ctx, gitClient, component.Spec.Source.GitSource.URL, baseBranch, files[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
 }
 
 // ConfigureRepositoryForPaC creates a merge request with initial Pipelines as Code configuration
 // and configures a webhook to notify in-cluster PaC unless application (on the repository side) is used.
-func (r *ComponentBuildReconciler) ConfigureRepositoryForPaC(ctx context.Context, component *appstudiov1alpha1.Component, config map[string][]byte, webhookTargetUrl, webhookSecret string) (prUrl string, err error) {
+// Returns the merge request or nil if the configuration in the base branch is up to date.
+func (r *ComponentBuildReconciler) ConfigureRepositoryForPaC(ctx context.Context, component *appstudiov1alpha1.Component, config map[string][]byte, webhookTargetUrl, webhookSecret string) (*gitprovider.MergeRequest, error) {
 	log := ctrllog.FromContext(ctx).WithValues("repository", component.Spec.Source.GitSource.URL)
 	ctx = ctrllog.IntoContext(ctx, log)
 
 	gitProvider, _ := gitops.GetGitProvider(*component)
 	isAppUsed := gitops.IsPaCApplicationConfigured(gitProvider, config)
-
-	var accessToken string
-	if !isAppUsed {
-		accessToken = strings.TrimSpace(string(config[gitops.GetProviderTokenKey(gitProvider)]))
+	repoUrl := component.Spec.Source.GitSource.URL
+
+	gitClient, err := gitproviderfactory.CreateGitClient(gitproviderfactory.GitClientConfig{
+		PacSecretData:             config,
+		GitProvider:               gitProvider,
+		RepoUrl:                   repoUrl,
+		IsAppInstallationExpected: true,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// https://github.com/owner/repository
-	gitSourceUrlParts := strings.Split(strings.TrimSuffix(component.Spec.Source.GitSource.URL, ".git"), "/")
-
-	commitMessage := "Appstudio update " + component.Name
-	branch := generateMergeRequestSourceBranch(component)
-	mrTitle := "Appstudio update " + component.Name
-	mrText := mergeRequestDescription
-	authorName := "redhat-appstudio"
-	authorEmail := "rhtap@redhat.com"
+	var gitAppName, gitAppSlug string
+	if isAppUsed {
+		// Customize PR data to reflect git application name
+		if gitAppName, gitAppSlug, err = gitClient.GetConfiguredGitAppName(); err != nil {
+			log.Error(err, "failed to get git application name", l.Action, l.ActionView, l.Audit, "true")
+			// Do not fail PaC provision if failed to read git application info
+		}
+	} else {
+		// Webhook
+		err = gitClient.SetupPaCWebhook(repoUrl, webhookTargetUrl, webhookSecret, getPaCWebhookOptions(ctx, component))
+		if err != nil {
+			log.Error(err, fmt.Sprintf("failed to setup Pipelines as Code webhook %s", webhookTargetUrl), l.Audit, "true")
+			return nil, err
+		} else {
+			log.Info(fmt.Sprintf("Pipelines as Code webhook \"%s\" configured for %s Component in %s namespace",
+				webhookTargetUrl, component.GetName(), component.GetNamespace()),
+				l.Audit, "true")
+		}
+	}
 
 	var baseBranch string
 	if component.Spec.Source.GitSource != nil {
 		baseBranch = component.Spec.Source.GitSource.Revision
 	}
-
-	switch gitProvider {
-	case "github":
-		owner := gitSourceUrlParts[3]
-		repository := gitSourceUrlParts[4]
-
-		var ghclient *github.GithubClient
-		if isAppUsed {
-			githubAppIdStr := string(config[gitops.PipelinesAsCode_githubAppIdKey])
-			githubAppId, err := strconv.ParseInt(githubAppIdStr, 10, 64)
-			if err != nil {
-				return "", fmt.Errorf("failed to convert %s to int: %w", githubAppIdStr, err)
-			}
-
-			privateKey := config[gitops.PipelinesAsCode_githubPrivateKey]
-			ghclient, err = github.NewGithubClientByApp(githubAppId, privateKey, owner)
-			if err != nil {
-				return "", err
-			}
-
-			// Check if the application is installed into target repository
-			appInstalled, err := github.IsAppInstalledIntoRepository(ghclient, owner, repository)
-			if err != nil {
-				return "", err
-			}
-			if !appInstalled {
-				return "", boerrors.NewBuildOpError(boerrors.EGitHubAppNotInstalled, fmt.Errorf("GitHub Application is not installed into the repository"))
-			}
-
-			// Customize PR data to reflect GitHub App name
-			if appName, appSlug, err := github.GetGitHubAppName(githubAppId, privateKey); err == nil {
-				commitMessage = fmt.Sprintf("%s update %s", appName, component.Name)
-				mrTitle = fmt.Sprintf("%s update %s", appName, component.Name)
-				authorName = appSlug
-			} else {
-				log.Error(err, "failed to get GitHub Application name", l.Action, l.ActionView, l.Audit, "true")
-				// Do not fail PaC provision if failed to read GitHub App info
-			}
-
-		} else {
-			// Webhook
-			ghclient = github.NewGithubClient(accessToken)
-
-			err = github.SetupPaCWebhook(ghclient, webhookTargetUrl, webhookSecret, owner, repository)
-			if err != nil {
-				log.Error(err, fmt.Sprintf("failed to setup Pipelines as Code webhook %s", webhookTargetUrl), l.Audit, "true")
-				return "", err
-			} else {
-				log.Info(fmt.Sprintf("Pipelines as Code webhook \"%s\" configured for %s Component in %s namespace",
-					webhookTargetUrl, component.GetName(), component.GetNamespace()),
-					l.Audit, "true")
-			}
+	if baseBranch == "" {
+		baseBranch, err = gitClient.GetDefaultBranch(repoUrl)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		if baseBranch == "" {
-			baseBranch, err = github.GetDefaultBranch(ghclient, owner, repository)
-			if err != nil {
-				return "", nil
-			}
-		}
+	mrOptions, err := r.getMergeRequestOptions(ctx, component)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := r.generatePaCFilesForComponent(ctx, component, gitClient, baseBranch)
+	if err != nil {
+		return nil, err
+	}
 
-		pipelineRunOnPushYaml, pipelineRunOnPRYaml, err := r.generatePaCPipelineRunConfigs(ctx, component, baseBranch)
+	templateData := newMergeRequestTemplateData(component, gitAppName, gitAppSlug)
+	templateData.TargetBranch = baseBranch
+	var siblings []appstudiov1alpha1.Component
+	if mrOptions.Batch {
+		// Put configuration of all the components in the same repository and target branch into one merge request
+		siblings, err = r.getPaCBatchSiblings(ctx, component, gitClient, baseBranch)
 		if err != nil {
-			return "", err
-		}
-		prData := &github.PaCPullRequestData{
-			Owner:         owner,
-			Repository:    repository,
-			CommitMessage: commitMessage,
-			Branch:        branch,
-			BaseBranch:    baseBranch,
-			PRTitle:       mrTitle,
-			PRText:        mrText,
-			AuthorName:    authorName,
-			AuthorEmail:   authorEmail,
-			Files: []github.File{
-				{FullPath: ".tekton/" + component.Name + "-" + pipelineRunOnPushFilename, Content: pipelineRunOnPushYaml},
-				{FullPath: ".tekton/" + component.Name + "-" + pipelineRunOnPRFilename, Content: pipelineRunOnPRYaml},
-			},
+			return nil, err
 		}
-		prUrl, err = github.CreatePaCPullRequest(ghclient, prData)
-		if err != nil {
-			// Handle case when GitHub application is not installed for the component repository
-			if strings.Contains(err.Error(), "Resource not accessible by integration") {
-				return "", fmt.Errorf(" Pipelines as Code GitHub application with %s ID is not installed for %s repository",
-					string(config[gitops.PipelinesAsCode_githubAppIdKey]), component.Spec.Source.GitSource.URL)
-			}
-			return "", err
+		for _, sibling := range siblings {
+			templateData.ComponentNames = append(templateData.ComponentNames, sibling.Name)
 		}
+		sort.Strings(templateData.ComponentNames)
+		templateData.Batch = true
+	}
 
-		return prUrl, nil
+	mrMetadata, err := r.getOnboardingMergeRequestMetadata(ctx, component.Namespace, templateData)
+	if err != nil {
+		return nil, err
+	}
 
-	case "gitlab":
-		glclient, err := gitlab.NewGitlabClient(accessToken)
+	for i := range siblings {
+		// Definitions of the other components are kept in the merge request as they are, each component updates its own ones
+		siblingFiles, err := r.getPaCBatchSiblingFiles(ctx, &siblings[i], gitClient, mrMetadata.BranchName, baseBranch)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
+		files = append(files, siblingFiles...)
+	}
+	mrData := &gitprovider.MergeRequestData{
+		CommitMessage:  mrMetadata.CommitMessage,
+		BranchName:     mrMetadata.BranchName,
+		BaseBranchName: baseBranch,
+		Title:          mrMetadata.Title,
+		Text:           mrMetadata.Body,
+		AuthorName:     mrMetadata.AuthorName,
+		AuthorEmail:    mrMetadata.AuthorEmail,
+		Files:          files,
+		Labels:         mrOptions.Labels,
+		Reviewers:      mrOptions.Reviewers,
+		TeamReviewers:  mrOptions.TeamReviewers,
+		Assignees:      mrOptions.Assignees,
+		Draft:          mrOptions.Draft,
+	}
 
-		gitlabNamespace := gitSourceUrlParts[3]
-		gitlabProjectName := gitSourceUrlParts[4]
-		projectPath := gitlabNamespace + "/" + gitlabProjectName
-
-		err = gitlab.SetupPaCWebhook(glclient, projectPath, webhookTargetUrl, webhookSecret)
-		if err != nil {
-			log.Error(err, fmt.Sprintf("failed to setup Pipelines as Code webhook %s", webhookTargetUrl), l.Audit, "true")
-			return "", err
+	mr, err := gitClient.EnsurePaCMergeRequest(repoUrl, mrData)
+	if err != nil {
+		return nil, err
+	}
+	if mr == nil {
+		return nil, nil
+	}
+	prUrl := mr.WebUrl
+
+	if mrOptions.AutoMerge {
+		// Do not fail PaC provision if auto-merge cannot be enabled, the merge request could be merged manually
+		if err := enablePaCMergeRequestAutoMerge(gitClient, repoUrl, mr, mrData.Draft, mrOptions.MergeMethod); err != nil {
+			log.Error(err, fmt.Sprintf("failed to enable auto-merge of merge request %s", prUrl), l.Audit, "true")
+			r.EventRecorder.Event(component, "Warning", "ErrorEnablingPaCMergeRequestAutoMerge",
+				fmt.Sprintf("Failed to enable auto-merge of Pipelines as Code configuration merge request %s: %s", prUrl, err.Error()))
 		} else {
-			log.Info(fmt.Sprintf("Pipelines as Code webhook \"%s\" configured for %s Component in %s namespace",
-				webhookTargetUrl, component.GetName(), component.GetNamespace()),
-				l.Audit, "true")
-		}
-
-		if baseBranch == "" {
-			baseBranch, err = gitlab.GetDefaultBranch(glclient, projectPath)
-			if err != nil {
-				return "", nil
-			}
+			log.Info(fmt.Sprintf("auto-merge with %s method enabled for merge request %s", mrOptions.MergeMethod, prUrl), l.Audit, "true")
+			r.EventRecorder.Event(component, "Normal", "PaCMergeRequestAutoMergeEnabled",
+				fmt.Sprintf("Auto-merge enabled for Pipelines as Code configuration merge request: %s", prUrl))
 		}
+	}
 
-		pipelineRunOnPushYaml, pipelineRunOnPRYaml, err := r.generatePaCPipelineRunConfigs(ctx, component, baseBranch)
-		if err != nil {
-			return "", err
-		}
-		mrData := &gitlab.PaCMergeRequestData{
-			ProjectPath:   projectPath,
-			CommitMessage: commitMessage,
-			Branch:        branch,
-			BaseBranch:    baseBranch,
-			MrTitle:       mrTitle,
-			MrText:        mrText,
-			AuthorName:    authorName,
-			AuthorEmail:   authorEmail,
-			Files: []gitlab.File{
-				{FullPath: ".tekton/" + component.Name + "-" + pipelineRunOnPushFilename, Content: pipelineRunOnPushYaml},
-				{FullPath: ".tekton/" + component.Name + "-" + pipelineRunOnPRFilename, Content: pipelineRunOnPRYaml},
-			},
-		}
-		mrUrl, err := gitlab.EnsurePaCMergeRequest(glclient, mrData)
-		return mrUrl, err
+	return mr, nil
+}
 
-	case "bitbucket":
-		// TODO implement
-		return "", fmt.Errorf("git provider %s is not supported", gitProvider)
-	default:
-		return "", fmt.Errorf("git provider %s is not supported", gitProvider)
+// enablePaCMergeRequestAutoMerge makes the git provider merge the onboarding merge request once its checks pass.
+func enablePaCMergeRequestAutoMerge(gitClient gitprovider.GitProvider, repoUrl string, mr *gitprovider.MergeRequest, isDraft bool, mergeMethod string) error {
+	if isDraft {
+		return fmt.Errorf("draft merge request cannot be merged automatically")
+	}
+	if mr.Number == 0 {
+		return fmt.Errorf("merge request number is unknown")
 	}
+	return gitClient.EnablePaCMergeRequestAutoMerge(repoUrl, mr.Number, mergeMethod)
 }
 
 // UnconfigureRepositoryForPaC creates a merge request that deletes Pipelines as Code configuration of the diven component in its repository.
@@ -665,198 +803,150 @@ func (r *ComponentBuildReconciler) UnconfigureRepositoryForPaC(ctx context.Conte
 
 	gitProvider, _ := gitops.GetGitProvider(*component)
 	isAppUsed := gitops.IsPaCApplicationConfigured(gitProvider, config)
+	repoUrl := component.Spec.Source.GitSource.URL
+
+	gitClient, err := gitproviderfactory.CreateGitClient(gitproviderfactory.GitClientConfig{
+		PacSecretData:             config,
+		GitProvider:               gitProvider,
+		RepoUrl:                   repoUrl,
+		IsAppInstallationExpected: true,
+	})
+	if err != nil {
+		return "", "", err
+	}
 
-	var accessToken string
 	if !isAppUsed {
-		accessToken = strings.TrimSpace(string(config[gitops.GetProviderTokenKey(gitProvider)]))
+		if webhookTargetUrl != "" {
+			err = gitClient.DeletePaCWebhook(repoUrl, webhookTargetUrl)
+			if err != nil {
+				// Just log the error and continue with merge request creation
+				log.Error(err, fmt.Sprintf("failed to delete Pipelines as Code webhook %s", webhookTargetUrl), l.Action, l.ActionDelete, l.Audit, "true")
+			} else {
+				log.Info(fmt.Sprintf("Pipelines as Code webhook \"%s\" deleted for %s Component in %s namespace",
+					webhookTargetUrl, component.GetName(), component.GetNamespace()),
+					l.Action, l.ActionDelete)
+			}
+		}
 	}
 
-	// https://github.com/owner/repository
-	gitSourceUrlParts := strings.Split(strings.TrimSuffix(component.Spec.Source.GitSource.URL, ".git"), "/")
-
-	commitMessage := "Appstudio purge " + component.Name
-	branch := "appstudio-purge-" + component.Name
-	mrTitle := "Appstudio purge " + component.Name
-	mrText := "Pipelines as Code configuration removal"
-	authorName := "redhat-appstudio"
-	authorEmail := "appstudio@redhat.com"
-
 	var baseBranch string
 	if component.Spec.Source.GitSource != nil {
 		baseBranch = component.Spec.Source.GitSource.Revision
 	}
-
-	switch gitProvider {
-	case "github":
-		owner := gitSourceUrlParts[3]
-		repository := gitSourceUrlParts[4]
-
-		var ghclient *github.GithubClient
-		if isAppUsed {
-			githubAppIdStr := string(config[gitops.PipelinesAsCode_githubAppIdKey])
-			githubAppId, err := strconv.ParseInt(githubAppIdStr, 10, 64)
-			if err != nil {
-				return "", "", fmt.Errorf("failed to convert %s to int: %w", githubAppIdStr, err)
-			}
-
-			privateKey := config[gitops.PipelinesAsCode_githubPrivateKey]
-			ghclient, err = github.NewGithubClientByApp(githubAppId, privateKey, owner)
-			if err != nil {
-				return "", "", err
-			}
-		} else {
-			// Webhook
-			ghclient = github.NewGithubClient(accessToken)
-
-			if webhookTargetUrl != "" {
-				err = github.DeletePaCWebhook(ghclient, webhookTargetUrl, owner, repository)
-				if err != nil {
-					// Just log the error and continue with merge request creation
-					log.Error(err, fmt.Sprintf("failed to delete Pipelines as Code webhook %s", webhookTargetUrl), l.Action, l.ActionDelete, l.Audit, "true")
-				} else {
-					log.Info(fmt.Sprintf("Pipelines as Code webhook \"%s\" deleted for %s Component in %s namespace",
-						webhookTargetUrl, component.GetName(), component.GetNamespace()),
-						l.Action, l.ActionDelete)
-				}
-			}
-		}
-
-		if baseBranch == "" {
-			baseBranch, err = github.GetDefaultBranch(ghclient, owner, repository)
-			if err != nil {
-				return "", "", nil
-			}
-		}
-
-		sourceBranch := generateMergeRequestSourceBranch(component)
-		pullRequest, err := github.FindUnmergedOnboardingMergeRequest(ghclient, owner, repository, sourceBranch, baseBranch, owner)
+	if baseBranch == "" {
+		baseBranch, err = gitClient.GetDefaultBranch(repoUrl)
 		if err != nil {
 			return "", "", err
 		}
+	}
 
-		if pullRequest == nil {
-			prData := &github.PaCPullRequestData{
-				Owner:         owner,
-				Repository:    repository,
-				CommitMessage: commitMessage,
-				Branch:        branch,
-				BaseBranch:    baseBranch,
-				PRTitle:       mrTitle,
-				PRText:        mrText,
-				AuthorName:    authorName,
-				AuthorEmail:   authorEmail,
-				Files: []github.File{
-					{FullPath: ".tekton/" + component.Name + "-" + pipelineRunOnPushFilename},
-					{FullPath: ".tekton/" + component.Name + "-" + pipelineRunOnPRFilename},
-				},
-			}
-			prUrl, err = github.UndoPaCPullRequest(ghclient, prData)
-			if err != nil {
-				// Handle case when GitHub application is not installed for the component repository
-				if strings.Contains(err.Error(), "Resource not accessible by integration") {
-					return "", "", fmt.Errorf(" Pipelines as Code GitHub application with %s ID is not installed for %s repository",
-						string(config[gitops.PipelinesAsCode_githubAppIdKey]), component.Spec.Source.GitSource.URL)
-				}
-				return "", "", err
-			}
-			return prUrl, "delete", nil
-		} else {
-			err := github.DeleteBranch(ghclient, owner, repository, sourceBranch)
-			if err == nil {
-				log.Info(fmt.Sprintf("pull request source branch %s is deleted", sourceBranch), l.Action, l.ActionDelete)
-				return prUrl, "close", nil
-			}
-			// Non-existing source branch should not be an error, just ignore it
-			// but other errors should be handled.
-			if ghErrResp, ok := err.(*gogithub.ErrorResponse); ok {
-				if ghErrResp.Response.StatusCode == 422 {
-					log.Info(fmt.Sprintf("Tried to delete source branch %s, but it does not exist in the repository", sourceBranch))
-					return prUrl, "close", nil
-				}
-			}
-			return "", "", err
+	var gitAppName, gitAppSlug string
+	if isAppUsed {
+		if gitAppName, gitAppSlug, err = gitClient.GetConfiguredGitAppName(); err != nil {
+			// Do not fail PaC cleanup if failed to read git application info
+			log.Error(err, "failed to get git application name", l.Action, l.ActionView)
 		}
-
-	case "gitlab":
-		glclient, err := gitlab.NewGitlabClient(accessToken)
+	}
+	mrOptions, err := r.getMergeRequestOptions(ctx, component)
+	if err != nil {
+		return "", "", err
+	}
+	templateData := newMergeRequestTemplateData(component, gitAppName, gitAppSlug)
+	templateData.TargetBranch = baseBranch
+	var batchSiblings []appstudiov1alpha1.Component
+	if mrOptions.Batch {
+		batchSiblings, err = r.getPaCBatchSiblings(ctx, component, gitClient, baseBranch)
 		if err != nil {
 			return "", "", err
 		}
+		for _, sibling := range batchSiblings {
+			templateData.ComponentNames = append(templateData.ComponentNames, sibling.Name)
+		}
+		sort.Strings(templateData.ComponentNames)
+		templateData.Batch = true
+	}
+	mrMetadata, err := r.getPurgeMergeRequestMetadata(ctx, component.Namespace, templateData)
+	if err != nil {
+		return "", "", err
+	}
 
-		gitlabNamespace := gitSourceUrlParts[3]
-		gitlabProjectName := gitSourceUrlParts[4]
-		projectPath := gitlabNamespace + "/" + gitlabProjectName
+	sourceBranch := mrMetadata.OnboardingBranchName
+	onboardingMrData := &gitprovider.MergeRequestData{
+		BranchName:     sourceBranch,
+		BaseBranchName: baseBranch,
+		AuthorName:     mrMetadata.AuthorName,
+	}
+	mr, err := gitClient.FindUnmergedPaCMergeRequest(repoUrl, onboardingMrData)
+	if err != nil {
+		return "", "", err
+	}
 
-		err = gitlab.DeletePaCWebhook(glclient, projectPath, webhookTargetUrl)
+	if mr == nil {
+		// Onboarding merge request is merged, create a new one to remove the configuration
+		pipelineRunOnPushPath, pipelineRunOnPRPath, err := r.getPipelineRunPaths(ctx, component, baseBranch)
 		if err != nil {
-			// Just log the error and continue with merge request creation
-			log.Error(err, "failed to delete Pipelines as Code webhook", l.Action, l.ActionDelete, l.Audit, "true")
+			return "", "", err
 		}
-
-		if baseBranch == "" {
-			baseBranch, err = gitlab.GetDefaultBranch(glclient, projectPath)
-			if err != nil {
-				return "", "", nil
-			}
+		mrData := &gitprovider.MergeRequestData{
+			CommitMessage:  mrMetadata.CommitMessage,
+			BranchName:     mrMetadata.BranchName,
+			BaseBranchName: baseBranch,
+			Title:          mrMetadata.Title,
+			Text:           mrMetadata.Body,
+			AuthorName:     mrMetadata.AuthorName,
+			AuthorEmail:    mrMetadata.AuthorEmail,
+			Files: []gitprovider.RepositoryFile{
+				{FullPath: pipelineRunOnPushPath},
+				{FullPath: pipelineRunOnPRPath},
+			},
 		}
-
-		sourceBranch := generateMergeRequestSourceBranch(component)
-		mr, err := gitlab.FindUnmergedOnboardingMergeRequest(glclient, projectPath, sourceBranch, baseBranch, authorName)
+		prUrl, err = gitClient.UndoPaCMergeRequest(repoUrl, mrData)
 		if err != nil {
 			return "", "", err
 		}
+		return prUrl, "delete", nil
+	}
 
-		if mr == nil {
-			mrData := &gitlab.PaCMergeRequestData{
-				ProjectPath:   projectPath,
-				CommitMessage: commitMessage,
-				Branch:        branch,
-				BaseBranch:    baseBranch,
-				MrTitle:       mrTitle,
-				MrText:        mrText,
-				AuthorName:    authorName,
-				AuthorEmail:   authorEmail,
-				Files: []gitlab.File{
-					{FullPath: ".tekton/" + component.Name + "-" + pipelineRunOnPushFilename},
-					{FullPath: ".tekton/" + component.Name + "-" + pipelineRunOnPRFilename},
-				},
-			}
-			mrUrl, err := gitlab.UndoPaCMergeRequest(glclient, mrData)
-			if err != nil {
-				return "", "", err
-			}
-			return mrUrl, "delete", nil
-		} else {
-			err := gitlab.DeleteBranch(glclient, projectPath, sourceBranch)
-			if err == nil {
-				log.Info(fmt.Sprintf("merge request source branch %s is deleted", sourceBranch), l.Action, l.ActionDelete)
-				return mr.WebURL, "close", nil
-			}
-			if glErrResp, ok := err.(*gogitlab.ErrorResponse); ok {
-				if glErrResp.Response.StatusCode == 404 {
-					log.Info(fmt.Sprintf("Tried to delete source branch %s, but it does not exist in repository", sourceBranch))
-					return mr.WebURL, "close", nil
-				}
-			}
+	if len(batchSiblings) > 0 {
+		// Batch onboarding merge request is shared with other components, it cannot be closed.
+		// Drop configuration of the deleted component from it, so the component is not onboarded on merge.
+		prUrl, err = r.removeComponentFromPaCBatchMergeRequest(ctx, component, batchSiblings, gitClient, sourceBranch, baseBranch, gitAppName, gitAppSlug)
+		if err != nil {
 			return "", "", err
 		}
+		if prUrl != "" {
+			log.Info(fmt.Sprintf("merge request %s is shared with other components, keeping it", prUrl))
+			return prUrl, "keep", nil
+		}
+		// The other components are already configured in the base branch, the merge request is not needed anymore
+	}
 
-	case "bitbucket":
-		// TODO implement
-		return "", "", fmt.Errorf("git provider %s is not supported", gitProvider)
-	default:
-		return "", "", fmt.Errorf("git provider %s is not supported", gitProvider)
+	// Onboarding merge request is not merged yet, close it by deleting its source branch
+	deleted, err := gitClient.DeleteBranch(repoUrl, sourceBranch)
+	if err != nil {
+		return "", "", err
 	}
+	if deleted {
+		log.Info(fmt.Sprintf("merge request source branch %s is deleted", sourceBranch), l.Action, l.ActionDelete)
+	} else {
+		// Non-existing source branch should not be an error, just ignore it
+		log.Info(fmt.Sprintf("Tried to delete source branch %s, but it does not exist in the repository", sourceBranch))
+	}
+	return mr.WebUrl, "close", nil
 }
 
 // generatePaCPipelineRunForComponent returns pipeline run definition to build component source with.
 // Generated pipeline run contains placeholders that are expanded by Pipeline-as-Code.
+// The pipeline is referenced via the given pipelineRef if set, otherwise the resolved pipeline definition is inlined.
+// Workspaces are always bound according to the resolved pipeline definition.
 func generatePaCPipelineRunForComponent(
 	component *appstudiov1alpha1.Component,
-	pipelineSpec *tektonapi.PipelineSpec,
+	resolvedPipeline *pipelineresolver.ResolvedPipeline,
+	pipelineRef *tektonapi.PipelineRef,
 	additionalPipelineParams []tektonapi.Param,
 	onPull bool,
 	pacTargetBranch string,
+	gitClient gitprovider.GitProvider,
 	log logr.Logger) (*tektonapi.PipelineRun, error) {
 
 	if pacTargetBranch == "" {
@@ -875,14 +965,11 @@ func generatePaCPipelineRunForComponent(
 		"pipelines.appstudio.openshift.io/type": "build",
 	}
 
-	var gitRepoAtShaUrl string
-	gitProvider, _ := gitops.GetGitProvider(*component)
-	switch gitProvider {
-	case "github":
-		gitRepoAtShaUrl = github.GetBrowseRepositoryAtShaLink(component.Spec.Source.GitSource.URL, "{{revision}}")
-	case "gitlab":
-		gitRepoAtShaUrl = gitlab.GetBrowseRepositoryAtShaLink(component.Spec.Source.GitSource.URL, "{{revision}}")
+	if resolvedPipeline.Digest != "" {
+		annotations[pipelineBundleDigestAnnotationName] = resolvedPipeline.Digest
 	}
+
+	gitRepoAtShaUrl := gitClient.GetBrowseRepositoryAtShaLink(component.Spec.Source.GitSource.URL, "{{revision}}")
 	if gitRepoAtShaUrl != "" {
 		annotations[gitRepoAtShaAnnotationName] = gitRepoAtShaUrl
 	}
@@ -933,9 +1020,11 @@ func generatePaCPipelineRunForComponent(
 		}
 	}
 
+	// Do not pass generated params the pipeline doesn't support
+	params = filterPipelineParams(params, resolvedPipeline.Spec, log)
 	params = mergeAndSortTektonParams(params, additionalPipelineParams)
 
-	pipelineRunWorkspaces := createWorkspaceBinding(pipelineSpec.Workspaces)
+	pipelineRunWorkspaces := createWorkspaceBinding(resolvedPipeline.Spec.Workspaces)
 
 	pipelineRun := &tektonapi.PipelineRun{
 		TypeMeta: metav1.TypeMeta{
@@ -949,11 +1038,15 @@ func generatePaCPipelineRunForComponent(
 			Annotations: annotations,
 		},
 		Spec: tektonapi.PipelineRunSpec{
-			PipelineSpec: pipelineSpec,
-			Params:       params,
-			Workspaces:   pipelineRunWorkspaces,
+			Params:     params,
+			Workspaces: pipelineRunWorkspaces,
 		},
 	}
+	if pipelineRef != nil {
+		pipelineRun.Spec.PipelineRef = pipelineRef
+	} else {
+		pipelineRun.Spec.PipelineSpec = resolvedPipeline.Spec
+	}
 
 	return pipelineRun, nil
 }
@@ -979,19 +1072,72 @@ func createWorkspaceBinding(pipelineWorkspaces []tektonapi.PipelineWorkspaceDecl
 	return pipelineRunWorkspaces
 }
 
-// retrievePipelineSpec retrieves pipeline definition with given name from the given bundle.
-func retrievePipelineSpec(bundleUri, pipelineName string) (*tektonapi.PipelineSpec, error) {
-	var obj runtime.Object
-	var err error
-	resolver := oci.NewResolver(bundleUri, authn.DefaultKeychain)
-
-	if obj, _, err = resolver.Get(context.TODO(), "pipeline", pipelineName); err != nil {
+// pinPaCPipelineBundle returns copy of the given bundles resolver reference with the bundle pinned to the given digest.
+func pinPaCPipelineBundle(pacPipelineRef *tektonapi.PipelineRef, digest string) (*tektonapi.PipelineRef, error) {
+	resolverName, params, err := pipelineresolver.GetResolverParams(pacPipelineRef)
+	if err != nil || resolverName != pipelineresolver.BundlesResolverName {
+		return pacPipelineRef, err
+	}
+	pinnedBundle, err := pipelineresolver.PinBundleDigest(params["bundle"], digest)
+	if err != nil {
 		return nil, err
 	}
-	pipelineSpecObj, ok := obj.(tektonapi.PipelineObject)
-	if !ok {
-		return nil, fmt.Errorf("failed to extract pipeline %s from bundle %s", bundleUri, pipelineName)
+	return setPipelineBundle(pacPipelineRef, pinnedBundle), nil
+}
+
+// getPaCPipelineRef returns reference to the selected pipeline to use in PaC PipelineRuns
+// according to the given reference configuration of the matched pipeline selector.
+// Returns nil if the pipeline definition should be inlined.
+func getPaCPipelineRef(pipelineRef *tektonapi.PipelineRef, pipelineReference *buildappstudiov1alpha1.PipelineReference) (*tektonapi.PipelineRef, error) {
+	if pipelineReference == nil {
+		return nil, nil
+	}
+
+	switch pipelineReference.Type {
+	case "", buildappstudiov1alpha1.PipelineReferenceInline:
+		return nil, nil
+	case buildappstudiov1alpha1.PipelineReferenceBundlesResolver:
+		resolverName, params, err := pipelineresolver.GetResolverParams(pipelineRef)
+		if err != nil {
+			return nil, err
+		}
+		if resolverName != pipelineresolver.BundlesResolverName || params["bundle"] == "" || params["name"] == "" {
+			return nil, fmt.Errorf("bundle and name of pipeline are required to reference it via bundles resolver")
+		}
+		return &tektonapi.PipelineRef{
+			ResolverRef: tektonapi.ResolverRef{
+				Resolver: pipelineresolver.BundlesResolverName,
+				Params: []tektonapi.Param{
+					{Name: "bundle", Value: *tektonapi.NewArrayOrString(params["bundle"])},
+					{Name: "name", Value: *tektonapi.NewArrayOrString(params["name"])},
+					{Name: "kind", Value: *tektonapi.NewArrayOrString("pipeline")},
+				},
+			},
+		}, nil
+	case buildappstudiov1alpha1.PipelineReferenceResolver:
+		if pipelineRef.Bundle != "" {
+			return getPaCPipelineRef(pipelineRef, &buildappstudiov1alpha1.PipelineReference{Type: buildappstudiov1alpha1.PipelineReferenceBundlesResolver})
+		}
+		if pipelineRef.Resolver == "" {
+			return nil, fmt.Errorf("pipeline %s is not referenced via a resolver", pipelineRef.Name)
+		}
+		return &tektonapi.PipelineRef{ResolverRef: *pipelineRef.ResolverRef.DeepCopy()}, nil
+	case buildappstudiov1alpha1.PipelineReferenceGitResolver:
+		git := pipelineReference.Git
+		if git == nil || git.URL == "" || git.Revision == "" || git.PathInRepo == "" {
+			return nil, fmt.Errorf("url, revision and pathInRepo of pipeline %s are required to reference it via git resolver", pipelineRef.Name)
+		}
+		return &tektonapi.PipelineRef{
+			ResolverRef: tektonapi.ResolverRef{
+				Resolver: pipelineresolver.GitResolverName,
+				Params: []tektonapi.Param{
+					{Name: "url", Value: *tektonapi.NewArrayOrString(git.URL)},
+					{Name: "revision", Value: *tektonapi.NewArrayOrString(git.Revision)},
+					{Name: "pathInRepo", Value: *tektonapi.NewArrayOrString(git.PathInRepo)},
+				},
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported pipeline reference type '%s'", pipelineReference.Type)
 	}
-	pipelineSpec := pipelineSpecObj.PipelineSpec()
-	return &pipelineSpec, nil
 }
@@ -0,0 +1,192 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitrepourl"
+	l "github.com/redhat-appstudio/build-service/pkg/logs"
+)
+
+// getPaCBatchSiblings returns the components which share the batch onboarding merge request with the given component,
+// i.e. the components in the same namespace, repository and target branch with PaC provision requested or done
+// and batch mode enabled. The given component itself is not included. The result is sorted by component name.
+func (r *ComponentBuildReconciler) getPaCBatchSiblings(ctx context.Context, component *appstudiov1alpha1.Component, gitClient gitprovider.GitProvider, targetBranch string) ([]appstudiov1alpha1.Component, error) {
+	log := ctrllog.FromContext(ctx)
+
+	componentList := &appstudiov1alpha1.ComponentList{}
+	if err := r.Client.List(ctx, componentList, client.InNamespace(component.Namespace)); err != nil {
+		log.Error(err, "failed to list Components", l.Action, l.ActionView)
+		return nil, err
+	}
+
+	repoUrl := component.Spec.Source.GitSource.URL
+	var defaultBranch string
+	var siblings []appstudiov1alpha1.Component
+	for _, sibling := range componentList.Items {
+		if sibling.Name == component.Name || !sibling.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if sibling.Spec.Source.GitSource == nil || !isSameGitRepository(sibling.Spec.Source.GitSource.URL, repoUrl) {
+			continue
+		}
+		pacProvision := sibling.Annotations[PaCProvisionAnnotationName]
+		if pacProvision != PaCProvisionRequestedAnnotationValue && pacProvision != PaCProvisionDoneAnnotationValue {
+			continue
+		}
+
+		siblingTargetBranch := sibling.Spec.Source.GitSource.Revision
+		if siblingTargetBranch == "" {
+			if defaultBranch == "" {
+				var err error
+				if defaultBranch, err = gitClient.GetDefaultBranch(repoUrl); err != nil {
+					return nil, err
+				}
+			}
+			siblingTargetBranch = defaultBranch
+		}
+		if siblingTargetBranch != targetBranch {
+			continue
+		}
+
+		mrOptions, err := r.getMergeRequestOptions(ctx, &sibling)
+		if err != nil {
+			return nil, err
+		}
+		if !mrOptions.Batch {
+			continue
+		}
+
+		if sibling.Status.Devfile == "" {
+			// PipelineRun definitions cannot be generated yet, the component will be added to the merge request on its own provision
+			log.Info(fmt.Sprintf("devfile model of %s component is not set yet, skipping it in batch merge request", sibling.Name))
+			continue
+		}
+
+		siblings = append(siblings, sibling)
+	}
+
+	sort.Slice(siblings, func(i, j int) bool {
+		return siblings[i].Name < siblings[j].Name
+	})
+	return siblings, nil
+}
+
+// getPaCBatchSiblingFiles returns PipelineRun definitions of the sibling as they are in the batch merge request branch.
+// The definitions are not regenerated on behalf of another component, the sibling updates them on its own provision.
+// Returns nil if the definitions are not in the branch, i.e. the sibling is not in the merge request yet.
+func (r *ComponentBuildReconciler) getPaCBatchSiblingFiles(ctx context.Context, sibling *appstudiov1alpha1.Component, gitClient gitprovider.GitProvider, batchBranch, baseBranch string) ([]gitprovider.RepositoryFile, error) {
+	pipelineRunOnPushPath, pipelineRunOnPRPath, err := r.getPipelineRunPaths(ctx, sibling, baseBranch)
+	if err != nil {
+		return nil, err
+	}
+	var files []gitprovider.RepositoryFile
+	for _, pipelineRunPath := range []string{pipelineRunOnPushPath, pipelineRunOnPRPath} {
+		content, err := gitClient.DownloadFileContent(sibling.Spec.Source.GitSource.URL, batchBranch, pipelineRunPath)
+		if err != nil {
+			return nil, err
+		}
+		if content == nil {
+			return nil, nil
+		}
+		files = append(files, gitprovider.RepositoryFile{FullPath: pipelineRunPath, Content: content})
+	}
+	return files, nil
+}
+
+// removeComponentFromPaCBatchMergeRequest updates the batch onboarding merge request, so it proposes PipelineRun definitions
+// of the given siblings only and the definitions of the given (deleted) component are dropped from its branch.
+// Definitions of the siblings are kept as they are in the branch.
+// Returns the merge request web URL or empty string if the base branch is already up to date with the siblings definitions,
+// i.e. the merge request is not needed anymore.
+func (r *ComponentBuildReconciler) removeComponentFromPaCBatchMergeRequest(ctx context.Context, component *appstudiov1alpha1.Component, siblings []appstudiov1alpha1.Component,
+	gitClient gitprovider.GitProvider, batchBranch, baseBranch, gitAppName, gitAppSlug string) (string, error) {
+	pipelineRunOnPushPath, pipelineRunOnPRPath, err := r.getPipelineRunPaths(ctx, component, baseBranch)
+	if err != nil {
+		return "", err
+	}
+	deletedFiles := []gitprovider.RepositoryFile{
+		{FullPath: pipelineRunOnPushPath},
+		{FullPath: pipelineRunOnPRPath},
+	}
+
+	templateData := newMergeRequestTemplateData(&siblings[0], gitAppName, gitAppSlug)
+	templateData.TargetBranch = baseBranch
+	templateData.ComponentNames = nil
+	templateData.Batch = true
+	var files []gitprovider.RepositoryFile
+	for i := range siblings {
+		siblingFiles, err := r.getPaCBatchSiblingFiles(ctx, &siblings[i], gitClient, batchBranch, baseBranch)
+		if err != nil {
+			return "", err
+		}
+		if siblingFiles == nil {
+			// The sibling is not in the merge request
+			continue
+		}
+		files = append(files, siblingFiles...)
+		templateData.ComponentNames = append(templateData.ComponentNames, siblings[i].Name)
+	}
+	sort.Strings(templateData.ComponentNames)
+
+	mrOptions, err := r.getMergeRequestOptions(ctx, &siblings[0])
+	if err != nil {
+		return "", err
+	}
+	mrMetadata, err := r.getOnboardingMergeRequestMetadata(ctx, component.Namespace, templateData)
+	if err != nil {
+		return "", err
+	}
+	mrData := &gitprovider.MergeRequestData{
+		CommitMessage:  mrMetadata.CommitMessage,
+		BranchName:     batchBranch,
+		BaseBranchName: baseBranch,
+		Title:          mrMetadata.Title,
+		Text:           mrMetadata.Body,
+		AuthorName:     mrMetadata.AuthorName,
+		AuthorEmail:    mrMetadata.AuthorEmail,
+		Files:          files,
+		DeletedFiles:   deletedFiles,
+		Labels:         mrOptions.Labels,
+		Reviewers:      mrOptions.Reviewers,
+		TeamReviewers:  mrOptions.TeamReviewers,
+		Assignees:      mrOptions.Assignees,
+	}
+	mr, err := gitClient.EnsurePaCMergeRequest(component.Spec.Source.GitSource.URL, mrData)
+	if err != nil || mr == nil {
+		return "", err
+	}
+	return mr.WebUrl, nil
+}
+
+// isSameGitRepository checks if the given URLs point to the same repository, e.g. https and ssh URLs of a repository.
+func isSameGitRepository(repoUrl1, repoUrl2 string) bool {
+	gitRepoUrl1, err1 := gitrepourl.ParseGitRepoUrl(repoUrl1)
+	gitRepoUrl2, err2 := gitrepourl.ParseGitRepoUrl(repoUrl2)
+	if err1 != nil || err2 != nil {
+		return repoUrl1 == repoUrl2
+	}
+	return gitRepoUrl1.GetWebUrl() == gitRepoUrl2.GetWebUrl()
+}
@@ -0,0 +1,397 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/gitops"
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitproviderfactory"
+	l "github.com/redhat-appstudio/build-service/pkg/logs"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// pacMergeRequestStatePollIntervalEnvVar is how often the state of opened onboarding merge request is checked, e.g. 30m.
+	pacMergeRequestStatePollIntervalEnvVar  = "PAC_MERGE_REQUEST_STATE_POLL_INTERVAL"
+	pacMergeRequestStatePollIntervalDefault = 10 * time.Minute
+
+	// pacClosedMergeRequestActionEnvVar defines what to do if the onboarding merge request is closed without merging:
+	// reopen - reopen the merge request,
+	// recreate - propose the configuration in a new merge request after the cooldown,
+	// error - mark Pipelines as Code provision failed (default).
+	// The component annotation overrides the setting.
+	pacClosedMergeRequestActionEnvVar = "PAC_CLOSED_MERGE_REQUEST_ACTION"
+	// pacClosedMergeRequestCooldownEnvVar is the time to wait after the merge request is closed before recreating it, e.g. 168h.
+	pacClosedMergeRequestCooldownEnvVar  = "PAC_CLOSED_MERGE_REQUEST_COOLDOWN"
+	pacClosedMergeRequestCooldownDefault = 24 * time.Hour
+
+	closedMergeRequestActionReopen   = "reopen"
+	closedMergeRequestActionRecreate = "recreate"
+	closedMergeRequestActionError    = "error"
+)
+
+// reconcilePaCMergeRequestState tracks the state of the onboarding merge request.
+// Polls the git provider while the merge request is open and records its state on the component once it is merged or closed.
+// The git provider is asked at most once per poll interval, regardless of how often the component is reconciled.
+// A merge request closed without merging is handled according to the configured action.
+func (r *ComponentBuildReconciler) reconcilePaCMergeRequestState(ctx context.Context, component *appstudiov1alpha1.Component) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx).WithName("PaC-merge-request-state")
+	ctx = ctrllog.IntoContext(ctx, log)
+
+	switch component.Annotations[PaCMergeRequestStateAnnotationName] {
+	case PaCMergeRequestStateOpenAnnotationValue:
+		return r.reconcileOpenPaCMergeRequest(ctx, component)
+	case PaCMergeRequestStateClosedUnmergedAnnotationValue:
+		return r.reconcileClosedPaCMergeRequest(ctx, component)
+	default:
+		return ctrl.Result{}, nil
+	}
+}
+
+func (r *ComponentBuildReconciler) reconcileOpenPaCMergeRequest(ctx context.Context, component *appstudiov1alpha1.Component) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+
+	mrNumber, err := strconv.ParseInt(component.Annotations[PaCMergeRequestNumberAnnotationName], 10, 64)
+	if err != nil {
+		// Cannot track the merge request without its number
+		return ctrl.Result{}, nil
+	}
+	pollInterval := getPaCMergeRequestStatePollInterval(ctx)
+	if remaining := getPaCMergeRequestNextCheckIn(component, pollInterval); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	// Record the check before calling the git provider, so failing checks are throttled as well
+	component.Annotations[PaCMergeRequestCheckedAtAnnotationName] = time.Now().UTC().Format(time.RFC3339)
+	if err := r.Client.Update(ctx, component); err != nil {
+		log.Error(err, "failed to record merge request state check time of the Component", l.Action, l.ActionUpdate)
+		return ctrl.Result{}, err
+	}
+
+	gitClient, err := r.getGitClientForComponent(ctx, component)
+	if err != nil {
+		log.Error(err, "failed to create git client")
+		return ctrl.Result{RequeueAfter: pollInterval}, nil
+	}
+	mr, err := gitClient.GetMergeRequest(component.Spec.Source.GitSource.URL, mrNumber)
+	if err != nil {
+		if retryAt, isRateLimited := boerrors.GetRetryAt(err); isRateLimited {
+			return ctrl.Result{RequeueAfter: getRateLimitRequeueAfter(retryAt)}, nil
+		}
+		log.Error(err, fmt.Sprintf("failed to get merge request %d", mrNumber), l.Action, l.ActionView)
+		return ctrl.Result{RequeueAfter: pollInterval}, nil
+	}
+	if mr.State == gitprovider.MergeRequestStateOpen {
+		return ctrl.Result{RequeueAfter: pollInterval}, nil
+	}
+
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: component.Name, Namespace: component.Namespace}, component); err != nil {
+		log.Error(err, "failed to get Component", l.Action, l.ActionView)
+		return ctrl.Result{}, err
+	}
+	if !setPaCMergeRequestAnnotations(component, mr) {
+		return ctrl.Result{}, nil
+	}
+	if mr.State == gitprovider.MergeRequestStateClosed {
+		component.Annotations[PaCMergeRequestClosedAtAnnotationName] = time.Now().UTC().Format(time.RFC3339)
+	}
+	if err := r.Client.Update(ctx, component); err != nil {
+		log.Error(err, "failed to update merge request state of the Component", l.Action, l.ActionUpdate)
+		return ctrl.Result{}, err
+	}
+	log.Info(fmt.Sprintf("merge request %s is %s", mr.WebUrl, mr.State), l.Action, l.ActionUpdate)
+	r.reportPaCMergeRequestState(component)
+
+	if mr.State == gitprovider.MergeRequestStateClosed {
+		return r.reconcileClosedPaCMergeRequest(ctx, component)
+	}
+	return ctrl.Result{}, nil
+}
+
+// reconcileClosedPaCMergeRequest re-proposes Pipelines as Code configuration
+// if the onboarding merge request is closed without merging, according to the configured action.
+func (r *ComponentBuildReconciler) reconcileClosedPaCMergeRequest(ctx context.Context, component *appstudiov1alpha1.Component) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	mrUrl := component.Annotations[PaCMergeRequestUrlAnnotationName]
+
+	switch getPaCClosedMergeRequestAction(ctx, component) {
+	case closedMergeRequestActionReopen:
+		return r.reopenPaCMergeRequest(ctx, component)
+
+	case closedMergeRequestActionRecreate:
+		// Recreate immediately if the closing time is unknown
+		closedAt, _ := time.Parse(time.RFC3339, component.Annotations[PaCMergeRequestClosedAtAnnotationName])
+		if remaining := getPaCClosedMergeRequestCooldown(ctx) - time.Since(closedAt); remaining > 0 {
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
+		if err := r.requestPaCProvision(ctx, component); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.Info(fmt.Sprintf("merge request %s is closed without merging, Pipelines as Code configuration is proposed again", mrUrl))
+		r.EventRecorder.Event(component, "Normal", "PaCMergeRequestRecreation",
+			fmt.Sprintf("Pipelines as Code configuration merge request %s was closed without merging, proposing the configuration in a new one", mrUrl))
+		return ctrl.Result{}, nil
+
+	default:
+		return ctrl.Result{}, r.setPaCProvisionError(ctx, component,
+			boerrors.NewBuildOpError(boerrors.EPaCMergeRequestClosed, fmt.Errorf("merge request %s is closed without merging", mrUrl)))
+	}
+}
+
+// reopenPaCMergeRequest reopens the onboarding merge request closed without merging.
+// If the merge request cannot be reopened, Pipelines as Code provision is marked failed.
+func (r *ComponentBuildReconciler) reopenPaCMergeRequest(ctx context.Context, component *appstudiov1alpha1.Component) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	mrUrl := component.Annotations[PaCMergeRequestUrlAnnotationName]
+
+	mrNumber, err := strconv.ParseInt(component.Annotations[PaCMergeRequestNumberAnnotationName], 10, 64)
+	if err != nil {
+		return ctrl.Result{}, r.setPaCProvisionError(ctx, component,
+			boerrors.NewBuildOpError(boerrors.EPaCMergeRequestClosed, fmt.Errorf("cannot reopen merge request %s, its number is unknown", mrUrl)))
+	}
+	gitClient, err := r.getGitClientForComponent(ctx, component)
+	if err != nil {
+		log.Error(err, "failed to create git client")
+		return ctrl.Result{RequeueAfter: getPaCMergeRequestStatePollInterval(ctx)}, nil
+	}
+	mr, err := gitClient.ReopenMergeRequest(component.Spec.Source.GitSource.URL, mrNumber)
+	if err != nil {
+		if retryAt, isRateLimited := boerrors.GetRetryAt(err); isRateLimited {
+			return ctrl.Result{RequeueAfter: getRateLimitRequeueAfter(retryAt)}, nil
+		}
+		log.Error(err, fmt.Sprintf("failed to reopen merge request %s", mrUrl), l.Action, l.ActionUpdate, l.Audit, "true")
+		return ctrl.Result{}, r.setPaCProvisionError(ctx, component,
+			boerrors.NewBuildOpError(boerrors.EPaCMergeRequestClosed, fmt.Errorf("failed to reopen merge request %s: %w", mrUrl, err)))
+	}
+
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: component.Name, Namespace: component.Namespace}, component); err != nil {
+		log.Error(err, "failed to get Component", l.Action, l.ActionView)
+		return ctrl.Result{}, err
+	}
+	setPaCMergeRequestAnnotations(component, mr)
+	if err := r.Client.Update(ctx, component); err != nil {
+		log.Error(err, "failed to update merge request state of the Component", l.Action, l.ActionUpdate)
+		return ctrl.Result{}, err
+	}
+	log.Info(fmt.Sprintf("merge request %s is reopened", mrUrl), l.Action, l.ActionUpdate, l.Audit, "true")
+	r.reportPaCMergeRequestState(component)
+
+	return ctrl.Result{RequeueAfter: getPaCMergeRequestStatePollInterval(ctx)}, nil
+}
+
+// requestPaCProvision requests a new Pipelines as Code provision of the component
+// and forgets the previous onboarding merge request.
+func (r *ComponentBuildReconciler) requestPaCProvision(ctx context.Context, component *appstudiov1alpha1.Component) error {
+	log := ctrllog.FromContext(ctx)
+
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: component.Name, Namespace: component.Namespace}, component); err != nil {
+		log.Error(err, "failed to get Component", l.Action, l.ActionView)
+		return err
+	}
+	component.Annotations[PaCProvisionAnnotationName] = PaCProvisionRequestedAnnotationValue
+	deletePaCMergeRequestAnnotations(component)
+	if err := r.Client.Update(ctx, component); err != nil {
+		log.Error(err, "failed to request Pipelines as Code provision of the Component", l.Action, l.ActionUpdate)
+		return err
+	}
+	return nil
+}
+
+// setPaCProvisionError marks Pipelines as Code provision of the component failed with the given error.
+func (r *ComponentBuildReconciler) setPaCProvisionError(ctx context.Context, component *appstudiov1alpha1.Component, boErr *boerrors.BuildOpError) error {
+	log := ctrllog.FromContext(ctx)
+
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: component.Name, Namespace: component.Namespace}, component); err != nil {
+		log.Error(err, "failed to get Component", l.Action, l.ActionView)
+		return err
+	}
+	component.Annotations[PaCProvisionAnnotationName] = PaCProvisionErrorAnnotationValue
+	component.Annotations[PaCProvisionErrorDetailsAnnotationName] = boErr.ShortError()
+	if err := r.Client.Update(ctx, component); err != nil {
+		log.Error(err, "failed to set Pipelines as Code provision error of the Component", l.Action, l.ActionUpdate)
+		return err
+	}
+	log.Error(boErr, "Pipelines as Code provision for the Component failed")
+	r.EventRecorder.Event(component, "Warning", "PaCProvisionFailed", boErr.Error())
+	return nil
+}
+
+// getGitClientForComponent creates git provider client for the component repository using Pipelines as Code secret.
+func (r *ComponentBuildReconciler) getGitClientForComponent(ctx context.Context, component *appstudiov1alpha1.Component) (gitprovider.GitProvider, error) {
+	gitProvider, err := gitops.GetGitProvider(*component)
+	if err != nil {
+		return nil, err
+	}
+	pacSecret, err := r.ensurePaCSecret(ctx, component, gitProvider)
+	if err != nil {
+		return nil, err
+	}
+	return gitproviderfactory.CreateGitClient(gitproviderfactory.GitClientConfig{
+		PacSecretData:             pacSecret.Data,
+		GitProvider:               gitProvider,
+		RepoUrl:                   component.Spec.Source.GitSource.URL,
+		IsAppInstallationExpected: true,
+	})
+}
+
+// setPaCMergeRequestAnnotations records the given onboarding merge request on the component.
+// Returns true if the merge request state has changed.
+func setPaCMergeRequestAnnotations(component *appstudiov1alpha1.Component, mr *gitprovider.MergeRequest) bool {
+	if component.Annotations == nil {
+		component.Annotations = make(map[string]string)
+	}
+	if mr.WebUrl != "" {
+		component.Annotations[PaCMergeRequestUrlAnnotationName] = mr.WebUrl
+	}
+	if mr.Number != 0 {
+		component.Annotations[PaCMergeRequestNumberAnnotationName] = strconv.FormatInt(mr.Number, 10)
+	} else {
+		delete(component.Annotations, PaCMergeRequestNumberAnnotationName)
+	}
+
+	var state string
+	switch mr.State {
+	case gitprovider.MergeRequestStateMerged:
+		state = PaCMergeRequestStateMergedAnnotationValue
+	case gitprovider.MergeRequestStateClosed:
+		state = PaCMergeRequestStateClosedUnmergedAnnotationValue
+	default:
+		state = PaCMergeRequestStateOpenAnnotationValue
+	}
+	isStateChanged := component.Annotations[PaCMergeRequestStateAnnotationName] != state
+	component.Annotations[PaCMergeRequestStateAnnotationName] = state
+	if state != PaCMergeRequestStateClosedUnmergedAnnotationValue {
+		delete(component.Annotations, PaCMergeRequestClosedAtAnnotationName)
+	}
+	if state != PaCMergeRequestStateOpenAnnotationValue {
+		delete(component.Annotations, PaCMergeRequestCheckedAtAnnotationName)
+	}
+	return isStateChanged
+}
+
+// getPaCMergeRequestNextCheckIn returns the time remaining until the state of the open onboarding merge request
+// should be checked again, or zero if the check is due.
+func getPaCMergeRequestNextCheckIn(component *appstudiov1alpha1.Component, pollInterval time.Duration) time.Duration {
+	checkedAt, err := time.Parse(time.RFC3339, component.Annotations[PaCMergeRequestCheckedAtAnnotationName])
+	if err != nil {
+		return 0
+	}
+	if remaining := pollInterval - time.Since(checkedAt); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+func deletePaCMergeRequestAnnotations(component *appstudiov1alpha1.Component) {
+	delete(component.Annotations, PaCMergeRequestUrlAnnotationName)
+	delete(component.Annotations, PaCMergeRequestNumberAnnotationName)
+	delete(component.Annotations, PaCMergeRequestStateAnnotationName)
+	delete(component.Annotations, PaCMergeRequestClosedAtAnnotationName)
+	delete(component.Annotations, PaCMergeRequestCheckedAtAnnotationName)
+}
+
+// reportPaCMergeRequestState emits an event about the current state of the onboarding merge request.
+func (r *ComponentBuildReconciler) reportPaCMergeRequestState(component *appstudiov1alpha1.Component) {
+	state := component.Annotations[PaCMergeRequestStateAnnotationName]
+	message := fmt.Sprintf("Pipelines as Code configuration merge request %s state: %s",
+		component.Annotations[PaCMergeRequestUrlAnnotationName], state)
+	eventType := "Normal"
+	if state == PaCMergeRequestStateClosedUnmergedAnnotationValue {
+		eventType = "Warning"
+	}
+	r.EventRecorder.Event(component, eventType, "PaCMergeRequestStateChanged", message)
+}
+
+// getPaCMergeRequestStatePollInterval returns configured poll interval of onboarding merge request state.
+func getPaCMergeRequestStatePollInterval(ctx context.Context) time.Duration {
+	pollIntervalStr := os.Getenv(pacMergeRequestStatePollIntervalEnvVar)
+	if pollIntervalStr == "" {
+		return pacMergeRequestStatePollIntervalDefault
+	}
+	pollInterval, err := time.ParseDuration(pollIntervalStr)
+	if err != nil || pollInterval <= 0 {
+		ctrllog.FromContext(ctx).Info(fmt.Sprintf("invalid merge request state poll interval '%s' in %s envVar, using default %s",
+			pollIntervalStr, pacMergeRequestStatePollIntervalEnvVar, pacMergeRequestStatePollIntervalDefault))
+		return pacMergeRequestStatePollIntervalDefault
+	}
+	return pollInterval
+}
+
+// getPaCClosedMergeRequestAction returns what to do with the onboarding merge request closed without merging.
+// The component annotation overrides the global configuration. Invalid values are ignored.
+func getPaCClosedMergeRequestAction(ctx context.Context, component *appstudiov1alpha1.Component) string {
+	log := ctrllog.FromContext(ctx)
+
+	action := closedMergeRequestActionError
+	actionSources := []struct {
+		source string
+		value  string
+	}{
+		{pacClosedMergeRequestActionEnvVar + " envVar", os.Getenv(pacClosedMergeRequestActionEnvVar)},
+		{PaCMergeRequestClosedActionAnnotationName + " annotation", component.Annotations[PaCMergeRequestClosedActionAnnotationName]},
+	}
+	for _, actionSource := range actionSources {
+		switch actionSource.value {
+		case "":
+			continue
+		case closedMergeRequestActionReopen, closedMergeRequestActionRecreate, closedMergeRequestActionError:
+			action = actionSource.value
+		default:
+			log.Info(fmt.Sprintf("invalid closed merge request action '%s' in %s, ignoring", actionSource.value, actionSource.source))
+		}
+	}
+	return action
+}
+
+// getPaCClosedMergeRequestCooldown returns configured time to wait before recreating closed onboarding merge request.
+func getPaCClosedMergeRequestCooldown(ctx context.Context) time.Duration {
+	cooldownStr := os.Getenv(pacClosedMergeRequestCooldownEnvVar)
+	if cooldownStr == "" {
+		return pacClosedMergeRequestCooldownDefault
+	}
+	cooldown, err := time.ParseDuration(cooldownStr)
+	if err != nil || cooldown < 0 {
+		ctrllog.FromContext(ctx).Info(fmt.Sprintf("invalid closed merge request cooldown '%s' in %s envVar, using default %s",
+			cooldownStr, pacClosedMergeRequestCooldownEnvVar, pacClosedMergeRequestCooldownDefault))
+		return pacClosedMergeRequestCooldownDefault
+	}
+	return cooldown
+}
+
+// getEarliestRequeueResult returns the result that requeues the earliest.
+func getEarliestRequeueResult(results ...ctrl.Result) ctrl.Result {
+	earliest := ctrl.Result{}
+	for _, result := range results {
+		if result.RequeueAfter == 0 {
+			earliest.Requeue = earliest.Requeue || result.Requeue
+			continue
+		}
+		if earliest.RequeueAfter == 0 || result.RequeueAfter < earliest.RequeueAfter {
+			earliest.RequeueAfter = result.RequeueAfter
+		}
+	}
+	return earliest
+}
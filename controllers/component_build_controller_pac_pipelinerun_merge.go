@@ -0,0 +1,152 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	l "github.com/redhat-appstudio/build-service/pkg/logs"
+)
+
+// pacOwnedPipelineRunParams are the PipelineRun parameters which are always regenerated by the service.
+// All other parameters in the existing PipelineRun definitions are considered as user customizations.
+var pacOwnedPipelineRunParams = map[string]bool{
+	"git-url":      true,
+	"revision":     true,
+	"output-image": true,
+}
+
+// preservePipelineRunCustomizations merges the generated PipelineRun definition into the one
+// which already exists in the given branch of the repository, so user modifications survive regeneration.
+// Returns the generated content as is if the file doesn't exist or cannot be parsed.
+func preservePipelineRunCustomizations(ctx context.Context, gitClient gitprovider.GitProvider, repoUrl, branchName string, file gitprovider.RepositoryFile) ([]byte, error) {
+	log := ctrllog.FromContext(ctx)
+
+	existingContent, err := gitClient.DownloadFileContent(repoUrl, branchName, file.FullPath)
+	if err != nil {
+		return nil, err
+	}
+	if existingContent == nil {
+		return file.Content, nil
+	}
+
+	mergedContent, err := mergePipelineRunDefinitions(existingContent, file.Content)
+	if err != nil {
+		log.Info(fmt.Sprintf("failed to merge existing %s into generated PipelineRun, overwriting it: %s", file.FullPath, err.Error()),
+			l.Action, l.ActionUpdate)
+		return file.Content, nil
+	}
+	return mergedContent, nil
+}
+
+// mergePipelineRunDefinitions updates the fields of the existing PipelineRun definition which are owned by the service
+// (pipeline, owned parameters, generated labels and annotations) from the generated one.
+// Other fields, like additional parameters, annotations, task run specs and workspaces, are kept.
+// The result is in Tekton API version of the generated definition.
+// Returns the existing content unchanged if the merge doesn't change the PipelineRun.
+func mergePipelineRunDefinitions(existingContent, generatedContent []byte) ([]byte, error) {
+	existing, existingApiVersion, err := unmarshalPipelineRun(existingContent)
+	if err != nil {
+		return nil, err
+	}
+	generated, generatedApiVersion, err := unmarshalPipelineRun(generatedContent)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := mergePipelineRuns(existing, generated)
+	if existingApiVersion == generatedApiVersion && equality.Semantic.DeepEqual(existing, merged) {
+		// Keep formatting and comments of the existing file
+		return existingContent, nil
+	}
+	return marshalPipelineRun(merged, generatedApiVersion)
+}
+
+// mergePipelineRuns returns a copy of the existing PipelineRun with the service owned fields taken from the generated one.
+func mergePipelineRuns(existing, generated *tektonapi.PipelineRun) *tektonapi.PipelineRun {
+	merged := existing.DeepCopy()
+
+	merged.TypeMeta = generated.TypeMeta
+	merged.Name = generated.Name
+	merged.Namespace = generated.Namespace
+	merged.Labels = mergeStringMaps(existing.Labels, generated.Labels)
+	merged.Annotations = mergeStringMaps(existing.Annotations, generated.Annotations)
+
+	merged.Spec.PipelineSpec = generated.Spec.PipelineSpec
+	merged.Spec.PipelineRef = generated.Spec.PipelineRef
+
+	// Keep order and user values of existing parameters, update the owned ones and add the missing ones
+	generatedParams := make(map[string]tektonapi.Param, len(generated.Spec.Params))
+	for _, param := range generated.Spec.Params {
+		generatedParams[param.Name] = param
+	}
+	merged.Spec.Params = nil
+	existingParams := make(map[string]bool, len(existing.Spec.Params))
+	for _, param := range existing.Spec.Params {
+		existingParams[param.Name] = true
+		if pacOwnedPipelineRunParams[param.Name] {
+			generatedParam, ok := generatedParams[param.Name]
+			if !ok {
+				continue
+			}
+			param = generatedParam
+		}
+		merged.Spec.Params = append(merged.Spec.Params, param)
+	}
+	for _, param := range generated.Spec.Params {
+		if !existingParams[param.Name] {
+			merged.Spec.Params = append(merged.Spec.Params, param)
+		}
+	}
+
+	// Keep user workspace bindings and add the missing ones required by the pipeline
+	for _, workspace := range generated.Spec.Workspaces {
+		found := false
+		for _, existingWorkspace := range existing.Spec.Workspaces {
+			if existingWorkspace.Name == workspace.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged.Spec.Workspaces = append(merged.Spec.Workspaces, workspace)
+		}
+	}
+
+	return merged
+}
+
+// mergeStringMaps returns union of the given maps. Values from the overrides take precedence.
+func mergeStringMaps(base, overrides map[string]string) map[string]string {
+	if base == nil && overrides == nil {
+		return nil
+	}
+	merged := make(map[string]string, len(base)+len(overrides))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
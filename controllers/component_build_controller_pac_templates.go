@@ -0,0 +1,408 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	l "github.com/redhat-appstudio/build-service/pkg/logs"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// pacMergeRequestTemplatesConfigMapName is the name of the config map with Go templates of merge request metadata.
+	// The config map in build-service namespace sets global templates,
+	// the config map in the component namespace overrides them per template.
+	pacMergeRequestTemplatesConfigMapName = "pac-merge-request-templates"
+
+	// Templates of onboarding merge request, i.e. the one that adds Pipelines as Code configuration
+	mrTemplateCommitMessageKey = "commit-message"
+	mrTemplateTitleKey         = "title"
+	mrTemplateBodyKey          = "body"
+	mrTemplateBranchKey        = "branch"
+	// Templates of batch onboarding merge request, i.e. the one that adds Pipelines as Code configuration
+	// of all components in the same repository and target branch
+	mrTemplateBatchCommitMessageKey = "batch-commit-message"
+	mrTemplateBatchTitleKey         = "batch-title"
+	mrTemplateBatchBodyKey          = "batch-body"
+	mrTemplateBatchBranchKey        = "batch-branch"
+	// Templates of purge merge request, i.e. the one that removes Pipelines as Code configuration
+	mrTemplatePurgeCommitMessageKey = "purge-commit-message"
+	mrTemplatePurgeTitleKey         = "purge-title"
+	mrTemplatePurgeBodyKey          = "purge-body"
+	mrTemplatePurgeBranchKey        = "purge-branch"
+	// Templates of commit author identity. The name is used for both onboarding and purge merge requests,
+	// the email of purge merge requests is set separately, it differs by default.
+	// The author templates are ignored if GitHub Application is used, GitHub attributes the commits to the application.
+	mrTemplateAuthorNameKey       = "author-name"
+	mrTemplateAuthorEmailKey      = "author-email"
+	mrTemplatePurgeAuthorEmailKey = "purge-author-email"
+	// Template of PipelineRun definition path in the repository, used for generation, up to date checks and purge.
+	// Rendered with pipelineRunPathTemplateData, must be a .yaml or .yml file under .tekton directory.
+	mrTemplatePipelineRunPathKey = "pipelinerun-path"
+)
+
+const (
+	// pacMergeRequestOptionsConfigMapName is the name of the config map in the component namespace
+	// that sets optional settings of onboarding merge requests.
+	pacMergeRequestOptionsConfigMapName = "pac-merge-request-options"
+
+	mrOptionLabelsKey        = "labels"
+	mrOptionReviewersKey     = "reviewers"
+	mrOptionTeamReviewersKey = "team-reviewers"
+	mrOptionAssigneesKey     = "assignees"
+	mrOptionDraftKey         = "draft"
+	mrOptionAutoMergeKey     = "auto-merge"
+	mrOptionMergeMethodKey   = "merge-method"
+	mrOptionBatchKey         = "batch"
+)
+
+var defaultMergeRequestTemplates = map[string]string{
+	mrTemplateCommitMessageKey:      "{{if .GitAppName}}{{.GitAppName}}{{else}}Appstudio{{end}} update {{.ComponentName}}",
+	mrTemplateTitleKey:              "{{if .GitAppName}}{{.GitAppName}}{{else}}Appstudio{{end}} update {{.ComponentName}}",
+	mrTemplateBodyKey:               mergeRequestDescription,
+	mrTemplateBranchKey:             pacMergeRequestSourceBranchPrefix + "{{.ComponentName}}",
+	mrTemplateBatchCommitMessageKey: "{{if .GitAppName}}{{.GitAppName}}{{else}}Appstudio{{end}} update {{join .ComponentNames \", \"}}",
+	mrTemplateBatchTitleKey:         "{{if .GitAppName}}{{.GitAppName}}{{else}}Appstudio{{end}} update {{join .ComponentNames \", \"}}",
+	mrTemplateBatchBodyKey:          mergeRequestDescription,
+	mrTemplateBatchBranchKey:        pacMergeRequestSourceBranchPrefix + "batch-{{.TargetBranch}}",
+	mrTemplatePurgeCommitMessageKey: "Appstudio purge {{.ComponentName}}",
+	mrTemplatePurgeTitleKey:         "Appstudio purge {{.ComponentName}}",
+	mrTemplatePurgeBodyKey:          "Pipelines as Code configuration removal",
+	mrTemplatePurgeBranchKey:        pacMergeRequestSourceBranchPrefix + "purge-{{.ComponentName}}",
+	mrTemplateAuthorNameKey:         "{{if .GitAppSlug}}{{.GitAppSlug}}{{else}}redhat-appstudio{{end}}",
+	mrTemplateAuthorEmailKey:        "rhtap@redhat.com",
+	mrTemplatePurgeAuthorEmailKey:   "appstudio@redhat.com",
+	mrTemplatePipelineRunPathKey:    pacConfigDirectory + "{{.ComponentName}}-{{.Event}}.yaml",
+}
+
+// mergeRequestTemplateData is the data merge request templates are rendered with.
+type mergeRequestTemplateData struct {
+	// Component gives access to all the Component fields, e.g. {{.Component.Spec.Source.GitSource.Revision}}
+	Component       *appstudiov1alpha1.Component
+	ComponentName   string
+	Namespace       string
+	ApplicationName string
+	RepositoryUrl   string
+	// TargetBranch is the base branch of the merge request
+	TargetBranch string
+	// Batch is true if the onboarding merge request is shared by all components in the same repository and target branch.
+	// ComponentNames are sorted names of the components in the onboarding merge request, only the component itself if not batch.
+	Batch          bool
+	ComponentNames []string
+	// GitAppName and GitAppSlug are set only if git application is used
+	GitAppName string
+	GitAppSlug string
+}
+
+func newMergeRequestTemplateData(component *appstudiov1alpha1.Component, gitAppName, gitAppSlug string) *mergeRequestTemplateData {
+	data := &mergeRequestTemplateData{
+		Component:       component,
+		ComponentName:   component.Name,
+		Namespace:       component.Namespace,
+		ApplicationName: component.Spec.Application,
+		ComponentNames:  []string{component.Name},
+		GitAppName:      gitAppName,
+		GitAppSlug:      gitAppSlug,
+	}
+	if component.Spec.Source.GitSource != nil {
+		data.RepositoryUrl = component.Spec.Source.GitSource.URL
+		data.TargetBranch = component.Spec.Source.GitSource.Revision
+	}
+	return data
+}
+
+// mergeRequestMetadata is rendered merge request metadata.
+type mergeRequestMetadata struct {
+	CommitMessage string
+	Title         string
+	Body          string
+	BranchName    string
+	AuthorName    string
+	AuthorEmail   string
+	// OnboardingBranchName is the source branch of onboarding merge request
+	OnboardingBranchName string
+}
+
+// getMergeRequestTemplates returns merge request templates for the given namespace.
+// Templates from the namespace config map override global ones, which override the defaults.
+func (r *ComponentBuildReconciler) getMergeRequestTemplates(ctx context.Context, namespace string) (map[string]string, error) {
+	log := ctrllog.FromContext(ctx)
+
+	templates := make(map[string]string, len(defaultMergeRequestTemplates))
+	for key, value := range defaultMergeRequestTemplates {
+		templates[key] = value
+	}
+
+	configMapNamespaces := []string{buildServiceNamespaceName}
+	if namespace != buildServiceNamespaceName {
+		configMapNamespaces = append(configMapNamespaces, namespace)
+	}
+	for _, configMapNamespace := range configMapNamespaces {
+		templatesConfigMap := &corev1.ConfigMap{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: pacMergeRequestTemplatesConfigMapName, Namespace: configMapNamespace}, templatesConfigMap); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			log.Error(err, fmt.Sprintf("failed to get merge request templates in %s namespace", configMapNamespace), l.Action, l.ActionView)
+			return nil, err
+		}
+		for key, value := range templatesConfigMap.Data {
+			if _, isKnown := defaultMergeRequestTemplates[key]; !isKnown {
+				log.Info(fmt.Sprintf("unknown merge request template '%s' in %s namespace is ignored", key, configMapNamespace))
+				continue
+			}
+			templates[key] = value
+		}
+	}
+	return templates, nil
+}
+
+// getOnboardingMergeRequestMetadata renders metadata of the merge request that adds Pipelines as Code configuration.
+func (r *ComponentBuildReconciler) getOnboardingMergeRequestMetadata(ctx context.Context, namespace string, data *mergeRequestTemplateData) (*mergeRequestMetadata, error) {
+	if data.Batch {
+		return r.getMergeRequestMetadata(ctx, namespace, data,
+			mrTemplateBatchCommitMessageKey, mrTemplateBatchTitleKey, mrTemplateBatchBodyKey, mrTemplateBatchBranchKey, mrTemplateAuthorEmailKey)
+	}
+	return r.getMergeRequestMetadata(ctx, namespace, data,
+		mrTemplateCommitMessageKey, mrTemplateTitleKey, mrTemplateBodyKey, mrTemplateBranchKey, mrTemplateAuthorEmailKey)
+}
+
+// getPurgeMergeRequestMetadata renders metadata of the merge request that removes Pipelines as Code configuration.
+func (r *ComponentBuildReconciler) getPurgeMergeRequestMetadata(ctx context.Context, namespace string, data *mergeRequestTemplateData) (*mergeRequestMetadata, error) {
+	return r.getMergeRequestMetadata(ctx, namespace, data,
+		mrTemplatePurgeCommitMessageKey, mrTemplatePurgeTitleKey, mrTemplatePurgeBodyKey, mrTemplatePurgeBranchKey, mrTemplatePurgeAuthorEmailKey)
+}
+
+func (r *ComponentBuildReconciler) getMergeRequestMetadata(ctx context.Context, namespace string, data *mergeRequestTemplateData,
+	commitMessageKey, titleKey, bodyKey, branchKey, authorEmailKey string) (*mergeRequestMetadata, error) {
+
+	templates, err := r.getMergeRequestTemplates(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return renderMergeRequestMetadata(templates, data, commitMessageKey, titleKey, bodyKey, branchKey, authorEmailKey)
+}
+
+func renderMergeRequestMetadata(templates map[string]string, data *mergeRequestTemplateData, commitMessageKey, titleKey, bodyKey, branchKey, authorEmailKey string) (*mergeRequestMetadata, error) {
+	onboardingBranchKey := mrTemplateBranchKey
+	if data.Batch {
+		onboardingBranchKey = mrTemplateBatchBranchKey
+	}
+	rendered := make(map[string]string)
+	for _, key := range []string{commitMessageKey, titleKey, bodyKey, branchKey, onboardingBranchKey, mrTemplateAuthorNameKey, authorEmailKey} {
+		value, err := renderMergeRequestTemplate(key, templates[key], data)
+		if err != nil {
+			return nil, boerrors.NewBuildOpError(boerrors.EPaCMergeRequestTemplateInvalid, err)
+		}
+		if key != bodyKey {
+			// Only body may be multiline
+			value = strings.TrimSpace(value)
+			if value == "" {
+				return nil, boerrors.NewBuildOpError(boerrors.EPaCMergeRequestTemplateInvalid,
+					fmt.Errorf("merge request template '%s' is rendered into empty string", key))
+			}
+		}
+		rendered[key] = value
+	}
+
+	return &mergeRequestMetadata{
+		CommitMessage:        rendered[commitMessageKey],
+		Title:                rendered[titleKey],
+		Body:                 rendered[bodyKey],
+		BranchName:           rendered[branchKey],
+		AuthorName:           rendered[mrTemplateAuthorNameKey],
+		AuthorEmail:          rendered[authorEmailKey],
+		OnboardingBranchName: rendered[onboardingBranchKey],
+	}, nil
+}
+
+// pipelineRunPathTemplateData is the data PipelineRun definition path template is rendered with.
+type pipelineRunPathTemplateData struct {
+	mergeRequestTemplateData
+	// Event is the event the PipelineRun is triggered on: push or pull-request
+	Event string
+}
+
+// getPipelineRunPaths returns paths of the on push and on pull request PipelineRun definitions of the given component in its repository.
+func (r *ComponentBuildReconciler) getPipelineRunPaths(ctx context.Context, component *appstudiov1alpha1.Component, targetBranch string) (string, string, error) {
+	templates, err := r.getMergeRequestTemplates(ctx, component.Namespace)
+	if err != nil {
+		return "", "", err
+	}
+	data := newMergeRequestTemplateData(component, "", "")
+	data.TargetBranch = targetBranch
+	return renderPipelineRunPaths(templates[mrTemplatePipelineRunPathKey], data)
+}
+
+func renderPipelineRunPaths(pathTemplate string, data *mergeRequestTemplateData) (string, string, error) {
+	var paths []string
+	for _, event := range []string{pipelineRunOnPushEvent, pipelineRunOnPREvent} {
+		rendered, err := renderMergeRequestTemplate(mrTemplatePipelineRunPathKey, pathTemplate, &pipelineRunPathTemplateData{*data, event})
+		if err != nil {
+			return "", "", boerrors.NewBuildOpError(boerrors.EPaCMergeRequestTemplateInvalid, err)
+		}
+		pipelineRunPath := strings.TrimSpace(rendered)
+		if !isValidPipelineRunPath(pipelineRunPath) {
+			return "", "", boerrors.NewBuildOpError(boerrors.EPaCMergeRequestTemplateInvalid,
+				fmt.Errorf("PipelineRun path '%s' must be a .yaml or .yml file under %s directory", pipelineRunPath, pacConfigDirectory))
+		}
+		paths = append(paths, pipelineRunPath)
+	}
+	if paths[0] == paths[1] {
+		return "", "", boerrors.NewBuildOpError(boerrors.EPaCMergeRequestTemplateInvalid,
+			fmt.Errorf("PipelineRun path template must depend on .Event, got the same '%s' path for all events", paths[0]))
+	}
+	return paths[0], paths[1], nil
+}
+
+// isValidPipelineRunPath checks that the given path is a clean relative path of a yaml file inside PaC configuration directory.
+func isValidPipelineRunPath(pipelineRunPath string) bool {
+	if path.Clean(pipelineRunPath) != pipelineRunPath || !strings.HasPrefix(pipelineRunPath, pacConfigDirectory) {
+		return false
+	}
+	extension := path.Ext(pipelineRunPath)
+	return extension == ".yaml" || extension == ".yml"
+}
+
+func renderMergeRequestTemplate(name, text string, data interface{}) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse merge request template '%s': %w", name, err)
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", fmt.Errorf("failed to render merge request template '%s': %w", name, err)
+	}
+	return rendered.String(), nil
+}
+
+// mergeRequestOptions are optional settings of onboarding merge request, see gitprovider.MergeRequestData for details.
+type mergeRequestOptions struct {
+	Labels        []string
+	Reviewers     []string
+	TeamReviewers []string
+	Assignees     []string
+	Draft         bool
+	// AutoMerge requests the git provider to merge the onboarding merge request once its checks pass
+	AutoMerge   bool
+	MergeMethod string
+	// Batch groups the component with other components in the same repository and target branch into one onboarding merge request
+	Batch bool
+}
+
+// getMergeRequestOptions returns labels, reviewers, assignees, draft, auto-merge and batch modes of the onboarding merge request.
+// Settings from 'pac-merge-request-options' config map in the component namespace are overridden by the component annotations.
+// Invalid values are ignored.
+func (r *ComponentBuildReconciler) getMergeRequestOptions(ctx context.Context, component *appstudiov1alpha1.Component) (*mergeRequestOptions, error) {
+	log := ctrllog.FromContext(ctx)
+
+	optionsConfigMap := &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: pacMergeRequestOptionsConfigMapName, Namespace: component.Namespace}, optionsConfigMap); err != nil {
+		if !errors.IsNotFound(err) {
+			log.Error(err, "failed to get merge request options", l.Action, l.ActionView)
+			return nil, err
+		}
+	}
+
+	options := &mergeRequestOptions{}
+	listOptions := []struct {
+		configMapKey   string
+		annotationName string
+		value          *[]string
+	}{
+		{mrOptionLabelsKey, PaCMergeRequestLabelsAnnotationName, &options.Labels},
+		{mrOptionReviewersKey, PaCMergeRequestReviewersAnnotationName, &options.Reviewers},
+		{mrOptionTeamReviewersKey, PaCMergeRequestTeamReviewersAnnotationName, &options.TeamReviewers},
+		{mrOptionAssigneesKey, PaCMergeRequestAssigneesAnnotationName, &options.Assignees},
+	}
+	for _, option := range listOptions {
+		value, exists := component.Annotations[option.annotationName]
+		if !exists {
+			value = optionsConfigMap.Data[option.configMapKey]
+		}
+		*option.value = parseCommaSeparatedList(value)
+	}
+
+	configMapSource := " key of " + pacMergeRequestOptionsConfigMapName + " config map"
+	boolOptions := []struct {
+		source string
+		value  string
+		option *bool
+	}{
+		{mrOptionDraftKey + configMapSource, optionsConfigMap.Data[mrOptionDraftKey], &options.Draft},
+		{PaCMergeRequestDraftAnnotationName + " annotation", component.Annotations[PaCMergeRequestDraftAnnotationName], &options.Draft},
+		{mrOptionAutoMergeKey + configMapSource, optionsConfigMap.Data[mrOptionAutoMergeKey], &options.AutoMerge},
+		{PaCMergeRequestAutoMergeAnnotationName + " annotation", component.Annotations[PaCMergeRequestAutoMergeAnnotationName], &options.AutoMerge},
+		{mrOptionBatchKey + configMapSource, optionsConfigMap.Data[mrOptionBatchKey], &options.Batch},
+		{PaCMergeRequestBatchAnnotationName + " annotation", component.Annotations[PaCMergeRequestBatchAnnotationName], &options.Batch},
+	}
+	for _, boolOption := range boolOptions {
+		if boolOption.value == "" {
+			continue
+		}
+		if value, err := strconv.ParseBool(boolOption.value); err == nil {
+			*boolOption.option = value
+		} else {
+			log.Info(fmt.Sprintf("invalid boolean value '%s' in %s, ignoring", boolOption.value, boolOption.source))
+		}
+	}
+
+	options.MergeMethod = gitprovider.MergeMethodMerge
+	mergeMethodSources := []struct {
+		source string
+		value  string
+	}{
+		{mrOptionMergeMethodKey + configMapSource, optionsConfigMap.Data[mrOptionMergeMethodKey]},
+		{PaCMergeRequestMergeMethodAnnotationName + " annotation", component.Annotations[PaCMergeRequestMergeMethodAnnotationName]},
+	}
+	for _, mergeMethodSource := range mergeMethodSources {
+		if mergeMethodSource.value == "" {
+			continue
+		}
+		if mergeMethod := strings.ToLower(strings.TrimSpace(mergeMethodSource.value)); gitprovider.IsValidMergeMethod(mergeMethod) {
+			options.MergeMethod = mergeMethod
+		} else {
+			log.Info(fmt.Sprintf("invalid merge method '%s' in %s, ignoring", mergeMethodSource.value, mergeMethodSource.source))
+		}
+	}
+
+	return options, nil
+}
+
+// parseCommaSeparatedList returns non empty trimmed items of the given comma separated list.
+func parseCommaSeparatedList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
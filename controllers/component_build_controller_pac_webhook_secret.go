@@ -0,0 +1,275 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/gitops"
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitproviderfactory"
+	l "github.com/redhat-appstudio/build-service/pkg/logs"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// pacWebhookSecretMaxAgeEnvVar is the maximum age of PaC webhook secrets, e.g. 720h.
+	// If not set, webhook secrets are rotated only on request.
+	pacWebhookSecretMaxAgeEnvVar = "PAC_WEBHOOK_SECRET_MAX_AGE"
+
+	// webhookSecretsRotationTimeAnnotationName holds JSON map of webhook secret keys to the time the secrets were generated.
+	// The annotation is set on the webhooks secret, because a webhook secret is shared by all components of the repository.
+	webhookSecretsRotationTimeAnnotationName = "appstudio.openshift.io/webhook-secrets-rotated-at"
+	// webhookSecretsPendingSyncAnnotationName holds JSON list of webhook secret keys which are already rotated in the secret,
+	// but not yet set in the repository webhook.
+	webhookSecretsPendingSyncAnnotationName = "appstudio.openshift.io/webhook-secrets-pending-sync"
+)
+
+// reconcilePaCWebhookSecretRotation rotates webhook secret of the component repository
+// if the rotation is requested via the component annotation or the secret is older than the configured max age.
+// The new secret is saved first and marked as pending until the webhook in the repository is updated,
+// so a retry after a failure sets the same saved secret into the webhook instead of generating another one.
+func (r *ComponentBuildReconciler) reconcilePaCWebhookSecretRotation(ctx context.Context, component *appstudiov1alpha1.Component) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx).WithName("PaC-webhook-secret-rotation")
+	ctx = ctrllog.IntoContext(ctx, log)
+
+	isRotationRequested := component.Annotations[PaCWebhookSecretRotateAnnotationName] == PaCWebhookSecretRotateRequestedAnnotationValue
+	maxAge := getPaCWebhookSecretMaxAge(ctx)
+	if !isRotationRequested && maxAge == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	gitProvider, err := gitops.GetGitProvider(*component)
+	if err != nil {
+		log.Error(err, "error detecting git provider")
+		return ctrl.Result{}, nil
+	}
+	pacSecret, err := r.ensurePaCSecret(ctx, component, gitProvider)
+	if err != nil {
+		log.Error(err, "failed to get Pipelines as Code secret", l.Action, l.ActionView)
+		return ctrl.Result{}, nil
+	}
+	if gitops.IsPaCApplicationConfigured(gitProvider, pacSecret.Data) {
+		// Git application doesn't use per repository webhooks
+		if isRotationRequested {
+			log.Info("Git application is used, there is no webhook secret to rotate")
+			return ctrl.Result{}, r.removePaCWebhookSecretRotateAnnotation(ctx, component)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	webhookSecretsSecret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: gitops.PipelinesAsCodeWebhooksSecretName, Namespace: component.Namespace}, webhookSecretsSecret); err != nil {
+		if errors.IsNotFound(err) {
+			// Webhook secret is generated on PaC provision, there is nothing to rotate
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "failed to get webhook secrets secret", l.Action, l.ActionView)
+		return ctrl.Result{}, err
+	}
+	webhookSecretKey := gitops.GetWebhookSecretKeyForComponent(*component)
+	isSyncPending := isWebhookSecretSyncPending(webhookSecretsSecret, webhookSecretKey)
+	if !isRotationRequested && !isSyncPending {
+		age := time.Since(getWebhookSecretRotationTime(webhookSecretsSecret, webhookSecretKey))
+		if age < maxAge {
+			return ctrl.Result{RequeueAfter: maxAge - age}, nil
+		}
+	}
+
+	webhookTargetUrl, err := r.getPaCWebhookTargetUrl(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	repoUrl := component.Spec.Source.GitSource.URL
+	gitClient, err := gitproviderfactory.CreateGitClient(gitproviderfactory.GitClientConfig{
+		PacSecretData:             pacSecret.Data,
+		GitProvider:               gitProvider,
+		RepoUrl:                   repoUrl,
+		IsAppInstallationExpected: true,
+	})
+	if err != nil {
+		return r.handlePaCWebhookSecretRotationError(ctx, component, err)
+	}
+
+	if !isSyncPending {
+		if webhookSecretsSecret.Data == nil {
+			webhookSecretsSecret.Data = make(map[string][]byte)
+		}
+		webhookSecretsSecret.Data[webhookSecretKey] = []byte(generatePaCWebhookSecretString())
+		setWebhookSecretRotationTime(webhookSecretsSecret, webhookSecretKey, time.Now())
+		setWebhookSecretSyncPending(webhookSecretsSecret, webhookSecretKey, true)
+		if err := r.Client.Update(ctx, webhookSecretsSecret); err != nil {
+			log.Error(err, "failed to update webhook secrets secret", l.Action, l.ActionUpdate)
+			return ctrl.Result{}, err
+		}
+	}
+
+	webhookSecretString := string(webhookSecretsSecret.Data[webhookSecretKey])
+	if err := gitClient.SetupPaCWebhook(repoUrl, webhookTargetUrl, webhookSecretString, getPaCWebhookOptions(ctx, component)); err != nil {
+		return r.handlePaCWebhookSecretRotationError(ctx, component, err)
+	}
+
+	setWebhookSecretSyncPending(webhookSecretsSecret, webhookSecretKey, false)
+	if err := r.Client.Update(ctx, webhookSecretsSecret); err != nil {
+		log.Error(err, "failed to update webhook secrets secret", l.Action, l.ActionUpdate)
+		return ctrl.Result{}, err
+	}
+
+	// Make sure PaC Repository references the webhook secret
+	if err := r.ensurePaCRepository(ctx, component, pacSecret.Data); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	message := fmt.Sprintf("Pipelines as Code webhook secret rotated for %s repository", repoUrl)
+	log.Info(message, l.Action, l.ActionUpdate, l.Audit, "true")
+	r.EventRecorder.Event(component, "Normal", "PaCWebhookSecretRotated", message)
+
+	if isRotationRequested {
+		if err := r.removePaCWebhookSecretRotateAnnotation(ctx, component); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if maxAge != 0 {
+		return ctrl.Result{RequeueAfter: maxAge}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *ComponentBuildReconciler) handlePaCWebhookSecretRotationError(ctx context.Context, component *appstudiov1alpha1.Component, err error) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+
+	if retryAt, isRateLimited := boerrors.GetRetryAt(err); isRateLimited {
+		requeueAfter := getRateLimitRequeueAfter(retryAt)
+		log.Info(fmt.Sprintf("Git provider rate limit reached, webhook secret rotation is postponed for %s", requeueAfter), "error", err.Error())
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+	r.EventRecorder.Event(component, "Warning", "ErrorRotatingPaCWebhookSecret", err.Error())
+	if boErr, ok := err.(*boerrors.BuildOpError); ok && boErr.IsPersistent() {
+		// The configuration must be fixed first, do not retry
+		log.Error(err, "failed to rotate Pipelines as Code webhook secret", l.Audit, "true")
+		return ctrl.Result{}, nil
+	}
+	log.Error(err, "Pipelines as Code webhook secret rotation transient error")
+	return ctrl.Result{}, err
+}
+
+func (r *ComponentBuildReconciler) removePaCWebhookSecretRotateAnnotation(ctx context.Context, component *appstudiov1alpha1.Component) error {
+	log := ctrllog.FromContext(ctx)
+
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: component.Name, Namespace: component.Namespace}, component); err != nil {
+		log.Error(err, "failed to get Component", l.Action, l.ActionView)
+		return err
+	}
+	delete(component.Annotations, PaCWebhookSecretRotateAnnotationName)
+	if err := r.Client.Update(ctx, component); err != nil {
+		log.Error(err, "failed to remove webhook secret rotation request from the Component", l.Action, l.ActionUpdate)
+		return err
+	}
+	return nil
+}
+
+// getPaCWebhookSecretMaxAge returns configured max age of webhook secrets or 0 if rotation by age is disabled.
+func getPaCWebhookSecretMaxAge(ctx context.Context) time.Duration {
+	maxAgeStr := os.Getenv(pacWebhookSecretMaxAgeEnvVar)
+	if maxAgeStr == "" {
+		return 0
+	}
+	maxAge, err := time.ParseDuration(maxAgeStr)
+	if err != nil || maxAge <= 0 {
+		ctrllog.FromContext(ctx).Info(fmt.Sprintf("invalid webhook secret max age '%s' in %s envVar, rotation by age is disabled", maxAgeStr, pacWebhookSecretMaxAgeEnvVar))
+		return 0
+	}
+	return maxAge
+}
+
+// getWebhookSecretRotationTime returns the time when the webhook secret with the given key was generated.
+// Secrets generated before the rotation time was recorded are considered as old as the webhooks secret.
+func getWebhookSecretRotationTime(webhookSecretsSecret *corev1.Secret, webhookSecretKey string) time.Time {
+	rotationTimes := map[string]string{}
+	if rotationTimesJson, exists := webhookSecretsSecret.Annotations[webhookSecretsRotationTimeAnnotationName]; exists {
+		_ = json.Unmarshal([]byte(rotationTimesJson), &rotationTimes)
+	}
+	if rotationTime, err := time.Parse(time.RFC3339, rotationTimes[webhookSecretKey]); err == nil {
+		return rotationTime
+	}
+	return webhookSecretsSecret.CreationTimestamp.Time
+}
+
+// setWebhookSecretRotationTime records the time when the webhook secret with the given key was generated.
+func setWebhookSecretRotationTime(webhookSecretsSecret *corev1.Secret, webhookSecretKey string, rotationTime time.Time) {
+	rotationTimes := map[string]string{}
+	if rotationTimesJson, exists := webhookSecretsSecret.Annotations[webhookSecretsRotationTimeAnnotationName]; exists {
+		_ = json.Unmarshal([]byte(rotationTimesJson), &rotationTimes)
+	}
+	rotationTimes[webhookSecretKey] = rotationTime.UTC().Format(time.RFC3339)
+	rotationTimesJson, _ := json.Marshal(rotationTimes)
+
+	if webhookSecretsSecret.Annotations == nil {
+		webhookSecretsSecret.Annotations = make(map[string]string)
+	}
+	webhookSecretsSecret.Annotations[webhookSecretsRotationTimeAnnotationName] = string(rotationTimesJson)
+}
+
+// isWebhookSecretSyncPending checks if the webhook secret with the given key is saved, but not yet set in the repository webhook.
+func isWebhookSecretSyncPending(webhookSecretsSecret *corev1.Secret, webhookSecretKey string) bool {
+	for _, pendingKey := range getWebhookSecretsPendingSync(webhookSecretsSecret) {
+		if pendingKey == webhookSecretKey {
+			return true
+		}
+	}
+	return false
+}
+
+// setWebhookSecretSyncPending marks or unmarks the webhook secret with the given key as not set in the repository webhook.
+func setWebhookSecretSyncPending(webhookSecretsSecret *corev1.Secret, webhookSecretKey string, isPending bool) {
+	pendingKeys := []string{}
+	for _, pendingKey := range getWebhookSecretsPendingSync(webhookSecretsSecret) {
+		if pendingKey != webhookSecretKey {
+			pendingKeys = append(pendingKeys, pendingKey)
+		}
+	}
+	if isPending {
+		pendingKeys = append(pendingKeys, webhookSecretKey)
+	}
+
+	if len(pendingKeys) == 0 {
+		delete(webhookSecretsSecret.Annotations, webhookSecretsPendingSyncAnnotationName)
+		return
+	}
+	pendingKeysJson, _ := json.Marshal(pendingKeys)
+	if webhookSecretsSecret.Annotations == nil {
+		webhookSecretsSecret.Annotations = make(map[string]string)
+	}
+	webhookSecretsSecret.Annotations[webhookSecretsPendingSyncAnnotationName] = string(pendingKeysJson)
+}
+
+func getWebhookSecretsPendingSync(webhookSecretsSecret *corev1.Secret) []string {
+	pendingKeys := []string{}
+	if pendingKeysJson, exists := webhookSecretsSecret.Annotations[webhookSecretsPendingSyncAnnotationName]; exists {
+		_ = json.Unmarshal([]byte(pendingKeysJson), &pendingKeys)
+	}
+	return pendingKeys
+}
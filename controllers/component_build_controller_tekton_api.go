@@ -0,0 +1,144 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-logr/logr"
+	tektonapiv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	l "github.com/redhat-appstudio/build-service/pkg/logs"
+)
+
+const (
+	// pipelineRunApiVersionEnvVar sets Tekton API version of generated PipelineRuns, both PaC and initial build ones.
+	// Supported values are tekton.dev/v1beta1 (default) and tekton.dev/v1.
+	pipelineRunApiVersionEnvVar = "PIPELINERUN_API_VERSION"
+
+	tektonApiVersionV1beta1 = "tekton.dev/v1beta1"
+	tektonApiVersionV1      = "tekton.dev/v1"
+)
+
+// getPipelineRunApiVersion returns Tekton API version to generate PipelineRuns in.
+func getPipelineRunApiVersion(log logr.Logger) string {
+	apiVersion := os.Getenv(pipelineRunApiVersionEnvVar)
+	switch apiVersion {
+	case tektonApiVersionV1beta1, tektonApiVersionV1:
+		return apiVersion
+	case "":
+		return tektonApiVersionV1beta1
+	default:
+		log.Info(fmt.Sprintf("invalid Tekton API version '%s' in %s envVar, using default %s", apiVersion, pipelineRunApiVersionEnvVar, tektonApiVersionV1beta1), l.Action, l.ActionView)
+		return tektonApiVersionV1beta1
+	}
+}
+
+// convertPipelineRun returns the given PipelineRun in the given Tekton API version.
+// Conversion to v1 follows Tekton rules, e.g. bundle references are replaced with bundles resolver references.
+func convertPipelineRun(pipelineRun *tektonapi.PipelineRun, apiVersion string) (client.Object, error) {
+	switch apiVersion {
+	case tektonApiVersionV1beta1:
+		return pipelineRun, nil
+	case tektonApiVersionV1:
+		pipelineRunV1 := &tektonapiv1.PipelineRun{}
+		if err := pipelineRun.DeepCopy().ConvertTo(context.Background(), pipelineRunV1); err != nil {
+			return nil, fmt.Errorf("failed to convert PipelineRun %s to %s: %w", pipelineRun.Name, apiVersion, err)
+		}
+		pipelineRunV1.TypeMeta = metav1.TypeMeta{Kind: "PipelineRun", APIVersion: tektonApiVersionV1}
+		normalizeBundleResolverKinds(pipelineRunV1)
+		return pipelineRunV1, nil
+	default:
+		return nil, fmt.Errorf("unsupported Tekton API version '%s'", apiVersion)
+	}
+}
+
+// normalizeBundleResolverKinds lowercases kind parameter of bundles resolver references.
+// Tekton conversion copies task kind as is, e.g. 'Task', but bundles resolver matches lowercased kinds of bundle layers.
+func normalizeBundleResolverKinds(pipelineRun *tektonapiv1.PipelineRun) {
+	normalizeParams := func(resolverRef *tektonapiv1.ResolverRef) {
+		if resolverRef.Resolver != "bundles" {
+			return
+		}
+		for i := range resolverRef.Params {
+			if resolverRef.Params[i].Name == "kind" {
+				resolverRef.Params[i].Value.StringVal = strings.ToLower(resolverRef.Params[i].Value.StringVal)
+			}
+		}
+	}
+
+	if pipelineRun.Spec.PipelineRef != nil {
+		normalizeParams(&pipelineRun.Spec.PipelineRef.ResolverRef)
+	}
+	if pipelineRun.Spec.PipelineSpec != nil {
+		for _, tasks := range [][]tektonapiv1.PipelineTask{pipelineRun.Spec.PipelineSpec.Tasks, pipelineRun.Spec.PipelineSpec.Finally} {
+			for i := range tasks {
+				if tasks[i].TaskRef != nil {
+					normalizeParams(&tasks[i].TaskRef.ResolverRef)
+				}
+			}
+		}
+	}
+}
+
+// marshalPipelineRun returns YAML definition of the given PipelineRun in the given Tekton API version.
+func marshalPipelineRun(pipelineRun *tektonapi.PipelineRun, apiVersion string) ([]byte, error) {
+	convertedPipelineRun, err := convertPipelineRun(pipelineRun, apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(convertedPipelineRun)
+}
+
+// unmarshalPipelineRun parses YAML definition of a PipelineRun in any supported Tekton API version.
+// Returns the PipelineRun converted to v1beta1 and the original API version.
+func unmarshalPipelineRun(content []byte) (*tektonapi.PipelineRun, string, error) {
+	typeMeta := &metav1.TypeMeta{}
+	if err := yaml.Unmarshal(content, typeMeta); err != nil {
+		return nil, "", err
+	}
+	if typeMeta.Kind != "PipelineRun" {
+		return nil, "", fmt.Errorf("expected PipelineRun but got '%s' kind", typeMeta.Kind)
+	}
+
+	pipelineRun := &tektonapi.PipelineRun{}
+	switch typeMeta.APIVersion {
+	case tektonApiVersionV1beta1:
+		if err := yaml.Unmarshal(content, pipelineRun); err != nil {
+			return nil, "", err
+		}
+	case tektonApiVersionV1:
+		pipelineRunV1 := &tektonapiv1.PipelineRun{}
+		if err := yaml.Unmarshal(content, pipelineRunV1); err != nil {
+			return nil, "", err
+		}
+		if err := pipelineRun.ConvertFrom(context.Background(), pipelineRunV1); err != nil {
+			return nil, "", err
+		}
+		pipelineRun.TypeMeta = metav1.TypeMeta{Kind: "PipelineRun", APIVersion: tektonApiVersionV1beta1}
+	default:
+		return nil, "", fmt.Errorf("unsupported Tekton API version '%s'", typeMeta.APIVersion)
+	}
+	return pipelineRun, typeMeta.APIVersion, nil
+}
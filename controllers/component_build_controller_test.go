@@ -18,7 +18,7 @@ package controllers
 
 import (
 	"fmt"
-	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -26,6 +26,7 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	pacv1alpha1 "github.com/openshift-pipelines/pipelines-as-code/pkg/apis/pipelinesascode/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -35,12 +36,11 @@ import (
 	gitopsprepare "github.com/redhat-appstudio/application-service/gitops/prepare"
 	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitproviderfactory"
 	"github.com/redhat-appstudio/build-service/pkg/github"
 	"github.com/redhat-appstudio/build-service/pkg/gitlab"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
-
-	gogithub "github.com/google/go-github/v45/github"
-	gogitlab "github.com/xanzy/go-gitlab"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -102,23 +102,7 @@ var _ = Describe("Component initial build controller", func() {
 			}
 			createSecret(pacSecretKey, pacSecretData)
 
-			github.NewGithubClientByApp = func(appId int64, privateKeyPem []byte, owner string) (*github.GithubClient, error) { return nil, nil }
-			github.NewGithubClientForSimpleBuildByApp = func(appId int64, privateKeyPem []byte) (*github.GithubClient, error) { return nil, nil }
-			github.GetGitHubAppName = func(appId int64, privateKeyPem []byte) (string, string, error) { return "appName", "app-slug", nil }
-			github.NewGithubClient = func(accessToken string) *github.GithubClient { return nil }
-			github.IsAppInstalledIntoRepository = func(g *github.GithubClient, owner, repository string) (bool, error) { return true, nil }
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) { return "", nil }
-			github.FindUnmergedOnboardingMergeRequest = func(*github.GithubClient, string, string, string, string, string) (*gogithub.PullRequest, error) {
-				return nil, nil
-			}
-			github.SetupPaCWebhook = func(g *github.GithubClient, webhookUrl, webhookSecret, owner, repository string) error { return nil }
-			gitlab.EnsurePaCMergeRequest = func(g *gitlab.GitlabClient, d *gitlab.PaCMergeRequestData) (string, error) { return "", nil }
-			gitlab.SetupPaCWebhook = func(g *gitlab.GitlabClient, projectPath, webhookUrl, webhookSecret string) error { return nil }
-			github.UndoPaCPullRequest = func(g *github.GithubClient, d *github.PaCPullRequestData) (string, error) { return "", nil }
-			github.DeletePaCWebhook = func(g *github.GithubClient, webhookUrl, owner, repository string) error { return nil }
-			gitlab.UndoPaCMergeRequest = func(g *gitlab.GitlabClient, d *gitlab.PaCMergeRequestData) (string, error) { return "", nil }
-			gitlab.DeletePaCWebhook = func(g *gitlab.GitlabClient, projectPath, webhookUrl string) error { return nil }
-			github.GetBranchSHA = func(g *github.GithubClient, owner, repository, branchName string) (string, error) { return "hash", nil }
+			ResetTestGitProviderClient()
 
 			createComponentForPaCBuild(getSampleComponentData(resourceKey))
 		})
@@ -135,24 +119,23 @@ var _ = Describe("Component initial build controller", func() {
 
 		It("should successfully submit PR with PaC definitions using GitHub application and set PaC annotation", func() {
 			isCreatePaCPullRequestInvoked := false
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				isCreatePaCPullRequestInvoked = true
-				Expect(d.Owner).To(Equal("devfile-samples"))
-				Expect(d.Repository).To(Equal("devfile-sample-java-springboot-basic"))
+				Expect(repoUrl).To(Equal(SampleRepoLink))
 				Expect(len(d.Files)).To(Equal(2))
 				for _, file := range d.Files {
 					Expect(strings.HasPrefix(file.FullPath, ".tekton/")).To(BeTrue())
 				}
 				Expect(d.CommitMessage).ToNot(BeEmpty())
-				Expect(d.Branch).ToNot(BeEmpty())
-				Expect(d.BaseBranch).To(Equal("main"))
-				Expect(d.PRTitle).ToNot(BeEmpty())
-				Expect(d.PRText).ToNot(BeEmpty())
-				Expect(d.AuthorName).ToNot(BeEmpty())
+				Expect(d.BranchName).ToNot(BeEmpty())
+				Expect(d.BaseBranchName).To(Equal("main"))
+				Expect(d.Title).ToNot(BeEmpty())
+				Expect(d.Text).ToNot(BeEmpty())
+				Expect(d.AuthorName).To(Equal("test-app-slug"))
 				Expect(d.AuthorEmail).ToNot(BeEmpty())
-				return "url", nil
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
 			}
-			github.SetupPaCWebhook = func(g *github.GithubClient, webhookUrl, webhookSecret, owner, repository string) error {
+			SetupPaCWebhookFunc = func(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *gitprovider.WebhookOptions) error {
 				defer GinkgoRecover()
 				Fail("Should not create webhook if GitHub application is used")
 				return nil
@@ -168,14 +151,14 @@ var _ = Describe("Component initial build controller", func() {
 		})
 
 		It("should fail to submit PR if GitHub application is not installed into repo", func() {
-			github.IsAppInstalledIntoRepository = func(*github.GithubClient, string, string) (bool, error) {
-				return false, nil
+			gitproviderfactory.CreateGitClient = func(gitClientConfig gitproviderfactory.GitClientConfig) (gitprovider.GitProvider, error) {
+				return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppNotInstalled, fmt.Errorf("GitHub Application is not installed into the repository"))
 			}
 
 			isCreatePaCPullRequestInvoked := false
-			github.CreatePaCPullRequest = func(*github.GithubClient, *github.PaCPullRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				isCreatePaCPullRequestInvoked = true
-				return "url", nil
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
 			}
 
 			setComponentDevfileModel(resourceKey)
@@ -201,9 +184,9 @@ var _ = Describe("Component initial build controller", func() {
 			}, timeout, interval).Should(BeTrue())
 
 			isCreatePaCPullRequestInvoked := false
-			github.CreatePaCPullRequest = func(*github.GithubClient, *github.PaCPullRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				isCreatePaCPullRequestInvoked = true
-				return "url", nil
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
 			}
 
 			setComponentDevfileModel(resourceKey)
@@ -220,9 +203,9 @@ var _ = Describe("Component initial build controller", func() {
 
 		It("should fail to submit PR if PaC secret is invalid", func() {
 			isCreatePaCPullRequestInvoked := false
-			github.CreatePaCPullRequest = func(*github.GithubClient, *github.PaCPullRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				isCreatePaCPullRequestInvoked = true
-				return "url", nil
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
 			}
 
 			deleteSecret(pacSecretKey)
@@ -251,30 +234,28 @@ var _ = Describe("Component initial build controller", func() {
 
 		It("should successfully submit PR with PaC definitions using GitHub token and set PaC annotation", func() {
 			isCreatePaCPullRequestInvoked := false
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				isCreatePaCPullRequestInvoked = true
-				Expect(d.Owner).To(Equal("devfile-samples"))
-				Expect(d.Repository).To(Equal("devfile-sample-java-springboot-basic"))
+				Expect(repoUrl).To(Equal(SampleRepoLink))
 				Expect(len(d.Files)).To(Equal(2))
 				for _, file := range d.Files {
 					Expect(strings.HasPrefix(file.FullPath, ".tekton/")).To(BeTrue())
 				}
 				Expect(d.CommitMessage).ToNot(BeEmpty())
-				Expect(d.Branch).ToNot(BeEmpty())
-				Expect(d.BaseBranch).ToNot(BeEmpty())
-				Expect(d.PRTitle).ToNot(BeEmpty())
-				Expect(d.PRText).ToNot(BeEmpty())
+				Expect(d.BranchName).ToNot(BeEmpty())
+				Expect(d.BaseBranchName).ToNot(BeEmpty())
+				Expect(d.Title).ToNot(BeEmpty())
+				Expect(d.Text).ToNot(BeEmpty())
 				Expect(d.AuthorName).ToNot(BeEmpty())
 				Expect(d.AuthorEmail).ToNot(BeEmpty())
-				return "url", nil
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
 			}
 			isSetupPaCWebhookInvoked := false
-			github.SetupPaCWebhook = func(g *github.GithubClient, webhookUrl, webhookSecret, owner, repository string) error {
+			SetupPaCWebhookFunc = func(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *gitprovider.WebhookOptions) error {
 				isSetupPaCWebhookInvoked = true
 				Expect(webhookUrl).To(Equal(pacWebhookUrl))
 				Expect(webhookSecret).ToNot(BeEmpty())
-				Expect(owner).To(Equal("devfile-samples"))
-				Expect(repository).To(Equal("devfile-sample-java-springboot-basic"))
+				Expect(repoUrl).To(Equal(SampleRepoLink))
 				return nil
 			}
 
@@ -295,30 +276,476 @@ var _ = Describe("Component initial build controller", func() {
 			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
 		})
 
+		It("should submit PR with metadata rendered from merge request templates", func() {
+			globalTemplatesKey := types.NamespacedName{Name: pacMergeRequestTemplatesConfigMapName, Namespace: buildServiceNamespaceName}
+			namespaceTemplatesKey := types.NamespacedName{Name: pacMergeRequestTemplatesConfigMapName, Namespace: resourceKey.Namespace}
+			createConfigMap(globalTemplatesKey, map[string]string{
+				mrTemplateTitleKey:       "Onboard {{.ComponentName}} from {{.ApplicationName}}",
+				mrTemplateBranchKey:      "konflux-{{.ComponentName}}",
+				mrTemplateAuthorNameKey:  "konflux",
+				mrTemplateAuthorEmailKey: "konflux@example.com",
+			})
+			defer deleteConfigMap(globalTemplatesKey)
+			createConfigMap(namespaceTemplatesKey, map[string]string{
+				mrTemplateTitleKey: "Onboard {{.ComponentName}} in {{.Namespace}}",
+				mrTemplateBodyKey:  "See {{.RepositoryUrl}}",
+			})
+			defer deleteConfigMap(namespaceTemplatesKey)
+
+			isCreatePaCPullRequestInvoked := false
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				isCreatePaCPullRequestInvoked = true
+				Expect(d.CommitMessage).To(Equal("Appstudio update " + resourceKey.Name))
+				Expect(d.Title).To(Equal(fmt.Sprintf("Onboard %s in %s", resourceKey.Name, resourceKey.Namespace)))
+				Expect(d.Text).To(Equal("See " + SampleRepoLink))
+				Expect(d.BranchName).To(Equal("konflux-" + resourceKey.Name))
+				Expect(d.AuthorName).To(Equal("konflux"))
+				Expect(d.AuthorEmail).To(Equal("konflux@example.com"))
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+
+			pacSecretData := map[string]string{"github.token": "ghp_token"}
+			createSecret(pacSecretKey, pacSecretData)
+
+			setComponentDevfileModel(resourceKey)
+
+			Eventually(func() bool {
+				return isCreatePaCPullRequestInvoked
+			}, timeout, interval).Should(BeTrue())
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+		})
+
+		It("should fail to submit PR if merge request template is invalid", func() {
+			namespaceTemplatesKey := types.NamespacedName{Name: pacMergeRequestTemplatesConfigMapName, Namespace: resourceKey.Namespace}
+			createConfigMap(namespaceTemplatesKey, map[string]string{
+				mrTemplateTitleKey: "Onboard {{.Unknown}}",
+			})
+			defer deleteConfigMap(namespaceTemplatesKey)
+
+			isCreatePaCPullRequestInvoked := false
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				isCreatePaCPullRequestInvoked = true
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+
+			pacSecretData := map[string]string{"github.token": "ghp_token"}
+			createSecret(pacSecretKey, pacSecretData)
+
+			setComponentDevfileModel(resourceKey)
+
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionErrorAnnotationValue)
+			expectedErr := boerrors.NewBuildOpError(boerrors.EPaCMergeRequestTemplateInvalid, nil)
+			waitComponentAnnotationValue(resourceKey, PaCProvisionErrorDetailsAnnotationName, expectedErr.ShortError())
+			Expect(isCreatePaCPullRequestInvoked).To(BeFalse())
+		})
+
+		It("should submit PR with labels, reviewers, assignees and draft mode from options and annotations", func() {
+			optionsKey := types.NamespacedName{Name: pacMergeRequestOptionsConfigMapName, Namespace: resourceKey.Namespace}
+			createConfigMap(optionsKey, map[string]string{
+				mrOptionLabelsKey:    "konflux, onboarding",
+				mrOptionReviewersKey: "alice",
+				mrOptionDraftKey:     "true",
+			})
+			defer deleteConfigMap(optionsKey)
+
+			isCreatePaCPullRequestInvoked := false
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				isCreatePaCPullRequestInvoked = true
+				Expect(d.Labels).To(Equal([]string{"konflux", "onboarding"}))
+				Expect(d.Reviewers).To(Equal([]string{"bob", "carol"}))
+				Expect(d.TeamReviewers).To(Equal([]string{"build-team"}))
+				Expect(d.Assignees).To(BeEmpty())
+				Expect(d.Draft).To(BeTrue())
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+
+			deleteComponent(resourceKey)
+			component := getSampleComponentData(resourceKey)
+			component.Annotations = map[string]string{
+				PaCMergeRequestReviewersAnnotationName:     "bob,carol",
+				PaCMergeRequestTeamReviewersAnnotationName: "build-team",
+				PaCMergeRequestDraftAnnotationName:         "not-a-bool",
+			}
+			createComponentForPaCBuild(component)
+			setComponentDevfileModel(resourceKey)
+
+			Eventually(func() bool {
+				return isCreatePaCPullRequestInvoked
+			}, timeout, interval).Should(BeTrue())
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+		})
+
+		It("should enable auto-merge of PR if requested", func() {
+			optionsKey := types.NamespacedName{Name: pacMergeRequestOptionsConfigMapName, Namespace: resourceKey.Namespace}
+			createConfigMap(optionsKey, map[string]string{
+				mrOptionAutoMergeKey:   "true",
+				mrOptionMergeMethodKey: "rebase",
+			})
+			defer deleteConfigMap(optionsKey)
+
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				return &gitprovider.MergeRequest{Number: 5, WebUrl: "https://github.com/owner/repository/pull/5", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+			isEnableAutoMergeInvoked := false
+			EnablePaCMergeRequestAutoMergeFunc = func(repoUrl string, mrNumber int64, mergeMethod string) error {
+				isEnableAutoMergeInvoked = true
+				Expect(repoUrl).To(Equal(SampleRepoLink))
+				Expect(mrNumber).To(Equal(int64(5)))
+				Expect(mergeMethod).To(Equal(gitprovider.MergeMethodSquash))
+				return nil
+			}
+
+			deleteComponent(resourceKey)
+			component := getSampleComponentData(resourceKey)
+			component.Annotations = map[string]string{
+				PaCMergeRequestMergeMethodAnnotationName: "squash",
+			}
+			createComponentForPaCBuild(component)
+			setComponentDevfileModel(resourceKey)
+
+			Eventually(func() bool {
+				return isEnableAutoMergeInvoked
+			}, timeout, interval).Should(BeTrue())
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+		})
+
+		It("should not fail PaC provision if auto-merge of PR cannot be enabled", func() {
+			isEnableAutoMergeInvoked := false
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				return &gitprovider.MergeRequest{Number: 5, WebUrl: "https://github.com/owner/repository/pull/5", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+			EnablePaCMergeRequestAutoMergeFunc = func(repoUrl string, mrNumber int64, mergeMethod string) error {
+				isEnableAutoMergeInvoked = true
+				Expect(mergeMethod).To(Equal(gitprovider.MergeMethodMerge))
+				return fmt.Errorf("Pull request Auto merge is not allowed for this repository")
+			}
+
+			deleteComponent(resourceKey)
+			component := getSampleComponentData(resourceKey)
+			component.Annotations = map[string]string{
+				PaCMergeRequestAutoMergeAnnotationName: "true",
+			}
+			createComponentForPaCBuild(component)
+			setComponentDevfileModel(resourceKey)
+
+			Eventually(func() bool {
+				return isEnableAutoMergeInvoked
+			}, timeout, interval).Should(BeTrue())
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+		})
+
+		It("should track onboarding PR state until it is merged", func() {
+			os.Setenv(pacMergeRequestStatePollIntervalEnvVar, "1s")
+			defer os.Unsetenv(pacMergeRequestStatePollIntervalEnvVar)
+
+			prUrl := "https://github.com/devfile-samples/devfile-sample-java-springboot-basic/pull/7"
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				return &gitprovider.MergeRequest{Number: 7, WebUrl: prUrl, State: gitprovider.MergeRequestStateOpen}, nil
+			}
+			isMerged := false
+			GetMergeRequestFunc = func(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+				Expect(mrNumber).To(Equal(int64(7)))
+				state := gitprovider.MergeRequestStateOpen
+				if isMerged {
+					state = gitprovider.MergeRequestStateMerged
+				}
+				return &gitprovider.MergeRequest{Number: 7, WebUrl: prUrl, State: state}, nil
+			}
+
+			setComponentDevfileModel(resourceKey)
+
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+			waitComponentAnnotationValue(resourceKey, PaCMergeRequestStateAnnotationName, PaCMergeRequestStateOpenAnnotationValue)
+			component := getComponent(resourceKey)
+			Expect(component.Annotations[PaCMergeRequestUrlAnnotationName]).To(Equal(prUrl))
+			Expect(component.Annotations[PaCMergeRequestNumberAnnotationName]).To(Equal("7"))
+
+			isMerged = true
+			waitComponentAnnotationValue(resourceKey, PaCMergeRequestStateAnnotationName, PaCMergeRequestStateMergedAnnotationValue)
+		})
+
+		It("should record closed unmerged onboarding PR and set error by default", func() {
+			os.Setenv(pacMergeRequestStatePollIntervalEnvVar, "1s")
+			defer os.Unsetenv(pacMergeRequestStatePollIntervalEnvVar)
+
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				return &gitprovider.MergeRequest{Number: 8, WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+			GetMergeRequestFunc = func(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+				return &gitprovider.MergeRequest{Number: 8, WebUrl: "url", State: gitprovider.MergeRequestStateClosed}, nil
+			}
+
+			setComponentDevfileModel(resourceKey)
+
+			waitComponentAnnotationValue(resourceKey, PaCMergeRequestStateAnnotationName, PaCMergeRequestStateClosedUnmergedAnnotationValue)
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionErrorAnnotationValue)
+			expectedErr := boerrors.NewBuildOpError(boerrors.EPaCMergeRequestClosed, nil)
+			waitComponentAnnotationValue(resourceKey, PaCProvisionErrorDetailsAnnotationName, expectedErr.ShortError())
+		})
+
+		It("should reopen closed unmerged onboarding PR if configured", func() {
+			os.Setenv(pacMergeRequestStatePollIntervalEnvVar, "1s")
+			defer os.Unsetenv(pacMergeRequestStatePollIntervalEnvVar)
+
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				return &gitprovider.MergeRequest{Number: 8, WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+			isReopened := false
+			GetMergeRequestFunc = func(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+				if isReopened {
+					return &gitprovider.MergeRequest{Number: 8, WebUrl: "url", State: gitprovider.MergeRequestStateMerged}, nil
+				}
+				return &gitprovider.MergeRequest{Number: 8, WebUrl: "url", State: gitprovider.MergeRequestStateClosed}, nil
+			}
+			ReopenMergeRequestFunc = func(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+				Expect(mrNumber).To(Equal(int64(8)))
+				isReopened = true
+				return &gitprovider.MergeRequest{Number: 8, WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+
+			deleteComponent(resourceKey)
+			component := getSampleComponentData(resourceKey)
+			component.Annotations = map[string]string{
+				PaCMergeRequestClosedActionAnnotationName: "reopen",
+			}
+			createComponentForPaCBuild(component)
+			setComponentDevfileModel(resourceKey)
+
+			Eventually(func() bool {
+				return isReopened
+			}, timeout, interval).Should(BeTrue())
+			waitComponentAnnotationValue(resourceKey, PaCMergeRequestStateAnnotationName, PaCMergeRequestStateMergedAnnotationValue)
+			ensureComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+		})
+
+		It("should propose PaC configuration again if onboarding PR is closed unmerged and recreation is configured", func() {
+			os.Setenv(pacMergeRequestStatePollIntervalEnvVar, "1s")
+			defer os.Unsetenv(pacMergeRequestStatePollIntervalEnvVar)
+			os.Setenv(pacClosedMergeRequestActionEnvVar, "recreate")
+			defer os.Unsetenv(pacClosedMergeRequestActionEnvVar)
+			os.Setenv(pacClosedMergeRequestCooldownEnvVar, "0s")
+			defer os.Unsetenv(pacClosedMergeRequestCooldownEnvVar)
+
+			ensurePaCMergeRequestCalls := 0
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				ensurePaCMergeRequestCalls++
+				return &gitprovider.MergeRequest{
+					Number: int64(ensurePaCMergeRequestCalls),
+					WebUrl: fmt.Sprintf("url-%d", ensurePaCMergeRequestCalls),
+					State:  gitprovider.MergeRequestStateOpen,
+				}, nil
+			}
+			GetMergeRequestFunc = func(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+				if mrNumber == 1 {
+					return &gitprovider.MergeRequest{Number: 1, WebUrl: "url-1", State: gitprovider.MergeRequestStateClosed}, nil
+				}
+				return &gitprovider.MergeRequest{Number: mrNumber, WebUrl: fmt.Sprintf("url-%d", mrNumber), State: gitprovider.MergeRequestStateOpen}, nil
+			}
+
+			setComponentDevfileModel(resourceKey)
+
+			waitComponentAnnotationValue(resourceKey, PaCMergeRequestUrlAnnotationName, "url-2")
+			waitComponentAnnotationValue(resourceKey, PaCMergeRequestStateAnnotationName, PaCMergeRequestStateOpenAnnotationValue)
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+		})
+
+		It("should keep user customizations of existing PipelineRun definitions in PR", func() {
+			DownloadFileContentFunc = func(repoUrl string, branchName string, filePath string) ([]byte, error) {
+				Expect(branchName).To(Equal("main"))
+				if filePath != ".tekton/"+resourceKey.Name+"-push.yaml" {
+					return nil, nil
+				}
+				return []byte(`apiVersion: tekton.dev/v1beta1
+kind: PipelineRun
+metadata:
+  name: outdated-name
+  annotations:
+    pipelinesascode.tekton.dev/on-cel: "true"
+spec:
+  params:
+  - name: output-image
+    value: quay.io/outdated/image:{{revision}}
+  - name: user-param
+    value: user-value
+`), nil
+			}
+
+			isCreatePaCPullRequestInvoked := false
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				isCreatePaCPullRequestInvoked = true
+				Expect(len(d.Files)).To(Equal(2))
+				for _, file := range d.Files {
+					Expect(string(file.Content)).To(ContainSubstring("name: output-image"))
+					Expect(string(file.Content)).ToNot(ContainSubstring("quay.io/outdated/image"))
+					Expect(string(file.Content)).ToNot(ContainSubstring("outdated-name"))
+					if strings.HasSuffix(file.FullPath, "-push.yaml") {
+						Expect(string(file.Content)).To(ContainSubstring("pipelinesascode.tekton.dev/on-cel"))
+						Expect(string(file.Content)).To(ContainSubstring("name: user-param"))
+					} else {
+						Expect(string(file.Content)).ToNot(ContainSubstring("user-param"))
+					}
+				}
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+
+			pacSecretData := map[string]string{"github.token": "ghp_token"}
+			createSecret(pacSecretKey, pacSecretData)
+
+			setComponentDevfileModel(resourceKey)
+
+			Eventually(func() bool {
+				return isCreatePaCPullRequestInvoked
+			}, timeout, interval).Should(BeTrue())
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+		})
+
+		It("should submit one PR with PaC definitions of all components in the same repository if batch mode is enabled", func() {
+			optionsKey := types.NamespacedName{Name: pacMergeRequestOptionsConfigMapName, Namespace: resourceKey.Namespace}
+			createConfigMap(optionsKey, map[string]string{
+				mrOptionBatchKey: "true",
+			})
+			defer deleteConfigMap(optionsKey)
+
+			siblingKey := types.NamespacedName{Name: "batch-sibling", Namespace: resourceKey.Namespace}
+			const siblingPipelineRun = "sibling PipelineRun in batch branch"
+			DownloadFileContentFunc = func(repoUrl string, branchName string, filePath string) ([]byte, error) {
+				if branchName == "appstudio-batch-main" && strings.HasPrefix(filePath, ".tekton/"+siblingKey.Name) {
+					return []byte(siblingPipelineRun), nil
+				}
+				return nil, nil
+			}
+			isBatchPaCPullRequestInvoked := false
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				Expect(d.BranchName).To(Equal("appstudio-batch-main"))
+				Expect(d.BaseBranchName).To(Equal("main"))
+				if len(d.Files) == 4 {
+					isBatchPaCPullRequestInvoked = true
+					Expect(d.Title).To(Equal(fmt.Sprintf("Test App Name update %s, %s", siblingKey.Name, resourceKey.Name)))
+					Expect(d.Files[0].FullPath).To(HavePrefix(".tekton/" + resourceKey.Name))
+					Expect(d.Files[2].FullPath).To(HavePrefix(".tekton/" + siblingKey.Name))
+					// Definitions of the sibling are kept as they are in the batch branch
+					Expect(string(d.Files[2].Content)).To(Equal(siblingPipelineRun))
+					Expect(string(d.Files[3].Content)).To(Equal(siblingPipelineRun))
+				}
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+
+			createComponentForPaCBuild(getSampleComponentData(siblingKey))
+			defer deleteComponent(siblingKey)
+			setComponentDevfileModel(siblingKey)
+			waitComponentAnnotationValue(siblingKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+
+			setComponentDevfileModel(resourceKey)
+
+			Eventually(func() bool {
+				return isBatchPaCPullRequestInvoked
+			}, timeout, interval).Should(BeTrue())
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+		})
+
+		It("should successfully submit PR with PaC definitions to GitHub Enterprise Server using GitHub token", func() {
+			os.Setenv(github.GithubEnterpriseUrlsEnvName, "https://github.mycompany.com")
+			defer os.Unsetenv(github.GithubEnterpriseUrlsEnvName)
+
+			const repoUrl = "https://github.mycompany.com/devfile-samples/devfile-sample-go-basic"
+			isCreateGitClientInvoked := false
+			gitproviderfactory.CreateGitClient = func(gitClientConfig gitproviderfactory.GitClientConfig) (gitprovider.GitProvider, error) {
+				isCreateGitClientInvoked = true
+				Expect(gitClientConfig.GitProvider).To(Equal("github"))
+				Expect(gitClientConfig.RepoUrl).To(Equal(repoUrl))
+				return testGitProviderClient, nil
+			}
+			isCreatePaCPullRequestInvoked := false
+			EnsurePaCMergeRequestFunc = func(url string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				isCreatePaCPullRequestInvoked = true
+				Expect(url).To(Equal(repoUrl))
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+
+			pacSecretData := map[string]string{"github.token": "ghp_token"}
+			createSecret(namespacePaCSecretKey, pacSecretData)
+
+			deleteComponent(resourceKey)
+
+			component := getSampleComponentData(resourceKey)
+			component.Annotations = map[string]string{
+				PaCProvisionAnnotationName:       PaCProvisionRequestedAnnotationValue,
+				gitops.GitProviderAnnotationName: "github",
+			}
+			component.Spec.Source.GitSource.URL = repoUrl
+			Expect(k8sClient.Create(ctx, component)).Should(Succeed())
+
+			setComponentDevfileModel(resourceKey)
+
+			waitPaCRepositoryCreated(resourceKey)
+			Eventually(func() bool {
+				return isCreateGitClientInvoked
+			}, timeout, interval).Should(BeTrue())
+			Eventually(func() bool {
+				return isCreatePaCPullRequestInvoked
+			}, timeout, interval).Should(BeTrue())
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+
+			pacRepository := &pacv1alpha1.Repository{}
+			Expect(k8sClient.Get(ctx, resourceKey, pacRepository)).To(Succeed())
+			Expect(pacRepository.Spec.GitProvider.URL).To(Equal("https://github.mycompany.com/api/v3/"))
+		})
+
+		It("should not use global PaC secret for GitHub Enterprise Server", func() {
+			const repoUrl = "https://github.mycompany.com/devfile-samples/devfile-sample-go-basic"
+			isCreatePaCPullRequestInvoked := false
+			EnsurePaCMergeRequestFunc = func(url string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				isCreatePaCPullRequestInvoked = true
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+
+			pacSecretData := map[string]string{"github.token": "ghp_token"}
+			createSecret(pacSecretKey, pacSecretData)
+
+			deleteComponent(resourceKey)
+
+			component := getSampleComponentData(resourceKey)
+			component.Annotations = map[string]string{
+				PaCProvisionAnnotationName:       PaCProvisionRequestedAnnotationValue,
+				gitops.GitProviderAnnotationName: "github",
+			}
+			component.Spec.Source.GitSource.URL = repoUrl
+			Expect(k8sClient.Create(ctx, component)).Should(Succeed())
+
+			setComponentDevfileModel(resourceKey)
+
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionErrorAnnotationValue)
+			expectedErr := boerrors.NewBuildOpError(boerrors.EPaCSecretNotFound, fmt.Errorf("something is wrong"))
+			waitComponentAnnotationValue(resourceKey, PaCProvisionErrorDetailsAnnotationName, expectedErr.ShortError())
+			ensureSecretNotCreated(namespacePaCSecretKey)
+			Expect(isCreatePaCPullRequestInvoked).Should(BeFalse())
+		})
+
 		It("should successfully submit MR with PaC definitions using GitLab token and set PaC annotation", func() {
+			const gitlabRepoUrl = "https://gitlab.com/devfile-samples/devfile-sample-go-basic"
 			isCreatePaCPullRequestInvoked := false
-			gitlab.EnsurePaCMergeRequest = func(c *gitlab.GitlabClient, d *gitlab.PaCMergeRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				isCreatePaCPullRequestInvoked = true
-				Expect(d.ProjectPath).To(Equal("devfile-samples/devfile-sample-go-basic"))
+				Expect(repoUrl).To(Equal(gitlabRepoUrl))
 				Expect(len(d.Files)).To(Equal(2))
 				for _, file := range d.Files {
 					Expect(strings.HasPrefix(file.FullPath, ".tekton/")).To(BeTrue())
 				}
 				Expect(d.CommitMessage).ToNot(BeEmpty())
-				Expect(d.Branch).ToNot(BeEmpty())
-				Expect(d.BaseBranch).ToNot(BeEmpty())
-				Expect(d.MrTitle).ToNot(BeEmpty())
-				Expect(d.MrText).ToNot(BeEmpty())
+				Expect(d.BranchName).ToNot(BeEmpty())
+				Expect(d.BaseBranchName).ToNot(BeEmpty())
+				Expect(d.Title).ToNot(BeEmpty())
+				Expect(d.Text).ToNot(BeEmpty())
 				Expect(d.AuthorName).ToNot(BeEmpty())
 				Expect(d.AuthorEmail).ToNot(BeEmpty())
-				return "url", nil
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
 			}
 			isSetupPaCWebhookInvoked := false
-			gitlab.SetupPaCWebhook = func(g *gitlab.GitlabClient, projectPath, webhookUrl, webhookSecret string) error {
+			SetupPaCWebhookFunc = func(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *gitprovider.WebhookOptions) error {
 				isSetupPaCWebhookInvoked = true
 				Expect(webhookUrl).To(Equal(pacWebhookUrl))
 				Expect(webhookSecret).ToNot(BeEmpty())
-				Expect(projectPath).To(Equal("devfile-samples/devfile-sample-go-basic"))
+				Expect(repoUrl).To(Equal(gitlabRepoUrl))
 				return nil
 			}
 
@@ -331,7 +758,7 @@ var _ = Describe("Component initial build controller", func() {
 			component.Annotations = map[string]string{
 				PaCProvisionAnnotationName: PaCProvisionRequestedAnnotationValue,
 			}
-			component.Spec.Source.GitSource.URL = "https://gitlab.com/devfile-samples/devfile-sample-go-basic"
+			component.Spec.Source.GitSource.URL = gitlabRepoUrl
 			Expect(k8sClient.Create(ctx, component)).Should(Succeed())
 
 			setComponentDevfileModel(resourceKey)
@@ -348,13 +775,118 @@ var _ = Describe("Component initial build controller", func() {
 			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
 		})
 
+		It("should successfully submit MR with PaC definitions to self-hosted GitLab served under sub-path", func() {
+			os.Setenv(gitlab.GitlabBaseUrlsEnvName, "mycompany.com=https://mycompany.com/gitlab")
+			defer os.Unsetenv(gitlab.GitlabBaseUrlsEnvName)
+
+			const gitlabRepoUrl = "https://mycompany.com/gitlab/devfile-samples/devfile-sample-go-basic"
+			isCreatePaCPullRequestInvoked := false
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				isCreatePaCPullRequestInvoked = true
+				Expect(repoUrl).To(Equal(gitlabRepoUrl))
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+			isSetupPaCWebhookInvoked := false
+			SetupPaCWebhookFunc = func(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *gitprovider.WebhookOptions) error {
+				isSetupPaCWebhookInvoked = true
+				Expect(repoUrl).To(Equal(gitlabRepoUrl))
+				return nil
+			}
+
+			pacSecretData := map[string]string{"gitlab.token": "glpat-token"}
+			createSecret(namespacePaCSecretKey, pacSecretData)
+
+			deleteComponent(resourceKey)
+
+			component := getSampleComponentData(resourceKey)
+			component.Annotations = map[string]string{
+				PaCProvisionAnnotationName:       PaCProvisionRequestedAnnotationValue,
+				gitops.GitProviderAnnotationName: "gitlab",
+			}
+			component.Spec.Source.GitSource.URL = gitlabRepoUrl
+			Expect(k8sClient.Create(ctx, component)).Should(Succeed())
+
+			setComponentDevfileModel(resourceKey)
+
+			waitPaCRepositoryCreated(resourceKey)
+			Eventually(func() bool {
+				return isCreatePaCPullRequestInvoked
+			}, timeout, interval).Should(BeTrue())
+			Eventually(func() bool {
+				return isSetupPaCWebhookInvoked
+			}, timeout, interval).Should(BeTrue())
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+
+			pacRepository := &pacv1alpha1.Repository{}
+			Expect(k8sClient.Get(ctx, resourceKey, pacRepository)).To(Succeed())
+			Expect(pacRepository.Spec.GitProvider.URL).To(Equal("https://mycompany.com/gitlab"))
+		})
+
+		It("should successfully submit PR with PaC definitions using Bitbucket app password and set PaC annotation", func() {
+			const bitbucketRepoUrl = "https://bitbucket.org/devfile-samples/devfile-sample-go-basic"
+			isCreatePaCPullRequestInvoked := false
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				isCreatePaCPullRequestInvoked = true
+				Expect(repoUrl).To(Equal(bitbucketRepoUrl))
+				Expect(len(d.Files)).To(Equal(2))
+				for _, file := range d.Files {
+					Expect(strings.HasPrefix(file.FullPath, ".tekton/")).To(BeTrue())
+				}
+				Expect(d.CommitMessage).ToNot(BeEmpty())
+				Expect(d.BranchName).ToNot(BeEmpty())
+				Expect(d.BaseBranchName).ToNot(BeEmpty())
+				Expect(d.Title).ToNot(BeEmpty())
+				Expect(d.Text).ToNot(BeEmpty())
+				Expect(d.AuthorName).ToNot(BeEmpty())
+				Expect(d.AuthorEmail).ToNot(BeEmpty())
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+			isSetupPaCWebhookInvoked := false
+			SetupPaCWebhookFunc = func(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *gitprovider.WebhookOptions) error {
+				isSetupPaCWebhookInvoked = true
+				Expect(webhookUrl).To(Equal(pacWebhookUrl))
+				Expect(webhookSecret).ToNot(BeEmpty())
+				Expect(repoUrl).To(Equal(bitbucketRepoUrl))
+				return nil
+			}
+
+			pacSecretData := map[string]string{"bitbucket.token": "app-password", "username": "user"}
+			createSecret(pacSecretKey, pacSecretData)
+
+			deleteComponent(resourceKey)
+
+			component := getSampleComponentData(resourceKey)
+			component.Annotations = map[string]string{
+				PaCProvisionAnnotationName: PaCProvisionRequestedAnnotationValue,
+			}
+			component.Spec.Source.GitSource.URL = bitbucketRepoUrl
+			Expect(k8sClient.Create(ctx, component)).Should(Succeed())
+
+			setComponentDevfileModel(resourceKey)
+
+			waitSecretCreated(namespacePaCSecretKey)
+			waitSecretCreated(webhookSecretKey)
+			waitPaCRepositoryCreated(resourceKey)
+			Eventually(func() bool {
+				return isCreatePaCPullRequestInvoked
+			}, timeout, interval).Should(BeTrue())
+			Eventually(func() bool {
+				return isSetupPaCWebhookInvoked
+			}, timeout, interval).Should(BeTrue())
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+
+			pacRepository := &pacv1alpha1.Repository{}
+			Expect(k8sClient.Get(ctx, resourceKey, pacRepository)).To(Succeed())
+			Expect(pacRepository.Spec.GitProvider.User).To(Equal("user"))
+		})
+
 		It("should provision PaC definitions after initial build if PaC annotation added", func() {
 			isCreatePaCPullRequestInvoked := false
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				isCreatePaCPullRequestInvoked = true
-				return "url", nil
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
 			}
-			github.SetupPaCWebhook = func(g *github.GithubClient, webhookUrl, webhookSecret, owner, repository string) error {
+			SetupPaCWebhookFunc = func(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *gitprovider.WebhookOptions) error {
 				defer GinkgoRecover()
 				Fail("Should not create webhook if GitHub application is used")
 				return nil
@@ -394,7 +926,7 @@ var _ = Describe("Component initial build controller", func() {
 
 		It("should reuse the same webhook secret for multicomponent repository", func() {
 			var webhookSecretStrings []string
-			github.SetupPaCWebhook = func(g *github.GithubClient, webhookUrl, webhookSecret, owner, repository string) error {
+			SetupPaCWebhookFunc = func(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *gitprovider.WebhookOptions) error {
 				webhookSecretStrings = append(webhookSecretStrings, webhookSecret)
 				return nil
 			}
@@ -433,7 +965,7 @@ var _ = Describe("Component initial build controller", func() {
 
 		It("should use different webhook secrets for different components of the same application", func() {
 			var webhookSecretStrings []string
-			github.SetupPaCWebhook = func(g *github.GithubClient, webhookUrl, webhookSecret, owner, repository string) error {
+			SetupPaCWebhookFunc = func(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *gitprovider.WebhookOptions) error {
 				webhookSecretStrings = append(webhookSecretStrings, webhookSecret)
 				return nil
 			}
@@ -470,8 +1002,8 @@ var _ = Describe("Component initial build controller", func() {
 		})
 
 		It("should not set PaC annotation if PaC definitions PR submission failed", func() {
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
-				return "", fmt.Errorf("Failed to submit PaC definitions PR")
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				return nil, fmt.Errorf("Failed to submit PaC definitions PR")
 			}
 
 			setComponentDevfileModel(resourceKey)
@@ -481,8 +1013,8 @@ var _ = Describe("Component initial build controller", func() {
 			ensureComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionRequestedAnnotationValue)
 
 			// Clean up after the test
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
-				return "", nil
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				return nil, nil
 			}
 			deleteComponent(resourceKey)
 			// Wait a bit to not to spoil the next tests.
@@ -493,18 +1025,13 @@ var _ = Describe("Component initial build controller", func() {
 
 		It("should successfully do PaC provision after error (when PaC GitHub Application was not installed)", func() {
 			appNotInstalledErr := boerrors.NewBuildOpError(boerrors.EGitHubAppNotInstalled, nil)
-			github.NewGithubClientByApp = func(appId int64, privateKeyPem []byte, owner string) (*github.GithubClient, error) {
+			gitproviderfactory.CreateGitClient = func(gitClientConfig gitproviderfactory.GitClientConfig) (gitprovider.GitProvider, error) {
 				return nil, appNotInstalledErr
 			}
-			github.NewGithubClient = func(accessToken string) *github.GithubClient {
-				defer GinkgoRecover()
-				Fail("GitHub client should be created using PaC GitHub Application")
-				return nil
-			}
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				defer GinkgoRecover()
 				Fail("PR creation should not be invoked")
-				return "", nil
+				return nil, nil
 			}
 
 			setComponentDevfileModel(resourceKey)
@@ -512,7 +1039,7 @@ var _ = Describe("Component initial build controller", func() {
 			waitComponentAnnotationValue(resourceKey, PaCProvisionErrorDetailsAnnotationName, appNotInstalledErr.ShortError())
 
 			// Ensure no more retries after permanent error
-			github.NewGithubClientByApp = func(appId int64, privateKeyPem []byte, owner string) (*github.GithubClient, error) {
+			gitproviderfactory.CreateGitClient = func(gitClientConfig gitproviderfactory.GitClientConfig) (gitprovider.GitProvider, error) {
 				defer GinkgoRecover()
 				Fail("Should not retry PaC provision on permanent error")
 				return nil, nil
@@ -520,13 +1047,13 @@ var _ = Describe("Component initial build controller", func() {
 			ensureComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionErrorAnnotationValue)
 
 			// Suppose PaC GH App is installed
-			github.NewGithubClientByApp = func(appId int64, privateKeyPem []byte, owner string) (*github.GithubClient, error) {
-				return nil, nil
+			gitproviderfactory.CreateGitClient = func(gitClientConfig gitproviderfactory.GitClientConfig) (gitprovider.GitProvider, error) {
+				return testGitProviderClient, nil
 			}
 			isCreatePaCPullRequestInvoked := false
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				isCreatePaCPullRequestInvoked = true
-				return "url", nil
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
 			}
 			// Update PaC annotation to retry
 			component := getComponent(resourceKey)
@@ -539,11 +1066,35 @@ var _ = Describe("Component initial build controller", func() {
 			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
 		})
 
+		It("should postpone PaC provision without error if git provider rate limit is reached", func() {
+			isRateLimited := true
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				if isRateLimited {
+					return nil, boerrors.NewRateLimitError(boerrors.EGitHubReachRateLimit, fmt.Errorf("API rate limit exceeded"), time.Now())
+				}
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+
+			setComponentDevfileModel(resourceKey)
+
+			waitPaCRepositoryCreated(resourceKey)
+			ensureComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionRequestedAnnotationValue)
+
+			// Rate limit is reset
+			isRateLimited = false
+			waitComponentAnnotationValue(resourceKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+
+			// Clean up after the test
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				return nil, nil
+			}
+		})
+
 		It("should not submit PaC definitions PR if PaC secret is missing", func() {
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				defer GinkgoRecover()
 				Fail("PR creation should not be invoked")
-				return "", nil
+				return nil, nil
 			}
 
 			deleteSecret(pacSecretKey)
@@ -555,20 +1106,20 @@ var _ = Describe("Component initial build controller", func() {
 		})
 
 		It("should do nothing if the component devfile model is not set", func() {
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				defer GinkgoRecover()
 				Fail("PR creation should not be invoked")
-				return "", nil
+				return nil, nil
 			}
 
 			ensureComponentInitialBuildAnnotationState(resourceKey, false)
 		})
 
 		It("should do nothing if initial build annotation is already set", func() {
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				defer GinkgoRecover()
 				Fail("PR creation should not be invoked")
-				return "", nil
+				return nil, nil
 			}
 
 			component := getComponent(resourceKey)
@@ -582,10 +1133,10 @@ var _ = Describe("Component initial build controller", func() {
 		})
 
 		It("should do nothing if a container image source is specified in component", func() {
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				defer GinkgoRecover()
 				Fail("PR creation should not be invoked")
-				return "", nil
+				return nil, nil
 			}
 
 			deleteComponent(resourceKey)
@@ -622,23 +1173,23 @@ var _ = Describe("Component initial build controller", func() {
 
 			const repoDefaultBranch = "cool-feature"
 
-			github.GetDefaultBranch = func(*github.GithubClient, string, string) (string, error) {
+			GetDefaultBranchFunc = func(repoUrl string) (string, error) {
 				return repoDefaultBranch, nil
 			}
 
 			isCreatePaCPullRequestInvoked := false
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
-				Expect(d.BaseBranch).To(Equal(repoDefaultBranch))
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				Expect(d.BaseBranchName).To(Equal(repoDefaultBranch))
 				for _, file := range d.Files {
 					var prYaml v1beta1.PipelineRun
 					if err := yaml.Unmarshal(file.Content, &prYaml); err != nil {
-						return "", err
+						return nil, err
 					}
 					targetBranches := prYaml.Annotations["pipelinesascode.tekton.dev/on-target-branch"]
 					Expect(targetBranches).To(Equal(fmt.Sprintf("[%s]", repoDefaultBranch)))
 				}
 				isCreatePaCPullRequestInvoked = true
-				return "url", nil
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
 			}
 
 			setComponentDevfileModel(resourceKey)
@@ -672,11 +1223,11 @@ var _ = Describe("Component initial build controller", func() {
 			}
 
 			isCreatePaCPullRequestInvoked := false
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				defer GinkgoRecover()
 				checkPROutputImage(d.Files[0].Content, userImageRepo)
 				isCreatePaCPullRequestInvoked = true
-				return "url", nil
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
 			}
 
 			// Create a component with user's ContainerImage
@@ -695,11 +1246,11 @@ var _ = Describe("Component initial build controller", func() {
 			// Switch to generated image repository
 
 			isCreatePaCPullRequestInvoked = false
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				defer GinkgoRecover()
 				checkPROutputImage(d.Files[0].Content, generatedImageRepo)
 				isCreatePaCPullRequestInvoked = true
-				return "url2", nil
+				return &gitprovider.MergeRequest{WebUrl: "url2", State: gitprovider.MergeRequestStateOpen}, nil
 			}
 
 			component = getComponent(resourceKey)
@@ -737,20 +1288,7 @@ var _ = Describe("Component initial build controller", func() {
 			createRoute(pacRouteKey, "pac-host")
 			createNamespace(buildServiceNamespaceName)
 
-			github.NewGithubClientByApp = func(appId int64, privateKeyPem []byte, owner string) (*github.GithubClient, error) { return nil, nil }
-			github.GetGitHubAppName = func(appId int64, privateKeyPem []byte) (string, string, error) { return "appName", "app-slug", nil }
-			github.NewGithubClient = func(accessToken string) *github.GithubClient { return nil }
-			github.CreatePaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) { return "", nil }
-			github.SetupPaCWebhook = func(g *github.GithubClient, webhookUrl, webhookSecret, owner, repository string) error { return nil }
-			github.IsAppInstalledIntoRepository = func(g *github.GithubClient, owner, repository string) (bool, error) { return true, nil }
-			github.FindUnmergedOnboardingMergeRequest = func(*github.GithubClient, string, string, string, string, string) (*gogithub.PullRequest, error) {
-				return nil, nil
-			}
-			gitlab.EnsurePaCMergeRequest = func(g *gitlab.GitlabClient, d *gitlab.PaCMergeRequestData) (string, error) { return "", nil }
-			gitlab.SetupPaCWebhook = func(g *gitlab.GitlabClient, projectPath, webhookUrl, webhookSecret string) error { return nil }
-			gitlab.FindUnmergedOnboardingMergeRequest = func(*gitlab.GitlabClient, string, string, string, string) (*gogitlab.MergeRequest, error) {
-				return nil, nil
-			}
+			ResetTestGitProviderClient()
 		})
 
 		_ = AfterEach(func() {
@@ -763,24 +1301,23 @@ var _ = Describe("Component initial build controller", func() {
 
 		It("should successfully submit PR with PaC definitions removal using GitHub application", func() {
 			isRemovePaCPullRequestInvoked := false
-			github.UndoPaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			UndoPaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (string, error) {
 				isRemovePaCPullRequestInvoked = true
-				Expect(d.Owner).To(Equal("devfile-samples"))
-				Expect(d.Repository).To(Equal("devfile-sample-java-springboot-basic"))
+				Expect(repoUrl).To(Equal(SampleRepoLink))
 				Expect(len(d.Files)).To(Equal(2))
 				for _, file := range d.Files {
 					Expect(strings.HasPrefix(file.FullPath, ".tekton/")).To(BeTrue())
 				}
 				Expect(d.CommitMessage).ToNot(BeEmpty())
-				Expect(d.Branch).ToNot(BeEmpty())
-				Expect(d.BaseBranch).ToNot(BeEmpty())
-				Expect(d.PRTitle).ToNot(BeEmpty())
-				Expect(d.PRText).ToNot(BeEmpty())
+				Expect(d.BranchName).ToNot(BeEmpty())
+				Expect(d.BaseBranchName).ToNot(BeEmpty())
+				Expect(d.Title).ToNot(BeEmpty())
+				Expect(d.Text).ToNot(BeEmpty())
 				Expect(d.AuthorName).ToNot(BeEmpty())
 				Expect(d.AuthorEmail).ToNot(BeEmpty())
 				return "url", nil
 			}
-			github.DeletePaCWebhook = func(g *github.GithubClient, webhookUrl, owner, repository string) error {
+			DeletePaCWebhookFunc = func(repoUrl string, webhookUrl string) error {
 				defer GinkgoRecover()
 				Fail("Should not try to delete webhook if GitHub application is used")
 				return nil
@@ -805,29 +1342,27 @@ var _ = Describe("Component initial build controller", func() {
 
 		It("should successfully submit PR with PaC definitions removal using GitHub token", func() {
 			isRemovePaCPullRequestInvoked := false
-			github.UndoPaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			UndoPaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (string, error) {
 				isRemovePaCPullRequestInvoked = true
-				Expect(d.Owner).To(Equal("devfile-samples"))
-				Expect(d.Repository).To(Equal("devfile-sample-java-springboot-basic"))
+				Expect(repoUrl).To(Equal(SampleRepoLink))
 				Expect(len(d.Files)).To(Equal(2))
 				for _, file := range d.Files {
 					Expect(strings.HasPrefix(file.FullPath, ".tekton/")).To(BeTrue())
 				}
 				Expect(d.CommitMessage).ToNot(BeEmpty())
-				Expect(d.Branch).ToNot(BeEmpty())
-				Expect(d.BaseBranch).ToNot(BeEmpty())
-				Expect(d.PRTitle).ToNot(BeEmpty())
-				Expect(d.PRText).ToNot(BeEmpty())
+				Expect(d.BranchName).ToNot(BeEmpty())
+				Expect(d.BaseBranchName).ToNot(BeEmpty())
+				Expect(d.Title).ToNot(BeEmpty())
+				Expect(d.Text).ToNot(BeEmpty())
 				Expect(d.AuthorName).ToNot(BeEmpty())
 				Expect(d.AuthorEmail).ToNot(BeEmpty())
 				return "url", nil
 			}
 			isDeletePaCWebhookInvoked := false
-			github.DeletePaCWebhook = func(g *github.GithubClient, webhookUrl, owner, repository string) error {
+			DeletePaCWebhookFunc = func(repoUrl string, webhookUrl string) error {
 				isDeletePaCWebhookInvoked = true
 				Expect(webhookUrl).To(Equal(pacWebhookUrl))
-				Expect(owner).To(Equal("devfile-samples"))
-				Expect(repository).To(Equal("devfile-sample-java-springboot-basic"))
+				Expect(repoUrl).To(Equal(SampleRepoLink))
 				return nil
 			}
 
@@ -849,34 +1384,35 @@ var _ = Describe("Component initial build controller", func() {
 		})
 
 		It("should successfully submit MR with PaC definitions removal using GitLab token", func() {
+			const gitlabRepoUrl = "https://gitlab.com/devfile-samples/devfile-sample-go-basic"
 			isRemovePaCPullRequestInvoked := false
-			gitlab.UndoPaCMergeRequest = func(c *gitlab.GitlabClient, d *gitlab.PaCMergeRequestData) (string, error) {
+			UndoPaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (string, error) {
 				isRemovePaCPullRequestInvoked = true
-				Expect(d.ProjectPath).To(Equal("devfile-samples/devfile-sample-go-basic"))
+				Expect(repoUrl).To(Equal(gitlabRepoUrl))
 				Expect(len(d.Files)).To(Equal(2))
 				for _, file := range d.Files {
 					Expect(strings.HasPrefix(file.FullPath, ".tekton/")).To(BeTrue())
 				}
 				Expect(d.CommitMessage).ToNot(BeEmpty())
-				Expect(d.Branch).ToNot(BeEmpty())
-				Expect(d.BaseBranch).ToNot(BeEmpty())
-				Expect(d.MrTitle).ToNot(BeEmpty())
-				Expect(d.MrText).ToNot(BeEmpty())
+				Expect(d.BranchName).ToNot(BeEmpty())
+				Expect(d.BaseBranchName).ToNot(BeEmpty())
+				Expect(d.Title).ToNot(BeEmpty())
+				Expect(d.Text).ToNot(BeEmpty())
 				Expect(d.AuthorName).ToNot(BeEmpty())
 				Expect(d.AuthorEmail).ToNot(BeEmpty())
 				return "url", nil
 			}
 			isDeletePaCWebhookInvoked := false
-			gitlab.DeletePaCWebhook = func(g *gitlab.GitlabClient, projectPath, webhookUrl string) error {
+			DeletePaCWebhookFunc = func(repoUrl string, webhookUrl string) error {
 				isDeletePaCWebhookInvoked = true
 				Expect(webhookUrl).To(Equal(pacWebhookUrl))
-				Expect(projectPath).To(Equal("devfile-samples/devfile-sample-go-basic"))
+				Expect(repoUrl).To(Equal(gitlabRepoUrl))
 				return nil
 			}
 			isDeleteBranchInvoked := false
-			gitlab.DeleteBranch = func(*gitlab.GitlabClient, string, string) error {
+			DeleteBranchFunc = func(repoUrl string, branchName string) (bool, error) {
 				isDeleteBranchInvoked = true
-				return nil
+				return true, nil
 			}
 
 			pacSecretData := map[string]string{"gitlab.token": "glpat-token"}
@@ -886,7 +1422,7 @@ var _ = Describe("Component initial build controller", func() {
 			component.Annotations = map[string]string{
 				PaCProvisionAnnotationName: PaCProvisionRequestedAnnotationValue,
 			}
-			component.Spec.Source.GitSource.URL = "https://gitlab.com/devfile-samples/devfile-sample-go-basic"
+			component.Spec.Source.GitSource.URL = gitlabRepoUrl
 			Expect(k8sClient.Create(ctx, component)).Should(Succeed())
 			setComponentDevfileModel(resourceKey)
 			waitPaCFinalizerOnComponent(resourceKey)
@@ -904,11 +1440,60 @@ var _ = Describe("Component initial build controller", func() {
 			}, timeout, interval).Should(BeFalse())
 		})
 
+		It("should successfully submit PR with PaC definitions removal using Bitbucket app password", func() {
+			const bitbucketRepoUrl = "https://bitbucket.org/devfile-samples/devfile-sample-go-basic"
+			isRemovePaCPullRequestInvoked := false
+			UndoPaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (string, error) {
+				isRemovePaCPullRequestInvoked = true
+				Expect(repoUrl).To(Equal(bitbucketRepoUrl))
+				Expect(len(d.Files)).To(Equal(2))
+				for _, file := range d.Files {
+					Expect(strings.HasPrefix(file.FullPath, ".tekton/")).To(BeTrue())
+				}
+				Expect(d.CommitMessage).ToNot(BeEmpty())
+				Expect(d.BranchName).ToNot(BeEmpty())
+				Expect(d.BaseBranchName).ToNot(BeEmpty())
+				Expect(d.Title).ToNot(BeEmpty())
+				Expect(d.Text).ToNot(BeEmpty())
+				Expect(d.AuthorName).ToNot(BeEmpty())
+				Expect(d.AuthorEmail).ToNot(BeEmpty())
+				return "url", nil
+			}
+			isDeletePaCWebhookInvoked := false
+			DeletePaCWebhookFunc = func(repoUrl string, webhookUrl string) error {
+				isDeletePaCWebhookInvoked = true
+				Expect(webhookUrl).To(Equal(pacWebhookUrl))
+				Expect(repoUrl).To(Equal(bitbucketRepoUrl))
+				return nil
+			}
+
+			pacSecretData := map[string]string{"bitbucket.token": "app-password", "username": "user"}
+			createSecret(pacSecretKey, pacSecretData)
+
+			component := getSampleComponentData(resourceKey)
+			component.Annotations = map[string]string{
+				PaCProvisionAnnotationName: PaCProvisionRequestedAnnotationValue,
+			}
+			component.Spec.Source.GitSource.URL = bitbucketRepoUrl
+			Expect(k8sClient.Create(ctx, component)).Should(Succeed())
+			setComponentDevfileModel(resourceKey)
+			waitPaCFinalizerOnComponent(resourceKey)
+
+			deleteComponent(resourceKey)
+
+			Eventually(func() bool {
+				return isRemovePaCPullRequestInvoked
+			}, timeout, interval).Should(BeTrue())
+			Eventually(func() bool {
+				return isDeletePaCWebhookInvoked
+			}, timeout, interval).Should(BeTrue())
+		})
+
 		It("should not block component deletion if PaC definitions removal failed", func() {
-			github.UndoPaCPullRequest = func(c *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			UndoPaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (string, error) {
 				return "", fmt.Errorf("failed to create PR")
 			}
-			github.DeletePaCWebhook = func(g *github.GithubClient, webhookUrl, owner, repository string) error {
+			DeletePaCWebhookFunc = func(repoUrl string, webhookUrl string) error {
 				return fmt.Errorf("failed to delete webhook")
 			}
 
@@ -923,7 +1508,7 @@ var _ = Describe("Component initial build controller", func() {
 		})
 
 		var assertCloseUnmergedPullRequest = func(expectedBaseBranch string, sourceBranchExists bool) {
-			github.UndoPaCPullRequest = func(g *github.GithubClient, d *github.PaCPullRequestData) (string, error) {
+			UndoPaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (string, error) {
 				defer GinkgoRecover()
 				Fail("Expect to close unmerged merge request other than delete .tekton/")
 				return "", nil
@@ -935,40 +1520,28 @@ var _ = Describe("Component initial build controller", func() {
 			} else {
 				component.Spec.Source.GitSource.Revision = ""
 			}
-			gitUrl := strings.TrimSuffix(component.Spec.Source.GitSource.URL, ".git")
-			gitSourceUrlParts := strings.Split(gitUrl, "/")
-			pullRequestUrl := fmt.Sprintf("%s/pull/1", gitUrl)
+			gitUrl := component.Spec.Source.GitSource.URL
+			pullRequestUrl := fmt.Sprintf("%s/pull/1", strings.TrimSuffix(gitUrl, ".git"))
 
 			expectedSourceBranch := pacMergeRequestSourceBranchPrefix + component.Name
 
 			isFindOnboardingMergeRequestInvoked := false
-			github.FindUnmergedOnboardingMergeRequest = func(
-				client *github.GithubClient, owner, repository, sourceBranch, baseBranch, authorName string) (*gogithub.PullRequest, error) {
+			FindUnmergedPaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
 				isFindOnboardingMergeRequestInvoked = true
-				Expect(sourceBranch).Should(Equal(expectedSourceBranch))
-				Expect(baseBranch).Should(Equal(expectedBaseBranch))
-				Expect(authorName).Should(Equal(owner))
-				Expect(gitSourceUrlParts[3]).Should(Equal(owner))
-				Expect(gitSourceUrlParts[4]).Should(Equal(repository))
-				return &gogithub.PullRequest{
-					URL: gogithub.String(pullRequestUrl),
+				Expect(repoUrl).Should(Equal(gitUrl))
+				Expect(d.BranchName).Should(Equal(expectedSourceBranch))
+				Expect(d.BaseBranchName).Should(Equal(expectedBaseBranch))
+				return &gitprovider.MergeRequest{
+					WebUrl: pullRequestUrl,
 				}, nil
 			}
 
 			isDeleteBranchInvoked := false
-			github.DeleteBranch = func(client *github.GithubClient, owner, repository, branch string) error {
+			DeleteBranchFunc = func(repoUrl string, branchName string) (bool, error) {
 				isDeleteBranchInvoked = true
-				Expect(branch).Should(Equal(expectedSourceBranch))
-				Expect(gitSourceUrlParts[3]).Should(Equal(owner))
-				Expect(gitSourceUrlParts[4]).Should(Equal(repository))
-				if !sourceBranchExists {
-					return &gogithub.ErrorResponse{
-						Response: &http.Response{
-							StatusCode: 422,
-						},
-					}
-				}
-				return nil
+				Expect(repoUrl).Should(Equal(gitUrl))
+				Expect(branchName).Should(Equal(expectedSourceBranch))
+				return sourceBranchExists, nil
 			}
 
 			pacSecretData := map[string]string{
@@ -986,11 +1559,11 @@ var _ = Describe("Component initial build controller", func() {
 			Eventually(func() bool {
 				return isFindOnboardingMergeRequestInvoked
 			}, timeout, interval).Should(BeTrue(),
-				"github.FindUnmergedOnboardingMergeRequest should be invoked, but not.")
+				"FindUnmergedPaCMergeRequest should be invoked, but not.")
 			Eventually(func() bool {
 				return isDeleteBranchInvoked
 			}, timeout, interval).Should(BeTrue(),
-				"github.DeleteBranch should be invoked, but not.")
+				"DeleteBranch should be invoked, but not.")
 		}
 
 		It("should close unmerged PaC pull request using GitHub application, opened based on branch specified in Revision", func() {
@@ -1003,7 +1576,7 @@ var _ = Describe("Component initial build controller", func() {
 
 		It("should close unmerged PaC pull request using GitHub application, opened based on default branch", func() {
 			defaultBranch := "devel"
-			github.GetDefaultBranch = func(*github.GithubClient, string, string) (string, error) {
+			GetDefaultBranchFunc = func(repoUrl string) (string, error) {
 				return defaultBranch, nil
 			}
 			assertCloseUnmergedPullRequest(defaultBranch, true)
@@ -1011,7 +1584,6 @@ var _ = Describe("Component initial build controller", func() {
 
 		var assertCloseUnmergedMR = func(expectedBaseBranch string, sourceBranchExists bool) {
 			const gitUrl = "https://gitlab.com/devfile-samples/devfile-sample-go-basic"
-			const expectedProjectPath = "devfile-samples/devfile-sample-go-basic"
 			component := getSampleComponentData(resourceKey)
 			component.Annotations = map[string]string{
 				PaCProvisionAnnotationName: PaCProvisionRequestedAnnotationValue,
@@ -1025,39 +1597,31 @@ var _ = Describe("Component initial build controller", func() {
 			Expect(k8sClient.Create(ctx, component)).Should(Succeed())
 
 			mrUrl := fmt.Sprintf("%s/-/merge_requests/1", gitUrl)
-			existingMR := &gogitlab.MergeRequest{
-				ID:     1,
-				WebURL: mrUrl,
+			existingMR := &gitprovider.MergeRequest{
+				Number: 1,
+				WebUrl: mrUrl,
 			}
 
 			isUndoPaCMergeRequestInvoked := false
-			gitlab.UndoPaCMergeRequest = func(g *gitlab.GitlabClient, d *gitlab.PaCMergeRequestData) (string, error) {
+			UndoPaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (string, error) {
 				isUndoPaCMergeRequestInvoked = true
 				return "", nil
 			}
 
-			gitlab.FindUnmergedOnboardingMergeRequest = func(
-				g *gitlab.GitlabClient, projectPath, sourceBranch, baseBranch, authorName string) (*gogitlab.MergeRequest, error) {
-				Expect(sourceBranch).Should(Equal(fmt.Sprintf("appstudio-%s", component.Name)))
-				Expect(projectPath).Should(Equal(expectedProjectPath))
-				Expect(baseBranch).Should(Equal(expectedBaseBranch))
-				Expect(authorName).Should(Equal("redhat-appstudio"))
+			FindUnmergedPaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				Expect(repoUrl).Should(Equal(gitUrl))
+				Expect(d.BranchName).Should(Equal(fmt.Sprintf("appstudio-%s", component.Name)))
+				Expect(d.BaseBranchName).Should(Equal(expectedBaseBranch))
+				Expect(d.AuthorName).Should(Equal("redhat-appstudio"))
 				return existingMR, nil
 			}
 
 			isDeleteBranchInvoked := false
-			gitlab.DeleteBranch = func(g *gitlab.GitlabClient, projectPath, sourceBranch string) error {
+			DeleteBranchFunc = func(repoUrl string, branchName string) (bool, error) {
 				isDeleteBranchInvoked = true
-				Expect(projectPath).Should(Equal(expectedProjectPath))
-				Expect(sourceBranch).Should(Equal(pacMergeRequestSourceBranchPrefix + component.Name))
-				if !sourceBranchExists {
-					return &gogitlab.ErrorResponse{
-						Response: &http.Response{
-							StatusCode: 404,
-						},
-					}
-				}
-				return nil
+				Expect(repoUrl).Should(Equal(gitUrl))
+				Expect(branchName).Should(Equal(pacMergeRequestSourceBranchPrefix + component.Name))
+				return sourceBranchExists, nil
 			}
 
 			pacSecretData := map[string]string{"gitlab.token": "glpat-token"}
@@ -1071,11 +1635,11 @@ var _ = Describe("Component initial build controller", func() {
 			Eventually(func() bool {
 				return isUndoPaCMergeRequestInvoked
 			}, timeout, interval).Should(BeFalse(),
-				"gitlab.UndoPaCMergeRequest should not be invoked, but it was invoked.")
+				"UndoPaCMergeRequest should not be invoked, but it was invoked.")
 			Eventually(func() bool {
 				return isDeleteBranchInvoked
 			}, timeout, interval).Should(BeTrue(),
-				"gitlab.DeleteBranch should be invoked, but not.")
+				"DeleteBranch should be invoked, but not.")
 		}
 
 		It("should close unmerged PaC merge request using GitLab token, opened based on branch specified in Revision", func() {
@@ -1088,18 +1652,86 @@ var _ = Describe("Component initial build controller", func() {
 
 		It("should close unmerged PaC merge request using GitLab token, opened based on default branch", func() {
 			defaultBranch := "devel"
-			gitlab.GetDefaultBranch = func(*gitlab.GitlabClient, string) (string, error) {
+			GetDefaultBranchFunc = func(repoUrl string) (string, error) {
 				return defaultBranch, nil
 			}
 			assertCloseUnmergedMR(defaultBranch, true)
 		})
+
+		It("should drop PaC definitions of deleted component from unmerged batch merge request", func() {
+			optionsKey := types.NamespacedName{Name: pacMergeRequestOptionsConfigMapName, Namespace: resourceKey.Namespace}
+			createConfigMap(optionsKey, map[string]string{
+				mrOptionBatchKey: "true",
+			})
+			defer deleteConfigMap(optionsKey)
+
+			pacSecretData := map[string]string{
+				"github-application-id": "12345",
+				"github-private-key":    githubAppPrivateKey,
+			}
+			createSecret(pacSecretKey, pacSecretData)
+
+			siblingKey := types.NamespacedName{Name: "batch-sibling", Namespace: resourceKey.Namespace}
+			createComponentForPaCBuild(getSampleComponentData(siblingKey))
+			defer deleteComponent(siblingKey)
+			setComponentDevfileModel(siblingKey)
+			waitComponentAnnotationValue(siblingKey, PaCProvisionAnnotationName, PaCProvisionDoneAnnotationValue)
+
+			createComponentForPaCBuild(getSampleComponentData(resourceKey))
+			setComponentDevfileModel(resourceKey)
+			waitPaCFinalizerOnComponent(resourceKey)
+
+			FindUnmergedPaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				Expect(d.BranchName).To(Equal("appstudio-batch-main"))
+				return &gitprovider.MergeRequest{Number: 1, WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+			DownloadFileContentFunc = func(repoUrl string, branchName string, filePath string) ([]byte, error) {
+				if branchName == "appstudio-batch-main" {
+					return []byte("PipelineRun in batch branch"), nil
+				}
+				return nil, nil
+			}
+			isBatchMergeRequestUpdated := false
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+				defer GinkgoRecover()
+				Expect(d.BranchName).To(Equal("appstudio-batch-main"))
+				Expect(d.Title).To(Equal(fmt.Sprintf("Test App Name update %s", siblingKey.Name)))
+				Expect(d.Files).To(HaveLen(2))
+				for _, file := range d.Files {
+					Expect(file.FullPath).To(HavePrefix(".tekton/" + siblingKey.Name))
+					Expect(string(file.Content)).To(Equal("PipelineRun in batch branch"))
+				}
+				Expect(d.DeletedFiles).To(HaveLen(2))
+				for _, file := range d.DeletedFiles {
+					Expect(file.FullPath).To(HavePrefix(".tekton/" + resourceKey.Name))
+				}
+				isBatchMergeRequestUpdated = true
+				return &gitprovider.MergeRequest{WebUrl: "url", State: gitprovider.MergeRequestStateOpen}, nil
+			}
+			isDeleteBranchInvoked := false
+			DeleteBranchFunc = func(repoUrl string, branchName string) (bool, error) {
+				isDeleteBranchInvoked = true
+				return true, nil
+			}
+			UndoPaCMergeRequestFunc = func(repoUrl string, d *gitprovider.MergeRequestData) (string, error) {
+				defer GinkgoRecover()
+				Fail("Should not create PaC definitions removal merge request while the batch merge request is not merged")
+				return "", nil
+			}
+
+			deleteComponent(resourceKey)
+
+			Eventually(func() bool {
+				return isBatchMergeRequestUpdated
+			}, timeout, interval).Should(BeTrue())
+			Expect(isDeleteBranchInvoked).To(BeFalse(), "shared batch merge request must not be closed")
+		})
 	})
 
 	Context("Test initial build", func() {
 
 		_ = BeforeEach(func() {
-			github.NewGithubClientForSimpleBuildByApp = func(appId int64, privateKeyPem []byte) (*github.GithubClient, error) { return nil, nil }
-			github.GetBranchSHA = func(g *github.GithubClient, owner, repository, branchName string) (string, error) { return "hash", nil }
+			ResetTestGitProviderClient()
 
 			pacSecretData := map[string]string{
 				"github-application-id": "12345",
@@ -1121,10 +1753,9 @@ var _ = Describe("Component initial build controller", func() {
 			gitSourceSHA := "d1a9e858489d1515621398fb02942da068f1c956"
 
 			isGetBranchShaInvoked := false
-			github.GetBranchSHA = func(g *github.GithubClient, owner, repository, branchName string) (string, error) {
+			GetBranchShaFunc = func(repoUrl string, branchName string) (string, error) {
 				isGetBranchShaInvoked = true
-				Expect(owner).To(Equal("devfile-samples"))
-				Expect(repository).To(Equal("devfile-sample-java-springboot-basic"))
+				Expect(repoUrl).To(Equal(SampleRepoLink))
 				return gitSourceSHA, nil
 			}
 
@@ -1146,7 +1777,7 @@ var _ = Describe("Component initial build controller", func() {
 
 		It("should submit initial build if retrieving of git commit SHA failed", func() {
 			isGetBranchShaInvoked := false
-			github.GetBranchSHA = func(g *github.GithubClient, owner, repository, branchName string) (string, error) {
+			GetBranchShaFunc = func(repoUrl string, branchName string) (string, error) {
 				isGetBranchShaInvoked = true
 				return "", fmt.Errorf("failed to get git commit SHA")
 			}
@@ -1213,6 +1844,7 @@ var _ = Describe("Component initial build controller", func() {
 
 			Expect(pipelineRun.Annotations["build.appstudio.redhat.com/pipeline_name"]).To(Equal(defaultPipelineName))
 			Expect(pipelineRun.Annotations["build.appstudio.redhat.com/bundle"]).To(Equal(defaultPipelineBundle))
+			Expect(pipelineRun.Annotations[pipelineBundleDigestAnnotationName]).To(Equal(testBundleDigest))
 
 			Expect(pipelineRun.Spec.PipelineSpec).To(BeNil())
 
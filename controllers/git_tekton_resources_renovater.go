@@ -30,8 +30,11 @@ import (
 	"github.com/redhat-appstudio/application-service/gitops"
 	gitopsprepare "github.com/redhat-appstudio/application-service/gitops/prepare"
 	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitrepourl"
 	"github.com/redhat-appstudio/build-service/pkg/github"
 	l "github.com/redhat-appstudio/build-service/pkg/logs"
+	registrymirrors "github.com/redhat-appstudio/build-service/pkg/registry-mirrors"
 	batch "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -133,10 +136,6 @@ func (r *GitTektonResourcesRenovater) Reconcile(ctx context.Context, req ctrl.Re
 		return ctrl.Result{}, nil
 	}
 	privateKey := pacSecret.Data[gitops.PipelinesAsCode_githubPrivateKey]
-	githubAppInstallations, slug, err := github.GetInstallations(githubAppId, privateKey)
-	if err != nil {
-		return ctrl.Result{}, err
-	}
 
 	// Get Components
 	componentList := &appstudiov1alpha1.ComponentList{}
@@ -144,15 +143,51 @@ func (r *GitTektonResourcesRenovater) Reconcile(ctx context.Context, req ctrl.Re
 		log.Error(err, "failed to list Components", l.Action, l.ActionView)
 		return ctrl.Result{}, err
 	}
-	componentUrlToBranchMap := make(map[string]string)
+	// Group GitHub components by GitHub instance, github.com or GitHub Enterprise Server ones
+	githubUrlToComponentUrlToBranchMap := make(map[string]map[string]string)
 	for _, component := range componentList.Items {
-		if component.Spec.Source.GitSource != nil {
-			url := strings.TrimSuffix(strings.TrimSuffix(component.Spec.Source.GitSource.URL, ".git"), "/")
-			componentUrlToBranchMap[url] = component.Spec.Source.GitSource.Revision
+		if component.Spec.Source.GitSource == nil {
+			continue
+		}
+		if gitProvider, _ := gitops.GetGitProvider(component); gitProvider != "github" {
+			continue
+		}
+		gitRepoUrl, err := gitrepourl.ParseGitRepoUrl(component.Spec.Source.GitSource.URL)
+		if err != nil {
+			continue
+		}
+		githubUrl := gitRepoUrl.GetProviderUrl()
+		if !github.IsAllowedGithubUrl(githubUrl) {
+			// Do not send the application credentials to GitHub instances not allowed by the cluster admin
+			continue
+		}
+		if _, exists := githubUrlToComponentUrlToBranchMap[githubUrl]; !exists {
+			githubUrlToComponentUrlToBranchMap[githubUrl] = make(map[string]string)
+		}
+		// Use canonical web URL to match repositories of the installations, the component might reference the repository via ssh
+		githubUrlToComponentUrlToBranchMap[githubUrl][gitRepoUrl.GetWebUrl()] = component.Spec.Source.GitSource.Revision
+	}
+
+	for githubUrl, componentUrlToBranchMap := range githubUrlToComponentUrlToBranchMap {
+		githubAppInstallations, slug, err := github.GetInstallations(githubAppId, privateKey, githubUrl)
+		if err != nil {
+			if boErr, ok := err.(*boerrors.BuildOpError); ok && boErr.IsPersistent() {
+				// The application might not exist in the GitHub instance, continue with others
+				log.Error(err, fmt.Sprintf("failed to get GitHub App installations on %s", githubUrl), l.Action, l.ActionView)
+				continue
+			}
+			return ctrl.Result{}, err
 		}
+
+		installationsToUpdate := getInstallationsToUpdate(githubAppInstallations, componentUrlToBranchMap)
+		r.createRenovaterJobs(ctx, installationsToUpdate, slug, githubUrl)
 	}
 
-	// Match installed repositories with Components and get custom branch if defined
+	return ctrl.Result{RequeueAfter: NextReconcile}, nil
+}
+
+// getInstallationsToUpdate matches installed repositories with Components and gets custom branch if defined
+func getInstallationsToUpdate(githubAppInstallations []github.ApplicationInstallation, componentUrlToBranchMap map[string]string) []installationStruct {
 	installationsToUpdate := []installationStruct{}
 	for _, githubAppInstallation := range githubAppInstallations {
 		repositories := []renovateRepository{}
@@ -182,6 +217,11 @@ func (r *GitTektonResourcesRenovater) Reconcile(ctx context.Context, req ctrl.Re
 				repositories: repositories,
 			})
 	}
+	return installationsToUpdate
+}
+
+func (r *GitTektonResourcesRenovater) createRenovaterJobs(ctx context.Context, installationsToUpdate []installationStruct, slug, githubUrl string) {
+	log := ctrllog.FromContext(ctx)
 
 	// Generate renovate jobs. Limit processed installations per job.
 	var installationPerJobInt int
@@ -200,20 +240,19 @@ func (r *GitTektonResourcesRenovater) Reconcile(ctx context.Context, req ctrl.Re
 		if end > len(installationsToUpdate) {
 			end = len(installationsToUpdate)
 		}
-		err = r.CreateRenovaterJob(ctx, installationsToUpdate[i:end], slug)
+		err := r.CreateRenovaterJob(ctx, installationsToUpdate[i:end], slug, githubUrl)
 		if err != nil {
 			log.Error(err, "failed to create a job", l.Action, l.ActionAdd)
 		}
 	}
-
-	return ctrl.Result{RequeueAfter: NextReconcile}, nil
 }
 
-func generateConfigJS(slug string, repositories []renovateRepository) string {
+func generateConfigJS(slug, githubUrl string, repositories []renovateRepository) string {
 	repositoriesData, _ := json.Marshal(repositories)
 	template := `
 	module.exports = {
 		platform: "github",
+		endpoint: "%s",
 		username: "%s[bot]",
 		gitAuthor:"%s <123456+%s[bot]@users.noreply.github.com>",
 		onboarding: false,
@@ -250,10 +289,10 @@ func generateConfigJS(slug string, repositories []renovateRepository) string {
 	if renovatePattern == "" {
 		renovatePattern = DefaultRenovateMatchPattern
 	}
-	return fmt.Sprintf(template, slug, slug, slug, repositoriesData, renovatePattern, renovatePattern, renovatePattern)
+	return fmt.Sprintf(template, github.GetApiUrl(githubUrl), slug, slug, slug, repositoriesData, renovatePattern, renovatePattern, renovatePattern)
 }
 
-func (r *GitTektonResourcesRenovater) CreateRenovaterJob(ctx context.Context, installations []installationStruct, slug string) error {
+func (r *GitTektonResourcesRenovater) CreateRenovaterJob(ctx context.Context, installations []installationStruct, slug, githubUrl string) error {
 	log := ctrllog.FromContext(ctx)
 
 	if len(installations) == 0 {
@@ -266,7 +305,7 @@ func (r *GitTektonResourcesRenovater) CreateRenovaterJob(ctx context.Context, in
 	renovateCmds := []string{}
 	for _, installation := range installations {
 		secretTokens[fmt.Sprint(installation.id)] = installation.token
-		configmaps[fmt.Sprintf("%d.js", installation.id)] = generateConfigJS(slug, installation.repositories)
+		configmaps[fmt.Sprintf("%d.js", installation.id)] = generateConfigJS(slug, githubUrl, installation.repositories)
 		renovateCmds = append(renovateCmds,
 			fmt.Sprintf("RENOVATE_TOKEN=$TOKEN_%d RENOVATE_CONFIG_FILE=/configs/%d.js renovate", installation.id, installation.id),
 		)
@@ -296,6 +335,11 @@ func (r *GitTektonResourcesRenovater) CreateRenovaterJob(ctx context.Context, in
 	if renovateImageUrl == "" {
 		renovateImageUrl = DefaultRenovateImageUrl
 	}
+	registryMirrors, err := registrymirrors.GetRegistryMirrors(ctx, r.Client, types.NamespacedName{Namespace: buildServiceNamespaceName, Name: registrymirrors.ConfigMapName})
+	if err != nil {
+		return err
+	}
+	renovateImageUrl = registryMirrors.Rewrite(renovateImageUrl)
 	job := &batch.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
@@ -25,6 +25,7 @@ import (
 
 	gitopsprepare "github.com/redhat-appstudio/application-service/gitops/prepare"
 	"github.com/redhat-appstudio/build-service/pkg/github"
+	registrymirrors "github.com/redhat-appstudio/build-service/pkg/registry-mirrors"
 	"k8s.io/apimachinery/pkg/types"
 )
 
@@ -58,7 +59,7 @@ var _ = Describe("Git tekton resources renovater", func() {
 				"https://github/test/repo1",
 				"https://github/test/repo2",
 			}
-			github.GetInstallations = func(appId int64, privateKeyPem []byte) ([]github.ApplicationInstallation, string, error) {
+			github.GetInstallations = func(appId int64, privateKeyPem []byte, githubUrl string) ([]github.ApplicationInstallation, string, error) {
 				repositories := generateRepositories(installedRepositoryUrls)
 				return []github.ApplicationInstallation{generateInstallation(repositories)}, "slug", nil
 			}
@@ -73,7 +74,7 @@ var _ = Describe("Git tekton resources renovater", func() {
 				"https://github/test/repo1",
 				"https://github/test/repo2",
 			}
-			github.GetInstallations = func(appId int64, privateKeyPem []byte) ([]github.ApplicationInstallation, string, error) {
+			github.GetInstallations = func(appId int64, privateKeyPem []byte, githubUrl string) ([]github.ApplicationInstallation, string, error) {
 				repositories := generateRepositories(installedRepositoryUrls)
 				return []github.ApplicationInstallation{generateInstallation(repositories)}, "slug", nil
 			}
@@ -83,6 +84,27 @@ var _ = Describe("Git tekton resources renovater", func() {
 			Expect(listJobs(buildServiceNamespaceName)).Should(HaveLen(1))
 			deleteComponent(componentNamespacedName)
 		})
+		It("It should pull renovate image from registry mirror", func() {
+			installedRepositoryUrls := []string{
+				"https://github/test/repo1",
+			}
+			github.GetInstallations = func(appId int64, privateKeyPem []byte, githubUrl string) ([]github.ApplicationInstallation, string, error) {
+				repositories := generateRepositories(installedRepositoryUrls)
+				return []github.ApplicationInstallation{generateInstallation(repositories)}, "slug", nil
+			}
+			mirrorsConfigMapKey := types.NamespacedName{Name: registrymirrors.ConfigMapName, Namespace: buildServiceNamespaceName}
+			createConfigMap(mirrorsConfigMapKey, map[string]string{
+				registrymirrors.ConfigMapKey: "- source: quay.io/redhat-appstudio\n  mirrors:\n  - registry.example.com/appstudio\n",
+			})
+			componentNamespacedName := createComponentForPaCBuild(getComponentData(componentConfig{gitURL: "https://github/test/repo1"}))
+			createBuildPipelineRunSelector(defaultSelector)
+			time.Sleep(time.Second)
+			jobs := listJobs(buildServiceNamespaceName)
+			Expect(jobs).Should(HaveLen(1))
+			Expect(jobs[0].Spec.Template.Spec.Containers[0].Image).To(Equal("registry.example.com/appstudio/renovate:35.47-slim"))
+			deleteComponent(componentNamespacedName)
+			deleteConfigMap(mirrorsConfigMapKey)
+		})
 		It("It should trigger 2 jobs", func() {
 			installedRepositoryUrls1 := []string{
 				"https://github/test1/repo1",
@@ -104,7 +126,7 @@ var _ = Describe("Git tekton resources renovater", func() {
 				"https://github/test5/repo1",
 				"https://github/test5/repo2",
 			}
-			github.GetInstallations = func(appId int64, privateKeyPem []byte) ([]github.ApplicationInstallation, string, error) {
+			github.GetInstallations = func(appId int64, privateKeyPem []byte, githubUrl string) ([]github.ApplicationInstallation, string, error) {
 				return []github.ApplicationInstallation{
 					generateInstallation(generateRepositories(installedRepositoryUrls1)),
 					generateInstallation(generateRepositories(installedRepositoryUrls2)),
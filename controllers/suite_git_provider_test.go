@@ -0,0 +1,154 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitproviderfactory"
+)
+
+// TestGitProviderClient is a fake git provider client that delegates all calls
+// to the corresponding package level functions, so each test could override only the needed ones.
+type TestGitProviderClient struct{}
+
+var _ gitprovider.GitProvider = (*TestGitProviderClient)(nil)
+
+var testGitProviderClient = &TestGitProviderClient{}
+
+var (
+	EnsurePaCMergeRequestFunc          func(repoUrl string, data *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error)
+	UndoPaCMergeRequestFunc            func(repoUrl string, data *gitprovider.MergeRequestData) (webUrl string, err error)
+	FindUnmergedPaCMergeRequestFunc    func(repoUrl string, data *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error)
+	GetMergeRequestFunc                func(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error)
+	ReopenMergeRequestFunc             func(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error)
+	EnablePaCMergeRequestAutoMergeFunc func(repoUrl string, mrNumber int64, mergeMethod string) error
+	SetupPaCWebhookFunc                func(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *gitprovider.WebhookOptions) error
+	DeletePaCWebhookFunc               func(repoUrl string, webhookUrl string) error
+	GetDefaultBranchFunc               func(repoUrl string) (string, error)
+	DeleteBranchFunc                   func(repoUrl string, branchName string) (bool, error)
+	GetBranchShaFunc                   func(repoUrl string, branchName string) (string, error)
+	DownloadFileContentFunc            func(repoUrl string, branchName string, filePath string) ([]byte, error)
+	GetBrowseRepositoryAtShaLinkFunc   func(repoUrl string, sha string) string
+	GetConfiguredGitAppNameFunc        func() (string, string, error)
+)
+
+// ResetTestGitProviderClient makes git client factory return the fake client
+// and restores default behaviour of all the fake client methods.
+func ResetTestGitProviderClient() {
+	gitproviderfactory.CreateGitClient = func(gitClientConfig gitproviderfactory.GitClientConfig) (gitprovider.GitProvider, error) {
+		return testGitProviderClient, nil
+	}
+
+	EnsurePaCMergeRequestFunc = func(repoUrl string, data *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+		return nil, nil
+	}
+	UndoPaCMergeRequestFunc = func(repoUrl string, data *gitprovider.MergeRequestData) (webUrl string, err error) {
+		return "", nil
+	}
+	FindUnmergedPaCMergeRequestFunc = func(repoUrl string, data *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+		return nil, nil
+	}
+	GetMergeRequestFunc = func(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+		return &gitprovider.MergeRequest{Number: mrNumber, State: gitprovider.MergeRequestStateOpen}, nil
+	}
+	ReopenMergeRequestFunc = func(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+		return &gitprovider.MergeRequest{Number: mrNumber, State: gitprovider.MergeRequestStateOpen}, nil
+	}
+	EnablePaCMergeRequestAutoMergeFunc = func(repoUrl string, mrNumber int64, mergeMethod string) error {
+		return nil
+	}
+	SetupPaCWebhookFunc = func(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *gitprovider.WebhookOptions) error {
+		return nil
+	}
+	DeletePaCWebhookFunc = func(repoUrl string, webhookUrl string) error {
+		return nil
+	}
+	GetDefaultBranchFunc = func(repoUrl string) (string, error) {
+		return "main", nil
+	}
+	DeleteBranchFunc = func(repoUrl string, branchName string) (bool, error) {
+		return true, nil
+	}
+	GetBranchShaFunc = func(repoUrl string, branchName string) (string, error) {
+		return "26239c94569cea79b32bce32f12c8abd8bbd0fd7", nil
+	}
+	DownloadFileContentFunc = func(repoUrl string, branchName string, filePath string) ([]byte, error) {
+		return nil, nil
+	}
+	GetBrowseRepositoryAtShaLinkFunc = func(repoUrl string, sha string) string {
+		return "https://github.com/devfile-samples/devfile-sample-java-springboot-basic?rev=" + sha
+	}
+	GetConfiguredGitAppNameFunc = func() (string, string, error) {
+		return "Test App Name", "test-app-slug", nil
+	}
+}
+
+func (*TestGitProviderClient) EnsurePaCMergeRequest(repoUrl string, data *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+	return EnsurePaCMergeRequestFunc(repoUrl, data)
+}
+
+func (*TestGitProviderClient) UndoPaCMergeRequest(repoUrl string, data *gitprovider.MergeRequestData) (webUrl string, err error) {
+	return UndoPaCMergeRequestFunc(repoUrl, data)
+}
+
+func (*TestGitProviderClient) FindUnmergedPaCMergeRequest(repoUrl string, data *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+	return FindUnmergedPaCMergeRequestFunc(repoUrl, data)
+}
+
+func (*TestGitProviderClient) GetMergeRequest(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+	return GetMergeRequestFunc(repoUrl, mrNumber)
+}
+
+func (*TestGitProviderClient) ReopenMergeRequest(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+	return ReopenMergeRequestFunc(repoUrl, mrNumber)
+}
+
+func (*TestGitProviderClient) EnablePaCMergeRequestAutoMerge(repoUrl string, mrNumber int64, mergeMethod string) error {
+	return EnablePaCMergeRequestAutoMergeFunc(repoUrl, mrNumber, mergeMethod)
+}
+
+func (*TestGitProviderClient) SetupPaCWebhook(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *gitprovider.WebhookOptions) error {
+	return SetupPaCWebhookFunc(repoUrl, webhookUrl, webhookSecret, webhookOptions)
+}
+
+func (*TestGitProviderClient) DeletePaCWebhook(repoUrl string, webhookUrl string) error {
+	return DeletePaCWebhookFunc(repoUrl, webhookUrl)
+}
+
+func (*TestGitProviderClient) GetDefaultBranch(repoUrl string) (string, error) {
+	return GetDefaultBranchFunc(repoUrl)
+}
+
+func (*TestGitProviderClient) DeleteBranch(repoUrl string, branchName string) (bool, error) {
+	return DeleteBranchFunc(repoUrl, branchName)
+}
+
+func (*TestGitProviderClient) GetBranchSha(repoUrl string, branchName string) (string, error) {
+	return GetBranchShaFunc(repoUrl, branchName)
+}
+
+func (*TestGitProviderClient) DownloadFileContent(repoUrl string, branchName string, filePath string) ([]byte, error) {
+	return DownloadFileContentFunc(repoUrl, branchName, filePath)
+}
+
+func (*TestGitProviderClient) GetBrowseRepositoryAtShaLink(repoUrl string, sha string) string {
+	return GetBrowseRepositoryAtShaLinkFunc(repoUrl, sha)
+}
+
+func (*TestGitProviderClient) GetConfiguredGitAppName() (string, string, error) {
+	return GetConfiguredGitAppNameFunc()
+}
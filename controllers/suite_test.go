@@ -37,11 +37,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	pacv1alpha1 "github.com/openshift-pipelines/pipelines-as-code/pkg/apis/pipelinesascode/v1alpha1"
+	tektonapiv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
 
 	appstudioredhatcomv1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
+	pipelineresolver "github.com/redhat-appstudio/build-service/pkg/pipeline-resolver"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -96,6 +98,9 @@ var _ = BeforeSuite(func() {
 	err = tektonapi.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = tektonapiv1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	err = pacv1alpha1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
@@ -122,10 +127,15 @@ var _ = BeforeSuite(func() {
 	})
 	Expect(err).ToNot(HaveOccurred())
 
+	// Do not pull pipeline bundles in tests
+	pipelineResolverClient := pipelineresolver.NewResolverClient(k8sManager.GetAPIReader(), []string{buildServiceNamespaceName}, nil, nil)
+	pipelineResolverClient.RegisterResolver(pipelineresolver.BundlesResolverName, &testBundlesResolver{})
+
 	err = (&ComponentBuildReconciler{
-		Client:        k8sManager.GetClient(),
-		Scheme:        k8sManager.GetScheme(),
-		EventRecorder: k8sManager.GetEventRecorderFor("ComponentOnboarding"),
+		Client:                 k8sManager.GetClient(),
+		Scheme:                 k8sManager.GetScheme(),
+		EventRecorder:          k8sManager.GetEventRecorderFor("ComponentOnboarding"),
+		PipelineResolverClient: pipelineResolverClient,
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
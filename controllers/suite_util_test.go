@@ -17,6 +17,7 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -40,6 +41,7 @@ import (
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
 	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
 	"github.com/redhat-appstudio/build-service/pkg/github"
+	pipelineresolver "github.com/redhat-appstudio/build-service/pkg/pipeline-resolver"
 )
 
 const (
@@ -60,6 +62,7 @@ const (
 	GitSecretName           = "git-secret"
 	ComponentContainerImage = "registry.io/username/image:tag"
 	SelectorDefaultName     = "default"
+	testBundleDigest        = "sha256:2b1a8e5f4c3d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f7"
 )
 
 type componentConfig struct {
@@ -146,9 +149,10 @@ func getSampleComponentData(componentKey types.NamespacedName) *appstudiov1alpha
 
 // createComponent creates sample component resource and verifies it was properly created
 func createComponentForPaCBuild(sampleComponentData *appstudiov1alpha1.Component) types.NamespacedName {
-	sampleComponentData.Annotations = map[string]string{
-		PaCProvisionAnnotationName: PaCProvisionRequestedAnnotationValue,
+	if sampleComponentData.Annotations == nil {
+		sampleComponentData.Annotations = make(map[string]string)
 	}
+	sampleComponentData.Annotations[PaCProvisionAnnotationName] = PaCProvisionRequestedAnnotationValue
 
 	Expect(k8sClient.Create(ctx, sampleComponentData)).Should(Succeed())
 
@@ -226,6 +230,19 @@ func waitPaCFinalizerOnComponent(componentKey types.NamespacedName) {
 	}, timeout, interval).Should(BeTrue())
 }
 
+// testBundlesResolver returns a pipeline with the params and workspaces of the default build pipeline for any bundle.
+type testBundlesResolver struct{}
+
+func (*testBundlesResolver) Resolve(ctx context.Context, params map[string]string, namespace string) (*pipelineresolver.ResolvedPipeline, error) {
+	pipelineSpec := &tektonapi.PipelineSpec{
+		Workspaces: []tektonapi.PipelineWorkspaceDeclaration{{Name: "workspace"}, {Name: "git-auth", Optional: true}},
+	}
+	for _, param := range []string{"git-url", "revision", "output-image", "path-context", "dockerfile", "rebuild", "skip-checks", "image-expires-after"} {
+		pipelineSpec.Params = append(pipelineSpec.Params, tektonapi.ParamSpec{Name: param, Type: tektonapi.ParamTypeString})
+	}
+	return &pipelineresolver.ResolvedPipeline{Spec: pipelineSpec, Digest: testBundleDigest}, nil
+}
+
 func listComponentPipelineRuns(componentKey types.NamespacedName) []tektonapi.PipelineRun {
 	pipelineRuns := &tektonapi.PipelineRunList{}
 	labelSelectors := client.ListOptions{
@@ -325,6 +342,40 @@ func createSecret(resourceKey types.NamespacedName, data map[string]string) {
 	}
 }
 
+func createConfigMap(resourceKey types.NamespacedName, data map[string]string) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resourceKey.Name,
+			Namespace: resourceKey.Namespace,
+		},
+		Data: data,
+	}
+	if err := k8sClient.Create(ctx, configMap); err != nil {
+		if !k8sErrors.IsAlreadyExists(err) {
+			Fail(err.Error())
+		}
+		deleteConfigMap(resourceKey)
+		configMap.ResourceVersion = ""
+		Expect(k8sClient.Create(ctx, configMap)).Should(Succeed())
+	}
+}
+
+func deleteConfigMap(resourceKey types.NamespacedName) {
+	configMap := &corev1.ConfigMap{}
+	if err := k8sClient.Get(ctx, resourceKey, configMap); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return
+		}
+		Fail(err.Error())
+	}
+	if err := k8sClient.Delete(ctx, configMap); err != nil && !k8sErrors.IsNotFound(err) {
+		Fail(err.Error())
+	}
+	Eventually(func() bool {
+		return k8sErrors.IsNotFound(k8sClient.Get(ctx, resourceKey, configMap))
+	}, timeout, interval).Should(BeTrue())
+}
+
 func deleteSecret(resourceKey types.NamespacedName) {
 	secret := &corev1.Secret{}
 	if err := k8sClient.Get(ctx, resourceKey, secret); err != nil {
@@ -49,6 +49,7 @@ import (
 
 	pacv1alpha1 "github.com/openshift-pipelines/pipelines-as-code/pkg/apis/pipelinesascode/v1alpha1"
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	tektonapiv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 
 	appstudioredhatcomv1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
@@ -103,6 +104,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := tektonapiv1.AddToScheme(scheme); err != nil {
+		setupLog.Error(err, "unable to add tekton v1 api to the scheme")
+		os.Exit(1)
+	}
+
 	if err := pacv1alpha1.AddToScheme(scheme); err != nil {
 		setupLog.Error(err, "unable to add pipelinesascode api to the scheme")
 		os.Exit(1)
@@ -0,0 +1,573 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+// Allow mocking for tests
+var NewBitbucketClient func(username, appPassword string) *BitbucketClient = newBitbucketClient
+
+const (
+	bitbucketCloudApiUrl = "https://api.bitbucket.org/2.0"
+)
+
+// BitbucketClient is a minimal Bitbucket Cloud REST API 2.0 client.
+// Authentication is done by the user name and an app password.
+type BitbucketClient struct {
+	ctx         context.Context
+	client      *http.Client
+	baseUrl     string
+	username    string
+	appPassword string
+}
+
+// ErrorResponse is returned for any non successful response of Bitbucket API.
+type ErrorResponse struct {
+	Response *http.Response
+	Message  string
+}
+
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Response.Request.Method, e.Response.Request.URL, e.Response.StatusCode, e.Message)
+}
+
+type Repository struct {
+	FullName   string `json:"full_name"`
+	MainBranch *struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+}
+
+type Branch struct {
+	Name   string `json:"name"`
+	Target struct {
+		Hash string `json:"hash"`
+	} `json:"target"`
+}
+
+type BranchRef struct {
+	Branch struct {
+		Name string `json:"name"`
+	} `json:"branch"`
+}
+
+type Link struct {
+	Href string `json:"href"`
+}
+
+type PullRequest struct {
+	ID          int64     `json:"id,omitempty"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	State       string    `json:"state,omitempty"`
+	Source      BranchRef `json:"source"`
+	Destination BranchRef `json:"destination"`
+	Author      *struct {
+		Nickname string `json:"nickname"`
+	} `json:"author,omitempty"`
+	CloseSourceBranch bool `json:"close_source_branch"`
+	Links             *struct {
+		Html Link `json:"html"`
+	} `json:"links,omitempty"`
+}
+
+// GetWebURL returns link to the pull request page in web UI
+func (pr *PullRequest) GetWebURL() string {
+	if pr == nil || pr.Links == nil {
+		return ""
+	}
+	return pr.Links.Html.Href
+}
+
+type Webhook struct {
+	UUID                 string   `json:"uuid,omitempty"`
+	Description          string   `json:"description"`
+	URL                  string   `json:"url"`
+	Secret               string   `json:"secret,omitempty"`
+	Active               bool     `json:"active"`
+	SkipCertVerification bool     `json:"skip_cert_verification"`
+	Events               []string `json:"events"`
+}
+
+type treeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+type page struct {
+	Next   string          `json:"next"`
+	Values json.RawMessage `json:"values"`
+}
+
+func newBitbucketClient(username, appPassword string) *BitbucketClient {
+	return newBitbucketClientWithBaseUrl(bitbucketCloudApiUrl, username, appPassword)
+}
+
+func newBitbucketClientWithBaseUrl(baseUrl, username, appPassword string) *BitbucketClient {
+	return &BitbucketClient{
+		ctx:         context.Background(),
+		client:      &http.Client{},
+		baseUrl:     strings.TrimSuffix(baseUrl, "/"),
+		username:    username,
+		appPassword: appPassword,
+	}
+}
+
+func repositoryPath(workspace, repository string) string {
+	return fmt.Sprintf("/repositories/%s/%s", url.PathEscape(workspace), url.PathEscape(repository))
+}
+
+// do sends the given request and decodes JSON response body into result, if result is not nil.
+// Returns the response and ErrorResponse if the request was not successful.
+func (c *BitbucketClient) do(req *http.Request, result interface{}) (*http.Response, error) {
+	req.SetBasicAuth(c.username, c.appPassword)
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		errResp := &ErrorResponse{Response: resp}
+		bitbucketError := struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}{}
+		if json.Unmarshal(body, &bitbucketError) == nil && bitbucketError.Error.Message != "" {
+			errResp.Message = bitbucketError.Error.Message
+		} else {
+			errResp.Message = string(body)
+		}
+		return resp, errResp
+	}
+
+	if result != nil {
+		switch r := result.(type) {
+		case *[]byte:
+			*r = body
+		default:
+			if err := json.Unmarshal(body, result); err != nil {
+				return resp, fmt.Errorf("failed to decode Bitbucket API response: %w", err)
+			}
+		}
+	}
+	return resp, nil
+}
+
+func (c *BitbucketClient) newRequest(method, path string, body interface{}) (*http.Request, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		bodyReader = bytes.NewReader(data)
+	}
+	requestUrl := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		requestUrl = c.baseUrl + path
+	}
+	req, err := http.NewRequestWithContext(c.ctx, method, requestUrl, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// getAllPages reads all pages of a paginated list and returns raw list items.
+func (c *BitbucketClient) getAllPages(path string) ([]json.RawMessage, *http.Response, error) {
+	var items []json.RawMessage
+	nextPageUrl := path
+	for nextPageUrl != "" {
+		req, err := c.newRequest(http.MethodGet, nextPageUrl, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		listPage := &page{}
+		resp, err := c.do(req, listPage)
+		if err != nil {
+			return nil, resp, err
+		}
+		var pageItems []json.RawMessage
+		if len(listPage.Values) != 0 {
+			if err := json.Unmarshal(listPage.Values, &pageItems); err != nil {
+				return nil, resp, err
+			}
+		}
+		items = append(items, pageItems...)
+		nextPageUrl = listPage.Next
+	}
+	return items, nil, nil
+}
+
+func (c *BitbucketClient) getDefaultBranch(workspace, repository string) (string, error) {
+	req, err := c.newRequest(http.MethodGet, repositoryPath(workspace, repository), nil)
+	if err != nil {
+		return "", err
+	}
+	repositoryInfo := &Repository{}
+	resp, err := c.do(req, repositoryInfo)
+	if err != nil {
+		return "", RefineGitHostingServiceError(resp, err)
+	}
+	if repositoryInfo.MainBranch == nil || repositoryInfo.MainBranch.Name == "" {
+		return "", fmt.Errorf("repository main branch is empty in Bitbucket API response")
+	}
+	return repositoryInfo.MainBranch.Name, nil
+}
+
+// getBranch returns the branch by its name or nil if the branch doesn't exist.
+func (c *BitbucketClient) getBranch(workspace, repository, branchName string) (*Branch, error) {
+	req, err := c.newRequest(http.MethodGet, repositoryPath(workspace, repository)+"/refs/branches/"+url.PathEscape(branchName), nil)
+	if err != nil {
+		return nil, err
+	}
+	branch := &Branch{}
+	resp, err := c.do(req, branch)
+	if err != nil {
+		if resp != nil && resp.StatusCode == 404 {
+			return nil, nil
+		}
+		return nil, RefineGitHostingServiceError(resp, err)
+	}
+	return branch, nil
+}
+
+func (c *BitbucketClient) branchExist(workspace, repository, branchName string) (bool, error) {
+	branch, err := c.getBranch(workspace, repository, branchName)
+	if err != nil {
+		return false, err
+	}
+	return branch != nil, nil
+}
+
+func (c *BitbucketClient) createBranch(workspace, repository, branchName, baseBranchName string) error {
+	baseBranch, err := c.getBranch(workspace, repository, baseBranchName)
+	if err != nil {
+		return err
+	}
+	if baseBranch == nil {
+		return fmt.Errorf("base branch %s not found in %s/%s repository", baseBranchName, workspace, repository)
+	}
+
+	newBranch := &Branch{Name: branchName}
+	newBranch.Target.Hash = baseBranch.Target.Hash
+	req, err := c.newRequest(http.MethodPost, repositoryPath(workspace, repository)+"/refs/branches", newBranch)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req, nil)
+	return RefineGitHostingServiceError(resp, err)
+}
+
+func (c *BitbucketClient) deleteBranch(workspace, repository, branchName string) error {
+	req, err := c.newRequest(http.MethodDelete, repositoryPath(workspace, repository)+"/refs/branches/"+url.PathEscape(branchName), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req, nil)
+	return RefineGitHostingServiceError(resp, err)
+}
+
+// getFileContent returns content of the given file in the given branch.
+// If the file doesn't exist, nil is returned.
+func (c *BitbucketClient) getFileContent(workspace, repository, branchName, filePath string) ([]byte, error) {
+	req, err := c.newRequest(http.MethodGet, repositoryPath(workspace, repository)+"/src/"+url.PathEscape(branchName)+"/"+filePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "*/*")
+	var content []byte
+	resp, err := c.do(req, &content)
+	if err != nil {
+		if resp != nil && resp.StatusCode == 404 {
+			return nil, nil
+		}
+		return nil, RefineGitHostingServiceError(resp, err)
+	}
+	return content, nil
+}
+
+func (c *BitbucketClient) filesUpToDate(workspace, repository, branchName string, files []File) (bool, error) {
+	for _, file := range files {
+		fileContent, err := c.getFileContent(workspace, repository, branchName, file.FullPath)
+		if err != nil {
+			return false, err
+		}
+		if fileContent == nil {
+			// Given file not found
+			return false, nil
+		}
+		if !bytes.Equal(fileContent, file.Content) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// filesExist checks if given files exist in the branch. The files could be located in different directories.
+// Returns subset of given files which exist.
+func (c *BitbucketClient) filesExist(workspace, repository, branchName string, files []File) ([]File, error) {
+	return gitprovider.FilesExist(files, func(file File) string { return file.FullPath },
+		func(directoryPath string, directoryFiles []File) ([]File, error) {
+			return c.filesExistInDirectory(workspace, repository, branchName, directoryPath, directoryFiles)
+		})
+}
+
+// filesExistInDirectory checks if given files exist under specified directory.
+// Returns subset of given files which exist.
+func (c *BitbucketClient) filesExistInDirectory(workspace, repository, branchName, directoryPath string, files []File) ([]File, error) {
+	existingFiles := make([]File, 0, len(files))
+
+	dirPath := repositoryPath(workspace, repository) + "/src/" + url.PathEscape(branchName) + "/" + strings.TrimSuffix(directoryPath, "/") + "/?pagelen=100"
+	dirContent, resp, err := c.getAllPages(dirPath)
+	if err != nil {
+		if resp != nil && resp.StatusCode == 404 {
+			return existingFiles, nil
+		}
+		return existingFiles, RefineGitHostingServiceError(resp, err)
+	}
+
+	for _, rawEntry := range dirContent {
+		entry := &treeEntry{}
+		if err := json.Unmarshal(rawEntry, entry); err != nil {
+			return existingFiles, err
+		}
+		if entry.Type != "commit_file" {
+			continue
+		}
+		for _, f := range files {
+			if entry.Path == f.FullPath {
+				existingFiles = append(existingFiles, File{FullPath: entry.Path})
+				break
+			}
+		}
+	}
+
+	return existingFiles, nil
+}
+
+// commitFilesIntoBranch creates a commit in the given branch that adds or updates given files.
+// Files without content are deleted.
+func (c *BitbucketClient) commitFilesIntoBranch(workspace, repository, branchName, commitMessage, authorName, authorEmail string, files []File) error {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	fields := map[string]string{
+		"branch":  branchName,
+		"message": commitMessage,
+		"author":  fmt.Sprintf("%s <%s>", authorName, authorEmail),
+	}
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		if file.Content == nil {
+			// A path listed in files field without content is deleted
+			if err := writer.WriteField("files", file.FullPath); err != nil {
+				return err
+			}
+			continue
+		}
+		fileWriter, err := writer.CreateFormFile(file.FullPath, file.FullPath)
+		if err != nil {
+			return err
+		}
+		if _, err := fileWriter.Write(file.Content); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.baseUrl+repositoryPath(workspace, repository)+"/src", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	resp, err := c.do(req, nil)
+	return RefineGitHostingServiceError(resp, err)
+}
+
+// Creates commit into specified branch that deletes given files.
+func (c *BitbucketClient) addDeleteCommitToBranch(workspace, repository, branchName, authorName, authorEmail, commitMessage string, files []File) error {
+	filesToDelete := make([]File, 0, len(files))
+	for _, file := range files {
+		filesToDelete = append(filesToDelete, File{FullPath: file.FullPath})
+	}
+	return c.commitFilesIntoBranch(workspace, repository, branchName, commitMessage, authorName, authorEmail, filesToDelete)
+}
+
+// listPullRequests returns pull requests in given state from the source branch into the destination branch.
+func (c *BitbucketClient) listPullRequests(workspace, repository, sourceBranch, destinationBranch, state string) ([]*PullRequest, error) {
+	query := fmt.Sprintf(`source.branch.name="%s" AND destination.branch.name="%s"`, sourceBranch, destinationBranch)
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("state", state)
+	params.Set("pagelen", "50")
+	rawPullRequests, resp, err := c.getAllPages(repositoryPath(workspace, repository) + "/pullrequests?" + params.Encode())
+	if err != nil {
+		return nil, RefineGitHostingServiceError(resp, err)
+	}
+
+	pullRequests := make([]*PullRequest, 0, len(rawPullRequests))
+	for _, rawPullRequest := range rawPullRequests {
+		pr := &PullRequest{}
+		if err := json.Unmarshal(rawPullRequest, pr); err != nil {
+			return nil, err
+		}
+		pullRequests = append(pullRequests, pr)
+	}
+	return pullRequests, nil
+}
+
+// findPullRequestByBranchesWithinRepository searches for an opened PR within repository by current and target (base) branch.
+func (c *BitbucketClient) findPullRequestByBranchesWithinRepository(workspace, repository, branchName, baseBranchName string) (*PullRequest, error) {
+	prs, err := c.listPullRequests(workspace, repository, branchName, baseBranchName, "OPEN")
+	if err != nil {
+		return nil, err
+	}
+	switch len(prs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return prs[0], nil
+	default:
+		return nil, fmt.Errorf("failed to find pull request by branch %s: %d matches found", branchName, len(prs))
+	}
+}
+
+func (c *BitbucketClient) getPullRequest(workspace, repository string, id int64) (*PullRequest, error) {
+	req, err := c.newRequest(http.MethodGet, fmt.Sprintf("%s/pullrequests/%d", repositoryPath(workspace, repository), id), nil)
+	if err != nil {
+		return nil, err
+	}
+	pr := &PullRequest{}
+	resp, err := c.do(req, pr)
+	if err != nil {
+		return nil, RefineGitHostingServiceError(resp, err)
+	}
+	return pr, nil
+}
+
+// createPullRequestWithinRepository create a new pull request into the same repository.
+// Returns url to the created pull request.
+func (c *BitbucketClient) createPullRequestWithinRepository(workspace, repository, branchName, baseBranchName, prTitle, prText string) (*PullRequest, error) {
+	newPRData := &PullRequest{
+		Title:             prTitle,
+		Description:       prText,
+		CloseSourceBranch: true,
+	}
+	newPRData.Source.Branch.Name = branchName
+	newPRData.Destination.Branch.Name = baseBranchName
+
+	req, err := c.newRequest(http.MethodPost, repositoryPath(workspace, repository)+"/pullrequests", newPRData)
+	if err != nil {
+		return nil, err
+	}
+	pr := &PullRequest{}
+	resp, err := c.do(req, pr)
+	if err != nil {
+		return nil, RefineGitHostingServiceError(resp, err)
+	}
+	return pr, nil
+}
+
+// getWebhookByTargetUrl returns webhook by its target url or nil if such webhook doesn't exist.
+func (c *BitbucketClient) getWebhookByTargetUrl(workspace, repository, webhookTargetUrl string) (*Webhook, error) {
+	rawWebhooks, resp, err := c.getAllPages(repositoryPath(workspace, repository) + "/hooks?pagelen=100")
+	if err != nil {
+		return nil, RefineGitHostingServiceError(resp, err)
+	}
+	for _, rawWebhook := range rawWebhooks {
+		webhook := &Webhook{}
+		if err := json.Unmarshal(rawWebhook, webhook); err != nil {
+			return nil, err
+		}
+		if webhook.URL == webhookTargetUrl {
+			return webhook, nil
+		}
+	}
+	// Webhook with the given URL not found
+	return nil, nil
+}
+
+func (c *BitbucketClient) createWebhook(workspace, repository string, webhook *Webhook) (*Webhook, error) {
+	req, err := c.newRequest(http.MethodPost, repositoryPath(workspace, repository)+"/hooks", webhook)
+	if err != nil {
+		return nil, err
+	}
+	createdWebhook := &Webhook{}
+	resp, err := c.do(req, createdWebhook)
+	if err != nil {
+		return nil, RefineGitHostingServiceError(resp, err)
+	}
+	return createdWebhook, nil
+}
+
+func (c *BitbucketClient) updateWebhook(workspace, repository string, webhook *Webhook) (*Webhook, error) {
+	req, err := c.newRequest(http.MethodPut, repositoryPath(workspace, repository)+"/hooks/"+url.PathEscape(webhook.UUID), webhook)
+	if err != nil {
+		return nil, err
+	}
+	updatedWebhook := &Webhook{}
+	resp, err := c.do(req, updatedWebhook)
+	if err != nil {
+		return nil, RefineGitHostingServiceError(resp, err)
+	}
+	return updatedWebhook, nil
+}
+
+func (c *BitbucketClient) deleteWebhook(workspace, repository, webhookUUID string) error {
+	req, err := c.newRequest(http.MethodDelete, repositoryPath(workspace, repository)+"/hooks/"+url.PathEscape(webhookUUID), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req, nil)
+	if resp != nil && resp.StatusCode == 404 {
+		return nil
+	}
+	return RefineGitHostingServiceError(resp, err)
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitrepourl"
+)
+
+var _ gitprovider.GitProvider = (*BitbucketClient)(nil)
+
+func (b *BitbucketClient) EnsurePaCMergeRequest(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+	workspace, repository, err := getWorkspaceAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+	pullRequest, err := EnsurePaCPullRequest(b, toPaCPullRequestData(workspace, repository, d))
+	if err != nil || pullRequest == nil {
+		return nil, err
+	}
+	return toMergeRequest(pullRequest), nil
+}
+
+func (b *BitbucketClient) UndoPaCMergeRequest(repoUrl string, d *gitprovider.MergeRequestData) (string, error) {
+	workspace, repository, err := getWorkspaceAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	return UndoPaCPullRequest(b, toPaCPullRequestData(workspace, repository, d))
+}
+
+func (b *BitbucketClient) FindUnmergedPaCMergeRequest(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+	workspace, repository, err := getWorkspaceAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	pullRequest, err := FindUnmergedOnboardingMergeRequest(b, workspace, repository, d.BranchName, d.BaseBranchName)
+	if err != nil {
+		return nil, err
+	}
+	if pullRequest == nil {
+		return nil, nil
+	}
+	return toMergeRequest(pullRequest), nil
+}
+
+func (b *BitbucketClient) GetMergeRequest(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+	workspace, repository, err := getWorkspaceAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	pullRequest, err := b.getPullRequest(workspace, repository, mrNumber)
+	if err != nil {
+		return nil, err
+	}
+	return toMergeRequest(pullRequest), nil
+}
+
+func (b *BitbucketClient) ReopenMergeRequest(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+	return nil, fmt.Errorf("declined pull requests cannot be reopened in Bitbucket")
+}
+
+func (b *BitbucketClient) EnablePaCMergeRequestAutoMerge(repoUrl string, mrNumber int64, mergeMethod string) error {
+	return fmt.Errorf("auto-merge is not supported by Bitbucket")
+}
+
+func (b *BitbucketClient) SetupPaCWebhook(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *gitprovider.WebhookOptions) error {
+	workspace, repository, err := getWorkspaceAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return err
+	}
+	events := toBitbucketWebhookEvents(webhookOptions.Events)
+	return SetupPaCWebhook(b, workspace, repository, webhookUrl, webhookSecret, webhookOptions.SSLVerification, events)
+}
+
+func (b *BitbucketClient) DeletePaCWebhook(repoUrl string, webhookUrl string) error {
+	workspace, repository, err := getWorkspaceAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return err
+	}
+	return DeletePaCWebhook(b, workspace, repository, webhookUrl)
+}
+
+func (b *BitbucketClient) GetDefaultBranch(repoUrl string) (string, error) {
+	workspace, repository, err := getWorkspaceAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	return GetDefaultBranch(b, workspace, repository)
+}
+
+func (b *BitbucketClient) DeleteBranch(repoUrl string, branchName string) (bool, error) {
+	workspace, repository, err := getWorkspaceAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return false, err
+	}
+
+	err = DeleteBranch(b, workspace, repository, branchName)
+	if err == nil {
+		return true, nil
+	}
+	if bbErrResp, ok := err.(*ErrorResponse); ok && bbErrResp.Response != nil {
+		if bbErrResp.Response.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+	}
+	return false, err
+}
+
+func (b *BitbucketClient) GetBranchSha(repoUrl string, branchName string) (string, error) {
+	workspace, repository, err := getWorkspaceAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	return GetBranchSHA(b, workspace, repository, branchName)
+}
+
+func (b *BitbucketClient) DownloadFileContent(repoUrl string, branchName string, filePath string) ([]byte, error) {
+	workspace, repository, err := getWorkspaceAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+	return b.getFileContent(workspace, repository, branchName, filePath)
+}
+
+func (b *BitbucketClient) GetBrowseRepositoryAtShaLink(repoUrl string, sha string) string {
+	return GetBrowseRepositoryAtShaLink(repoUrl, sha)
+}
+
+func (b *BitbucketClient) GetConfiguredGitAppName() (string, string, error) {
+	return "", "", fmt.Errorf("Bitbucket application is not supported")
+}
+
+// toBitbucketWebhookEvents maps git provider independent webhook events to Bitbucket webhook event keys.
+func toBitbucketWebhookEvents(events []string) []string {
+	var bitbucketEvents []string
+	for _, event := range events {
+		switch event {
+		case gitprovider.WebhookEventPullRequest:
+			bitbucketEvents = gitprovider.AppendUniqueWebhookEvents(bitbucketEvents, "pullrequest:created", "pullrequest:updated")
+		case gitprovider.WebhookEventPush, gitprovider.WebhookEventTagPush:
+			// Bitbucket delivers pushed tags as push events
+			bitbucketEvents = gitprovider.AppendUniqueWebhookEvents(bitbucketEvents, "repo:push")
+		case gitprovider.WebhookEventComment:
+			bitbucketEvents = gitprovider.AppendUniqueWebhookEvents(bitbucketEvents, "pullrequest:comment_created")
+		}
+	}
+	return bitbucketEvents
+}
+
+func toMergeRequest(pullRequest *PullRequest) *gitprovider.MergeRequest {
+	var state string
+	switch pullRequest.State {
+	case "MERGED":
+		state = gitprovider.MergeRequestStateMerged
+	case "DECLINED", "SUPERSEDED":
+		state = gitprovider.MergeRequestStateClosed
+	default:
+		state = gitprovider.MergeRequestStateOpen
+	}
+	return &gitprovider.MergeRequest{
+		Number: pullRequest.ID,
+		WebUrl: pullRequest.GetWebURL(),
+		Title:  pullRequest.Title,
+		State:  state,
+	}
+}
+
+func toPaCPullRequestData(workspace, repository string, d *gitprovider.MergeRequestData) *PaCPullRequestData {
+	var files []File
+	for _, file := range d.Files {
+		files = append(files, File{FullPath: file.FullPath, Content: file.Content})
+	}
+	var deletedFiles []File
+	for _, file := range d.DeletedFiles {
+		deletedFiles = append(deletedFiles, File{FullPath: file.FullPath})
+	}
+	return &PaCPullRequestData{
+		Workspace:     workspace,
+		Repository:    repository,
+		CommitMessage: d.CommitMessage,
+		Branch:        d.BranchName,
+		BaseBranch:    d.BaseBranchName,
+		PRTitle:       d.Title,
+		PRText:        d.Text,
+		AuthorName:    d.AuthorName,
+		AuthorEmail:   d.AuthorEmail,
+		Files:         files,
+		DeletedFiles:  deletedFiles,
+	}
+}
+
+// getWorkspaceAndRepoFromUrl extracts workspace and repository name from repository URL,
+// e.g. https://bitbucket.org/workspace/repository or git@bitbucket.org:workspace/repository.git
+func getWorkspaceAndRepoFromUrl(repoUrl string) (string, string, error) {
+	gitRepoUrl, err := gitrepourl.ParseGitRepoUrl(repoUrl)
+	if err != nil {
+		return "", "", err
+	}
+	if strings.Contains(gitRepoUrl.OwnerPath, "/") {
+		return "", "", fmt.Errorf("failed to get workspace and repository from %s URL", repoUrl)
+	}
+	return gitRepoUrl.OwnerPath, gitRepoUrl.Repository, nil
+}
@@ -0,0 +1,320 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitrepourl"
+)
+
+// Allow mocking for tests
+var EnsurePaCPullRequest func(b *BitbucketClient, d *PaCPullRequestData) (*PullRequest, error) = ensurePaCPullRequest
+var UndoPaCPullRequest func(b *BitbucketClient, d *PaCPullRequestData) (string, error) = undoPaCPullRequest
+var SetupPaCWebhook func(b *BitbucketClient, workspace, repository, webhookUrl, webhookSecret string, sslVerification bool, events []string) error = setupPaCWebhook
+var DeletePaCWebhook func(b *BitbucketClient, workspace, repository, webhookUrl string) error = deletePaCWebhook
+var GetDefaultBranch func(*BitbucketClient, string, string) (string, error) = getDefaultBranch
+var FindUnmergedOnboardingMergeRequest func(*BitbucketClient, string, string, string, string) (*PullRequest, error) = findUnmergedOnboardingMergeRequest
+var GetBranchSHA func(*BitbucketClient, string, string, string) (string, error) = getBranchSHA
+var DeleteBranch func(*BitbucketClient, string, string, string) error = deleteBranch
+
+const (
+	webhookDescription = "Pipelines as Code"
+)
+
+type File struct {
+	FullPath string
+	Content  []byte
+}
+
+type PaCPullRequestData struct {
+	Workspace     string
+	Repository    string
+	CommitMessage string
+	Branch        string
+	BaseBranch    string
+	PRTitle       string
+	PRText        string
+	AuthorName    string
+	AuthorEmail   string
+	Files         []File
+	// DeletedFiles are removed from the branch if it already exists
+	DeletedFiles []File
+}
+
+// ensurePaCPullRequest creates a new pull request or updates existing (if needed) and returns it.
+// If there is no error and the pull request is nil, it means that the PR is not needed (main branch is up to date).
+func ensurePaCPullRequest(bbclient *BitbucketClient, d *PaCPullRequestData) (*PullRequest, error) {
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranch == "" {
+		baseBranch, err := bbclient.getDefaultBranch(d.Workspace, d.Repository)
+		if err != nil {
+			return nil, err
+		}
+		d.BaseBranch = baseBranch
+	}
+
+	// Check if Pipelines as Code configuration up to date in the main branch
+	upToDate, err := bbclient.filesUpToDate(d.Workspace, d.Repository, d.BaseBranch, d.Files)
+	if err != nil {
+		return nil, err
+	}
+	if upToDate {
+		// Nothing to do, the configuration is alredy in the main branch of the repository
+		return nil, nil
+	}
+
+	// Check if branch with a proposal exists
+	branchExists, err := bbclient.branchExist(d.Workspace, d.Repository, d.Branch)
+	if err != nil {
+		return nil, err
+	}
+
+	if branchExists {
+		upToDate, err := bbclient.filesUpToDate(d.Workspace, d.Repository, d.Branch, d.Files)
+		if err != nil {
+			return nil, err
+		}
+		if !upToDate {
+			// Update branch
+			err = bbclient.commitFilesIntoBranch(d.Workspace, d.Repository, d.Branch, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// Remove files which are not proposed anymore
+		deletedFiles, err := bbclient.filesExist(d.Workspace, d.Repository, d.Branch, d.DeletedFiles)
+		if err != nil {
+			return nil, err
+		}
+		if len(deletedFiles) > 0 {
+			err = bbclient.addDeleteCommitToBranch(d.Workspace, d.Repository, d.Branch, d.AuthorName, d.AuthorEmail, d.CommitMessage, deletedFiles)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		pr, err := bbclient.findPullRequestByBranchesWithinRepository(d.Workspace, d.Repository, d.Branch, d.BaseBranch)
+		if err != nil {
+			return nil, err
+		}
+		if pr != nil {
+			return pr, nil
+		}
+
+		pr, err = bbclient.createPullRequestWithinRepository(d.Workspace, d.Repository, d.Branch, d.BaseBranch, d.PRTitle, d.PRText)
+		if err != nil {
+			if strings.Contains(err.Error(), "no changes to be pulled") {
+				// This could happen when a PR was created and merged, but PR branch was not deleted. Then main was updated.
+				// Current branch has correct configuration, but it's not possible to create a PR,
+				// because current branch reference is included into main branch.
+				if err := bbclient.deleteBranch(d.Workspace, d.Repository, d.Branch); err != nil {
+					return nil, err
+				}
+				return ensurePaCPullRequest(bbclient, d)
+			}
+			return nil, err
+		}
+		return pr, nil
+
+	} else {
+		// Create branch, commit and pull request
+		if err := bbclient.createBranch(d.Workspace, d.Repository, d.Branch, d.BaseBranch); err != nil {
+			return nil, err
+		}
+
+		err = bbclient.commitFilesIntoBranch(d.Workspace, d.Repository, d.Branch, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files)
+		if err != nil {
+			return nil, err
+		}
+
+		return bbclient.createPullRequestWithinRepository(d.Workspace, d.Repository, d.Branch, d.BaseBranch, d.PRTitle, d.PRText)
+	}
+}
+
+// undoPaCPullRequest creates a new pull request to remove PaC configuration for the component.
+// Returns the pull request web URL.
+// If there is no error and web URL is empty, it means that the PR is not needed (PaC configuraton has already been deleted).
+func undoPaCPullRequest(bbclient *BitbucketClient, d *PaCPullRequestData) (string, error) {
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranch == "" {
+		baseBranch, err := bbclient.getDefaultBranch(d.Workspace, d.Repository)
+		if err != nil {
+			return "", err
+		}
+		d.BaseBranch = baseBranch
+	}
+
+	files, err := bbclient.filesExist(d.Workspace, d.Repository, d.BaseBranch, d.Files)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		// Nothing to prune
+		return "", nil
+	}
+
+	// Need to create PR that deletes PaC configuration of the component
+
+	// Check if branch exists
+	branchExists, err := bbclient.branchExist(d.Workspace, d.Repository, d.Branch)
+	if err != nil {
+		return "", err
+	}
+	if branchExists {
+		if err := bbclient.deleteBranch(d.Workspace, d.Repository, d.Branch); err != nil {
+			return "", err
+		}
+	}
+
+	// Create branch, commit and pull request
+	if err := bbclient.createBranch(d.Workspace, d.Repository, d.Branch, d.BaseBranch); err != nil {
+		return "", err
+	}
+
+	err = bbclient.addDeleteCommitToBranch(d.Workspace, d.Repository, d.Branch, d.AuthorName, d.AuthorEmail, d.CommitMessage, files)
+	if err != nil {
+		return "", err
+	}
+
+	pr, err := bbclient.createPullRequestWithinRepository(d.Workspace, d.Repository, d.Branch, d.BaseBranch, d.PRTitle, d.PRText)
+	if err != nil {
+		return "", err
+	}
+	return pr.GetWebURL(), nil
+}
+
+// setupPaCWebhook creates or updates Pipelines as Code webhook configuration.
+// Events are Bitbucket webhook event keys.
+func setupPaCWebhook(bbclient *BitbucketClient, workspace, repository, webhookUrl, webhookSecret string, sslVerification bool, events []string) error {
+	existingWebhook, err := bbclient.getWebhookByTargetUrl(workspace, repository, webhookUrl)
+	if err != nil {
+		return err
+	}
+
+	defaultWebhook := getDefaultWebhookConfig(webhookUrl, webhookSecret, sslVerification, events)
+
+	if existingWebhook == nil {
+		// Webhook does not exist
+		_, err = bbclient.createWebhook(workspace, repository, defaultWebhook)
+		return err
+	}
+
+	// Webhook exists
+	// Need to always update the webhook in order to make sure that the webhook secret is up to date
+	// (it is not possible to read existing webhook secret)
+	existingWebhook.Secret = webhookSecret
+	existingWebhook.Active = true
+	existingWebhook.SkipCertVerification = defaultWebhook.SkipCertVerification
+	// The webhook is managed by build-service, so the subscribed events must match the configured ones
+	existingWebhook.Events = defaultWebhook.Events
+
+	_, err = bbclient.updateWebhook(workspace, repository, existingWebhook)
+	return err
+}
+
+func getDefaultWebhookConfig(webhookUrl, webhookSecret string, sslVerification bool, events []string) *Webhook {
+	return &Webhook{
+		Description:          webhookDescription,
+		URL:                  webhookUrl,
+		Secret:               webhookSecret,
+		Active:               true,
+		SkipCertVerification: !sslVerification,
+		Events:               events,
+	}
+}
+
+func deletePaCWebhook(bbclient *BitbucketClient, workspace, repository, webhookUrl string) error {
+	existingWebhook, err := bbclient.getWebhookByTargetUrl(workspace, repository, webhookUrl)
+	if err != nil {
+		return err
+	}
+	if existingWebhook == nil {
+		// Webhook doesn't exist, nothing to do
+		return nil
+	}
+
+	return bbclient.deleteWebhook(workspace, repository, existingWebhook.UUID)
+}
+
+// RefineGitHostingServiceError generates expected permanent error from Bitbucket response.
+// If no one is detected, the original error will be returned.
+// RefineGitHostingServiceError should be called just after every Bitbucket API call.
+func RefineGitHostingServiceError(response *http.Response, originErr error) error {
+	if originErr == nil || response == nil {
+		return originErr
+	}
+	switch response.StatusCode {
+	case http.StatusUnauthorized:
+		// User name or app password can't be recognized by Bitbucket.
+		return boerrors.NewBuildOpError(boerrors.EBitbucketTokenUnauthorized, originErr)
+	case http.StatusForbidden:
+		return boerrors.NewBuildOpError(boerrors.EBitbucketTokenInsufficientScope, originErr)
+	default:
+		return originErr
+	}
+}
+
+func getDefaultBranch(client *BitbucketClient, workspace, repository string) (string, error) {
+	return client.getDefaultBranch(workspace, repository)
+}
+
+// getBranchSHA returns SHA of the top commit in the given branch
+func getBranchSHA(client *BitbucketClient, workspace, repository, branchName string) (string, error) {
+	branch, err := client.getBranch(workspace, repository, branchName)
+	if err != nil {
+		return "", err
+	}
+	if branch == nil || branch.Target.Hash == "" {
+		return "", fmt.Errorf("unexpected response while getting branch top commit SHA")
+	}
+	return branch.Target.Hash, nil
+}
+
+// findUnmergedOnboardingMergeRequest finds out the unmerged pull request that is opened during the component onboarding
+// An onboarding pull request fulfills both:
+// 1) opened based on the base branch which is determined by the Revision or is the default branch of component repository
+// 2) opened from source branch appstudio-{component.Name}
+// If no onboarding pull request is found, nil is returned.
+func findUnmergedOnboardingMergeRequest(
+	bbclient *BitbucketClient, workspace, repository, sourceBranch, baseBranch string) (*PullRequest, error) {
+	pullRequests, err := bbclient.listPullRequests(workspace, repository, sourceBranch, baseBranch, "OPEN")
+	if err != nil {
+		return nil, err
+	}
+	if len(pullRequests) == 0 {
+		return nil, nil
+	}
+	return pullRequests[0], nil
+}
+
+func deleteBranch(client *BitbucketClient, workspace, repository, branch string) error {
+	return client.deleteBranch(workspace, repository, branch)
+}
+
+func GetBrowseRepositoryAtShaLink(repoUrl, sha string) string {
+	gitRepoUrl, err := gitrepourl.ParseGitRepoUrl(repoUrl)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%s/src/%s", gitRepoUrl.GetWebUrl(), sha)
+}
@@ -0,0 +1,508 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+const (
+	testWorkspace  = "workspace"
+	testRepository = "repository"
+	testUsername   = "user"
+	testPassword   = "app-password"
+)
+
+// fakeBitbucket is an in-memory implementation of the subset of Bitbucket Cloud API used by the client.
+type fakeBitbucket struct {
+	mu sync.Mutex
+
+	mainBranch string
+	// branch name -> file path -> content
+	branches     map[string]map[string]string
+	pullRequests []*PullRequest
+	webhooks     []*Webhook
+	commits      int
+}
+
+func newFakeBitbucket() *fakeBitbucket {
+	return &fakeBitbucket{
+		mainBranch: "main",
+		branches: map[string]map[string]string{
+			"main": {"README.md": "readme"},
+		},
+	}
+}
+
+func (f *fakeBitbucket) start(t *testing.T) *BitbucketClient {
+	server := httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(server.Close)
+	return newBitbucketClientWithBaseUrl(server.URL, testUsername, testPassword)
+}
+
+func writeJSON(w http.ResponseWriter, status int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(obj)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{"type": "error", "error": map[string]string{"message": message}})
+}
+
+func (f *fakeBitbucket) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if username, password, ok := r.BasicAuth(); !ok || username != testUsername || password != testPassword {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	prefix := fmt.Sprintf("/repositories/%s/%s", testWorkspace, testRepository)
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		writeError(w, http.StatusNotFound, "Repository not found")
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+
+	switch {
+	case path == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"full_name":  testWorkspace + "/" + testRepository,
+			"mainbranch": map[string]string{"name": f.mainBranch},
+		})
+
+	case path == "/refs/branches" && r.Method == http.MethodPost:
+		branch := &Branch{}
+		_ = json.NewDecoder(r.Body).Decode(branch)
+		for name := range f.branches {
+			if fmt.Sprintf("sha-%s", name) == branch.Target.Hash {
+				files := map[string]string{}
+				for k, v := range f.branches[name] {
+					files[k] = v
+				}
+				f.branches[branch.Name] = files
+				writeJSON(w, http.StatusCreated, branch)
+				return
+			}
+		}
+		writeError(w, http.StatusBadRequest, "unknown target")
+
+	case strings.HasPrefix(path, "/refs/branches/"):
+		name := strings.TrimPrefix(path, "/refs/branches/")
+		if _, exists := f.branches[name]; !exists {
+			writeError(w, http.StatusNotFound, "Branch not found")
+			return
+		}
+		if r.Method == http.MethodDelete {
+			delete(f.branches, name)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		branch := &Branch{Name: name}
+		branch.Target.Hash = "sha-" + name
+		writeJSON(w, http.StatusOK, branch)
+
+	case path == "/src" && r.Method == http.MethodPost:
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		files, exists := f.branches[r.FormValue("branch")]
+		if !exists {
+			writeError(w, http.StatusNotFound, "Branch not found")
+			return
+		}
+		for _, filePath := range r.MultipartForm.Value["files"] {
+			delete(files, filePath)
+		}
+		for filePath, headers := range r.MultipartForm.File {
+			file, _ := headers[0].Open()
+			content, _ := io.ReadAll(file)
+			files[filePath] = string(content)
+		}
+		f.commits++
+		w.WriteHeader(http.StatusCreated)
+
+	case strings.HasPrefix(path, "/src/"):
+		parts := strings.SplitN(strings.TrimPrefix(path, "/src/"), "/", 2)
+		files, exists := f.branches[parts[0]]
+		if !exists {
+			writeError(w, http.StatusNotFound, "Branch not found")
+			return
+		}
+		if strings.HasSuffix(parts[1], "/") {
+			dir := parts[1]
+			var entries []treeEntry
+			for filePath := range files {
+				if strings.HasPrefix(filePath, dir) {
+					entries = append(entries, treeEntry{Path: filePath, Type: "commit_file"})
+				}
+			}
+			if len(entries) == 0 {
+				writeError(w, http.StatusNotFound, "No such file or directory")
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"values": entries})
+			return
+		}
+		content, exists := files[parts[1]]
+		if !exists {
+			writeError(w, http.StatusNotFound, "No such file or directory")
+			return
+		}
+		_, _ = w.Write([]byte(content))
+
+	case path == "/pullrequests" && r.Method == http.MethodPost:
+		pr := &PullRequest{}
+		_ = json.NewDecoder(r.Body).Decode(pr)
+		if f.branches[pr.Source.Branch.Name] == nil {
+			writeError(w, http.StatusBadRequest, "Source branch not found")
+			return
+		}
+		pr.ID = int64(len(f.pullRequests) + 1)
+		pr.State = "OPEN"
+		pr.Links = &struct {
+			Html Link `json:"html"`
+		}{Html: Link{Href: fmt.Sprintf("https://bitbucket.org/%s/%s/pull-requests/%d", testWorkspace, testRepository, pr.ID)}}
+		f.pullRequests = append(f.pullRequests, pr)
+		writeJSON(w, http.StatusCreated, pr)
+
+	case path == "/pullrequests" && r.Method == http.MethodGet:
+		var prs []*PullRequest
+		for _, pr := range f.pullRequests {
+			query := r.URL.Query().Get("q")
+			if pr.State == r.URL.Query().Get("state") &&
+				strings.Contains(query, fmt.Sprintf(`source.branch.name="%s"`, pr.Source.Branch.Name)) &&
+				strings.Contains(query, fmt.Sprintf(`destination.branch.name="%s"`, pr.Destination.Branch.Name)) {
+				prs = append(prs, pr)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"values": prs})
+
+	case strings.HasPrefix(path, "/pullrequests/") && r.Method == http.MethodGet:
+		for _, pr := range f.pullRequests {
+			if fmt.Sprintf("/pullrequests/%d", pr.ID) == path {
+				writeJSON(w, http.StatusOK, pr)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "Pull request not found")
+
+	case path == "/hooks" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"values": f.webhooks})
+
+	case path == "/hooks" && r.Method == http.MethodPost:
+		webhook := &Webhook{}
+		_ = json.NewDecoder(r.Body).Decode(webhook)
+		webhook.UUID = fmt.Sprintf("{uuid-%d}", len(f.webhooks)+1)
+		f.webhooks = append(f.webhooks, webhook)
+		writeJSON(w, http.StatusCreated, webhook)
+
+	case strings.HasPrefix(path, "/hooks/"):
+		uuid := strings.TrimPrefix(path, "/hooks/")
+		for i, webhook := range f.webhooks {
+			if webhook.UUID != uuid {
+				continue
+			}
+			switch r.Method {
+			case http.MethodPut:
+				updatedWebhook := &Webhook{}
+				_ = json.NewDecoder(r.Body).Decode(updatedWebhook)
+				updatedWebhook.UUID = uuid
+				f.webhooks[i] = updatedWebhook
+				writeJSON(w, http.StatusOK, updatedWebhook)
+			case http.MethodDelete:
+				f.webhooks = append(f.webhooks[:i], f.webhooks[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+			}
+			return
+		}
+		writeError(w, http.StatusNotFound, "Webhook not found")
+
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func getPullRequestData() *PaCPullRequestData {
+	return &PaCPullRequestData{
+		Workspace:     testWorkspace,
+		Repository:    testRepository,
+		CommitMessage: "Appstudio update component",
+		Branch:        "appstudio-component",
+		PRTitle:       "Appstudio update component",
+		PRText:        "Pipelines as Code configuration proposal",
+		AuthorName:    "redhat-appstudio",
+		AuthorEmail:   "rhtap@redhat.com",
+		Files: []File{
+			{FullPath: ".tekton/component-push.yaml", Content: []byte("push")},
+			{FullPath: ".tekton/component-pull-request.yaml", Content: []byte("pull-request")},
+		},
+	}
+}
+
+func TestEnsurePaCPullRequest(t *testing.T) {
+	fake := newFakeBitbucket()
+	bbclient := fake.start(t)
+
+	prData := getPullRequestData()
+	pr, err := ensurePaCPullRequest(bbclient, prData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.GetWebURL() != "https://bitbucket.org/workspace/repository/pull-requests/1" {
+		t.Fatalf("unexpected pull request URL: %s", pr.GetWebURL())
+	}
+	if prData.BaseBranch != "main" {
+		t.Errorf("expected base branch to fall back to the main branch, got %s", prData.BaseBranch)
+	}
+	if fake.branches["appstudio-component"][".tekton/component-push.yaml"] != "push" {
+		t.Errorf("expected PaC configuration to be committed into the proposal branch")
+	}
+
+	t.Run("should return existing pull request", func(t *testing.T) {
+		pr, err := ensurePaCPullRequest(bbclient, getPullRequestData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pr.GetWebURL() != "https://bitbucket.org/workspace/repository/pull-requests/1" {
+			t.Fatalf("unexpected pull request URL: %s", pr.GetWebURL())
+		}
+		if len(fake.pullRequests) != 1 || fake.commits != 1 {
+			t.Errorf("expected no new pull requests nor commits")
+		}
+	})
+
+	t.Run("should not propose up to date configuration", func(t *testing.T) {
+		fake.branches["main"] = fake.branches["appstudio-component"]
+		pr, err := ensurePaCPullRequest(bbclient, getPullRequestData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pr != nil {
+			t.Errorf("expected no pull request, got %s", pr.GetWebURL())
+		}
+	})
+}
+
+func TestUndoPaCPullRequest(t *testing.T) {
+	fake := newFakeBitbucket()
+	bbclient := fake.start(t)
+
+	prData := getPullRequestData()
+	prData.Branch = "appstudio-purge-component"
+	prData.Files = []File{{FullPath: ".tekton/component-push.yaml"}, {FullPath: ".tekton/component-pull-request.yaml"}}
+
+	prUrl, err := undoPaCPullRequest(bbclient, prData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prUrl != "" {
+		t.Fatalf("expected no pull request if configuration doesn't exist, got %s", prUrl)
+	}
+
+	fake.branches["main"][".tekton/component-push.yaml"] = "push"
+	fake.branches["main"][".tekton/other-push.yaml"] = "other"
+	prUrl, err = undoPaCPullRequest(bbclient, prData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prUrl == "" {
+		t.Fatal("expected purge pull request")
+	}
+	purgeBranch := fake.branches["appstudio-purge-component"]
+	if _, exists := purgeBranch[".tekton/component-push.yaml"]; exists {
+		t.Errorf("expected component PaC configuration to be deleted")
+	}
+	if _, exists := purgeBranch[".tekton/other-push.yaml"]; !exists {
+		t.Errorf("expected other files to be kept")
+	}
+}
+
+func TestUndoPaCPullRequestWithFilesInSubdirectories(t *testing.T) {
+	fake := newFakeBitbucket()
+	bbclient := fake.start(t)
+
+	prData := getPullRequestData()
+	prData.Branch = "appstudio-purge-component"
+	prData.Files = []File{{FullPath: ".tekton/app/component-push.yaml"}, {FullPath: ".tekton/app/pull-requests/component.yaml"}}
+
+	fake.branches["main"][".tekton/app/component-push.yaml"] = "push"
+	fake.branches["main"][".tekton/app/pull-requests/component.yaml"] = "pull request"
+	fake.branches["main"][".tekton/other-app/component-push.yaml"] = "other"
+	prUrl, err := undoPaCPullRequest(bbclient, prData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prUrl == "" {
+		t.Fatal("expected purge pull request")
+	}
+	purgeBranch := fake.branches["appstudio-purge-component"]
+	for _, file := range prData.Files {
+		if _, exists := purgeBranch[file.FullPath]; exists {
+			t.Errorf("expected %s to be deleted", file.FullPath)
+		}
+	}
+	if _, exists := purgeBranch[".tekton/other-app/component-push.yaml"]; !exists {
+		t.Errorf("expected other files to be kept")
+	}
+}
+
+func TestSetupPaCWebhook(t *testing.T) {
+	fake := newFakeBitbucket()
+	bbclient := fake.start(t)
+
+	webhookUrl := "https://pac.example.com"
+	events := toBitbucketWebhookEvents(gitprovider.DefaultWebhookEvents)
+	if err := setupPaCWebhook(bbclient, testWorkspace, testRepository, webhookUrl, "secret1", false, events); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.webhooks) != 1 || fake.webhooks[0].Secret != "secret1" || !reflect.DeepEqual(fake.webhooks[0].Events, events) || !fake.webhooks[0].SkipCertVerification {
+		t.Fatalf("unexpected webhooks: %v", fake.webhooks)
+	}
+
+	fake.webhooks[0].Active = false
+	fake.webhooks[0].Events = []string{"repo:push", "issue:created"}
+	if err := setupPaCWebhook(bbclient, testWorkspace, testRepository, webhookUrl, "secret2", true, events); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.webhooks) != 1 {
+		t.Fatalf("expected existing webhook to be updated, got %d webhooks", len(fake.webhooks))
+	}
+	if !fake.webhooks[0].Active || fake.webhooks[0].Secret != "secret2" || !reflect.DeepEqual(fake.webhooks[0].Events, events) || fake.webhooks[0].SkipCertVerification {
+		t.Errorf("webhook is not reconciled: %v", fake.webhooks[0])
+	}
+
+	if err := deletePaCWebhook(bbclient, testWorkspace, testRepository, webhookUrl); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.webhooks) != 0 {
+		t.Errorf("expected webhook to be deleted")
+	}
+	// Deleting non-existing webhook is not an error
+	if err := deletePaCWebhook(bbclient, testWorkspace, testRepository, webhookUrl); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetBranchSHA(t *testing.T) {
+	fake := newFakeBitbucket()
+	bbclient := fake.start(t)
+
+	sha, err := getBranchSHA(bbclient, testWorkspace, testRepository, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "sha-main" {
+		t.Errorf("unexpected SHA: %s", sha)
+	}
+
+	if _, err := getBranchSHA(bbclient, testWorkspace, testRepository, "non-existing"); err == nil {
+		t.Errorf("expected error for non-existing branch")
+	}
+}
+
+func TestFindUnmergedOnboardingMergeRequest(t *testing.T) {
+	fake := newFakeBitbucket()
+	bbclient := fake.start(t)
+
+	pr, err := findUnmergedOnboardingMergeRequest(bbclient, testWorkspace, testRepository, "appstudio-component", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr != nil {
+		t.Fatalf("expected no pull request")
+	}
+
+	if _, err := ensurePaCPullRequest(bbclient, getPullRequestData()); err != nil {
+		t.Fatal(err)
+	}
+	pr, err = findUnmergedOnboardingMergeRequest(bbclient, testWorkspace, testRepository, "appstudio-component", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr == nil || pr.GetWebURL() == "" {
+		t.Fatalf("expected onboarding pull request to be found")
+	}
+}
+
+func TestGetMergeRequest(t *testing.T) {
+	fake := newFakeBitbucket()
+	bbclient := fake.start(t)
+
+	if _, err := ensurePaCPullRequest(bbclient, getPullRequestData()); err != nil {
+		t.Fatal(err)
+	}
+	repoUrl := fmt.Sprintf("https://bitbucket.org/%s/%s", testWorkspace, testRepository)
+	mr, err := bbclient.GetMergeRequest(repoUrl, 1)
+	if err != nil {
+		t.Fatalf("GetMergeRequest(): unexpected error: %v", err)
+	}
+	if mr.Number != 1 || mr.WebUrl == "" || mr.State != gitprovider.MergeRequestStateOpen {
+		t.Errorf("GetMergeRequest(): unexpected merge request: %+v", mr)
+	}
+
+	fake.pullRequests[0].State = "DECLINED"
+	mr, err = bbclient.GetMergeRequest(repoUrl, 1)
+	if err != nil {
+		t.Fatalf("GetMergeRequest(): unexpected error: %v", err)
+	}
+	if mr.State != gitprovider.MergeRequestStateClosed {
+		t.Errorf("GetMergeRequest(): got state %s, want %s", mr.State, gitprovider.MergeRequestStateClosed)
+	}
+
+	if _, err := bbclient.GetMergeRequest(repoUrl, 2); err == nil {
+		t.Errorf("GetMergeRequest(): expected error for non-existing pull request")
+	}
+}
+
+func TestRefineGitHostingServiceError(t *testing.T) {
+	bbclient := newBitbucketClientWithBaseUrl(httptest.NewServer(http.HandlerFunc(newFakeBitbucket().serveHTTP)).URL, "wrong-user", "wrong-password")
+
+	_, err := bbclient.getDefaultBranch(testWorkspace, testRepository)
+	boErr, ok := err.(*boerrors.BuildOpError)
+	if !ok {
+		t.Fatalf("expected BuildOpError, got %v", err)
+	}
+	if boErr.ShortError() != boerrors.NewBuildOpError(boerrors.EBitbucketTokenUnauthorized, nil).ShortError() {
+		t.Errorf("unexpected error: %s", boErr.ShortError())
+	}
+}
+
+func TestGetBrowseRepositoryAtShaLink(t *testing.T) {
+	link := GetBrowseRepositoryAtShaLink("https://bitbucket.org/workspace/repository.git", "abcd")
+	if link != "https://bitbucket.org/workspace/repository/src/abcd" {
+		t.Errorf("unexpected link: %s", link)
+	}
+	link = GetBrowseRepositoryAtShaLink("git@bitbucket.org:workspace/repository.git", "abcd")
+	if link != "https://bitbucket.org/workspace/repository/src/abcd" {
+		t.Errorf("unexpected link for ssh repository URL: %s", link)
+	}
+}
@@ -17,20 +17,24 @@ limitations under the License.
 package boerrors
 
 import (
+	"errors"
 	"fmt"
+	"time"
 )
 
 var _ error = (*BuildOpError)(nil)
 
 // BuildOpError extends standard error to:
 //  1. Keep persistent / transient property of the error.
-//     All errors, except ETransientErrorId considered persistent.
+//     All errors, except ETransientErrorId and rate limit ones, considered persistent.
 //  2. Have error ID to show the root cause of the error and optionally short message.
 type BuildOpError struct {
 	// id is used to determine if error is persistent and to know the root cause of the error
 	id BOErrorId
 	// typically used to log the error message along with nested errors
 	err error
+	// retryAt is set for rate limit errors and tells when the operation could be retried
+	retryAt time.Time
 	// Optional. To provide extra information about this error
 	// If set, it will be appended to the error message returned from Error
 	ExtraInfo string
@@ -44,6 +48,25 @@ func NewBuildOpError(id BOErrorId, err error) *BuildOpError {
 	}
 }
 
+// NewRateLimitError creates a transient error that says the operation could be retried after the given time.
+func NewRateLimitError(id BOErrorId, err error, retryAt time.Time) *BuildOpError {
+	return &BuildOpError{
+		id:        id,
+		err:       err,
+		retryAt:   retryAt,
+		ExtraInfo: "",
+	}
+}
+
+// GetRetryAt returns the time when the failed operation could be retried if the error chain contains a rate limit error.
+func GetRetryAt(err error) (time.Time, bool) {
+	var boErr *BuildOpError
+	if errors.As(err, &boErr) && !boErr.retryAt.IsZero() {
+		return boErr.retryAt, true
+	}
+	return time.Time{}, false
+}
+
 func (r BuildOpError) Error() string {
 	if r.err == nil {
 		return ""
@@ -65,7 +88,11 @@ func (r BuildOpError) ShortError() string {
 }
 
 func (r BuildOpError) IsPersistent() bool {
-	return r.id != ETransientError
+	return r.id != ETransientError && r.retryAt.IsZero()
+}
+
+func (r BuildOpError) Unwrap() error {
+	return r.err
 }
 
 type BOErrorId int
@@ -80,12 +107,19 @@ const (
 	EPaCSecretInvalid BOErrorId = 51
 	// Pipelines as Code public route to recieve webhook events doesn't exist in expected namespaces.
 	EPaCRouteDoesNotExist BOErrorId = 52
+	// Merge request templates configured in 'pac-merge-request-templates' config map cannot be parsed or rendered.
+	EPaCMergeRequestTemplateInvalid BOErrorId = 53
+	// Onboarding merge request was closed without merging, so Pipelines as Code configuration is not in the repository.
+	EPaCMergeRequestClosed BOErrorId = 54
 
 	// Happens when Component source repository is hosted on unsupported / unknown git provider.
 	// For example: https://my-gitlab.com
 	// If self-hosted instance of the supported git providers is used, then "git-provider" annotation must be set:
 	// git-provider: gitlab
 	EUnknownGitProvider BOErrorId = 60
+	// Happens when Component source repository is hosted on a self-hosted git provider instance
+	// which is not in the list of instances allowed by the cluster admin.
+	EGitProviderHostNotAllowed BOErrorId = 61
 
 	// Happens when configured in cluster Pipelines as Code application is not installed in Component source repository.
 	// User must install the application to fix this error.
@@ -114,6 +148,20 @@ const (
 	EGitLabTokenUnauthorized BOErrorId = 90
 	// EGitLabTokenInsufficientScope the access token does not have sufficient scope and 403 is responded.
 	EGitLabTokenInsufficientScope BOErrorId = 91
+	// EGitLabReachRateLimit reach the GitLab API rate limit and 429 is responded.
+	EGitLabReachRateLimit BOErrorId = 92
+
+	// EBitbucketTokenUnauthorized user name or app password is not recognized by Bitbucket and 401 is responded.
+	EBitbucketTokenUnauthorized BOErrorId = 100
+	// EBitbucketTokenInsufficientScope the app password does not have sufficient permissions and 403 is responded.
+	EBitbucketTokenInsufficientScope BOErrorId = 101
+
+	// EPipelineBundleUnauthorized registry denied access to the build pipeline bundle, 401 or 403 is responded.
+	// Credentials for the registry must be added into image pull secrets of 'appstudio-pipeline' service account
+	// or into the global bundle pull secret in 'build-service' namespace.
+	EPipelineBundleUnauthorized BOErrorId = 110
+	// Build pipeline is referenced via cluster resolver from a namespace other than the component one or the shared ones.
+	EPipelineNamespaceNotAllowed BOErrorId = 111
 
 	// Value of 'image.redhat.com/image' component annotation is not a valid json or the json has invalid structure.
 	EFailedToParseImageAnnotation BOErrorId = 200
@@ -127,11 +175,14 @@ var boErrorMessages = map[BOErrorId]string{
 	ETransientError: "",
 	EUnknownError:   "unknown error",
 
-	EPaCSecretNotFound:    "Pipelines as Code secret does not exist",
-	EPaCSecretInvalid:     "Invalid Pipelines as Code secret",
-	EPaCRouteDoesNotExist: "Pipelines as Code public route does not exist",
+	EPaCSecretNotFound:              "Pipelines as Code secret does not exist",
+	EPaCSecretInvalid:               "Invalid Pipelines as Code secret",
+	EPaCRouteDoesNotExist:           "Pipelines as Code public route does not exist",
+	EPaCMergeRequestTemplateInvalid: "Invalid Pipelines as Code merge request template",
+	EPaCMergeRequestClosed:          "Pipelines as Code configuration merge request is closed without merging",
 
-	EUnknownGitProvider: "unknown git provider of the source repository",
+	EUnknownGitProvider:        "unknown git provider of the source repository",
+	EGitProviderHostNotAllowed: "git provider instance of the source repository is not allowed",
 
 	EGitHubAppNotInstalled:         "GitHub Application is not installed in user repository",
 	EGitHubAppMalformedPrivateKey:  "invalid GitHub Application private key",
@@ -144,6 +195,13 @@ var boErrorMessages = map[BOErrorId]string{
 
 	EGitLabTokenInsufficientScope: "GitLab access token does not have enough scope",
 	EGitLabTokenUnauthorized:      "Access token is unrecognizable by remote GitLab service",
+	EGitLabReachRateLimit:         "Reach GitLab API rate limit",
+
+	EBitbucketTokenUnauthorized:      "Credentials are unrecognizable by Bitbucket",
+	EBitbucketTokenInsufficientScope: "Bitbucket app password does not have enough permissions",
+
+	EPipelineBundleUnauthorized:  "Access to the build pipeline bundle is denied by the registry",
+	EPipelineNamespaceNotAllowed: "Build pipeline cannot be taken from the referenced namespace",
 
 	EFailedToParseImageAnnotation:        "Failed to parse image.redhat.com/image annotation value",
 	EComponentGitSecretMissing:           "Specified secret with git credential not found",
@@ -19,6 +19,7 @@ package boerrors
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestPersistentErrorDetection(t *testing.T) {
@@ -123,3 +124,23 @@ func TestShortErrorMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestRateLimitError(t *testing.T) {
+	retryAt := time.Now().Add(time.Minute)
+	boErr := NewRateLimitError(EGitHubReachRateLimit, fmt.Errorf("API rate limit exceeded"), retryAt)
+	if boErr.IsPersistent() {
+		t.Errorf("Rate limit error must not be persistent")
+	}
+
+	gotRetryAt, isRateLimited := GetRetryAt(fmt.Errorf("failed to create pull request: %w", boErr))
+	if !isRateLimited {
+		t.Fatalf("Expected wrapped rate limit error to be detected")
+	}
+	if !gotRetryAt.Equal(retryAt) {
+		t.Errorf("Expected retry at %s, but got %s", retryAt, gotRetryAt)
+	}
+
+	if _, isRateLimited := GetRetryAt(NewBuildOpError(EGitHubTokenUnauthorized, fmt.Errorf("bad credentials"))); isRateLimited {
+		t.Errorf("Expected non rate limit error not to be detected as rate limit one")
+	}
+}
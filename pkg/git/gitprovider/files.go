@@ -0,0 +1,44 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitprovider
+
+import "path"
+
+// FilesExist checks if given files exist. The files could be located in different directories,
+// filesExistInDirectory is called once per directory with the files located in it and returns the ones which exist.
+// Returns subset of given files which exist.
+func FilesExist[F any](files []F, getPath func(file F) string, filesExistInDirectory func(directoryPath string, files []F) ([]F, error)) ([]F, error) {
+	var directories []string
+	directoryFiles := make(map[string][]F)
+	for _, file := range files {
+		directory := path.Dir(getPath(file))
+		if _, exists := directoryFiles[directory]; !exists {
+			directories = append(directories, directory)
+		}
+		directoryFiles[directory] = append(directoryFiles[directory], file)
+	}
+
+	existingFiles := make([]F, 0, len(files))
+	for _, directory := range directories {
+		existingDirectoryFiles, err := filesExistInDirectory(directory, directoryFiles[directory])
+		if err != nil {
+			return existingFiles, err
+		}
+		existingFiles = append(existingFiles, existingDirectoryFiles...)
+	}
+	return existingFiles, nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitprovider
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestFilesExist(t *testing.T) {
+	existingFiles := map[string]bool{
+		".tekton/push.yaml":                  true,
+		"components/backend/.tekton/pr.yaml": true,
+	}
+	files := []RepositoryFile{
+		{FullPath: ".tekton/push.yaml"},
+		{FullPath: "components/backend/.tekton/pr.yaml"},
+		{FullPath: ".tekton/pr.yaml"},
+		{FullPath: "components/frontend/.tekton/pr.yaml"},
+	}
+
+	var requestedDirectories []string
+	filesExistInDirectory := func(directoryPath string, directoryFiles []RepositoryFile) ([]RepositoryFile, error) {
+		requestedDirectories = append(requestedDirectories, directoryPath)
+		var existingDirectoryFiles []RepositoryFile
+		for _, file := range directoryFiles {
+			if existingFiles[file.FullPath] {
+				existingDirectoryFiles = append(existingDirectoryFiles, file)
+			}
+		}
+		return existingDirectoryFiles, nil
+	}
+	getPath := func(file RepositoryFile) string { return file.FullPath }
+
+	got, err := FilesExist(files, getPath, filesExistInDirectory)
+	if err != nil {
+		t.Fatalf("FilesExist(): unexpected error: %v", err)
+	}
+	want := []RepositoryFile{{FullPath: ".tekton/push.yaml"}, {FullPath: "components/backend/.tekton/pr.yaml"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FilesExist(): got %v, want %v", got, want)
+	}
+	wantDirectories := []string{".tekton", "components/backend/.tekton", "components/frontend/.tekton"}
+	if !reflect.DeepEqual(requestedDirectories, wantDirectories) {
+		t.Errorf("FilesExist(): got %v directories requested, want %v", requestedDirectories, wantDirectories)
+	}
+
+	_, err = FilesExist(files, getPath, func(directoryPath string, directoryFiles []RepositoryFile) ([]RepositoryFile, error) {
+		return nil, fmt.Errorf("failed to list %s directory", directoryPath)
+	})
+	if err == nil {
+		t.Error("FilesExist(): expected error")
+	}
+}
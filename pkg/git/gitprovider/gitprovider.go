@@ -0,0 +1,186 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitprovider
+
+import "time"
+
+// GitProvider defines operations with a git hosting service (GitHub, GitLab, Bitbucket, etc.)
+// which are needed to provision Pipelines as Code for a component.
+// All methods accept the component git repository URL, e.g. https://github.com/owner/repository
+type GitProvider interface {
+	// EnsurePaCMergeRequest creates a new merge request with Pipelines as Code configuration or updates the existing one.
+	// Returns the merge request.
+	// If there is no error and the merge request is nil, it means that the merge request is not needed (main branch is up to date).
+	EnsurePaCMergeRequest(repoUrl string, d *MergeRequestData) (*MergeRequest, error)
+
+	// UndoPaCMergeRequest creates a new merge request to remove Pipelines as Code configuration of the component.
+	// Returns the merge request web URL.
+	// If there is no error and web URL is empty, it means that the merge request is not needed (the configuration is already deleted).
+	UndoPaCMergeRequest(repoUrl string, d *MergeRequestData) (string, error)
+
+	// FindUnmergedPaCMergeRequest searches for an opened onboarding merge request from d.BranchName into d.BaseBranchName.
+	// Returns nil if there is no such merge request.
+	FindUnmergedPaCMergeRequest(repoUrl string, d *MergeRequestData) (*MergeRequest, error)
+
+	// GetMergeRequest returns the merge request with the given number, including its state.
+	GetMergeRequest(repoUrl string, mrNumber int64) (*MergeRequest, error)
+
+	// ReopenMergeRequest reopens the merge request with the given number that was closed without merging.
+	ReopenMergeRequest(repoUrl string, mrNumber int64) (*MergeRequest, error)
+
+	// EnablePaCMergeRequestAutoMerge makes the git provider merge the given merge request automatically
+	// as soon as its requirements (e.g. required checks) are met. See MergeMethod* constants for mergeMethod.
+	EnablePaCMergeRequestAutoMerge(repoUrl string, mrNumber int64, mergeMethod string) error
+
+	// SetupPaCWebhook creates or updates Pipelines as Code webhook configuration in the repository.
+	// Existing webhook is reconciled to match the given options.
+	SetupPaCWebhook(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *WebhookOptions) error
+
+	// DeletePaCWebhook deletes Pipelines as Code webhook in the repository.
+	// Does nothing if the webhook doesn't exist.
+	DeletePaCWebhook(repoUrl string, webhookUrl string) error
+
+	// GetDefaultBranch returns name of the default branch of the repository.
+	GetDefaultBranch(repoUrl string) (string, error)
+
+	// DeleteBranch deletes the given branch in the repository.
+	// Returns false if the branch doesn't exist.
+	DeleteBranch(repoUrl string, branchName string) (bool, error)
+
+	// GetBranchSha returns SHA of the top commit in the given branch.
+	GetBranchSha(repoUrl string, branchName string) (string, error)
+
+	// DownloadFileContent returns content of the given file in the given branch of the repository.
+	// Returns nil if the file doesn't exist.
+	DownloadFileContent(repoUrl string, branchName string, filePath string) ([]byte, error)
+
+	// GetBrowseRepositoryAtShaLink returns web URL to browse the repository at the given commit.
+	GetBrowseRepositoryAtShaLink(repoUrl string, sha string) string
+
+	// GetConfiguredGitAppName returns name and slug of the git application the client is authenticated as.
+	// Returns an error if the client is not created by an application.
+	GetConfiguredGitAppName() (string, string, error)
+}
+
+// Git provider independent events which Pipelines as Code webhook could be subscribed to.
+// Each git provider maps them to its own event types.
+const (
+	WebhookEventPullRequest = "pull_request"
+	WebhookEventPush        = "push"
+	WebhookEventTagPush     = "tag_push"
+	WebhookEventComment     = "comment"
+)
+
+// DefaultWebhookEvents are the events Pipelines as Code needs to trigger builds on pull requests and pushes
+// and to react on comments like /retest
+var DefaultWebhookEvents = []string{WebhookEventPullRequest, WebhookEventPush, WebhookEventComment}
+
+// IsValidWebhookEvent checks if the given event is one of the supported git provider independent events.
+func IsValidWebhookEvent(event string) bool {
+	switch event {
+	case WebhookEventPullRequest, WebhookEventPush, WebhookEventTagPush, WebhookEventComment:
+		return true
+	default:
+		return false
+	}
+}
+
+// AppendUniqueWebhookEvents appends the given git provider specific events which are not in the list yet.
+// Several git provider independent events could map to the same git provider event.
+func AppendUniqueWebhookEvents(events []string, eventsToAdd ...string) []string {
+	isAdded := make(map[string]bool, len(events)+len(eventsToAdd))
+	for _, event := range events {
+		isAdded[event] = true
+	}
+	for _, event := range eventsToAdd {
+		if !isAdded[event] {
+			events = append(events, event)
+			isAdded[event] = true
+		}
+	}
+	return events
+}
+
+// Git provider independent methods of merging a merge request.
+const (
+	MergeMethodMerge  = "merge"
+	MergeMethodSquash = "squash"
+	MergeMethodRebase = "rebase"
+)
+
+// IsValidMergeMethod checks if the given merge method is one of the supported git provider independent merge methods.
+func IsValidMergeMethod(mergeMethod string) bool {
+	switch mergeMethod {
+	case MergeMethodMerge, MergeMethodSquash, MergeMethodRebase:
+		return true
+	default:
+		return false
+	}
+}
+
+type WebhookOptions struct {
+	// SSLVerification makes the git provider verify TLS certificate of the webhook URL on events delivery
+	SSLVerification bool
+	// Events to subscribe the webhook to, see WebhookEvent* constants
+	Events []string
+}
+
+type RepositoryFile struct {
+	FullPath string
+	Content  []byte
+}
+
+type MergeRequestData struct {
+	CommitMessage  string
+	BranchName     string
+	BaseBranchName string
+	Title          string
+	Text           string
+	AuthorName     string
+	AuthorEmail    string
+	Files          []RepositoryFile
+	// DeletedFiles are removed from an existing merge request branch if they are there,
+	// e.g. definitions of a component that is not part of a batch merge request anymore.
+	DeletedFiles []RepositoryFile
+	// Labels, Reviewers, TeamReviewers and Assignees are added to a new or an existing merge request.
+	// Reviewers and Assignees are user names. TeamReviewers are team slugs and supported by GitHub only.
+	// Bitbucket ignores these settings.
+	Labels        []string
+	Reviewers     []string
+	TeamReviewers []string
+	Assignees     []string
+	// Draft makes a new merge request draft. Existing merge requests are not changed.
+	Draft bool
+}
+
+// Git provider independent states of a merge request.
+const (
+	MergeRequestStateOpen   = "open"
+	MergeRequestStateMerged = "merged"
+	// MergeRequestStateClosed means that the merge request is closed without merging
+	MergeRequestStateClosed = "closed"
+)
+
+type MergeRequest struct {
+	// Number is the merge request number (GitHub, Bitbucket) or internal id (GitLab) within the repository
+	Number    int64
+	WebUrl    string
+	Title     string
+	CreatedAt *time.Time
+	// State is one of MergeRequestState* constants
+	State string
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitproviderfactory
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redhat-appstudio/application-service/gitops"
+	"github.com/redhat-appstudio/build-service/pkg/bitbucket"
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitrepourl"
+	"github.com/redhat-appstudio/build-service/pkg/git/ratelimit"
+	"github.com/redhat-appstudio/build-service/pkg/git/signing"
+	"github.com/redhat-appstudio/build-service/pkg/github"
+	"github.com/redhat-appstudio/build-service/pkg/gitlab"
+)
+
+// Allow mocking for tests
+var CreateGitClient func(gitClientConfig GitClientConfig) (gitprovider.GitProvider, error) = createGitClient
+
+type GitClientConfig struct {
+	// PacSecretData is the content of Pipelines as Code secret with git provider credentials
+	PacSecretData map[string][]byte
+	// GitProvider is the name of the git provider, e.g. github
+	GitProvider string
+	// RepoUrl is the component git repository URL
+	RepoUrl string
+	// IsAppInstallationExpected makes sense only for GitHub Application.
+	// If set, the client is created for the application installation into the repository owner account
+	// and fails if the application is not installed into the repository.
+	// Otherwise, a client for a random installation of the application is created.
+	IsAppInstallationExpected bool
+}
+
+type gitClientCreator func(gitClientConfig GitClientConfig) (gitprovider.GitProvider, error)
+
+// gitClientCreators is the registry of supported git providers keyed by provider name.
+var gitClientCreators = map[string]gitClientCreator{
+	"github":    createGithubClient,
+	"gitlab":    createGitlabClient,
+	"bitbucket": createBitbucketClient,
+}
+
+// createGitClient creates a client for the git provider of the given repository.
+// Credentials are taken from Pipelines as Code secret data.
+func createGitClient(gitClientConfig GitClientConfig) (gitprovider.GitProvider, error) {
+	create, exists := gitClientCreators[gitClientConfig.GitProvider]
+	if !exists {
+		return nil, fmt.Errorf("git provider %s is not supported", gitClientConfig.GitProvider)
+	}
+	return create(gitClientConfig)
+}
+
+func createGithubClient(gitClientConfig GitClientConfig) (gitprovider.GitProvider, error) {
+	config := gitClientConfig.PacSecretData
+	repoUrl := gitClientConfig.RepoUrl
+
+	gitRepoUrl, err := gitrepourl.ParseGitRepoUrl(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+	githubUrl := gitRepoUrl.GetProviderUrl()
+	if !github.IsAllowedGithubUrl(githubUrl) {
+		return nil, boerrors.NewBuildOpError(boerrors.EGitProviderHostNotAllowed,
+			fmt.Errorf("GitHub Enterprise Server %s is not in %s list", githubUrl, github.GithubEnterpriseUrlsEnvName))
+	}
+
+	if !gitops.IsPaCApplicationConfigured("github", config) {
+		accessToken := strings.TrimSpace(string(config[gitops.GetProviderTokenKey("github")]))
+		if err := checkApiQuota(githubUrl, ratelimit.GetCredentialId(accessToken), boerrors.EGitHubReachRateLimit); err != nil {
+			return nil, err
+		}
+		ghclient, err := github.NewGithubClient(accessToken, githubUrl)
+		if err != nil {
+			return nil, err
+		}
+		commitSigner, err := getCommitSigner(config)
+		if err != nil {
+			return nil, err
+		}
+		ghclient.SetCommitSigner(commitSigner)
+		return ghclient, nil
+	}
+
+	githubAppIdStr := string(config[gitops.PipelinesAsCode_githubAppIdKey])
+	githubAppId, err := strconv.ParseInt(githubAppIdStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s to int: %w", githubAppIdStr, err)
+	}
+	privateKey := config[gitops.PipelinesAsCode_githubPrivateKey]
+
+	if !gitClientConfig.IsAppInstallationExpected {
+		ghclient, err := github.NewGithubClientForSimpleBuildByApp(githubAppId, privateKey, githubUrl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GitHub client for simple build: %w", err)
+		}
+		// The quota belongs to the application installation which is known only when the client is created
+		if err := checkApiQuota(githubUrl, ghclient.GetRateLimitCredential(), boerrors.EGitHubReachRateLimit); err != nil {
+			return nil, err
+		}
+		return ghclient, nil
+	}
+
+	owner := gitRepoUrl.OwnerPath
+	repository := gitRepoUrl.Repository
+
+	ghclient, err := github.NewGithubClientByApp(githubAppId, privateKey, owner, githubUrl)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkApiQuota(githubUrl, ghclient.GetRateLimitCredential(), boerrors.EGitHubReachRateLimit); err != nil {
+		return nil, err
+	}
+
+	// Check if the application is installed into target repository
+	appInstalled, err := github.IsAppInstalledIntoRepository(ghclient, owner, repository)
+	if err != nil {
+		return nil, err
+	}
+	if !appInstalled {
+		return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppNotInstalled, fmt.Errorf("GitHub Application is not installed into the repository"))
+	}
+	return ghclient, nil
+}
+
+func createGitlabClient(gitClientConfig GitClientConfig) (gitprovider.GitProvider, error) {
+	accessToken := strings.TrimSpace(string(gitClientConfig.PacSecretData[gitops.GetProviderTokenKey("gitlab")]))
+
+	if !gitlab.IsAllowedRepositoryUrl(gitClientConfig.RepoUrl) {
+		return nil, boerrors.NewBuildOpError(boerrors.EGitProviderHostNotAllowed,
+			fmt.Errorf("GitLab instance of %s is not in %s list", gitClientConfig.RepoUrl, gitlab.GitlabBaseUrlsEnvName))
+	}
+	baseUrl, _, err := gitlab.GetBaseUrlAndProjectPath(gitClientConfig.RepoUrl)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkApiQuota(baseUrl, ratelimit.GetCredentialId(accessToken), boerrors.EGitLabReachRateLimit); err != nil {
+		return nil, err
+	}
+	glclient, err := gitlab.NewGitlabClient(accessToken, baseUrl)
+	if err != nil {
+		return nil, err
+	}
+	commitSigner, err := getCommitSigner(gitClientConfig.PacSecretData)
+	if err != nil {
+		return nil, err
+	}
+	glclient.SetCommitSigner(commitSigner)
+	return glclient, nil
+}
+
+// getCommitSigner returns signer for commits created by build-service if a signing key is configured.
+// Commits created by GitHub Application are signed by GitHub, so the key is needed only for webhook mode.
+func getCommitSigner(pacSecretData map[string][]byte) (signing.CommitSigner, error) {
+	commitSigner, err := signing.NewCommitSignerFromSecretData(pacSecretData)
+	if err != nil {
+		return nil, boerrors.NewBuildOpError(boerrors.EPaCSecretInvalid, fmt.Errorf("invalid commit signing key: %w", err))
+	}
+	return commitSigner, nil
+}
+
+// checkApiQuota returns rate limit error if the remaining API quota of the credential on the git provider host is low,
+// so the operation is postponed until the quota is reset instead of failing in the middle.
+func checkApiQuota(host, credential string, rateLimitErrId boerrors.BOErrorId) error {
+	if backoffUntil, isLimited := ratelimit.DefaultHostLimiter.GetBackoffUntil(host, credential); isLimited {
+		return boerrors.NewRateLimitError(rateLimitErrId, fmt.Errorf("API quota of %s is almost exhausted", host), backoffUntil)
+	}
+	return nil
+}
+
+func createBitbucketClient(gitClientConfig GitClientConfig) (gitprovider.GitProvider, error) {
+	config := gitClientConfig.PacSecretData
+	accessToken := strings.TrimSpace(string(config[gitops.GetProviderTokenKey("bitbucket")]))
+	return bitbucket.NewBitbucketClient(string(config["username"]), accessToken), nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitrepourl
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// GitRepoUrl holds parts of a git repository URL.
+type GitRepoUrl struct {
+	// Scheme of the git provider web URL, http or https.
+	// Repositories referenced via SSH are considered to be served over https.
+	Scheme string
+	// Host of the git provider, with port if any, e.g. github.com
+	Host string
+	// OwnerPath is the path of the repository owner.
+	// For GitHub and Bitbucket it is the owner (workspace) name, for GitLab it is the namespace path
+	// that might contain subgroups, e.g. group/subgroup
+	OwnerPath string
+	// Repository is the repository name without .git suffix
+	Repository string
+}
+
+// ParseGitRepoUrl decomposes the given git repository URL.
+// Supported formats:
+//
+//	https://github.com/owner/repository
+//	https://github.com/owner/repository.git/
+//	git@github.com:owner/repository.git
+//	ssh://git@github.com/owner/repository.git
+func ParseGitRepoUrl(repoUrl string) (*GitRepoUrl, error) {
+	repoUrl = strings.TrimSpace(repoUrl)
+
+	var scheme, host, repoPath string
+	if strings.Contains(repoUrl, "://") {
+		u, err := url.Parse(repoUrl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse git repository URL %s: %w", repoUrl, err)
+		}
+		switch u.Scheme {
+		case "http", "https":
+			scheme = u.Scheme
+			host = u.Host
+		case "ssh", "git", "git+ssh":
+			// SSH port doesn't make sense for the web URL
+			scheme = "https"
+			host = u.Hostname()
+		default:
+			return nil, fmt.Errorf("unsupported scheme %s in git repository URL %s", u.Scheme, repoUrl)
+		}
+		repoPath = u.Path
+	} else {
+		// scp-like syntax: [user@]host:owner/repository.git
+		hostAndPath := strings.SplitN(repoUrl, ":", 2)
+		if len(hostAndPath) != 2 {
+			return nil, fmt.Errorf("failed to parse git repository URL %s", repoUrl)
+		}
+		userAndHost := hostAndPath[0]
+		scheme = "https"
+		host = userAndHost[strings.LastIndex(userAndHost, "@")+1:]
+		repoPath = hostAndPath[1]
+	}
+	if host == "" || strings.ContainsAny(host, "/ ") {
+		return nil, fmt.Errorf("failed to get host from git repository URL %s", repoUrl)
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	lastSeparatorIndex := strings.LastIndex(repoPath, "/")
+	if lastSeparatorIndex <= 0 || lastSeparatorIndex == len(repoPath)-1 {
+		return nil, fmt.Errorf("failed to get owner and repository from git repository URL %s", repoUrl)
+	}
+
+	return &GitRepoUrl{
+		Scheme:     scheme,
+		Host:       host,
+		OwnerPath:  repoPath[:lastSeparatorIndex],
+		Repository: repoPath[lastSeparatorIndex+1:],
+	}, nil
+}
+
+// GetProviderUrl returns the git provider URL, e.g. https://github.com
+func (u *GitRepoUrl) GetProviderUrl() string {
+	return u.Scheme + "://" + u.Host
+}
+
+// GetRepositoryPath returns the full path of the repository within the git provider, e.g. owner/repository
+func (u *GitRepoUrl) GetRepositoryPath() string {
+	return u.OwnerPath + "/" + u.Repository
+}
+
+// GetWebUrl returns the canonical web URL of the repository, e.g. https://github.com/owner/repository
+func (u *GitRepoUrl) GetWebUrl() string {
+	return u.GetProviderUrl() + "/" + u.GetRepositoryPath()
+}
+
+// GetGitProviderUrl takes a git repository URL and returns the git provider URL.
+// Examples:
+//
+//	For https://github.com/foo/bar returns https://github.com
+//	For git@github.com:foo/bar returns https://github.com
+func GetGitProviderUrl(repoUrl string) (string, error) {
+	gitRepoUrl, err := ParseGitRepoUrl(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	return gitRepoUrl.GetProviderUrl(), nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitrepourl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGitRepoUrl(t *testing.T) {
+	tests := []struct {
+		name    string
+		repoUrl string
+		want    *GitRepoUrl
+		wantErr bool
+	}{
+		{
+			name:    "should parse https URL",
+			repoUrl: "https://github.com/owner/repository",
+			want:    &GitRepoUrl{Scheme: "https", Host: "github.com", OwnerPath: "owner", Repository: "repository"},
+		},
+		{
+			name:    "should parse https URL with .git suffix",
+			repoUrl: "https://github.com/owner/repository.git",
+			want:    &GitRepoUrl{Scheme: "https", Host: "github.com", OwnerPath: "owner", Repository: "repository"},
+		},
+		{
+			name:    "should parse https URL with trailing slash",
+			repoUrl: "https://github.com/owner/repository/",
+			want:    &GitRepoUrl{Scheme: "https", Host: "github.com", OwnerPath: "owner", Repository: "repository"},
+		},
+		{
+			name:    "should parse https URL with .git suffix and trailing slash",
+			repoUrl: "https://github.com/owner/repository.git/",
+			want:    &GitRepoUrl{Scheme: "https", Host: "github.com", OwnerPath: "owner", Repository: "repository"},
+		},
+		{
+			name:    "should parse https URL with user info",
+			repoUrl: "https://user@bitbucket.org/workspace/repository.git",
+			want:    &GitRepoUrl{Scheme: "https", Host: "bitbucket.org", OwnerPath: "workspace", Repository: "repository"},
+		},
+		{
+			name:    "should keep http scheme and port",
+			repoUrl: "http://gitlab.mycompany.com:8080/namespace/project",
+			want:    &GitRepoUrl{Scheme: "http", Host: "gitlab.mycompany.com:8080", OwnerPath: "namespace", Repository: "project"},
+		},
+		{
+			name:    "should parse nested namespace path",
+			repoUrl: "https://gitlab.com/group/subgroup/project",
+			want:    &GitRepoUrl{Scheme: "https", Host: "gitlab.com", OwnerPath: "group/subgroup", Repository: "project"},
+		},
+		{
+			name:    "should parse scp-like ssh URL",
+			repoUrl: "git@github.com:owner/repository.git",
+			want:    &GitRepoUrl{Scheme: "https", Host: "github.com", OwnerPath: "owner", Repository: "repository"},
+		},
+		{
+			name:    "should parse scp-like ssh URL with leading slash in path",
+			repoUrl: "git@gitlab.com:/group/subgroup/project.git",
+			want:    &GitRepoUrl{Scheme: "https", Host: "gitlab.com", OwnerPath: "group/subgroup", Repository: "project"},
+		},
+		{
+			name:    "should parse ssh URL",
+			repoUrl: "ssh://git@github.com/owner/repository.git",
+			want:    &GitRepoUrl{Scheme: "https", Host: "github.com", OwnerPath: "owner", Repository: "repository"},
+		},
+		{
+			name:    "should drop port of ssh URL",
+			repoUrl: "ssh://git@gitlab.mycompany.com:2222/namespace/project.git/",
+			want:    &GitRepoUrl{Scheme: "https", Host: "gitlab.mycompany.com", OwnerPath: "namespace", Repository: "project"},
+		},
+		{
+			name:    "should fail on URL without scheme",
+			repoUrl: "github.com/owner/repository.git",
+			wantErr: true,
+		},
+		{
+			name:    "should fail on invalid URL",
+			repoUrl: "not-even-a-url",
+			wantErr: true,
+		},
+		{
+			name:    "should fail on unsupported scheme",
+			repoUrl: "ftp://github.com/owner/repository",
+			wantErr: true,
+		},
+		{
+			name:    "should fail if repository is missing",
+			repoUrl: "https://github.com/owner",
+			wantErr: true,
+		},
+		{
+			name:    "should fail if owner and repository are missing",
+			repoUrl: "git@github.com:",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGitRepoUrl(tt.repoUrl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGitRepoUrl(): unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGitRepoUrl(): got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetGitProviderUrl(t *testing.T) {
+	tests := []struct {
+		name    string
+		repoUrl string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "github ssh",
+			repoUrl: "git@github.com:redhat-appstudio/application-service.git",
+			want:    "https://github.com",
+		},
+		{
+			name:    "gitlab ssh",
+			repoUrl: "git@gitlab.com:namespace/project-name.git",
+			want:    "https://gitlab.com",
+		},
+		{
+			name:    "bitbucket ssh",
+			repoUrl: "git@bitbucket.org:organization/project-name.git",
+			want:    "https://bitbucket.org",
+		},
+		{
+			name:    "github https",
+			repoUrl: "https://github.com/redhat-appstudio/application-service.git",
+			want:    "https://github.com",
+		},
+		{
+			name:    "bitbucket https",
+			repoUrl: "https://sbose78@bitbucket.org/sbose78/appstudio.git",
+			want:    "https://bitbucket.org",
+		},
+		{
+			name:    "no scheme",
+			repoUrl: "github.com/redhat-appstudio/application-service.git",
+			wantErr: true, //fully qualified URL is a must
+		},
+		{
+			name:    "invalid url",
+			repoUrl: "not-even-a-url",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetGitProviderUrl(tt.repoUrl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetGitProviderUrl(): unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetGitProviderUrl(): got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetWebUrl(t *testing.T) {
+	gitRepoUrl, err := ParseGitRepoUrl("ssh://git@gitlab.com:2222/group/subgroup/project.git")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if webUrl := gitRepoUrl.GetWebUrl(); webUrl != "https://gitlab.com/group/subgroup/project" {
+		t.Errorf("GetWebUrl(): unexpected web URL: %s", webUrl)
+	}
+}
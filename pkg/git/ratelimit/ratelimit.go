@@ -0,0 +1,168 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lowQuotaThreshold is the number of remaining API requests below which new operations against the host are postponed.
+// It should be enough to finish an operation that has already started, e.g. Pipelines as Code provision.
+const lowQuotaThreshold = 20
+
+// DefaultHostLimiter is shared by all git provider clients.
+var DefaultHostLimiter = NewHostLimiter(lowQuotaThreshold)
+
+// HostLimiter tracks API quota that git providers report in responses.
+// Git providers count requests per credential and some of them have separate quotas for different API resources,
+// so the quota is tracked per git provider host, credential and resource.
+// It is safe for concurrent use.
+type HostLimiter struct {
+	mutex sync.Mutex
+	// minRemaining is the number of remaining requests below which the quota is considered exhausted
+	minRemaining int
+	quotas       map[quotaKey]hostQuota
+}
+
+type quotaKey struct {
+	host       string
+	credential string
+	// resource is the API the quota applies to, e.g. core or graphql for GitHub, empty if the provider doesn't report it
+	resource string
+}
+
+type hostQuota struct {
+	remaining int
+	resetAt   time.Time
+}
+
+func NewHostLimiter(minRemaining int) *HostLimiter {
+	return &HostLimiter{
+		minRemaining: minRemaining,
+		quotas:       make(map[quotaKey]hostQuota),
+	}
+}
+
+// Update remembers API quota of the resource reported by the git provider for the credential.
+func (l *HostLimiter) Update(host, credential, resource string, remaining int, resetAt time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.quotas[quotaKey{host: normalizeHost(host), credential: credential, resource: resource}] = hostQuota{remaining: remaining, resetAt: resetAt}
+}
+
+// GetBackoffUntil returns the time of the quota reset if the remaining quota of any resource is low
+// for the credential on the host. If several resources are exhausted, the latest reset time is returned.
+func (l *HostLimiter) GetBackoffUntil(host, credential string) (time.Time, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	host = normalizeHost(host)
+	now := time.Now()
+	var backoffUntil time.Time
+	isLimited := false
+	for key, quota := range l.quotas {
+		if key.host != host || key.credential != credential {
+			continue
+		}
+		if !now.Before(quota.resetAt) {
+			// The quota has been renewed
+			delete(l.quotas, key)
+			continue
+		}
+		if quota.remaining < l.minRemaining {
+			isLimited = true
+			if quota.resetAt.After(backoffUntil) {
+				backoffUntil = quota.resetAt
+			}
+		}
+	}
+	return backoffUntil, isLimited
+}
+
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "/"))
+}
+
+// GetCredentialId returns an identifier of the given access token to track its quota without keeping the token itself.
+func GetCredentialId(accessToken string) string {
+	hash := sha256.Sum256([]byte(accessToken))
+	return hex.EncodeToString(hash[:8])
+}
+
+// NewTransport wraps the given transport to record API quota reported in responses of the git provider host
+// for the given credential, see GetCredentialId.
+// If base is nil, http.DefaultTransport is used.
+func NewTransport(base http.RoundTripper, host, credential string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{
+		base:       base,
+		host:       host,
+		credential: credential,
+		limiter:    DefaultHostLimiter,
+	}
+}
+
+type transport struct {
+	base       http.RoundTripper
+	host       string
+	credential string
+	limiter    *HostLimiter
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if remaining, resetAt, ok := parseRateLimitHeaders(resp.Header); ok {
+		// GitHub has separate quotas for REST API, GraphQL API, search, etc.
+		t.limiter.Update(t.host, t.credential, resp.Header.Get("X-RateLimit-Resource"), remaining, resetAt)
+	}
+	return resp, nil
+}
+
+// parseRateLimitHeaders reads remaining quota and the quota reset time from response headers.
+// GitHub uses X-RateLimit-* headers, GitLab uses RateLimit-* headers.
+// Reset time is given in Unix epoch seconds by both.
+func parseRateLimitHeaders(header http.Header) (int, time.Time, bool) {
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		remainingStr := header.Get(prefix + "Remaining")
+		resetStr := header.Get(prefix + "Reset")
+		if remainingStr == "" || resetStr == "" {
+			continue
+		}
+		remaining, err := strconv.Atoi(remainingStr)
+		if err != nil {
+			return 0, time.Time{}, false
+		}
+		reset, err := strconv.ParseInt(resetStr, 10, 64)
+		if err != nil {
+			return 0, time.Time{}, false
+		}
+		return remaining, time.Unix(reset, 0), true
+	}
+	return 0, time.Time{}, false
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter(t *testing.T) {
+	limiter := NewHostLimiter(10)
+	resetAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	if _, isLimited := limiter.GetBackoffUntil("https://github.com", "token"); isLimited {
+		t.Errorf("unknown host should not be limited")
+	}
+
+	limiter.Update("https://github.com", "token", "core", 100, resetAt)
+	if _, isLimited := limiter.GetBackoffUntil("https://github.com", "token"); isLimited {
+		t.Errorf("host with enough quota should not be limited")
+	}
+
+	limiter.Update("https://github.com", "token", "core", 5, resetAt)
+	backoffUntil, isLimited := limiter.GetBackoffUntil("https://GitHub.com/", "token")
+	if !isLimited {
+		t.Fatalf("host with low quota should be limited")
+	}
+	if !backoffUntil.Equal(resetAt) {
+		t.Errorf("expected backoff until %s, got %s", resetAt, backoffUntil)
+	}
+	if _, isLimited := limiter.GetBackoffUntil("https://gitlab.com", "token"); isLimited {
+		t.Errorf("other hosts should not be limited")
+	}
+	if _, isLimited := limiter.GetBackoffUntil("https://github.com", "installation-1"); isLimited {
+		t.Errorf("other credentials should not be limited")
+	}
+
+	// Quota of another resource must not overwrite the exhausted one
+	limiter.Update("https://github.com", "token", "graphql", 4000, resetAt.Add(time.Hour))
+	if _, isLimited := limiter.GetBackoffUntil("https://github.com", "token"); !isLimited {
+		t.Errorf("host should be limited while any resource quota is low")
+	}
+
+	limiter.Update("https://github.com", "token", "core", 0, time.Now().Add(-time.Second))
+	if _, isLimited := limiter.GetBackoffUntil("https://github.com", "token"); isLimited {
+		t.Errorf("host should not be limited after quota reset")
+	}
+}
+
+func TestGetCredentialId(t *testing.T) {
+	id := GetCredentialId("ghp_token")
+	if id == "" || strings.Contains(id, "ghp_token") {
+		t.Errorf("credential id must not be empty nor contain the token, got %s", id)
+	}
+	if id != GetCredentialId("ghp_token") {
+		t.Errorf("credential id must be stable")
+	}
+	if id == GetCredentialId("ghp_other_token") {
+		t.Errorf("different tokens must have different ids")
+	}
+}
+
+func TestTransportRecordsQuota(t *testing.T) {
+	resetAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	tests := []struct {
+		name          string
+		header        map[string]string
+		wantIsLimited bool
+	}{
+		{
+			name: "should record GitHub rate limit headers",
+			header: map[string]string{
+				"X-RateLimit-Remaining": "1",
+				"X-RateLimit-Reset":     strconv.FormatInt(resetAt.Unix(), 10),
+			},
+			wantIsLimited: true,
+		},
+		{
+			name: "should record GitLab rate limit headers",
+			header: map[string]string{
+				"RateLimit-Remaining": "1",
+				"RateLimit-Reset":     strconv.FormatInt(resetAt.Unix(), 10),
+			},
+			wantIsLimited: true,
+		},
+		{
+			name: "should ignore malformed rate limit headers",
+			header: map[string]string{
+				"RateLimit-Remaining": "none",
+				"RateLimit-Reset":     strconv.FormatInt(resetAt.Unix(), 10),
+			},
+			wantIsLimited: false,
+		},
+		{
+			name:          "should not limit if no rate limit headers",
+			header:        map[string]string{},
+			wantIsLimited: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for key, value := range tt.header {
+					w.Header().Set(key, value)
+				}
+			}))
+			defer server.Close()
+
+			limiter := NewHostLimiter(10)
+			client := &http.Client{Transport: &transport{base: http.DefaultTransport, host: server.URL, credential: "token", limiter: limiter}}
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			backoffUntil, isLimited := limiter.GetBackoffUntil(server.URL, "token")
+			if isLimited != tt.wantIsLimited {
+				t.Fatalf("expected limited: %t, got %t", tt.wantIsLimited, isLimited)
+			}
+			if isLimited && !backoffUntil.Equal(resetAt) {
+				t.Errorf("expected backoff until %s, got %s", resetAt, backoffUntil)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Keys of Pipelines as Code secret that hold the key to sign commits with.
+// Only one of GPG or SSH key should be set. If both are set, GPG key is used.
+const (
+	GPGPrivateKeyKey = "commit-signing-gpg-key"
+	GPGPassphraseKey = "commit-signing-gpg-passphrase"
+	SSHPrivateKeyKey = "commit-signing-ssh-key"
+	SSHPassphraseKey = "commit-signing-ssh-passphrase"
+)
+
+// CommitSigner creates signatures of git commits.
+type CommitSigner interface {
+	// Sign returns armored detached signature of the given commit object payload.
+	Sign(payload []byte) (string, error)
+}
+
+// NewCommitSignerFromSecretData creates commit signer from the signing key in the given secret data.
+// Returns nil if no signing key is configured.
+func NewCommitSignerFromSecretData(data map[string][]byte) (CommitSigner, error) {
+	if gpgKey := data[GPGPrivateKeyKey]; len(gpgKey) != 0 {
+		return NewGPGCommitSigner(gpgKey, data[GPGPassphraseKey])
+	}
+	if sshKey := data[SSHPrivateKeyKey]; len(sshKey) != 0 {
+		return NewSSHCommitSigner(sshKey, data[SSHPassphraseKey])
+	}
+	return nil, nil
+}
+
+type gpgCommitSigner struct {
+	entity *openpgp.Entity
+}
+
+// NewGPGCommitSigner creates commit signer from armored GPG private key.
+func NewGPGCommitSigner(armoredPrivateKey, passphrase []byte) (CommitSigner, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(armoredPrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GPG key: %w", err)
+	}
+	if len(entities) == 0 || entities[0].PrivateKey == nil {
+		return nil, fmt.Errorf("GPG private key not found")
+	}
+	entity := entities[0]
+
+	if entity.PrivateKey.Encrypted {
+		if err := entity.PrivateKey.Decrypt(passphrase); err != nil {
+			return nil, fmt.Errorf("failed to decrypt GPG key: %w", err)
+		}
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+			if err := subkey.PrivateKey.Decrypt(passphrase); err != nil {
+				return nil, fmt.Errorf("failed to decrypt GPG subkey: %w", err)
+			}
+		}
+	}
+	if _, canSign := entity.SigningKey(time.Now()); !canSign {
+		return nil, fmt.Errorf("GPG key cannot be used for signing")
+	}
+
+	return &gpgCommitSigner{entity: entity}, nil
+}
+
+func (s *gpgCommitSigner) Sign(payload []byte) (string, error) {
+	signature := new(bytes.Buffer)
+	if err := openpgp.ArmoredDetachSign(signature, s.entity, bytes.NewReader(payload), nil); err != nil {
+		return "", err
+	}
+	return signature.String(), nil
+}
+
+const (
+	sshSignatureMagic     = "SSHSIG"
+	sshSignatureVersion   = 1
+	sshSignatureNamespace = "git"
+	sshSignatureHashAlg   = "sha512"
+	// sshSignatureLineLength is the line length of armored signature that ssh-keygen uses
+	sshSignatureLineLength = 70
+)
+
+type sshCommitSigner struct {
+	signer ssh.Signer
+}
+
+// NewSSHCommitSigner creates commit signer from PEM encoded SSH private key.
+func NewSSHCommitSigner(privateKeyPem, passphrase []byte) (CommitSigner, error) {
+	var signer ssh.Signer
+	var err error
+	if len(passphrase) != 0 {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(privateKeyPem, passphrase)
+	} else {
+		signer, err = ssh.ParsePrivateKey(privateKeyPem)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key: %w", err)
+	}
+	return newSSHCommitSigner(signer), nil
+}
+
+func newSSHCommitSigner(signer ssh.Signer) *sshCommitSigner {
+	return &sshCommitSigner{signer: signer}
+}
+
+// Sign creates signature in the format of 'ssh-keygen -Y sign', see PROTOCOL.sshsig of OpenSSH.
+func (s *sshCommitSigner) Sign(payload []byte) (string, error) {
+	signedData := getSSHSignedData(payload)
+
+	var signature *ssh.Signature
+	var err error
+	if algorithmSigner, ok := s.signer.(ssh.AlgorithmSigner); ok && s.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// SHA-1 based ssh-rsa signatures are not accepted by git hosting services
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, signedData, ssh.KeyAlgoRSASHA512)
+	} else {
+		signature, err = s.signer.Sign(rand.Reader, signedData)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	blob := []byte(sshSignatureMagic)
+	blob = append(blob, ssh.Marshal(struct {
+		Version   uint32
+		PublicKey []byte
+		Namespace string
+		Reserved  string
+		HashAlg   string
+		Signature []byte
+	}{
+		Version:   sshSignatureVersion,
+		PublicKey: s.signer.PublicKey().Marshal(),
+		Namespace: sshSignatureNamespace,
+		HashAlg:   sshSignatureHashAlg,
+		Signature: ssh.Marshal(signature),
+	})...)
+
+	return armorSSHSignature(blob), nil
+}
+
+// getSSHSignedData returns the data that is actually signed by SSH key for the given message.
+func getSSHSignedData(message []byte) []byte {
+	hash := sha512.Sum512(message)
+	signedData := []byte(sshSignatureMagic)
+	return append(signedData, ssh.Marshal(struct {
+		Namespace string
+		Reserved  string
+		HashAlg   string
+		Hash      []byte
+	}{
+		Namespace: sshSignatureNamespace,
+		HashAlg:   sshSignatureHashAlg,
+		Hash:      hash[:],
+	})...)
+}
+
+func armorSSHSignature(blob []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	var sb strings.Builder
+	sb.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(encoded) > sshSignatureLineLength {
+		sb.WriteString(encoded[:sshSignatureLineLength] + "\n")
+		encoded = encoded[sshSignatureLineLength:]
+	}
+	sb.WriteString(encoded + "\n")
+	sb.WriteString("-----END SSH SIGNATURE-----\n")
+	return sb.String()
+}
+
+// CommitData holds the fields of a commit object that are covered by the commit signature.
+type CommitData struct {
+	TreeSha     string
+	ParentShas  []string
+	AuthorName  string
+	AuthorEmail string
+	// Date is used as author and committer date
+	Date    time.Time
+	Message string
+}
+
+// GetCommitPayload returns git commit object content without signature, i.e. the data to sign.
+// The author is also the committer of the commit.
+func GetCommitPayload(commit CommitData) []byte {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("tree %s\n", commit.TreeSha))
+	for _, parentSha := range commit.ParentShas {
+		sb.WriteString(fmt.Sprintf("parent %s\n", parentSha))
+	}
+	signature := fmt.Sprintf("%s <%s> %d %s", commit.AuthorName, commit.AuthorEmail, commit.Date.Unix(), commit.Date.Format("-0700"))
+	sb.WriteString(fmt.Sprintf("author %s\n", signature))
+	sb.WriteString(fmt.Sprintf("committer %s\n", signature))
+	sb.WriteString("\n")
+	sb.WriteString(commit.Message)
+	return []byte(sb.String())
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGetCommitPayload(t *testing.T) {
+	commit := CommitData{
+		TreeSha:     "tree-sha",
+		ParentShas:  []string{"parent-sha"},
+		AuthorName:  "redhat-appstudio",
+		AuthorEmail: "rhtap@redhat.com",
+		Date:        time.Unix(1677000000, 0).UTC(),
+		Message:     "Appstudio update component",
+	}
+	want := "tree tree-sha\n" +
+		"parent parent-sha\n" +
+		"author redhat-appstudio <rhtap@redhat.com> 1677000000 +0000\n" +
+		"committer redhat-appstudio <rhtap@redhat.com> 1677000000 +0000\n" +
+		"\n" +
+		"Appstudio update component"
+	if got := string(GetCommitPayload(commit)); got != want {
+		t.Errorf("GetCommitPayload(): got %q, want %q", got, want)
+	}
+}
+
+func TestNewCommitSignerFromSecretData(t *testing.T) {
+	signer, err := NewCommitSignerFromSecretData(map[string][]byte{"github.token": []byte("token")})
+	if err != nil || signer != nil {
+		t.Errorf("NewCommitSignerFromSecretData(): expected no signer if signing key is not configured")
+	}
+
+	if _, err := NewCommitSignerFromSecretData(map[string][]byte{SSHPrivateKeyKey: []byte("not a key")}); err == nil {
+		t.Errorf("NewCommitSignerFromSecretData(): expected error for invalid SSH key")
+	}
+	if _, err := NewCommitSignerFromSecretData(map[string][]byte{GPGPrivateKeyKey: []byte("not a key")}); err == nil {
+		t.Errorf("NewCommitSignerFromSecretData(): expected error for invalid GPG key")
+	}
+}
+
+func TestGPGCommitSigner(t *testing.T) {
+	entity, err := openpgp.NewEntity("appstudio", "", "rhtap@redhat.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := new(bytes.Buffer)
+	armorWriter, err := armor.Encode(privateKey, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivate(armorWriter, nil); err != nil {
+		t.Fatal(err)
+	}
+	armorWriter.Close()
+
+	signer, err := NewCommitSignerFromSecretData(map[string][]byte{GPGPrivateKeyKey: privateKey.Bytes()})
+	if err != nil {
+		t.Fatalf("NewCommitSignerFromSecretData(): unexpected error: %v", err)
+	}
+	payload := []byte("tree tree-sha\n\nmessage")
+	signature, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatalf("Sign(): unexpected error: %v", err)
+	}
+	if _, err := openpgp.CheckArmoredDetachedSignature(openpgp.EntityList{entity}, bytes.NewReader(payload), strings.NewReader(signature), nil); err != nil {
+		t.Errorf("Sign(): signature verification failed: %v", err)
+	}
+}
+
+func TestSSHCommitSigner(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshSigner, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte("tree tree-sha\n\nmessage")
+	armoredSignature, err := newSSHCommitSigner(sshSigner).Sign(payload)
+	if err != nil {
+		t.Fatalf("Sign(): unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(armoredSignature, "-----BEGIN SSH SIGNATURE-----\n") || !strings.HasSuffix(armoredSignature, "-----END SSH SIGNATURE-----\n") {
+		t.Fatalf("Sign(): signature is not armored: %s", armoredSignature)
+	}
+
+	encodedBlob := strings.TrimPrefix(armoredSignature, "-----BEGIN SSH SIGNATURE-----\n")
+	encodedBlob = strings.TrimSuffix(encodedBlob, "-----END SSH SIGNATURE-----\n")
+	blob, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(encodedBlob, "\n", ""))
+	if err != nil {
+		t.Fatalf("Sign(): failed to decode signature: %v", err)
+	}
+	if !bytes.HasPrefix(blob, []byte(sshSignatureMagic)) {
+		t.Fatalf("Sign(): signature doesn't start with %s", sshSignatureMagic)
+	}
+	var parsedBlob struct {
+		Version   uint32
+		PublicKey []byte
+		Namespace string
+		Reserved  string
+		HashAlg   string
+		Signature []byte
+	}
+	if err := ssh.Unmarshal(blob[len(sshSignatureMagic):], &parsedBlob); err != nil {
+		t.Fatalf("Sign(): failed to parse signature: %v", err)
+	}
+	if parsedBlob.Namespace != "git" || parsedBlob.HashAlg != "sha512" {
+		t.Errorf("Sign(): unexpected signature namespace %s or hash algorithm %s", parsedBlob.Namespace, parsedBlob.HashAlg)
+	}
+	if !bytes.Equal(parsedBlob.PublicKey, sshSigner.PublicKey().Marshal()) {
+		t.Errorf("Sign(): signature contains wrong public key")
+	}
+	signature := &ssh.Signature{}
+	if err := ssh.Unmarshal(parsedBlob.Signature, signature); err != nil {
+		t.Fatalf("Sign(): failed to parse signature: %v", err)
+	}
+	if err := sshSigner.PublicKey().Verify(getSSHSignedData(payload), signature); err != nil {
+		t.Errorf("Sign(): signature verification failed: %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// installationTokenExpirationMargin is the time before the installation token expiration
+	// when the token is no longer given out from the cache, so it doesn't expire in the middle of a reconcile.
+	installationTokenExpirationMargin = 5 * time.Minute
+
+	installationIdCacheLabelValue    = "installation_id"
+	installationTokenCacheLabelValue = "installation_token"
+)
+
+var (
+	// AppCacheHitsMetric counts GitHub Application installation IDs and tokens taken from the cache.
+	AppCacheHitsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "redhat_appstudio",
+		Subsystem: "buildservice",
+		Name:      "github_app_cache_hits_total",
+		Help:      "The number of GitHub Application installation IDs and installation tokens taken from the cache.",
+	}, []string{"cache"})
+	// AppCacheMissesMetric counts GitHub Application installation IDs and tokens requested from GitHub.
+	AppCacheMissesMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "redhat_appstudio",
+		Subsystem: "buildservice",
+		Name:      "github_app_cache_misses_total",
+		Help:      "The number of GitHub Application installation IDs and installation tokens not found in the cache.",
+	}, []string{"cache"})
+)
+
+// appInstallationsCache holds installation IDs and unexpired installation tokens of GitHub Applications.
+// It allows to avoid listing all the application installations and minting a new installation token on each reconcile.
+// The cache is safe for concurrent use.
+type appInstallationsCache struct {
+	mutex sync.Mutex
+	// installationIds maps application, GitHub instance and owner to the application installation ID
+	installationIds map[string]int64
+	// installationTokens maps application, GitHub instance and installation ID to the installation token
+	installationTokens map[string]*github.InstallationToken
+}
+
+var githubAppCache = newAppInstallationsCache()
+
+func newAppInstallationsCache() *appInstallationsCache {
+	return &appInstallationsCache{
+		installationIds:    make(map[string]int64),
+		installationTokens: make(map[string]*github.InstallationToken),
+	}
+}
+
+func getAppCacheKeyPrefix(appId int64, githubUrl string) string {
+	if isGithubCom(githubUrl) {
+		githubUrl = GithubComUrl
+	}
+	return fmt.Sprintf("%d@%s/", appId, strings.ToLower(strings.TrimSuffix(githubUrl, "/")))
+}
+
+func getInstallationIdCacheKey(appId int64, githubUrl, owner string) string {
+	return getAppCacheKeyPrefix(appId, githubUrl) + "owner/" + strings.ToLower(owner)
+}
+
+func getInstallationTokenCacheKey(appId int64, githubUrl string, installationId int64) string {
+	return fmt.Sprintf("%sinstallation/%d", getAppCacheKeyPrefix(appId, githubUrl), installationId)
+}
+
+// getInstallationId returns cached ID of the application installation into the given owner account.
+func (c *appInstallationsCache) getInstallationId(appId int64, githubUrl, owner string) (int64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	installationId, found := c.installationIds[getInstallationIdCacheKey(appId, githubUrl, owner)]
+	recordAppCacheAccess(installationIdCacheLabelValue, found)
+	return installationId, found
+}
+
+// getAnyInstallationId returns ID of a randomly picked cached installation of the application.
+func (c *appInstallationsCache) getAnyInstallationId(appId int64, githubUrl string) (int64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	keyPrefix := getAppCacheKeyPrefix(appId, githubUrl)
+	var installationIds []int64
+	for key, installationId := range c.installationIds {
+		if strings.HasPrefix(key, keyPrefix) {
+			installationIds = append(installationIds, installationId)
+		}
+	}
+	found := len(installationIds) > 0
+	recordAppCacheAccess(installationIdCacheLabelValue, found)
+	if !found {
+		return 0, false
+	}
+	return installationIds[rand.Intn(len(installationIds))], true
+}
+
+func (c *appInstallationsCache) setInstallationId(appId int64, githubUrl, owner string, installationId int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.installationIds[getInstallationIdCacheKey(appId, githubUrl, owner)] = installationId
+}
+
+// getInstallationToken returns cached installation token if it is not going to expire soon.
+func (c *appInstallationsCache) getInstallationToken(appId int64, githubUrl string, installationId int64) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := getInstallationTokenCacheKey(appId, githubUrl, installationId)
+	token, found := c.installationTokens[key]
+	if found && time.Now().Add(installationTokenExpirationMargin).After(token.GetExpiresAt()) {
+		delete(c.installationTokens, key)
+		found = false
+	}
+	recordAppCacheAccess(installationTokenCacheLabelValue, found)
+	if !found {
+		return "", false
+	}
+	return token.GetToken(), true
+}
+
+func (c *appInstallationsCache) setInstallationToken(appId int64, githubUrl string, installationId int64, token *github.InstallationToken) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.installationTokens[getInstallationTokenCacheKey(appId, githubUrl, installationId)] = token
+}
+
+// invalidateInstallationToken removes the installation token, but keeps the installation ID.
+func (c *appInstallationsCache) invalidateInstallationToken(appId int64, githubUrl string, installationId int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.installationTokens, getInstallationTokenCacheKey(appId, githubUrl, installationId))
+}
+
+// invalidateInstallation removes the installation ID and the installation token,
+// e.g. when the application is uninstalled from the owner account.
+func (c *appInstallationsCache) invalidateInstallation(appId int64, githubUrl string, installationId int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	keyPrefix := getAppCacheKeyPrefix(appId, githubUrl)
+	for key, cachedInstallationId := range c.installationIds {
+		if cachedInstallationId == installationId && strings.HasPrefix(key, keyPrefix) {
+			delete(c.installationIds, key)
+		}
+	}
+	delete(c.installationTokens, getInstallationTokenCacheKey(appId, githubUrl, installationId))
+}
+
+// invalidateApp removes all cached data of the application,
+// e.g. when the application private key doesn't match anymore.
+func (c *appInstallationsCache) invalidateApp(appId int64, githubUrl string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	keyPrefix := getAppCacheKeyPrefix(appId, githubUrl)
+	for key := range c.installationIds {
+		if strings.HasPrefix(key, keyPrefix) {
+			delete(c.installationIds, key)
+		}
+	}
+	for key := range c.installationTokens {
+		if strings.HasPrefix(key, keyPrefix) {
+			delete(c.installationTokens, key)
+		}
+	}
+}
+
+func recordAppCacheAccess(cache string, hit bool) {
+	if hit {
+		AppCacheHitsMetric.WithLabelValues(cache).Inc()
+	} else {
+		AppCacheMissesMetric.WithLabelValues(cache).Inc()
+	}
+}
+
+// installationTokenInvalidatingTransport drops the cached installation token
+// when GitHub rejects it or reports that the installation doesn't exist anymore.
+type installationTokenInvalidatingTransport struct {
+	base           http.RoundTripper
+	appId          int64
+	githubUrl      string
+	installationId int64
+}
+
+func (t *installationTokenInvalidatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		githubAppCache.invalidateInstallationToken(t.appId, t.githubUrl, t.installationId)
+	case resp.StatusCode == http.StatusNotFound && strings.Contains(req.URL.Path, "/installation/"):
+		// Not found on installation endpoints means that the application was uninstalled.
+		// Other endpoints respond 404 on missing repository content, which is not related to the installation.
+		githubAppCache.invalidateInstallation(t.appId, t.githubUrl, t.installationId)
+	}
+	return resp, nil
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const testAppId = 12345
+
+// fakeGithubAppServer emulates GitHub Enterprise Server API endpoints used to authenticate as application installation.
+type fakeGithubAppServer struct {
+	server *httptest.Server
+
+	listInstallationsRequests int32
+	createTokenRequests       int32
+	// repositoriesStatus is the status code responded on installation repositories listing
+	repositoriesStatus int32
+}
+
+func newFakeGithubAppServer(t *testing.T) *fakeGithubAppServer {
+	fake := &fakeGithubAppServer{repositoriesStatus: http.StatusOK}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/app", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&github.App{Slug: github.String("test-app")})
+	})
+	mux.HandleFunc("/api/v3/app/installations", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fake.listInstallationsRequests, 1)
+		installations := []*github.Installation{
+			{ID: github.Int64(1), Account: &github.User{Login: github.String("owner-one")}},
+			{ID: github.Int64(2), Account: &github.User{Login: github.String("owner-two")}},
+		}
+		_ = json.NewEncoder(w).Encode(installations)
+	})
+	mux.HandleFunc("/api/v3/app/installations/", func(w http.ResponseWriter, r *http.Request) {
+		requestNumber := atomic.AddInt32(&fake.createTokenRequests, 1)
+		token := &github.InstallationToken{
+			Token:     github.String(fmt.Sprintf("ghs_token%d", requestNumber)),
+			ExpiresAt: timePtr(time.Now().Add(time.Hour)),
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(token)
+	})
+	mux.HandleFunc("/api/v3/installation/repositories", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&fake.repositoriesStatus)))
+		_, _ = w.Write([]byte(`{"total_count": 0, "repositories": []}`))
+	})
+	fake.server = httptest.NewServer(mux)
+	t.Cleanup(fake.server.Close)
+
+	return fake
+}
+
+func generateAppPrivateKey(t *testing.T) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func resetAppCache() {
+	githubAppCache = newAppInstallationsCache()
+	AppCacheHitsMetric.Reset()
+	AppCacheMissesMetric.Reset()
+}
+
+func TestNewGithubClientByAppUsesCache(t *testing.T) {
+	resetAppCache()
+	fake := newFakeGithubAppServer(t)
+	privateKey := generateAppPrivateKey(t)
+
+	if _, err := newGithubClientByApp(testAppId, privateKey, "owner-one", fake.server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newGithubClientByApp(testAppId, privateKey, "owner-one", fake.server.URL); err != nil {
+		t.Fatal(err)
+	}
+	// Installation of the other owner is remembered from the first listing
+	if _, err := newGithubClientByApp(testAppId, privateKey, "Owner-Two", fake.server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := atomic.LoadInt32(&fake.listInstallationsRequests); got != 1 {
+		t.Errorf("expected installations to be listed once, got %d", got)
+	}
+	if got := atomic.LoadInt32(&fake.createTokenRequests); got != 2 {
+		t.Errorf("expected an installation token to be created per installation, got %d", got)
+	}
+	if got := testutil.ToFloat64(AppCacheHitsMetric.WithLabelValues(installationIdCacheLabelValue)); got != 2 {
+		t.Errorf("unexpected installation ID cache hits: %v", got)
+	}
+	if got := testutil.ToFloat64(AppCacheMissesMetric.WithLabelValues(installationIdCacheLabelValue)); got != 1 {
+		t.Errorf("unexpected installation ID cache misses: %v", got)
+	}
+	if got := testutil.ToFloat64(AppCacheHitsMetric.WithLabelValues(installationTokenCacheLabelValue)); got != 1 {
+		t.Errorf("unexpected installation token cache hits: %v", got)
+	}
+	if got := testutil.ToFloat64(AppCacheMissesMetric.WithLabelValues(installationTokenCacheLabelValue)); got != 2 {
+		t.Errorf("unexpected installation token cache misses: %v", got)
+	}
+}
+
+func TestNewGithubClientForSimpleBuildByAppUsesCache(t *testing.T) {
+	resetAppCache()
+	fake := newFakeGithubAppServer(t)
+	privateKey := generateAppPrivateKey(t)
+
+	if _, err := newGithubClientByApp(testAppId, privateKey, "owner-one", fake.server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newGithubClientForSimpleBuildByApp(testAppId, privateKey, fake.server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := atomic.LoadInt32(&fake.listInstallationsRequests); got != 1 {
+		t.Errorf("expected installations to be listed once, got %d", got)
+	}
+}
+
+func TestInstallationTokenInvalidation(t *testing.T) {
+	resetAppCache()
+	fake := newFakeGithubAppServer(t)
+	privateKey := generateAppPrivateKey(t)
+
+	ghclient, err := newGithubClientByApp(testAppId, privateKey, "owner-one", fake.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Revoked token
+	atomic.StoreInt32(&fake.repositoriesStatus, http.StatusUnauthorized)
+	if _, err := ghclient.isAppInstalledIntoRepository("owner-one", "repository"); err == nil {
+		t.Fatal("expected error on unauthorized request")
+	}
+	if _, isCached := githubAppCache.getInstallationToken(testAppId, fake.server.URL, 1); isCached {
+		t.Error("installation token should be invalidated on 401")
+	}
+	if _, isCached := githubAppCache.getInstallationId(testAppId, fake.server.URL, "owner-one"); !isCached {
+		t.Error("installation ID should be kept on 401")
+	}
+
+	ghclient, err = newGithubClientByApp(testAppId, privateKey, "owner-one", fake.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&fake.createTokenRequests); got != 2 {
+		t.Errorf("expected a new installation token to be created, got %d token requests", got)
+	}
+
+	// Uninstalled application
+	atomic.StoreInt32(&fake.repositoriesStatus, http.StatusNotFound)
+	if _, err := ghclient.isAppInstalledIntoRepository("owner-one", "repository"); err == nil {
+		t.Fatal("expected error on not found installation")
+	}
+	if _, isCached := githubAppCache.getInstallationId(testAppId, fake.server.URL, "owner-one"); isCached {
+		t.Error("installation ID should be invalidated on 404")
+	}
+	if _, isCached := githubAppCache.getInstallationId(testAppId, fake.server.URL, "owner-two"); !isCached {
+		t.Error("installation ID of other owner should be kept")
+	}
+}
+
+func TestInstallationTokenExpiration(t *testing.T) {
+	resetAppCache()
+
+	token := &github.InstallationToken{
+		Token:     github.String("ghs_token"),
+		ExpiresAt: timePtr(time.Now().Add(installationTokenExpirationMargin / 2)),
+	}
+	githubAppCache.setInstallationToken(testAppId, GithubComUrl, 1, token)
+	if _, isCached := githubAppCache.getInstallationToken(testAppId, GithubComUrl, 1); isCached {
+		t.Error("installation token that is about to expire should not be given out")
+	}
+
+	token.ExpiresAt = timePtr(time.Now().Add(time.Hour))
+	githubAppCache.setInstallationToken(testAppId, "", 1, token)
+	if cachedToken, isCached := githubAppCache.getInstallationToken(testAppId, GithubComUrl, 1); !isCached || cachedToken != "ghs_token" {
+		t.Error("expected installation token to be cached")
+	}
+}
+
+func TestGetInstallationsCreatesNewTokens(t *testing.T) {
+	resetAppCache()
+	fake := newFakeGithubAppServer(t)
+	privateKey := generateAppPrivateKey(t)
+
+	// The token is still given out to reconciles, but it is going to expire soon for a job
+	token := &github.InstallationToken{
+		Token:     github.String("ghs_cached_token"),
+		ExpiresAt: timePtr(time.Now().Add(2 * installationTokenExpirationMargin)),
+	}
+	githubAppCache.setInstallationToken(testAppId, fake.server.URL, 1, token)
+
+	installations, slug, err := getInstallations(testAppId, privateKey, fake.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slug != "test-app" || len(installations) != 2 {
+		t.Fatalf("unexpected installations of %s application: %#v", slug, installations)
+	}
+	for _, installation := range installations {
+		if installation.Token == "ghs_cached_token" {
+			t.Errorf("cached installation token should not be given out for installation %d", installation.ID)
+		}
+	}
+	if got := atomic.LoadInt32(&fake.createTokenRequests); got != 2 {
+		t.Errorf("expected an installation token to be created per installation, got %d", got)
+	}
+}
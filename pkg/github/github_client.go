@@ -23,25 +23,50 @@ import (
 	"io"
 	"math/rand"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
 	ghinstallation "github.com/bradleyfalzon/ghinstallation/v2"
 	"github.com/google/go-github/v45/github"
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/ratelimit"
+	"github.com/redhat-appstudio/build-service/pkg/git/signing"
 	"golang.org/x/oauth2"
 )
 
 // Allow mocking for tests
-var NewGithubClient func(accessToken string) *GithubClient = newGithubClient
-var NewGithubClientByApp func(appId int64, privateKeyPem []byte, owner string) (*GithubClient, error) = newGithubClientByApp
-var NewGithubClientForSimpleBuildByApp func(appId int64, privateKeyPem []byte) (*GithubClient, error) = newGithubClientForSimpleBuildByApp
-var GetGitHubAppName func(appId int64, privateKeyPem []byte) (string, string, error) = getGitHubAppName
-var GetInstallations func(appId int64, privateKeyPem []byte) ([]ApplicationInstallation, string, error) = getInstallations
+var NewGithubClient func(accessToken, githubUrl string) (*GithubClient, error) = newGithubClient
+var NewGithubClientByApp func(appId int64, privateKeyPem []byte, owner, githubUrl string) (*GithubClient, error) = newGithubClientByApp
+var NewGithubClientForSimpleBuildByApp func(appId int64, privateKeyPem []byte, githubUrl string) (*GithubClient, error) = newGithubClientForSimpleBuildByApp
+var GetGitHubAppName func(appId int64, privateKeyPem []byte, githubUrl string) (string, string, error) = getGitHubAppName
+var GetInstallations func(appId int64, privateKeyPem []byte, githubUrl string) ([]ApplicationInstallation, string, error) = getInstallations
+
+const (
+	GithubComUrl = "https://github.com"
+
+	// GithubEnterpriseUrlsEnvName is the name of the environment variable with comma separated list
+	// of GitHub Enterprise Server instances build-service is allowed to talk to,
+	// e.g. https://github.mycompany.com,https://github.othercompany.com:8443
+	GithubEnterpriseUrlsEnvName = "GITHUB_ENTERPRISE_URLS"
+
+	githubComApiUrl    = "https://api.github.com/"
+	githubComUploadUrl = "https://uploads.github.com/"
+)
 
 type GithubClient struct {
 	ctx    context.Context
 	client *github.Client
+
+	githubUrl string
+	// appId and appPrivateKeyPem are set only if the client is created by GitHub Application
+	appId            int64
+	appPrivateKeyPem []byte
+	// commitSigner is used to sign commits if the client is not created by GitHub Application
+	commitSigner signing.CommitSigner
+	// rateLimitCredential identifies the credential the client's API quota is tracked for
+	rateLimitCredential string
 }
 
 type ApplicationInstallation struct {
@@ -50,55 +75,147 @@ type ApplicationInstallation struct {
 	Repositories []*github.Repository
 }
 
-func newGithubClient(accessToken string) *GithubClient {
+// newGithubClient creates GitHub client for the instance with the given URL, e.g. https://github.com
+// If the URL doesn't point to github.com, the instance is considered to be GitHub Enterprise Server.
+func newGithubClient(accessToken, githubUrl string) (*GithubClient, error) {
 	gh := &GithubClient{}
 	gh.ctx = context.Background()
 
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: accessToken},
 	)
+	gh.rateLimitCredential = ratelimit.GetCredentialId(accessToken)
 	tc := oauth2.NewClient(gh.ctx, ts)
+	tc.Transport = ratelimit.NewTransport(tc.Transport, getRateLimitHost(githubUrl), gh.rateLimitCredential)
+
+	client, err := newGithubApiClient(tc, githubUrl)
+	if err != nil {
+		return nil, err
+	}
+	gh.client = client
+	gh.githubUrl = githubUrl
 
-	gh.client = github.NewClient(tc)
+	return gh, nil
+}
 
-	return gh
+// SetCommitSigner makes the client sign commits it creates with the given signer.
+// Has no effect if the client is created by GitHub Application, since GitHub signs such commits itself.
+func (c *GithubClient) SetCommitSigner(commitSigner signing.CommitSigner) {
+	c.commitSigner = commitSigner
 }
 
-func newGithubClientByApp(appId int64, privateKeyPem []byte, owner string) (*GithubClient, error) {
-	itr, err := ghinstallation.NewAppsTransport(http.DefaultTransport, appId, privateKeyPem) // 172616 (appstudio) 184730(Michkov)
+// newGithubApiClient creates go-github client that talks to github.com or to GitHub Enterprise Server API.
+func newGithubApiClient(httpClient *http.Client, githubUrl string) (*github.Client, error) {
+	if isGithubCom(githubUrl) {
+		return github.NewClient(httpClient), nil
+	}
+	client, err := github.NewEnterpriseClient(GetApiUrl(githubUrl), getUploadUrl(githubUrl), httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub Enterprise client for %s: %w", githubUrl, err)
+	}
+	return client, nil
+}
+
+// newGithubAppClient creates go-github client authenticated as the GitHub Application itself.
+func newGithubAppClient(appId int64, privateKeyPem []byte, githubUrl string) (*github.Client, error) {
+	itr, err := ghinstallation.NewAppsTransport(http.DefaultTransport, appId, privateKeyPem)
 	if err != nil {
 		// Inability to create transport based on a private key indicates that the key is bad formatted
 		return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppMalformedPrivateKey, err)
 	}
-	client := github.NewClient(&http.Client{Transport: itr})
+	itr.BaseURL = strings.TrimSuffix(GetApiUrl(githubUrl), "/")
+	return newGithubApiClient(&http.Client{Transport: itr}, githubUrl)
+}
 
-	var installId int64
-	opt := &github.RepositoryListByOrgOptions{
-		ListOptions: github.ListOptions{PerPage: 100},
+func isGithubCom(githubUrl string) bool {
+	return githubUrl == "" || strings.EqualFold(strings.TrimSuffix(githubUrl, "/"), GithubComUrl)
+}
+
+// IsAllowedGithubUrl checks if credentials may be sent to the GitHub instance with the given URL.
+// Only github.com and GitHub Enterprise Server instances listed by the cluster admin are allowed,
+// otherwise anyone could obtain the credentials by pointing a component to own server.
+func IsAllowedGithubUrl(githubUrl string) bool {
+	if isGithubCom(githubUrl) {
+		return true
+	}
+	githubUrl = strings.TrimSuffix(githubUrl, "/")
+	for _, enterpriseUrl := range strings.Split(os.Getenv(GithubEnterpriseUrlsEnvName), ",") {
+		enterpriseUrl = strings.TrimSuffix(strings.TrimSpace(enterpriseUrl), "/")
+		if enterpriseUrl != "" && strings.EqualFold(enterpriseUrl, githubUrl) {
+			return true
+		}
 	}
-	for installId == 0 {
-		installations, resp, err := client.Apps.ListInstallations(context.Background(), &opt.ListOptions)
-		if err != nil {
-			if resp != nil && resp.Response != nil && resp.Response.StatusCode != 0 {
-				switch resp.StatusCode {
-				case 401:
-					return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppPrivateKeyNotMatched, err)
-				case 404:
-					return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppDoesNotExist, err)
+	return false
+}
+
+// GetRateLimitCredential returns the identifier of the client's credential the API quota is tracked for.
+func (c *GithubClient) GetRateLimitCredential() string {
+	return c.rateLimitCredential
+}
+
+// getRateLimitHost returns the key under which API quota of the GitHub instance is tracked.
+func getRateLimitHost(githubUrl string) string {
+	if isGithubCom(githubUrl) {
+		return GithubComUrl
+	}
+	return githubUrl
+}
+
+// GetApiUrl returns REST API URL of the GitHub instance with the given URL.
+// Examples:
+//
+//	For https://github.com returns https://api.github.com/
+//	For https://github.mycompany.com returns https://github.mycompany.com/api/v3/
+func GetApiUrl(githubUrl string) string {
+	if isGithubCom(githubUrl) {
+		return githubComApiUrl
+	}
+	return strings.TrimSuffix(githubUrl, "/") + "/api/v3/"
+}
+
+// getGraphqlUrl returns GraphQL API URL of the GitHub instance with the given URL.
+func getGraphqlUrl(githubUrl string) string {
+	if isGithubCom(githubUrl) {
+		return githubComApiUrl + "graphql"
+	}
+	return strings.TrimSuffix(githubUrl, "/") + "/api/graphql"
+}
+
+func getUploadUrl(githubUrl string) string {
+	if isGithubCom(githubUrl) {
+		return githubComUploadUrl
+	}
+	return strings.TrimSuffix(githubUrl, "/") + "/api/uploads/"
+}
+
+func newGithubClientByApp(appId int64, privateKeyPem []byte, owner, githubUrl string) (*GithubClient, error) {
+	client, err := newGithubAppClient(appId, privateKeyPem, githubUrl) // 172616 (appstudio) 184730(Michkov)
+	if err != nil {
+		return nil, err
+	}
+
+	installId, isCached := githubAppCache.getInstallationId(appId, githubUrl, owner)
+	if !isCached {
+		opt := &github.RepositoryListByOrgOptions{
+			ListOptions: github.ListOptions{PerPage: 100},
+		}
+		for installId == 0 {
+			installations, resp, err := client.Apps.ListInstallations(context.Background(), &opt.ListOptions)
+			if err != nil {
+				return nil, refineListInstallationsError(appId, githubUrl, resp, err)
+			}
+			for _, val := range installations {
+				// Remember all the installations on the page, other owners will likely be requested soon
+				githubAppCache.setInstallationId(appId, githubUrl, val.GetAccount().GetLogin(), val.GetID())
+				if strings.EqualFold(val.GetAccount().GetLogin(), owner) {
+					installId = val.GetID()
 				}
 			}
-			return nil, boerrors.NewBuildOpError(boerrors.ETransientError, err)
-		}
-		for _, val := range installations {
-			if strings.EqualFold(val.GetAccount().GetLogin(), owner) {
-				installId = val.GetID()
+			if resp.NextPage == 0 {
 				break
 			}
+			opt.Page = resp.NextPage
 		}
-		if resp.NextPage == 0 {
-			break
-		}
-		opt.Page = resp.NextPage
 	}
 	if installId == 0 {
 		err := fmt.Errorf("unable to find GitHub InstallationID for user %s", owner)
@@ -107,69 +224,137 @@ func newGithubClientByApp(appId int64, privateKeyPem []byte, owner string) (*Git
 	// The user has the application installed,
 	// but it doesn't guarantee that the application is installed into all user's repositories.
 
-	token, _, err := client.Apps.CreateInstallationToken(
-		context.Background(),
-		installId,
-		&github.InstallationTokenOptions{})
+	token, err := getInstallationToken(client, appId, githubUrl, installId)
 	if err != nil {
-		// TODO analyze the error
 		return nil, err
 	}
 
-	return NewGithubClient(token.GetToken()), nil
+	return newGithubInstallationClient(token, appId, privateKeyPem, githubUrl, installId)
 }
 
 // newGithubClientForSimpleBuildByApp creates GitHub client based on an installation token.
 // The installation token is generated based on a randomly picked app installation.
 // This tricky approach is required for simple builds to make requests to GitHub API. Otherwise, rate limit will be hit.
-func newGithubClientForSimpleBuildByApp(appId int64, privateKeyPem []byte) (*GithubClient, error) {
-	itr, err := ghinstallation.NewAppsTransport(http.DefaultTransport, appId, privateKeyPem)
+func newGithubClientForSimpleBuildByApp(appId int64, privateKeyPem []byte, githubUrl string) (*GithubClient, error) {
+	client, err := newGithubAppClient(appId, privateKeyPem, githubUrl)
 	if err != nil {
-		// Inability to create transport based on a private key indicates that the key is bad formatted
-		return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppMalformedPrivateKey, err)
+		return nil, err
 	}
-	client := github.NewClient(&http.Client{Transport: itr})
 
-	opt := &github.RepositoryListByOrgOptions{
-		ListOptions: github.ListOptions{PerPage: 100},
+	installId, isCached := githubAppCache.getAnyInstallationId(appId, githubUrl)
+	if !isCached {
+		opt := &github.RepositoryListByOrgOptions{
+			ListOptions: github.ListOptions{PerPage: 100},
+		}
+		installations, resp, err := client.Apps.ListInstallations(context.Background(), &opt.ListOptions)
+		if err != nil {
+			return nil, refineListInstallationsError(appId, githubUrl, resp, err)
+		}
+
+		if len(installations) < 1 {
+			return nil, fmt.Errorf("GitHub app is not installed in any repository")
+		}
+		for _, val := range installations {
+			githubAppCache.setInstallationId(appId, githubUrl, val.GetAccount().GetLogin(), val.GetID())
+		}
+		installId = installations[rand.Intn(len(installations))].GetID()
 	}
-	installations, resp, err := client.Apps.ListInstallations(context.Background(), &opt.ListOptions)
+
+	token, err := getInstallationToken(client, appId, githubUrl, installId)
 	if err != nil {
-		if resp != nil && resp.Response != nil && resp.Response.StatusCode != 0 {
-			switch resp.StatusCode {
-			case 401:
-				return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppPrivateKeyNotMatched, err)
-			case 404:
-				return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppDoesNotExist, err)
-			}
-		}
-		return nil, boerrors.NewBuildOpError(boerrors.ETransientError, err)
+		return nil, err
+	}
+
+	return newGithubInstallationClient(token, appId, privateKeyPem, githubUrl, installId)
+}
+
+// newGithubInstallationClient creates GitHub client authenticated by the application installation token.
+// The client drops the token from the cache if GitHub doesn't accept it anymore.
+func newGithubInstallationClient(token string, appId int64, privateKeyPem []byte, githubUrl string, installId int64) (*GithubClient, error) {
+	gh := &GithubClient{}
+	gh.ctx = context.Background()
+
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+	// Installation tokens are renewed, but the quota belongs to the installation
+	gh.rateLimitCredential = fmt.Sprintf("installation-%d", installId)
+	tc := oauth2.NewClient(gh.ctx, ts)
+	tc.Transport = &installationTokenInvalidatingTransport{
+		base:           ratelimit.NewTransport(tc.Transport, getRateLimitHost(githubUrl), gh.rateLimitCredential),
+		appId:          appId,
+		githubUrl:      githubUrl,
+		installationId: installId,
 	}
 
-	if len(installations) < 1 {
-		return nil, fmt.Errorf("GitHub app is not installed in any repository")
+	client, err := newGithubApiClient(tc, githubUrl)
+	if err != nil {
+		return nil, err
 	}
-	installId := installations[rand.Intn(len(installations))].GetID()
+	gh.client = client
+	gh.githubUrl = githubUrl
+	gh.appId = appId
+	gh.appPrivateKeyPem = privateKeyPem
+
+	return gh, nil
+}
+
+// getInstallationToken returns cached installation token or creates a new one using the application client.
+func getInstallationToken(appClient *github.Client, appId int64, githubUrl string, installId int64) (string, error) {
+	if token, isCached := githubAppCache.getInstallationToken(appId, githubUrl, installId); isCached {
+		return token, nil
+	}
+	return createInstallationToken(appClient, appId, githubUrl, installId)
+}
 
-	token, _, err := client.Apps.CreateInstallationToken(
+// createInstallationToken creates a new installation token valid for an hour and puts it into the cache.
+func createInstallationToken(appClient *github.Client, appId int64, githubUrl string, installId int64) (string, error) {
+	token, resp, err := appClient.Apps.CreateInstallationToken(
 		context.Background(),
 		installId,
 		&github.InstallationTokenOptions{})
 	if err != nil {
+		if resp != nil && resp.Response != nil {
+			switch resp.StatusCode {
+			case 401:
+				// The application credentials are not valid anymore
+				githubAppCache.invalidateApp(appId, githubUrl)
+			case 404:
+				// The application has been uninstalled
+				githubAppCache.invalidateInstallation(appId, githubUrl, installId)
+			}
+		}
 		// TODO analyze the error
-		return nil, err
+		return "", refineRateLimitError(err)
 	}
+	githubAppCache.setInstallationToken(appId, githubUrl, installId, token)
+	return token.GetToken(), nil
+}
 
-	return NewGithubClient(token.GetToken()), nil
+// refineListInstallationsError converts error of application installations listing into BuildOpError
+// and drops cached data of the application if its credentials are not valid anymore.
+func refineListInstallationsError(appId int64, githubUrl string, resp *github.Response, err error) error {
+	if resp != nil && resp.Response != nil && resp.Response.StatusCode != 0 {
+		switch resp.StatusCode {
+		case 401:
+			githubAppCache.invalidateApp(appId, githubUrl)
+			return boerrors.NewBuildOpError(boerrors.EGitHubAppPrivateKeyNotMatched, err)
+		case 404:
+			githubAppCache.invalidateApp(appId, githubUrl)
+			return boerrors.NewBuildOpError(boerrors.EGitHubAppDoesNotExist, err)
+		}
+	}
+	if rateLimitErr := refineRateLimitError(err); rateLimitErr != err {
+		return rateLimitErr
+	}
+	return boerrors.NewBuildOpError(boerrors.ETransientError, err)
 }
 
-func getInstallations(appId int64, privateKeyPem []byte) ([]ApplicationInstallation, string, error) {
-	itr, err := ghinstallation.NewAppsTransport(http.DefaultTransport, appId, privateKeyPem)
+func getInstallations(appId int64, privateKeyPem []byte, githubUrl string) ([]ApplicationInstallation, string, error) {
+	client, err := newGithubAppClient(appId, privateKeyPem, githubUrl)
 	if err != nil {
-		// Inability to create transport based on a private key indicates that the key is bad formatted
-		return nil, "", boerrors.NewBuildOpError(boerrors.EGitHubAppMalformedPrivateKey, err)
+		return nil, "", err
 	}
-	client := github.NewClient(&http.Client{Transport: itr})
 	appInstallations := []ApplicationInstallation{}
 	opt := &github.RepositoryListByOrgOptions{
 		ListOptions: github.ListOptions{PerPage: 100},
@@ -182,34 +367,28 @@ func getInstallations(appId int64, privateKeyPem []byte) ([]ApplicationInstallat
 	for {
 		installations, resp, err := client.Apps.ListInstallations(context.Background(), &opt.ListOptions)
 		if err != nil {
-			if resp != nil && resp.Response != nil && resp.Response.StatusCode != 0 {
-				switch resp.StatusCode {
-				case 401:
-					return nil, "", boerrors.NewBuildOpError(boerrors.EGitHubAppPrivateKeyNotMatched, err)
-				case 404:
-					return nil, "", boerrors.NewBuildOpError(boerrors.EGitHubAppDoesNotExist, err)
-				}
-			}
-			return nil, "", boerrors.NewBuildOpError(boerrors.ETransientError, err)
+			return nil, "", refineListInstallationsError(appId, githubUrl, resp, err)
 		}
 		for _, val := range installations {
-			token, _, err := client.Apps.CreateInstallationToken(
-				context.Background(),
-				*val.ID,
-				&github.InstallationTokenOptions{})
+			githubAppCache.setInstallationId(appId, githubUrl, val.GetAccount().GetLogin(), val.GetID())
+
+			// The token is passed to a long running job, so a cached token could expire before the job finishes
+			token, err := createInstallationToken(client, appId, githubUrl, val.GetID())
 			if err != nil {
-				// TODO analyze the error
 				continue
 			}
-			installationClient := NewGithubClient(token.GetToken())
+			installationClient, err := newGithubInstallationClient(token, appId, privateKeyPem, githubUrl, val.GetID())
+			if err != nil {
+				return nil, "", err
+			}
 
 			repositories, err := getRepositoriesFromClient(installationClient)
 			if err != nil {
 				continue
 			}
 			appInstallations = append(appInstallations, ApplicationInstallation{
-				Token:        token.GetToken(),
-				ID:           *val.ID,
+				Token:        token,
+				ID:           val.GetID(),
 				Repositories: repositories,
 			})
 		}
@@ -312,26 +491,41 @@ func (c *GithubClient) getDefaultBranch(owner, repository string) (string, error
 	return *repositoryInfo.DefaultBranch, nil
 }
 
-func (c *GithubClient) filesUpToDate(owner, repository, branch string, files []File) (bool, error) {
-	for _, file := range files {
-		opts := &github.RepositoryContentGetOptions{
-			Ref: "refs/heads/" + branch,
+// getFileContent returns content of the given file in the given branch.
+// Returns nil if the file doesn't exist.
+func (c *GithubClient) getFileContent(owner, repository, branch, filePath string) ([]byte, error) {
+	opts := &github.RepositoryContentGetOptions{
+		Ref: "refs/heads/" + branch,
+	}
+
+	fileContentReader, resp, err := c.client.Repositories.DownloadContents(c.ctx, owner, repository, filePath, opts)
+	if err != nil {
+		if resp == nil || resp.Response == nil {
+			// Transport error, there is no response to analyze
+			return nil, err
+		}
+		// It's not clear when it returns 404 or 200 with the error message. Check both.
+		if resp.StatusCode == 404 || strings.Contains(err.Error(), "no file named") {
+			// Given file not found
+			return nil, nil
 		}
 
-		fileContentReader, resp, err := c.client.Repositories.DownloadContents(c.ctx, owner, repository, file.FullPath, opts)
-		if err != nil {
-			// It's not clear when it returns 404 or 200 with the error message. Check both.
-			if resp.StatusCode == 404 || strings.Contains(err.Error(), "no file named") {
-				// Given file not found
-				return false, nil
-			}
+		return nil, RefineGitHostingServiceError(resp.Response, err)
+	}
+	defer fileContentReader.Close()
+	return io.ReadAll(fileContentReader)
+}
 
-			return false, RefineGitHostingServiceError(resp.Response, err)
-		}
-		fileContent, err := io.ReadAll(fileContentReader)
+func (c *GithubClient) filesUpToDate(owner, repository, branch string, files []File) (bool, error) {
+	for _, file := range files {
+		fileContent, err := c.getFileContent(owner, repository, branch, file.FullPath)
 		if err != nil {
 			return false, err
 		}
+		if fileContent == nil {
+			// Given file not found
+			return false, nil
+		}
 
 		if !bytes.Equal(fileContent, file.Content) {
 			return false, nil
@@ -340,6 +534,15 @@ func (c *GithubClient) filesUpToDate(owner, repository, branch string, files []F
 	return true, nil
 }
 
+// filesExist checks if given files exist in the branch. The files could be located in different directories.
+// Returns subset of given files which exist.
+func (c *GithubClient) filesExist(owner, repository, branch string, files []File) ([]File, error) {
+	return gitprovider.FilesExist(files, func(file File) string { return file.FullPath },
+		func(directoryPath string, directoryFiles []File) ([]File, error) {
+			return c.filesExistInDirectory(owner, repository, branch, directoryPath, directoryFiles)
+		})
+}
+
 // filesExistInDirectory checks if given files exist under specified directory.
 // Returns subset of given files which exist.
 func (c *GithubClient) filesExistInDirectory(owner, repository, branch, directoryPath string, files []File) ([]File, error) {
@@ -415,12 +618,9 @@ func (c *GithubClient) addCommitToBranch(owner, repository, authorName, authorEm
 	}
 
 	// Create the commit using the tree.
-	date := time.Now()
-	author := &github.CommitAuthor{Date: &date, Name: &authorName, Email: &authorEmail}
-	commit := &github.Commit{Author: author, Message: &commitMessage, Tree: tree, Parents: []*github.Commit{parent.Commit}}
-	newCommit, resp, err := c.client.Git.CreateCommit(c.ctx, owner, repository, commit)
+	newCommit, err := c.createCommit(owner, repository, authorName, authorEmail, commitMessage, tree, parent.Commit)
 	if err != nil {
-		return RefineGitHostingServiceError(resp.Response, err)
+		return err
 	}
 
 	// Attach the created commit to the given branch.
@@ -445,12 +645,9 @@ func (c *GithubClient) addDeleteCommitToBranch(owner, repository, authorName, au
 	}
 
 	// Create the commit using the tree.
-	date := time.Now()
-	author := &github.CommitAuthor{Date: &date, Name: &authorName, Email: &authorEmail}
-	commit := &github.Commit{Author: author, Message: &commitMessage, Tree: tree, Parents: []*github.Commit{parent.Commit}}
-	newCommit, resp, err := c.client.Git.CreateCommit(c.ctx, owner, repository, commit)
+	newCommit, err := c.createCommit(owner, repository, authorName, authorEmail, commitMessage, tree, parent.Commit)
 	if err != nil {
-		return RefineGitHostingServiceError(resp.Response, err)
+		return err
 	}
 
 	// Attach the created commit to the given branch.
@@ -459,6 +656,41 @@ func (c *GithubClient) addDeleteCommitToBranch(owner, repository, authorName, au
 	return RefineGitHostingServiceError(resp.Response, err)
 }
 
+// createCommit creates a verified commit if possible.
+// Commits created by GitHub Application without explicit author are signed by GitHub,
+// so the given author is ignored in such case and the commit is attributed to the application.
+// Otherwise, the commit is signed by the configured commit signing key, if any.
+func (c *GithubClient) createCommit(owner, repository, authorName, authorEmail, commitMessage string, tree *github.Tree, parent *github.Commit) (*github.Commit, error) {
+	commit := &github.Commit{Message: &commitMessage, Tree: tree, Parents: []*github.Commit{parent}}
+	if c.appId == 0 {
+		// Git stores time with seconds precision
+		date := time.Now().UTC().Truncate(time.Second)
+		author := &github.CommitAuthor{Date: &date, Name: &authorName, Email: &authorEmail}
+		commit.Author = author
+		if c.commitSigner != nil {
+			commit.Committer = author
+			payload := signing.GetCommitPayload(signing.CommitData{
+				TreeSha:     tree.GetSHA(),
+				ParentShas:  []string{parent.GetSHA()},
+				AuthorName:  authorName,
+				AuthorEmail: authorEmail,
+				Date:        date,
+				Message:     commitMessage,
+			})
+			signature, err := c.commitSigner.Sign(payload)
+			if err != nil {
+				return nil, fmt.Errorf("failed to sign commit: %w", err)
+			}
+			commit.Verification = &github.SignatureVerification{Signature: &signature}
+		}
+	}
+	newCommit, resp, err := c.client.Git.CreateCommit(c.ctx, owner, repository, commit)
+	if err != nil {
+		return nil, RefineGitHostingServiceError(resp.Response, err)
+	}
+	return newCommit, nil
+}
+
 // findPullRequestByBranchesWithinRepository searches for a PR within repository by current and target (base) branch.
 func (c *GithubClient) findPullRequestByBranchesWithinRepository(owner, repository, branchName, baseBranchName string) (*github.PullRequest, error) {
 	opts := &github.PullRequestListOptions{
@@ -481,9 +713,26 @@ func (c *GithubClient) findPullRequestByBranchesWithinRepository(owner, reposito
 	}
 }
 
+func (c *GithubClient) getPullRequest(owner, repository string, number int) (*github.PullRequest, error) {
+	pr, resp, err := c.client.PullRequests.Get(c.ctx, owner, repository, number)
+	if err != nil {
+		return nil, RefineGitHostingServiceError(resp.Response, err)
+	}
+	return pr, nil
+}
+
+// reopenPullRequest reopens closed pull request. GitHub doesn't allow it if the head branch is deleted.
+func (c *GithubClient) reopenPullRequest(owner, repository string, number int) (*github.PullRequest, error) {
+	pr, resp, err := c.client.PullRequests.Edit(c.ctx, owner, repository, number, &github.PullRequest{State: github.String("open")})
+	if err != nil {
+		return nil, RefineGitHostingServiceError(resp.Response, err)
+	}
+	return pr, nil
+}
+
 // createPullRequestWithinRepository create a new pull request into the same repository.
-// Returns url to the created pull request.
-func (c *GithubClient) createPullRequestWithinRepository(owner, repository, branchName, baseBranchName, prTitle, prText string) (string, error) {
+// Returns the created pull request.
+func (c *GithubClient) createPullRequestWithinRepository(owner, repository, branchName, baseBranchName, prTitle, prText string, draft bool) (*github.PullRequest, error) {
 	branch := fmt.Sprintf("%s:%s", owner, branchName)
 
 	newPRData := &github.NewPullRequest{
@@ -492,17 +741,158 @@ func (c *GithubClient) createPullRequestWithinRepository(owner, repository, bran
 		Base:                &baseBranchName,
 		Body:                &prText,
 		MaintainerCanModify: github.Bool(true),
+		Draft:               github.Bool(draft),
 	}
 
 	pr, resp, err := c.client.PullRequests.Create(c.ctx, owner, repository, newPRData)
 	if err != nil {
-		return "", RefineGitHostingServiceError(resp.Response, err)
+		return nil, RefineGitHostingServiceError(resp.Response, err)
+	}
+
+	return pr, nil
+}
+
+// addPullRequestMetadata adds given labels, requested reviewers and assignees to the pull request.
+// Only the ones that the pull request doesn't have yet are added.
+// GitHub removes a reviewer from the requested ones after the review is submitted,
+// so reviewers who have already reviewed the pull request are not requested again.
+func (c *GithubClient) addPullRequestMetadata(owner, repository string, pr *github.PullRequest, labels, reviewers, teamReviewers, assignees []string) error {
+	var existingLabels, existingReviewers, existingTeamReviewers, existingAssignees []string
+	for _, label := range pr.Labels {
+		existingLabels = append(existingLabels, label.GetName())
+	}
+	for _, reviewer := range pr.RequestedReviewers {
+		existingReviewers = append(existingReviewers, reviewer.GetLogin())
+	}
+	for _, team := range pr.RequestedTeams {
+		existingTeamReviewers = append(existingTeamReviewers, team.GetSlug())
+	}
+	for _, assignee := range pr.Assignees {
+		existingAssignees = append(existingAssignees, assignee.GetLogin())
+	}
+
+	if labelsToAdd := getMissingItems(labels, existingLabels); len(labelsToAdd) > 0 {
+		_, resp, err := c.client.Issues.AddLabelsToIssue(c.ctx, owner, repository, pr.GetNumber(), labelsToAdd)
+		if err != nil {
+			return RefineGitHostingServiceError(resp.Response, err)
+		}
+	}
+
+	reviewersRequest := github.ReviewersRequest{
+		Reviewers:     getMissingItems(reviewers, existingReviewers),
+		TeamReviewers: getMissingItems(teamReviewers, existingTeamReviewers),
+	}
+	if len(reviewersRequest.Reviewers) > 0 || len(reviewersRequest.TeamReviewers) > 0 {
+		reviewedBy, err := c.getPullRequestReviewers(owner, repository, pr.GetNumber())
+		if err != nil {
+			return err
+		}
+		reviewersRequest.Reviewers = getMissingItems(reviewersRequest.Reviewers, reviewedBy)
+		// A team request is fulfilled by a review of any team member, which cannot be matched without listing the team members
+		if len(reviewedBy) > 0 {
+			reviewersRequest.TeamReviewers = nil
+		}
+	}
+	if len(reviewersRequest.Reviewers) > 0 || len(reviewersRequest.TeamReviewers) > 0 {
+		_, resp, err := c.client.PullRequests.RequestReviewers(c.ctx, owner, repository, pr.GetNumber(), reviewersRequest)
+		if err != nil {
+			return RefineGitHostingServiceError(resp.Response, err)
+		}
+	}
+
+	if assigneesToAdd := getMissingItems(assignees, existingAssignees); len(assigneesToAdd) > 0 {
+		_, resp, err := c.client.Issues.AddAssignees(c.ctx, owner, repository, pr.GetNumber(), assigneesToAdd)
+		if err != nil {
+			return RefineGitHostingServiceError(resp.Response, err)
+		}
+	}
+	return nil
+}
+
+// getPullRequestReviewers returns logins of users who have submitted a review of the pull request.
+func (c *GithubClient) getPullRequestReviewers(owner, repository string, number int) ([]string, error) {
+	var reviewers []string
+	listOpts := &github.ListOptions{PerPage: 100}
+	for {
+		reviews, resp, err := c.client.PullRequests.ListReviews(c.ctx, owner, repository, number, listOpts)
+		if err != nil {
+			return nil, RefineGitHostingServiceError(resp.Response, err)
+		}
+		for _, review := range reviews {
+			reviewers = append(reviewers, review.GetUser().GetLogin())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		listOpts.Page = resp.NextPage
+	}
+	return reviewers, nil
+}
+
+// getMissingItems returns items that are not in the existing ones. GitHub names are case insensitive.
+func getMissingItems(items, existingItems []string) []string {
+	var missingItems []string
+	for _, item := range items {
+		isMissing := true
+		for _, existingItem := range existingItems {
+			if strings.EqualFold(item, existingItem) {
+				isMissing = false
+				break
+			}
+		}
+		if isMissing {
+			missingItems = append(missingItems, item)
+		}
+	}
+	return missingItems
+}
+
+// enablePullRequestAutoMerge enables auto-merge of the pull request with the given GitHub merge method: MERGE, SQUASH or REBASE.
+// Auto-merge is available only via GraphQL API.
+func (c *GithubClient) enablePullRequestAutoMerge(owner, repository string, number int, mergeMethod string) error {
+	pr, err := c.getPullRequest(owner, repository, number)
+	if err != nil {
+		return err
 	}
 
-	return pr.GetHTMLURL(), nil
+	query := struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}{
+		Query: `mutation($pullRequestId: ID!, $mergeMethod: PullRequestMergeMethod!) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $pullRequestId, mergeMethod: $mergeMethod}) {
+    clientMutationId
+  }
+}`,
+		Variables: map[string]interface{}{
+			"pullRequestId": pr.GetNodeID(),
+			"mergeMethod":   mergeMethod,
+		},
+	}
+	req, err := c.client.NewRequest("POST", getGraphqlUrl(c.githubUrl), query)
+	if err != nil {
+		return err
+	}
+	result := struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+	resp, err := c.client.Do(c.ctx, req, &result)
+	if err != nil {
+		return RefineGitHostingServiceError(resp.Response, err)
+	}
+	// GraphQL API reports errors in the response body
+	if len(result.Errors) > 0 {
+		var messages []string
+		for _, graphqlError := range result.Errors {
+			messages = append(messages, graphqlError.Message)
+		}
+		return fmt.Errorf("failed to enable auto-merge of pull request %d: %s", number, strings.Join(messages, "; "))
+	}
+	return nil
 }
 
-// getWebhookByTargetUrl returns webhook by its target url or nil if such webhook doesn't exist.
 func (c *GithubClient) getWebhookByTargetUrl(owner, repository, webhookTargetUrl string) (*github.Hook, error) {
 	// Suppose that the repository does not have more than 100 webhooks
 	listOpts := &github.ListOptions{PerPage: 100}
@@ -546,13 +936,11 @@ func (c *GithubClient) deleteWebhook(owner, repository string, webhookId int64)
 	return nil
 }
 
-func getGitHubAppName(appId int64, privateKeyPem []byte) (string, string, error) {
-	itr, err := ghinstallation.NewAppsTransport(http.DefaultTransport, appId, privateKeyPem)
+func getGitHubAppName(appId int64, privateKeyPem []byte, githubUrl string) (string, string, error) {
+	client, err := newGithubAppClient(appId, privateKeyPem, githubUrl)
 	if err != nil {
-		// Inability to create transport based on a private key indicates that the key is bad formatted
-		return "", "", boerrors.NewBuildOpError(boerrors.EGitHubAppMalformedPrivateKey, err)
+		return "", "", err
 	}
-	client := github.NewClient(&http.Client{Transport: itr})
 	githubApp, _, err := client.Apps.Get(context.TODO(), "")
 	if err != nil {
 		return "", "", err
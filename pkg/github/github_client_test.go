@@ -0,0 +1,372 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/redhat-appstudio/build-service/pkg/git/signing"
+)
+
+func TestNewGithubClient(t *testing.T) {
+	tests := []struct {
+		name           string
+		githubUrl      string
+		wantApiUrl     string
+		wantUploadUrl  string
+		wantGraphqlUrl string
+	}{
+		{
+			name:           "should use github.com API",
+			githubUrl:      "https://github.com",
+			wantApiUrl:     "https://api.github.com/",
+			wantUploadUrl:  "https://uploads.github.com/",
+			wantGraphqlUrl: "https://api.github.com/graphql",
+		},
+		{
+			name:           "should use github.com API if GitHub URL is not set",
+			githubUrl:      "",
+			wantApiUrl:     "https://api.github.com/",
+			wantUploadUrl:  "https://uploads.github.com/",
+			wantGraphqlUrl: "https://api.github.com/graphql",
+		},
+		{
+			name:           "should use GitHub Enterprise Server API",
+			githubUrl:      "https://github.mycompany.com",
+			wantApiUrl:     "https://github.mycompany.com/api/v3/",
+			wantUploadUrl:  "https://github.mycompany.com/api/uploads/",
+			wantGraphqlUrl: "https://github.mycompany.com/api/graphql",
+		},
+		{
+			name:           "should use GitHub Enterprise Server API with custom port",
+			githubUrl:      "https://github.mycompany.com:8443/",
+			wantApiUrl:     "https://github.mycompany.com:8443/api/v3/",
+			wantUploadUrl:  "https://github.mycompany.com:8443/api/uploads/",
+			wantGraphqlUrl: "https://github.mycompany.com:8443/api/graphql",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ghclient, err := newGithubClient("ghp_token", tt.githubUrl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ghclient.client.BaseURL.String(); got != tt.wantApiUrl {
+				t.Errorf("newGithubClient(): got API URL %s, want %s", got, tt.wantApiUrl)
+			}
+			if got := ghclient.client.UploadURL.String(); got != tt.wantUploadUrl {
+				t.Errorf("newGithubClient(): got upload URL %s, want %s", got, tt.wantUploadUrl)
+			}
+			if got := GetApiUrl(tt.githubUrl); got != tt.wantApiUrl {
+				t.Errorf("GetApiUrl(): got %s, want %s", got, tt.wantApiUrl)
+			}
+			if got := getGraphqlUrl(tt.githubUrl); got != tt.wantGraphqlUrl {
+				t.Errorf("getGraphqlUrl(): got %s, want %s", got, tt.wantGraphqlUrl)
+			}
+		})
+	}
+}
+
+func TestIsAllowedGithubUrl(t *testing.T) {
+	tests := []struct {
+		name           string
+		enterpriseUrls string
+		githubUrl      string
+		want           bool
+	}{
+		{
+			name:      "should allow github.com",
+			githubUrl: "https://github.com",
+			want:      true,
+		},
+		{
+			name:      "should not allow GitHub Enterprise Server if none configured",
+			githubUrl: "https://github.mycompany.com",
+			want:      false,
+		},
+		{
+			name:           "should allow configured GitHub Enterprise Server",
+			enterpriseUrls: "https://github.othercompany.com, https://GitHub.mycompany.com/",
+			githubUrl:      "https://github.mycompany.com",
+			want:           true,
+		},
+		{
+			name:           "should not allow GitHub Enterprise Server on different port",
+			enterpriseUrls: "https://github.mycompany.com",
+			githubUrl:      "https://github.mycompany.com:8443",
+			want:           false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(GithubEnterpriseUrlsEnvName, tt.enterpriseUrls)
+			if got := IsAllowedGithubUrl(tt.githubUrl); got != tt.want {
+				t.Errorf("IsAllowedGithubUrl(%s): got %t, want %t", tt.githubUrl, got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeCommitSigner struct {
+	payload []byte
+}
+
+func (s *fakeCommitSigner) Sign(payload []byte) (string, error) {
+	s.payload = payload
+	return "signature", nil
+}
+
+func TestCreateCommit(t *testing.T) {
+	tests := []struct {
+		name          string
+		appId         int64
+		commitSigner  *fakeCommitSigner
+		wantAuthor    bool
+		wantSignature string
+	}{
+		{
+			name:       "should create unsigned commit if no signing key configured",
+			wantAuthor: true,
+		},
+		{
+			name:          "should sign commit by the signing key",
+			commitSigner:  &fakeCommitSigner{},
+			wantAuthor:    true,
+			wantSignature: "signature",
+		},
+		{
+			name:         "should let GitHub sign commit created by application",
+			appId:        1234,
+			commitSigner: &fakeCommitSigner{},
+			wantAuthor:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestBody map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				w.Write([]byte(`{"sha": "commit-sha"}`))
+			}))
+			defer server.Close()
+
+			ghclient, err := newGithubClient("ghp_token", server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ghclient.appId = tt.appId
+			if tt.commitSigner != nil {
+				ghclient.SetCommitSigner(tt.commitSigner)
+			}
+
+			tree := &github.Tree{SHA: github.String("tree-sha")}
+			parent := &github.Commit{SHA: github.String("parent-sha")}
+			commit, err := ghclient.createCommit("owner", "repository", "redhat-appstudio", "rhtap@redhat.com", "Appstudio update component", tree, parent)
+			if err != nil {
+				t.Fatalf("createCommit(): unexpected error: %v", err)
+			}
+			if commit.GetSHA() != "commit-sha" {
+				t.Errorf("createCommit(): got commit %s, want commit-sha", commit.GetSHA())
+			}
+
+			_, hasAuthor := requestBody["author"]
+			if hasAuthor != tt.wantAuthor {
+				t.Errorf("createCommit(): got author set %t, want %t", hasAuthor, tt.wantAuthor)
+			}
+			signature, _ := requestBody["signature"].(string)
+			if signature != tt.wantSignature {
+				t.Errorf("createCommit(): got signature %q, want %q", signature, tt.wantSignature)
+			}
+			if tt.wantSignature != "" {
+				committer, _ := requestBody["committer"].(map[string]interface{})
+				wantPayload := signing.GetCommitPayload(signing.CommitData{
+					TreeSha:     "tree-sha",
+					ParentShas:  []string{"parent-sha"},
+					AuthorName:  "redhat-appstudio",
+					AuthorEmail: "rhtap@redhat.com",
+					Date:        parseCommitDate(t, committer["date"]),
+					Message:     "Appstudio update component",
+				})
+				if string(tt.commitSigner.payload) != string(wantPayload) {
+					t.Errorf("createCommit(): signed payload %q doesn't match commit %q", tt.commitSigner.payload, wantPayload)
+				}
+			}
+		})
+	}
+}
+
+func parseCommitDate(t *testing.T, date interface{}) time.Time {
+	dateStr, _ := date.(string)
+	parsedDate, err := time.Parse(time.RFC3339, dateStr)
+	if err != nil {
+		t.Fatalf("failed to parse commit date %v: %v", date, err)
+	}
+	return parsedDate
+}
+
+func TestGetFileContentOnTransportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ghclient, err := newGithubClient("ghp_token", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	if _, err := ghclient.getFileContent("owner", "repository", "main", ".tekton/pipeline.yaml"); err == nil {
+		t.Error("getFileContent(): expected error if the server is not reachable")
+	}
+}
+
+func TestAddPullRequestMetadata(t *testing.T) {
+	tests := []struct {
+		name         string
+		reviews      string
+		wantRequests map[string]interface{}
+	}{
+		{
+			name:    "should add missing metadata",
+			reviews: `[]`,
+			wantRequests: map[string]interface{}{
+				"POST /api/v3/repos/owner/repository/issues/5/labels": []interface{}{"onboarding"},
+				"POST /api/v3/repos/owner/repository/pulls/5/requested_reviewers": map[string]interface{}{
+					"reviewers":      []interface{}{"bob", "dave"},
+					"team_reviewers": []interface{}{"qa-team"},
+				},
+			},
+		},
+		{
+			name:    "should not request review again from users who have reviewed the pull request",
+			reviews: `[{"id": 1, "user": {"login": "Dave"}, "state": "APPROVED"}]`,
+			wantRequests: map[string]interface{}{
+				"POST /api/v3/repos/owner/repository/issues/5/labels": []interface{}{"onboarding"},
+				"POST /api/v3/repos/owner/repository/pulls/5/requested_reviewers": map[string]interface{}{
+					"reviewers": []interface{}{"bob"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := map[string]interface{}{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/pulls/5/reviews") {
+					w.Write([]byte(tt.reviews))
+					return
+				}
+				var body interface{}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				requests[r.Method+" "+r.URL.Path] = body
+				if strings.HasSuffix(r.URL.Path, "/labels") {
+					w.Write([]byte(`[]`))
+				} else {
+					w.Write([]byte(`{}`))
+				}
+			}))
+			defer server.Close()
+
+			ghclient, err := newGithubClient("ghp_token", server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pr := &github.PullRequest{
+				Number:             github.Int(5),
+				Labels:             []*github.Label{{Name: github.String("konflux")}},
+				RequestedReviewers: []*github.User{{Login: github.String("Alice")}},
+				RequestedTeams:     []*github.Team{{Slug: github.String("build-team")}},
+				Assignees:          []*github.User{{Login: github.String("carol")}},
+			}
+			err = ghclient.addPullRequestMetadata("owner", "repository", pr,
+				[]string{"konflux", "onboarding"}, []string{"alice", "bob", "dave"}, []string{"build-team", "qa-team"}, []string{"carol"})
+			if err != nil {
+				t.Fatalf("addPullRequestMetadata(): unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(requests, tt.wantRequests) {
+				t.Errorf("addPullRequestMetadata(): got requests %v, want %v", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestEnablePullRequestAutoMerge(t *testing.T) {
+	tests := []struct {
+		name            string
+		graphqlResponse string
+		expectError     bool
+	}{
+		{
+			name:            "should enable auto-merge",
+			graphqlResponse: `{"data": {"enablePullRequestAutoMerge": {"clientMutationId": null}}}`,
+		},
+		{
+			name:            "should return GraphQL errors",
+			graphqlResponse: `{"errors": [{"message": "Pull request Auto merge is not allowed for this repository"}]}`,
+			expectError:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var graphqlRequest struct {
+				Query     string            `json:"query"`
+				Variables map[string]string `json:"variables"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v3/repos/owner/repository/pulls/5":
+					w.Write([]byte(`{"number": 5, "node_id": "PR_node"}`))
+				case "/api/graphql":
+					if err := json.NewDecoder(r.Body).Decode(&graphqlRequest); err != nil {
+						t.Errorf("failed to decode request: %v", err)
+					}
+					w.Write([]byte(tt.graphqlResponse))
+				default:
+					t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			ghclient, err := newGithubClient("ghp_token", server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ghclient.enablePullRequestAutoMerge("owner", "repository", 5, "SQUASH")
+			if tt.expectError != (err != nil) {
+				t.Fatalf("enablePullRequestAutoMerge(): expected error: %t, got: %v", tt.expectError, err)
+			}
+			if !strings.Contains(graphqlRequest.Query, "enablePullRequestAutoMerge") {
+				t.Errorf("enablePullRequestAutoMerge(): unexpected query: %s", graphqlRequest.Query)
+			}
+			wantVariables := map[string]string{"pullRequestId": "PR_node", "mergeMethod": "SQUASH"}
+			if !reflect.DeepEqual(graphqlRequest.Variables, wantVariables) {
+				t.Errorf("enablePullRequestAutoMerge(): got variables %v, want %v", graphqlRequest.Variables, wantVariables)
+			}
+		})
+	}
+}
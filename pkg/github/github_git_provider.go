@@ -0,0 +1,284 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitrepourl"
+)
+
+var _ gitprovider.GitProvider = (*GithubClient)(nil)
+
+func (g *GithubClient) EnsurePaCMergeRequest(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+	owner, repository, err := getOwnerAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := CreatePaCPullRequest(g, toPaCPullRequestData(owner, repository, d))
+	if err != nil {
+		return nil, g.refineAppNotInstalledError(repoUrl, refineRateLimitError(err))
+	}
+	if pr == nil {
+		return nil, nil
+	}
+	return toMergeRequest(pr), nil
+}
+
+func (g *GithubClient) UndoPaCMergeRequest(repoUrl string, d *gitprovider.MergeRequestData) (string, error) {
+	owner, repository, err := getOwnerAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return "", err
+	}
+
+	prUrl, err := UndoPaCPullRequest(g, toPaCPullRequestData(owner, repository, d))
+	if err != nil {
+		return "", g.refineAppNotInstalledError(repoUrl, refineRateLimitError(err))
+	}
+	return prUrl, nil
+}
+
+func (g *GithubClient) FindUnmergedPaCMergeRequest(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+	owner, repository, err := getOwnerAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	// Onboarding pull request is created within the repository, so the head is owned by the repository owner
+	pullRequest, err := FindUnmergedOnboardingMergeRequest(g, owner, repository, d.BranchName, d.BaseBranchName, owner)
+	if err != nil {
+		return nil, refineRateLimitError(err)
+	}
+	if pullRequest == nil {
+		return nil, nil
+	}
+	return toMergeRequest(pullRequest), nil
+}
+
+func (g *GithubClient) GetMergeRequest(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+	owner, repository, err := getOwnerAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	pullRequest, err := g.getPullRequest(owner, repository, int(mrNumber))
+	if err != nil {
+		return nil, refineRateLimitError(err)
+	}
+	return toMergeRequest(pullRequest), nil
+}
+
+func (g *GithubClient) ReopenMergeRequest(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+	owner, repository, err := getOwnerAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	pullRequest, err := g.reopenPullRequest(owner, repository, int(mrNumber))
+	if err != nil {
+		return nil, refineRateLimitError(err)
+	}
+	return toMergeRequest(pullRequest), nil
+}
+
+func (g *GithubClient) EnablePaCMergeRequestAutoMerge(repoUrl string, mrNumber int64, mergeMethod string) error {
+	owner, repository, err := getOwnerAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return err
+	}
+
+	githubMergeMethod, err := toGithubMergeMethod(mergeMethod)
+	if err != nil {
+		return err
+	}
+	return refineRateLimitError(g.enablePullRequestAutoMerge(owner, repository, int(mrNumber), githubMergeMethod))
+}
+
+func (g *GithubClient) SetupPaCWebhook(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *gitprovider.WebhookOptions) error {
+	owner, repository, err := getOwnerAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return err
+	}
+	events := toGithubWebhookEvents(webhookOptions.Events)
+	return refineRateLimitError(SetupPaCWebhook(g, webhookUrl, webhookSecret, owner, repository, webhookOptions.SSLVerification, events))
+}
+
+func (g *GithubClient) DeletePaCWebhook(repoUrl string, webhookUrl string) error {
+	owner, repository, err := getOwnerAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return err
+	}
+	return refineRateLimitError(DeletePaCWebhook(g, webhookUrl, owner, repository))
+}
+
+func (g *GithubClient) GetDefaultBranch(repoUrl string) (string, error) {
+	owner, repository, err := getOwnerAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	defaultBranch, err := GetDefaultBranch(g, owner, repository)
+	return defaultBranch, refineRateLimitError(err)
+}
+
+func (g *GithubClient) DeleteBranch(repoUrl string, branchName string) (bool, error) {
+	owner, repository, err := getOwnerAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return false, err
+	}
+
+	err = DeleteBranch(g, owner, repository, branchName)
+	if err == nil {
+		return true, nil
+	}
+	// GitHub responds with 422 if the branch reference doesn't exist
+	if ghErrResp, ok := err.(*github.ErrorResponse); ok && ghErrResp.Response != nil {
+		if ghErrResp.Response.StatusCode == 422 {
+			return false, nil
+		}
+	}
+	return false, refineRateLimitError(err)
+}
+
+func (g *GithubClient) GetBranchSha(repoUrl string, branchName string) (string, error) {
+	owner, repository, err := getOwnerAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	sha, err := GetBranchSHA(g, owner, repository, branchName)
+	return sha, refineRateLimitError(err)
+}
+
+func (g *GithubClient) DownloadFileContent(repoUrl string, branchName string, filePath string) ([]byte, error) {
+	owner, repository, err := getOwnerAndRepoFromUrl(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+	content, err := g.getFileContent(owner, repository, branchName, filePath)
+	return content, refineRateLimitError(err)
+}
+
+func (g *GithubClient) GetBrowseRepositoryAtShaLink(repoUrl string, sha string) string {
+	return GetBrowseRepositoryAtShaLink(repoUrl, sha)
+}
+
+func (g *GithubClient) GetConfiguredGitAppName() (string, string, error) {
+	if g.appId == 0 {
+		return "", "", fmt.Errorf("GitHub client is not created by GitHub Application")
+	}
+	return GetGitHubAppName(g.appId, g.appPrivateKeyPem, g.githubUrl)
+}
+
+// refineAppNotInstalledError handles case when GitHub application is not installed for the component repository
+func (g *GithubClient) refineAppNotInstalledError(repoUrl string, err error) error {
+	if g.appId != 0 && strings.Contains(err.Error(), "Resource not accessible by integration") {
+		return fmt.Errorf(" Pipelines as Code GitHub application with %d ID is not installed for %s repository", g.appId, repoUrl)
+	}
+	return err
+}
+
+// toGithubWebhookEvents maps git provider independent webhook events to GitHub webhook events.
+func toGithubWebhookEvents(events []string) []string {
+	var githubEvents []string
+	for _, event := range events {
+		switch event {
+		case gitprovider.WebhookEventPullRequest:
+			githubEvents = gitprovider.AppendUniqueWebhookEvents(githubEvents, "pull_request")
+		case gitprovider.WebhookEventPush, gitprovider.WebhookEventTagPush:
+			// GitHub delivers pushed tags as push events
+			githubEvents = gitprovider.AppendUniqueWebhookEvents(githubEvents, "push")
+		case gitprovider.WebhookEventComment:
+			githubEvents = gitprovider.AppendUniqueWebhookEvents(githubEvents, "issue_comment", "commit_comment")
+		}
+	}
+	return githubEvents
+}
+
+func toMergeRequest(pullRequest *github.PullRequest) *gitprovider.MergeRequest {
+	state := gitprovider.MergeRequestStateOpen
+	if pullRequest.GetMerged() || pullRequest.MergedAt != nil {
+		state = gitprovider.MergeRequestStateMerged
+	} else if pullRequest.GetState() == "closed" {
+		state = gitprovider.MergeRequestStateClosed
+	}
+	return &gitprovider.MergeRequest{
+		Number:    int64(pullRequest.GetNumber()),
+		WebUrl:    pullRequest.GetHTMLURL(),
+		Title:     pullRequest.GetTitle(),
+		CreatedAt: pullRequest.CreatedAt,
+		State:     state,
+	}
+}
+
+// toGithubMergeMethod maps git provider independent merge method to GitHub pull request merge method.
+func toGithubMergeMethod(mergeMethod string) (string, error) {
+	switch mergeMethod {
+	case gitprovider.MergeMethodMerge, "":
+		return "MERGE", nil
+	case gitprovider.MergeMethodSquash:
+		return "SQUASH", nil
+	case gitprovider.MergeMethodRebase:
+		return "REBASE", nil
+	default:
+		return "", fmt.Errorf("unsupported merge method: %s", mergeMethod)
+	}
+}
+
+func toPaCPullRequestData(owner, repository string, d *gitprovider.MergeRequestData) *PaCPullRequestData {
+	var files []File
+	for _, file := range d.Files {
+		files = append(files, File{FullPath: file.FullPath, Content: file.Content})
+	}
+	var deletedFiles []File
+	for _, file := range d.DeletedFiles {
+		deletedFiles = append(deletedFiles, File{FullPath: file.FullPath})
+	}
+	return &PaCPullRequestData{
+		Owner:         owner,
+		Repository:    repository,
+		CommitMessage: d.CommitMessage,
+		Branch:        d.BranchName,
+		BaseBranch:    d.BaseBranchName,
+		PRTitle:       d.Title,
+		PRText:        d.Text,
+		AuthorName:    d.AuthorName,
+		AuthorEmail:   d.AuthorEmail,
+		Files:         files,
+		DeletedFiles:  deletedFiles,
+		Labels:        d.Labels,
+		Reviewers:     d.Reviewers,
+		TeamReviewers: d.TeamReviewers,
+		Assignees:     d.Assignees,
+		Draft:         d.Draft,
+	}
+}
+
+// getOwnerAndRepoFromUrl extracts owner and repository name from repository URL,
+// e.g. https://github.com/owner/repository or git@github.com:owner/repository.git
+func getOwnerAndRepoFromUrl(repoUrl string) (string, string, error) {
+	gitRepoUrl, err := gitrepourl.ParseGitRepoUrl(repoUrl)
+	if err != nil {
+		return "", "", err
+	}
+	if strings.Contains(gitRepoUrl.OwnerPath, "/") {
+		return "", "", fmt.Errorf("failed to get owner and repository from %s URL", repoUrl)
+	}
+	return gitRepoUrl.OwnerPath, gitRepoUrl.Repository, nil
+}
@@ -18,18 +18,21 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v45/github"
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitrepourl"
 )
 
 // Allow mocking for tests
-var CreatePaCPullRequest func(g *GithubClient, d *PaCPullRequestData) (string, error) = ensurePaCPullRequest
+var CreatePaCPullRequest func(g *GithubClient, d *PaCPullRequestData) (*github.PullRequest, error) = ensurePaCPullRequest
 var UndoPaCPullRequest func(g *GithubClient, d *PaCPullRequestData) (string, error) = undoPaCPullRequest
-var SetupPaCWebhook func(g *GithubClient, webhookUrl, webhookSecret, owner, repository string) error = setupPaCWebhook
+var SetupPaCWebhook func(g *GithubClient, webhookUrl, webhookSecret, owner, repository string, sslVerification bool, events []string) error = setupPaCWebhook
 var DeletePaCWebhook func(g *GithubClient, webhookUrl, owner, repository string) error = deletePaCWebhook
 var IsAppInstalledIntoRepository func(g *GithubClient, owner, repository string) (bool, error) = isAppInstalledIntoRepository
 var GetDefaultBranch func(*GithubClient, string, string) (string, error) = getDefaultBranch
@@ -40,10 +43,9 @@ var DeleteBranch func(*GithubClient, string, string, string) error = deleteBranc
 const (
 	// Allowed values are 'json' and 'form' according to the doc: https://docs.github.com/en/rest/webhooks/repos#create-a-repository-webhook
 	webhookContentType = "json"
-)
 
-var (
-	appStudioPaCWebhookEvents = [...]string{"pull_request", "push", "issue_comment", "commit_comment"}
+	// defaultSecondaryRateLimitRetryAfter is used when GitHub doesn't tell when to retry after hitting secondary rate limit
+	defaultSecondaryRateLimitRetryAfter = time.Minute
 )
 
 type File struct {
@@ -62,16 +64,23 @@ type PaCPullRequestData struct {
 	AuthorName    string
 	AuthorEmail   string
 	Files         []File
+	// DeletedFiles are removed from the branch if it already exists
+	DeletedFiles  []File
+	Labels        []string
+	Reviewers     []string
+	TeamReviewers []string
+	Assignees     []string
+	Draft         bool
 }
 
-// ensurePaCPullRequest creates a new pull request or updates existing (if needed) and returns its web URL.
-// If there is no error and web URL is empty, it means that the PR is not needed (main branch is up to date).
-func ensurePaCPullRequest(ghclient *GithubClient, d *PaCPullRequestData) (string, error) {
+// ensurePaCPullRequest creates a new pull request or updates existing (if needed) and returns it.
+// If there is no error and the pull request is nil, it means that the PR is not needed (main branch is up to date).
+func ensurePaCPullRequest(ghclient *GithubClient, d *PaCPullRequestData) (*github.PullRequest, error) {
 	// Fallback to the default branch if base branch is not set
 	if d.BaseBranch == "" {
 		baseBranch, err := ghclient.getDefaultBranch(d.Owner, d.Repository)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		d.BaseBranch = baseBranch
 	}
@@ -79,73 +88,106 @@ func ensurePaCPullRequest(ghclient *GithubClient, d *PaCPullRequestData) (string
 	// Check if Pipelines as Code configuration up to date in the main branch
 	upToDate, err := ghclient.filesUpToDate(d.Owner, d.Repository, d.BaseBranch, d.Files)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if upToDate {
 		// Nothing to do, the configuration is alredy in the main branch of the repository
-		return "", nil
+		return nil, nil
 	}
 
 	// Check if branch with a proposal exists
 	branchExists, err := ghclient.referenceExist(d.Owner, d.Repository, d.Branch)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if branchExists {
 		upToDate, err := ghclient.filesUpToDate(d.Owner, d.Repository, d.Branch, d.Files)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		if !upToDate {
 			// Update branch
 			branchRef, err := ghclient.getReference(d.Owner, d.Repository, d.Branch)
 			if err != nil {
-				return "", err
+				return nil, err
 			}
 
 			err = ghclient.addCommitToBranch(d.Owner, d.Repository, d.AuthorName, d.AuthorEmail, d.CommitMessage, d.Files, branchRef)
 			if err != nil {
-				return "", err
+				return nil, err
+			}
+		}
+
+		// Remove files which are not proposed anymore
+		deletedFiles, err := ghclient.filesExist(d.Owner, d.Repository, d.Branch, d.DeletedFiles)
+		if err != nil {
+			return nil, err
+		}
+		if len(deletedFiles) > 0 {
+			branchRef, err := ghclient.getReference(d.Owner, d.Repository, d.Branch)
+			if err != nil {
+				return nil, err
+			}
+			err = ghclient.addDeleteCommitToBranch(d.Owner, d.Repository, d.AuthorName, d.AuthorEmail, d.CommitMessage, deletedFiles, branchRef)
+			if err != nil {
+				return nil, err
 			}
 		}
 
 		pr, err := ghclient.findPullRequestByBranchesWithinRepository(d.Owner, d.Repository, d.Branch, d.BaseBranch)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		if pr != nil {
-			return *pr.HTMLURL, nil
+			if err := ghclient.addPullRequestMetadata(d.Owner, d.Repository, pr, d.Labels, d.Reviewers, d.TeamReviewers, d.Assignees); err != nil {
+				return nil, err
+			}
+			return pr, nil
 		}
 
-		prUrl, err := ghclient.createPullRequestWithinRepository(d.Owner, d.Repository, d.Branch, d.BaseBranch, d.PRTitle, d.PRText)
+		pr, err = createPaCPullRequest(ghclient, d)
 		if err != nil {
 			if strings.Contains(err.Error(), "No commits between") {
 				// This could happen when a PR was created and merged, but PR branch was not deleted. Then main was updated.
 				// Current branch has correct configuration, but it's not possible to create a PR,
 				// because current branch reference is included into main branch.
 				if err := ghclient.deleteReference(d.Owner, d.Repository, d.Branch); err != nil {
-					return "", err
+					return nil, err
 				}
 				return ensurePaCPullRequest(ghclient, d)
 			}
+			return nil, err
 		}
-		return prUrl, nil
+		return pr, nil
 
 	} else {
 		// Create branch, commit and pull request
 		branchRef, err := ghclient.createReference(d.Owner, d.Repository, d.Branch, d.BaseBranch)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		err = ghclient.addCommitToBranch(d.Owner, d.Repository, d.AuthorName, d.AuthorEmail, d.CommitMessage, d.Files, branchRef)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
-		return ghclient.createPullRequestWithinRepository(d.Owner, d.Repository, d.Branch, d.BaseBranch, d.PRTitle, d.PRText)
+		return createPaCPullRequest(ghclient, d)
+	}
+}
+
+// createPaCPullRequest creates the pull request with Pipelines as Code configuration
+// and adds configured labels, reviewers and assignees to it.
+func createPaCPullRequest(ghclient *GithubClient, d *PaCPullRequestData) (*github.PullRequest, error) {
+	pr, err := ghclient.createPullRequestWithinRepository(d.Owner, d.Repository, d.Branch, d.BaseBranch, d.PRTitle, d.PRText, d.Draft)
+	if err != nil {
+		return nil, err
+	}
+	if err := ghclient.addPullRequestMetadata(d.Owner, d.Repository, pr, d.Labels, d.Reviewers, d.TeamReviewers, d.Assignees); err != nil {
+		return nil, err
 	}
+	return pr, nil
 }
 
 // undoPaCPullRequest creates a new pull request to remove PaC configuration for the component.
@@ -161,7 +203,7 @@ func undoPaCPullRequest(ghclient *GithubClient, d *PaCPullRequestData) (string,
 		d.BaseBranch = baseBranch
 	}
 
-	files, err := ghclient.filesExistInDirectory(d.Owner, d.Repository, d.BaseBranch, ".tekton", d.Files)
+	files, err := ghclient.filesExist(d.Owner, d.Repository, d.BaseBranch, d.Files)
 	if err != nil {
 		return "", err
 	}
@@ -194,17 +236,22 @@ func undoPaCPullRequest(ghclient *GithubClient, d *PaCPullRequestData) (string,
 		return "", err
 	}
 
-	return ghclient.createPullRequestWithinRepository(d.Owner, d.Repository, d.Branch, d.BaseBranch, d.PRTitle, d.PRText)
+	pr, err := createPaCPullRequest(ghclient, d)
+	if err != nil {
+		return "", err
+	}
+	return pr.GetHTMLURL(), nil
 }
 
-// SetupPaCWebhook creates or updates Pipelines as Code webhook configuration
-func setupPaCWebhook(ghclient *GithubClient, webhookUrl, webhookSecret, owner, repository string) error {
+// SetupPaCWebhook creates or updates Pipelines as Code webhook configuration.
+// Events are GitHub webhook event names.
+func setupPaCWebhook(ghclient *GithubClient, webhookUrl, webhookSecret, owner, repository string, sslVerification bool, events []string) error {
 	existingWebhook, err := ghclient.getWebhookByTargetUrl(owner, repository, webhookUrl)
 	if err != nil {
 		return err
 	}
 
-	defaultWebhook := getDefaultWebhookConfig(webhookUrl, webhookSecret)
+	defaultWebhook := getDefaultWebhookConfig(webhookUrl, webhookSecret, sslVerification, events)
 
 	if existingWebhook == nil {
 		// Webhook does not exist
@@ -220,22 +267,11 @@ func setupPaCWebhook(ghclient *GithubClient, webhookUrl, webhookSecret, owner, r
 	if existingWebhook.Config["content_type"] != webhookContentType {
 		existingWebhook.Config["content_type"] = webhookContentType
 	}
-	if existingWebhook.Config["insecure_ssl"] != "1" {
-		existingWebhook.Config["insecure_ssl"] = "1"
-	}
-
-	for _, requiredWebhookEvent := range appStudioPaCWebhookEvents {
-		requiredEventFound := false
-		for _, existingWebhookEvent := range existingWebhook.Events {
-			if existingWebhookEvent == requiredWebhookEvent {
-				requiredEventFound = true
-				break
-			}
-		}
-		if !requiredEventFound {
-			existingWebhook.Events = append(existingWebhook.Events, requiredWebhookEvent)
-		}
+	if existingWebhook.Config["insecure_ssl"] != defaultWebhook.Config["insecure_ssl"] {
+		existingWebhook.Config["insecure_ssl"] = defaultWebhook.Config["insecure_ssl"]
 	}
+	// The webhook is managed by build-service, so the subscribed events must match the configured ones
+	existingWebhook.Events = defaultWebhook.Events
 
 	if *existingWebhook.Active != *defaultWebhook.Active {
 		existingWebhook.Active = defaultWebhook.Active
@@ -245,14 +281,18 @@ func setupPaCWebhook(ghclient *GithubClient, webhookUrl, webhookSecret, owner, r
 	return err
 }
 
-func getDefaultWebhookConfig(webhookUrl, webhookSecret string) *github.Hook {
+func getDefaultWebhookConfig(webhookUrl, webhookSecret string, sslVerification bool, events []string) *github.Hook {
+	insecureSSL := "1"
+	if sslVerification {
+		insecureSSL = "0"
+	}
 	return &github.Hook{
-		Events: appStudioPaCWebhookEvents[:],
+		Events: events,
 		Config: map[string]interface{}{
 			"url":          webhookUrl,
 			"content_type": webhookContentType,
 			"secret":       webhookSecret,
-			"insecure_ssl": "1", // TODO make this field configurable and set defaults to 0
+			"insecure_ssl": insecureSSL,
 		},
 		Active: github.Bool(true),
 	}
@@ -283,8 +323,8 @@ func RefineGitHostingServiceError(response *http.Response, originErr error) erro
 	if response == nil {
 		return originErr
 	}
-	if _, ok := originErr.(*github.RateLimitError); ok {
-		return boerrors.NewBuildOpError(boerrors.EGitHubReachRateLimit, originErr)
+	if err := refineRateLimitError(originErr); err != originErr {
+		return err
 	}
 	switch response.StatusCode {
 	case http.StatusUnauthorized:
@@ -305,6 +345,28 @@ func RefineGitHostingServiceError(response *http.Response, originErr error) erro
 	}
 }
 
+// refineRateLimitError converts GitHub rate limit errors into errors that carry the time when the operation may be retried.
+// Other errors are returned as is.
+func refineRateLimitError(originErr error) error {
+	if _, isRateLimitError := boerrors.GetRetryAt(originErr); isRateLimitError {
+		return originErr
+	}
+	var rateLimitErr *github.RateLimitError
+	if errors.As(originErr, &rateLimitErr) {
+		return boerrors.NewRateLimitError(boerrors.EGitHubReachRateLimit, originErr, rateLimitErr.Rate.Reset.Time)
+	}
+	var abuseRateLimitErr *github.AbuseRateLimitError
+	if errors.As(originErr, &abuseRateLimitErr) {
+		// Secondary rate limit, GitHub might suggest how long to wait.
+		retryAfter := defaultSecondaryRateLimitRetryAfter
+		if abuseRateLimitErr.RetryAfter != nil {
+			retryAfter = *abuseRateLimitErr.RetryAfter
+		}
+		return boerrors.NewRateLimitError(boerrors.EGitHubReachRateLimit, originErr, time.Now().Add(retryAfter))
+	}
+	return originErr
+}
+
 func getDefaultBranch(client *GithubClient, owner string, repository string) (string, error) {
 	return client.getDefaultBranch(owner, repository)
 }
@@ -349,11 +411,10 @@ func deleteBranch(client *GithubClient, owner, repository, branch string) error
 }
 
 func GetBrowseRepositoryAtShaLink(repoUrl, sha string) string {
-	repoUrl = strings.TrimSuffix(repoUrl, ".git")
-	gitSourceUrlParts := strings.Split(repoUrl, "/")
-	gitProviderHost := "https://" + gitSourceUrlParts[2]
-	owner := gitSourceUrlParts[3]
-	repository := gitSourceUrlParts[4]
+	gitRepoUrl, err := gitrepourl.ParseGitRepoUrl(repoUrl)
+	if err != nil {
+		return ""
+	}
 
-	return fmt.Sprintf("%s/%s/%s?rev=%s", gitProviderHost, owner, repository, sha)
+	return fmt.Sprintf("%s?rev=%s", gitRepoUrl.GetWebUrl(), sha)
 }
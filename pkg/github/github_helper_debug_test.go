@@ -20,12 +20,15 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/google/go-github/v45/github"
 )
 
 // THIS FILE IS NOT UNIT TESTS
 // Put your own data below and comment out function override in the test to debug interactions with GitHub
 var (
-	repoUrl = "https://github.com/user/test-component-repository"
+	repoUrl   = "https://github.com/user/test-component-repository"
+	githubUrl = "https://github.com"
 	// Webhook
 	accessToken = "ghp_token"
 	// Application
@@ -35,18 +38,23 @@ var (
 
 var (
 	StubIsAppInstalledIntoRepository = func(g *GithubClient, owner, repository string) (bool, error) { return true, nil }
-	StubCreatePaCPullRequest         = func(g *GithubClient, d *PaCPullRequestData) (string, error) { return "", nil }
+	StubCreatePaCPullRequest         = func(g *GithubClient, d *PaCPullRequestData) (*github.PullRequest, error) { return nil, nil }
 	StubUndoPaCPullRequest           = func(g *GithubClient, d *PaCPullRequestData) (string, error) { return "", nil }
-	StubSetupPaCWebhook              = func(g *GithubClient, webhookUrl, webhookSecret, owner, repository string) error { return nil }
+	StubSetupPaCWebhook              = func(*GithubClient, string, string, string, string, bool, []string) error { return nil }
 	StubDeletePaCWebhook             = func(g *GithubClient, webhookUrl, owner, repository string) error { return nil }
 	StubGetBranchSHA                 = func(g *GithubClient, owner, repository, branch string) (string, error) { return "abcd", nil }
-	StubGetGitHubAppName             = func(appId int64, privateKeyPem []byte) (string, string, error) { return "appName", "app-slug", nil }
+	StubGetGitHubAppName             = func(appId int64, privateKeyPem []byte, githubUrl string) (string, string, error) {
+		return "appName", "app-slug", nil
+	}
 )
 
 func TestCreatePaCPullRequest(t *testing.T) {
 	CreatePaCPullRequest = StubCreatePaCPullRequest
 
-	ghclient := NewGithubClient(accessToken)
+	ghclient, err := NewGithubClient(accessToken, githubUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	pipelineOnPush := []byte("pipelineOnPush:\n  bundle: 'test-bundle-1'\n  when: 'on-push'\n")
 	pipelineOnPR := []byte("pipelineOnPR:\n  bundle: 'test-bundle-2'\n  when: 'on-pr'\n")
@@ -69,11 +77,11 @@ func TestCreatePaCPullRequest(t *testing.T) {
 		},
 	}
 
-	url, err := CreatePaCPullRequest(ghclient, prData)
+	pr, err := CreatePaCPullRequest(ghclient, prData)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if url != "" && !strings.HasPrefix(url, "http") {
+	if pr != nil && !strings.HasPrefix(pr.GetHTMLURL(), "http") {
 		t.Fatal("Pull Request URL must not be empty")
 	}
 }
@@ -95,7 +103,7 @@ func TestCreatePaCPullRequestViaGitHubApplication(t *testing.T) {
 		// Private key file by given path doesn't exist
 		return
 	}
-	ghclient, err := NewGithubClientByApp(githubAppId, []byte(githubAppPrivateKey), owner)
+	ghclient, err := NewGithubClientByApp(githubAppId, []byte(githubAppPrivateKey), owner, githubUrl)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -124,11 +132,11 @@ func TestCreatePaCPullRequestViaGitHubApplication(t *testing.T) {
 		},
 	}
 
-	url, err := CreatePaCPullRequest(ghclient, prData)
+	pr, err := CreatePaCPullRequest(ghclient, prData)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if url != "" && !strings.HasPrefix(url, "http") {
+	if pr != nil && !strings.HasPrefix(pr.GetHTMLURL(), "http") {
 		t.Fatal("Pull Request URL must not be empty")
 	}
 }
@@ -136,7 +144,10 @@ func TestCreatePaCPullRequestViaGitHubApplication(t *testing.T) {
 func TestUndoPaCPullRequest(t *testing.T) {
 	UndoPaCPullRequest = StubUndoPaCPullRequest
 
-	ghclient := NewGithubClient(accessToken)
+	ghclient, err := NewGithubClient(accessToken, githubUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	componentName := "unittest-component-name"
 	gitSourceUrlParts := strings.Split(repoUrl, "/")
@@ -171,13 +182,16 @@ func TestSetupPaCWebhook(t *testing.T) {
 	targetWebhookUrl := "https://pac.route.my-cluster.net"
 	webhookSecretString := "23f29e8f7fa8c58c1e8e50ecfbd49aec314f4908"
 
-	ghclient := NewGithubClient(accessToken)
+	ghclient, err := NewGithubClient(accessToken, githubUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	gitSourceUrlParts := strings.Split(repoUrl, "/")
 	owner := gitSourceUrlParts[3]
 	repository := gitSourceUrlParts[4]
 
-	err := SetupPaCWebhook(ghclient, targetWebhookUrl, webhookSecretString, owner, repository)
+	err = SetupPaCWebhook(ghclient, targetWebhookUrl, webhookSecretString, owner, repository, true, []string{"pull_request", "push"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -188,13 +202,16 @@ func TestDeletePaCWebhook(t *testing.T) {
 
 	targetWebhookUrl := "https://pac.route.my-cluster.net"
 
-	ghclient := NewGithubClient(accessToken)
+	ghclient, err := NewGithubClient(accessToken, githubUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	gitSourceUrlParts := strings.Split(repoUrl, "/")
 	owner := gitSourceUrlParts[3]
 	repository := gitSourceUrlParts[4]
 
-	err := DeletePaCWebhook(ghclient, targetWebhookUrl, owner, repository)
+	err = DeletePaCWebhook(ghclient, targetWebhookUrl, owner, repository)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -213,7 +230,7 @@ func TestGetBranchSHA(t *testing.T) {
 		// Private key file by given path doesn't exist
 		return
 	}
-	ghclient, err := NewGithubClientForSimpleBuildByApp(githubAppId, []byte(githubAppPrivateKey))
+	ghclient, err := NewGithubClientForSimpleBuildByApp(githubAppId, []byte(githubAppPrivateKey), githubUrl)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -236,7 +253,7 @@ func TestGetGitHubAppName(t *testing.T) {
 		return
 	}
 
-	appName, appSlug, err := GetGitHubAppName(githubAppId, githubAppPrivateKey)
+	appName, appSlug, err := GetGitHubAppName(githubAppId, githubAppPrivateKey, githubUrl)
 	if err != nil {
 		t.Fatal(err)
 	}
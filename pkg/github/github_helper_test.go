@@ -0,0 +1,157 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+func TestRefineGitHostingServiceErrorOnRateLimit(t *testing.T) {
+	resetAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	retryAfter := 2 * time.Minute
+	response := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}
+
+	tests := []struct {
+		name          string
+		originErr     error
+		wantRateLimit bool
+		wantRetryAt   time.Time
+	}{
+		{
+			name:          "should use rate limit reset time",
+			originErr:     &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: resetAt}}, Response: response},
+			wantRateLimit: true,
+			wantRetryAt:   resetAt,
+		},
+		{
+			name:          "should use secondary rate limit retry after",
+			originErr:     &github.AbuseRateLimitError{RetryAfter: &retryAfter, Response: response},
+			wantRateLimit: true,
+			wantRetryAt:   time.Now().Add(retryAfter),
+		},
+		{
+			name:          "should use default retry delay if secondary rate limit retry after is not set",
+			originErr:     &github.AbuseRateLimitError{Response: response},
+			wantRateLimit: true,
+			wantRetryAt:   time.Now().Add(defaultSecondaryRateLimitRetryAfter),
+		},
+		{
+			name:          "should not change other errors",
+			originErr:     fmt.Errorf("something went wrong"),
+			wantRateLimit: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RefineGitHostingServiceError(response, tt.originErr)
+			retryAt, isRateLimited := boerrors.GetRetryAt(err)
+			if isRateLimited != tt.wantRateLimit {
+				t.Fatalf("RefineGitHostingServiceError(): expected rate limit error: %t, got: %v", tt.wantRateLimit, err)
+			}
+			if !isRateLimited {
+				if err != tt.originErr {
+					t.Errorf("RefineGitHostingServiceError(): expected original error, got: %v", err)
+				}
+				return
+			}
+			expectedErr := boerrors.NewBuildOpError(boerrors.EGitHubReachRateLimit, nil)
+			if shortError := err.(*boerrors.BuildOpError).ShortError(); shortError != expectedErr.ShortError() {
+				t.Errorf("RefineGitHostingServiceError(): unexpected error: %s", shortError)
+			}
+			if diff := retryAt.Sub(tt.wantRetryAt); diff < -time.Second || diff > time.Second {
+				t.Errorf("RefineGitHostingServiceError(): got retry at %s, want %s", retryAt, tt.wantRetryAt)
+			}
+		})
+	}
+}
+
+func TestToGithubWebhookEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []string
+		want   []string
+	}{
+		{
+			name:   "should map default events",
+			events: gitprovider.DefaultWebhookEvents,
+			want:   []string{"pull_request", "push", "issue_comment", "commit_comment"},
+		},
+		{
+			name:   "should not duplicate push event",
+			events: []string{gitprovider.WebhookEventPush, gitprovider.WebhookEventTagPush},
+			want:   []string{"push"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toGithubWebhookEvents(tt.events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("toGithubWebhookEvents(): got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToMergeRequestState(t *testing.T) {
+	mergedAt := time.Now()
+	tests := []struct {
+		name        string
+		pullRequest *github.PullRequest
+		want        string
+	}{
+		{
+			name:        "should map open pull request",
+			pullRequest: &github.PullRequest{State: github.String("open")},
+			want:        gitprovider.MergeRequestStateOpen,
+		},
+		{
+			name:        "should map merged pull request",
+			pullRequest: &github.PullRequest{State: github.String("closed"), MergedAt: &mergedAt},
+			want:        gitprovider.MergeRequestStateMerged,
+		},
+		{
+			name:        "should map closed unmerged pull request",
+			pullRequest: &github.PullRequest{State: github.String("closed")},
+			want:        gitprovider.MergeRequestStateClosed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toMergeRequest(tt.pullRequest).State; got != tt.want {
+				t.Errorf("toMergeRequest(): got state %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDefaultWebhookConfig(t *testing.T) {
+	webhook := getDefaultWebhookConfig("https://pac.example.com", "secret", true, []string{"push"})
+	if webhook.Config["insecure_ssl"] != "0" {
+		t.Errorf("getDefaultWebhookConfig(): expected SSL verification to be enabled, got insecure_ssl: %v", webhook.Config["insecure_ssl"])
+	}
+	webhook = getDefaultWebhookConfig("https://pac.example.com", "secret", false, []string{"push"})
+	if webhook.Config["insecure_ssl"] != "1" {
+		t.Errorf("getDefaultWebhookConfig(): expected SSL verification to be disabled, got insecure_ssl: %v", webhook.Config["insecure_ssl"])
+	}
+}
@@ -18,29 +18,77 @@ package gitlab
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/ratelimit"
+	"github.com/redhat-appstudio/build-service/pkg/git/signing"
 	"github.com/xanzy/go-gitlab"
 )
 
 // Allow mocking for tests
-var NewGitlabClient func(accessToken string) (*GitlabClient, error) = newGitlabClient
+var NewGitlabClient func(accessToken, baseUrl string) (*GitlabClient, error) = newGitlabClient
 
 type GitlabClient struct {
 	client *gitlab.Client
+
+	// baseUrl and accessToken are used for git operations that are not available via API
+	baseUrl     string
+	accessToken string
+	// commitSigner is used to sign commits, if set
+	commitSigner signing.CommitSigner
 }
 
-func newGitlabClient(accessToken string) (*GitlabClient, error) {
+// newGitlabClient creates GitLab client for the instance with the given base URL, e.g. https://gitlab.com
+// API path suffix is added automatically if missing.
+func newGitlabClient(accessToken, baseUrl string) (*GitlabClient, error) {
 	glc := &GitlabClient{}
-	c, err := gitlab.NewClient(accessToken)
+	c, err := gitlab.NewClient(accessToken,
+		gitlab.WithBaseURL(baseUrl),
+		gitlab.WithHTTPClient(&http.Client{Transport: ratelimit.NewTransport(http.DefaultTransport, baseUrl, ratelimit.GetCredentialId(accessToken))}),
+		gitlab.WithCustomRetry(retryOnServerError),
+	)
 	if err != nil {
 		return nil, err
 	}
 	glc.client = c
+	glc.baseUrl = baseUrl
+	glc.accessToken = accessToken
 
 	return glc, nil
 }
 
+// SetCommitSigner makes the client sign commits it creates with the given signer.
+func (c *GitlabClient) SetCommitSigner(commitSigner signing.CommitSigner) {
+	c.commitSigner = commitSigner
+}
+
+// retryOnServerError retries requests on GitLab server errors only.
+// Unlike go-gitlab default, requests that hit rate limit are not retried,
+// so the reconcile is requeued instead of blocking until the rate limit is reset.
+func retryOnServerError(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	if err != nil {
+		return false, err
+	}
+	return resp.StatusCode >= 500, nil
+}
+
 func (c *GitlabClient) getBranch(projectPath, branchName string) (*gitlab.Branch, error) {
 	branch, resp, err := c.client.Branches.GetBranch(projectPath, branchName)
 	if err != nil {
@@ -88,16 +136,30 @@ func (c *GitlabClient) getDefaultBranch(projectPath string) (string, error) {
 	return projectInfo.DefaultBranch, nil
 }
 
+// getFileContent returns content of the given file in the given branch.
+// Returns nil if the file doesn't exist.
+func (c *GitlabClient) getFileContent(projectPath, branchName, filePath string) ([]byte, error) {
+	opts := &gitlab.GetRawFileOptions{
+		Ref: &branchName,
+	}
+	fileContent, resp, err := c.client.RepositoryFiles.GetRawFile(projectPath, filePath, opts)
+	if err != nil {
+		if resp == nil || resp.StatusCode != 404 {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return fileContent, nil
+}
+
 func (c *GitlabClient) filesUpToDate(projectPath, branchName string, files []File) (bool, error) {
 	for _, file := range files {
-		opts := &gitlab.GetRawFileOptions{
-			Ref: &branchName,
-		}
-		fileContent, resp, err := c.client.RepositoryFiles.GetRawFile(projectPath, file.FullPath, opts)
+		fileContent, err := c.getFileContent(projectPath, branchName, file.FullPath)
 		if err != nil {
-			if resp.StatusCode != 404 {
-				return false, err
-			}
+			return false, err
+		}
+		if fileContent == nil {
+			// Given file not found
 			return false, nil
 		}
 		if !bytes.Equal(fileContent, file.Content) {
@@ -107,6 +169,15 @@ func (c *GitlabClient) filesUpToDate(projectPath, branchName string, files []Fil
 	return true, nil
 }
 
+// filesExist checks if given files exist in the branch. The files could be located in different directories.
+// Returns subset of given files which exist.
+func (c *GitlabClient) filesExist(projectPath, branchName string, files []File) ([]File, error) {
+	return gitprovider.FilesExist(files, func(file File) string { return file.FullPath },
+		func(directoryPath string, directoryFiles []File) ([]File, error) {
+			return c.filesExistInDirectory(projectPath, branchName, directoryPath, directoryFiles)
+		})
+}
+
 // filesExistInDirectory checks if given files exist under specified directory.
 // Returns subset of given files which exist.
 func (c *GitlabClient) filesExistInDirectory(projectPath, branchName, directoryPath string, files []File) ([]File, error) {
@@ -138,6 +209,10 @@ func (c *GitlabClient) filesExistInDirectory(projectPath, branchName, directoryP
 }
 
 func (c *GitlabClient) commitFilesIntoBranch(projectPath, branchName, commitMessage, authorName, authorEmail string, files []File) error {
+	if c.commitSigner != nil {
+		return c.pushSignedCommit(projectPath, branchName, commitMessage, authorName, authorEmail, files, false)
+	}
+
 	actions := []*gitlab.CommitActionOptions{}
 	for _, file := range files {
 		filePath := file.FullPath
@@ -178,6 +253,10 @@ func (c *GitlabClient) commitFilesIntoBranch(projectPath, branchName, commitMess
 
 // Creates commit into specified branch that deletes given files.
 func (c *GitlabClient) addDeleteCommitToBranch(projectPath, branchName, authorName, authorEmail, commitMessage string, files []File) error {
+	if c.commitSigner != nil {
+		return c.pushSignedCommit(projectPath, branchName, commitMessage, authorName, authorEmail, files, true)
+	}
+
 	actions := []*gitlab.CommitActionOptions{}
 	fileActionType := gitlab.FileDelete
 	for _, file := range files {
@@ -199,6 +278,95 @@ func (c *GitlabClient) addDeleteCommitToBranch(projectPath, branchName, authorNa
 	return err
 }
 
+// pushSignedCommit creates a signed commit in the given branch and pushes it using git protocol,
+// because GitLab API doesn't allow to create commits with custom signature.
+// The branch is cloned into memory with depth 1, so no disk space is used.
+// If isDelete is set, given files are deleted from the branch, otherwise they are created or updated.
+func (c *GitlabClient) pushSignedCommit(projectPath, branchName, commitMessage, authorName, authorEmail string, files []File, isDelete bool) error {
+	auth := &githttp.BasicAuth{Username: "oauth2", Password: c.accessToken}
+	branchRef := plumbing.NewBranchReferenceName(branchName)
+
+	repo, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{
+		URL:           strings.TrimSuffix(c.baseUrl, "/") + "/" + projectPath + ".git",
+		Auth:          auth,
+		ReferenceName: branchRef,
+		SingleBranch:  true,
+		Depth:         1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clone %s branch of %s: %w", branchName, projectPath, err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if isDelete {
+			if _, err := worktree.Remove(file.FullPath); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", file.FullPath, err)
+			}
+			continue
+		}
+		if err := util.WriteFile(worktree.Filesystem, file.FullPath, file.Content, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.FullPath, err)
+		}
+		if _, err := worktree.Add(file.FullPath); err != nil {
+			return fmt.Errorf("failed to add %s: %w", file.FullPath, err)
+		}
+	}
+
+	author := &object.Signature{Name: authorName, Email: authorEmail, When: time.Now()}
+	commitHash, err := worktree.Commit(commitMessage, &git.CommitOptions{Author: author, Committer: author})
+	if err != nil {
+		return err
+	}
+	signedCommitHash, err := c.signCommit(repo, commitHash)
+	if err != nil {
+		return err
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(branchRef, signedCommitHash)); err != nil {
+		return err
+	}
+
+	refSpec := gitconfig.RefSpec(fmt.Sprintf("%s:%s", branchRef, branchRef))
+	if err := repo.Push(&git.PushOptions{Auth: auth, RefSpecs: []gitconfig.RefSpec{refSpec}}); err != nil {
+		return fmt.Errorf("failed to push into %s branch of %s: %w", branchName, projectPath, err)
+	}
+	return nil
+}
+
+// signCommit stores signed copy of the given commit and returns its hash.
+func (c *GitlabClient) signCommit(repo *git.Repository, commitHash plumbing.Hash) (plumbing.Hash, error) {
+	commit, err := repo.CommitObject(commitHash)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	unsignedCommit := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(unsignedCommit); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	reader, err := unsignedCommit.Reader()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	payload, err := io.ReadAll(reader)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	signature, err := c.commitSigner.Sign(payload)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to sign commit: %w", err)
+	}
+	commit.PGPSignature = signature
+
+	signedCommit := repo.Storer.NewEncodedObject()
+	if err := commit.Encode(signedCommit); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return repo.Storer.SetEncodedObject(signedCommit)
+}
+
 func (c *GitlabClient) diffNotEmpty(projectPath, branchName, baseBranchName string) (bool, error) {
 	straight := false
 	opts := &gitlab.CompareOptions{
@@ -215,12 +383,10 @@ func (c *GitlabClient) diffNotEmpty(projectPath, branchName, baseBranchName stri
 
 func (c *GitlabClient) findMergeRequestByBranches(projectPath, branch, targetBranch string) (*gitlab.MergeRequest, error) {
 	openedState := "opened"
-	viewType := "simple"
 	opts := &gitlab.ListProjectMergeRequestsOptions{
 		State:        &openedState,
 		SourceBranch: &branch,
 		TargetBranch: &targetBranch,
-		View:         &viewType,
 		ListOptions:  gitlab.ListOptions{PerPage: 100},
 	}
 	mrs, _, err := c.client.MergeRequests.ListProjectMergeRequests(projectPath, opts)
@@ -237,18 +403,141 @@ func (c *GitlabClient) findMergeRequestByBranches(projectPath, branch, targetBra
 	}
 }
 
-func (c *GitlabClient) createMergeRequestWithinRepository(projectPath, branchName, baseBranchName, mrTitle, mrText string) (string, error) {
+func (c *GitlabClient) getMergeRequest(projectPath string, mrIid int) (*gitlab.MergeRequest, error) {
+	mr, resp, err := c.client.MergeRequests.GetMergeRequest(projectPath, mrIid, nil)
+	if err != nil {
+		return nil, RefineGitHostingServiceError(resp.Response, err)
+	}
+	return mr, nil
+}
+
+func (c *GitlabClient) reopenMergeRequest(projectPath string, mrIid int) (*gitlab.MergeRequest, error) {
+	opts := &gitlab.UpdateMergeRequestOptions{
+		StateEvent: gitlab.String("reopen"),
+	}
+	mr, resp, err := c.client.MergeRequests.UpdateMergeRequest(projectPath, mrIid, opts)
+	if err != nil {
+		return nil, RefineGitHostingServiceError(resp.Response, err)
+	}
+	return mr, nil
+}
+
+func (c *GitlabClient) createMergeRequestWithinRepository(projectPath, branchName, baseBranchName, mrTitle, mrText string, labels []string, reviewerIds, assigneeIds []int) (*gitlab.MergeRequest, error) {
 	opts := &gitlab.CreateMergeRequestOptions{
 		SourceBranch: &branchName,
 		TargetBranch: &baseBranchName,
 		Title:        &mrTitle,
 		Description:  &mrText,
 	}
+	if len(labels) > 0 {
+		opts.Labels = (*gitlab.Labels)(&labels)
+	}
+	if len(reviewerIds) > 0 {
+		opts.ReviewerIDs = &reviewerIds
+	}
+	if len(assigneeIds) > 0 {
+		opts.AssigneeIDs = &assigneeIds
+	}
 	mr, _, err := c.client.MergeRequests.CreateMergeRequest(projectPath, opts)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return mr, nil
+}
+
+// addMergeRequestMetadata adds given labels, reviewers and assignees to the merge request.
+// The merge request is updated only if it lacks some of them.
+func (c *GitlabClient) addMergeRequestMetadata(projectPath string, mr *gitlab.MergeRequest, labels []string, reviewerIds, assigneeIds []int) error {
+	opts := &gitlab.UpdateMergeRequestOptions{}
+	isUpdateNeeded := false
+
+	var labelsToAdd []string
+	for _, label := range labels {
+		if !containsString(mr.Labels, label) {
+			labelsToAdd = append(labelsToAdd, label)
+		}
+	}
+	if len(labelsToAdd) > 0 {
+		opts.AddLabels = (*gitlab.Labels)(&labelsToAdd)
+		isUpdateNeeded = true
+	}
+
+	// Reviewers and assignees are replaced on update, so keep the existing ones
+	if mergedReviewerIds, isChanged := mergeUserIds(mr.Reviewers, reviewerIds); isChanged {
+		opts.ReviewerIDs = &mergedReviewerIds
+		isUpdateNeeded = true
 	}
-	return mr.WebURL, nil
+	if mergedAssigneeIds, isChanged := mergeUserIds(mr.Assignees, assigneeIds); isChanged {
+		opts.AssigneeIDs = &mergedAssigneeIds
+		isUpdateNeeded = true
+	}
+
+	if !isUpdateNeeded {
+		return nil
+	}
+	_, _, err := c.client.MergeRequests.UpdateMergeRequest(projectPath, mr.IID, opts)
+	return err
+}
+
+// setMergeWhenPipelineSucceeds makes GitLab merge the merge request when its pipeline succeeds.
+// GitLab uses the merge method configured for the project, only squashing can be set per merge request.
+func (c *GitlabClient) setMergeWhenPipelineSucceeds(projectPath string, mrIid int, squash bool) error {
+	opts := &gitlab.AcceptMergeRequestOptions{
+		MergeWhenPipelineSucceeds: gitlab.Bool(true),
+		Squash:                    gitlab.Bool(squash),
+	}
+	_, _, err := c.client.MergeRequests.AcceptMergeRequest(projectPath, mrIid, opts)
+	return err
+}
+
+// getUserIds converts GitLab user names into user IDs.
+func (c *GitlabClient) getUserIds(usernames []string) ([]int, error) {
+	var userIds []int
+	for _, username := range usernames {
+		username := username
+		users, _, err := c.client.Users.ListUsers(&gitlab.ListUsersOptions{Username: &username})
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("GitLab user %s not found", username)
+		}
+		userIds = append(userIds, users[0].ID)
+	}
+	return userIds, nil
+}
+
+func containsString(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeUserIds returns IDs of the existing users extended with the given user IDs
+// and whether any of the given users is new.
+func mergeUserIds(existingUsers []*gitlab.BasicUser, userIds []int) ([]int, bool) {
+	mergedUserIds := make([]int, 0, len(existingUsers)+len(userIds))
+	for _, user := range existingUsers {
+		mergedUserIds = append(mergedUserIds, user.ID)
+	}
+	isChanged := false
+	for _, userId := range userIds {
+		isExisting := false
+		for _, mergedUserId := range mergedUserIds {
+			if mergedUserId == userId {
+				isExisting = true
+				break
+			}
+		}
+		if !isExisting {
+			mergedUserIds = append(mergedUserIds, userId)
+			isChanged = true
+		}
+	}
+	return mergedUserIds, isChanged
 }
 
 func (c *GitlabClient) getWebhookByTargetUrl(projectPath, webhookTargetUrl string) (*gitlab.ProjectHook, error) {
@@ -266,14 +555,14 @@ func (c *GitlabClient) getWebhookByTargetUrl(projectPath, webhookTargetUrl strin
 	return nil, nil
 }
 
-func (c *GitlabClient) createPaCWebhook(projectPath, webhookTargetUrl, webhookSecret string) (*gitlab.ProjectHook, error) {
-	opts := getPaCWebhookOpts(webhookTargetUrl, webhookSecret)
+func (c *GitlabClient) createPaCWebhook(projectPath, webhookTargetUrl, webhookSecret string, webhookOptions *PaCWebhookOptions) (*gitlab.ProjectHook, error) {
+	opts := getPaCWebhookOpts(webhookTargetUrl, webhookSecret, webhookOptions)
 	hook, resp, err := c.client.Projects.AddProjectHook(projectPath, opts)
 	return hook, RefineGitHostingServiceError(resp.Response, err)
 }
 
-func (c *GitlabClient) updatePaCWebhook(projectPath string, webhookId int, webhookTargetUrl, webhookSecret string) (*gitlab.ProjectHook, error) {
-	opts := gitlab.EditProjectHookOptions(*getPaCWebhookOpts(webhookTargetUrl, webhookSecret))
+func (c *GitlabClient) updatePaCWebhook(projectPath string, webhookId int, webhookTargetUrl, webhookSecret string, webhookOptions *PaCWebhookOptions) (*gitlab.ProjectHook, error) {
+	opts := gitlab.EditProjectHookOptions(*getPaCWebhookOpts(webhookTargetUrl, webhookSecret, webhookOptions))
 	hook, resp, err := c.client.Projects.EditProjectHook(projectPath, webhookId, &opts)
 	return hook, RefineGitHostingServiceError(resp.Response, err)
 }
@@ -286,19 +575,15 @@ func (c *GitlabClient) deleteWebhook(projectPath string, webhookId int) error {
 	return RefineGitHostingServiceError(resp.Response, err)
 }
 
-func getPaCWebhookOpts(webhookTargetUrl, webhookSecret string) *gitlab.AddProjectHookOptions {
-	enableSSLVerification := false
-
-	mergeRequestsEvents := true
-	pushEvents := true
-	noteEvents := true
-
+// getPaCWebhookOpts returns all managed webhook settings, so updating existing webhook resets it to the desired state.
+func getPaCWebhookOpts(webhookTargetUrl, webhookSecret string, webhookOptions *PaCWebhookOptions) *gitlab.AddProjectHookOptions {
 	return &gitlab.AddProjectHookOptions{
 		URL:                   &webhookTargetUrl,
 		Token:                 &webhookSecret,
-		EnableSSLVerification: &enableSSLVerification,
-		MergeRequestsEvents:   &mergeRequestsEvents,
-		PushEvents:            &pushEvents,
-		NoteEvents:            &noteEvents,
+		EnableSSLVerification: gitlab.Bool(webhookOptions.EnableSSLVerification),
+		MergeRequestsEvents:   gitlab.Bool(webhookOptions.MergeRequestsEvents),
+		PushEvents:            gitlab.Bool(webhookOptions.PushEvents),
+		TagPushEvents:         gitlab.Bool(webhookOptions.TagPushEvents),
+		NoteEvents:            gitlab.Bool(webhookOptions.NoteEvents),
 	}
 }
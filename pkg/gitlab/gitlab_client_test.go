@@ -0,0 +1,134 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/xanzy/go-gitlab"
+)
+
+type fakeCommitSigner struct{}
+
+func (s *fakeCommitSigner) Sign(payload []byte) (string, error) {
+	return "-----BEGIN SSH SIGNATURE-----\nc2lnbmF0dXJl\n-----END SSH SIGNATURE-----\n", nil
+}
+
+func TestPushSignedCommit(t *testing.T) {
+	baseDir := t.TempDir()
+	projectPath := "group/subgroup/repository"
+	remoteDir := filepath.Join(baseDir, projectPath+".git")
+	if _, err := git.PlainInit(remoteDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create the branch with an initial commit in the remote repository
+	localRepo, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, _ := localRepo.Worktree()
+	if err := util.WriteFile(worktree.Filesystem, "README.md", []byte("readme"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add("README.md"); err != nil {
+		t.Fatal(err)
+	}
+	author := &object.Signature{Name: "user", Email: "user@example.com", When: time.Now()}
+	if _, err := worktree.Commit("Initial commit", &git.CommitOptions{Author: author}); err != nil {
+		t.Fatal(err)
+	}
+	head, _ := localRepo.Head()
+	if err := localRepo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("appstudio-test"), head.Hash())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := localRepo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remoteDir}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := localRepo.Push(&git.PushOptions{RefSpecs: []gitconfig.RefSpec{"refs/heads/appstudio-test:refs/heads/appstudio-test"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	glclient := &GitlabClient{baseUrl: "file://" + baseDir, commitSigner: &fakeCommitSigner{}}
+	files := []File{{FullPath: ".tekton/component-push.yaml", Content: []byte("kind: PipelineRun")}}
+	if err := glclient.pushSignedCommit(projectPath, "appstudio-test", "Appstudio update component", "redhat-appstudio", "rhtap@redhat.com", files, false); err != nil {
+		t.Fatalf("pushSignedCommit(): unexpected error: %v", err)
+	}
+
+	remoteRepo, _ := git.PlainOpen(remoteDir)
+	branchRef, err := remoteRepo.Reference(plumbing.NewBranchReferenceName("appstudio-test"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := remoteRepo.CommitObject(branchRef.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Message != "Appstudio update component" || commit.Author.Name != "redhat-appstudio" {
+		t.Errorf("pushSignedCommit(): unexpected commit %s by %s", commit.Message, commit.Author.Name)
+	}
+	if !strings.Contains(commit.PGPSignature, "BEGIN SSH SIGNATURE") {
+		t.Errorf("pushSignedCommit(): commit is not signed")
+	}
+	if _, err := commit.File(".tekton/component-push.yaml"); err != nil {
+		t.Errorf("pushSignedCommit(): file is not committed: %v", err)
+	}
+	if commit.NumParents() != 1 || commit.ParentHashes[0] != head.Hash() {
+		t.Errorf("pushSignedCommit(): commit is not attached to the branch")
+	}
+
+	// Delete a file
+	if err := glclient.pushSignedCommit(projectPath, "appstudio-test", "Appstudio purge component", "redhat-appstudio", "rhtap@redhat.com", files, true); err != nil {
+		t.Fatalf("pushSignedCommit(): unexpected error: %v", err)
+	}
+	branchRef, _ = remoteRepo.Reference(plumbing.NewBranchReferenceName("appstudio-test"), true)
+	commit, err = remoteRepo.CommitObject(branchRef.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := commit.File(".tekton/component-push.yaml"); err == nil {
+		t.Errorf("pushSignedCommit(): file is not deleted")
+	}
+	if _, err := commit.File("README.md"); err != nil {
+		t.Errorf("pushSignedCommit(): other files must be kept: %v", err)
+	}
+}
+
+func TestMergeUserIds(t *testing.T) {
+	existingUsers := []*gitlab.BasicUser{{ID: 1}, {ID: 2}}
+
+	mergedUserIds, isChanged := mergeUserIds(existingUsers, []int{2, 3})
+	if !isChanged || !reflect.DeepEqual(mergedUserIds, []int{1, 2, 3}) {
+		t.Errorf("mergeUserIds(): got %v, %t, want [1 2 3], true", mergedUserIds, isChanged)
+	}
+
+	mergedUserIds, isChanged = mergeUserIds(existingUsers, []int{1})
+	if isChanged || !reflect.DeepEqual(mergedUserIds, []int{1, 2}) {
+		t.Errorf("mergeUserIds(): got %v, %t, want [1 2], false", mergedUserIds, isChanged)
+	}
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"fmt"
+
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ gitprovider.GitProvider = (*GitlabClient)(nil)
+
+func (g *GitlabClient) EnsurePaCMergeRequest(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+	_, projectPath, err := GetBaseUrlAndProjectPath(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+	mr, err := EnsurePaCMergeRequest(g, toPaCMergeRequestData(projectPath, d))
+	if err != nil {
+		return nil, refineRateLimitError(err)
+	}
+	if mr == nil {
+		return nil, nil
+	}
+	return toMergeRequest(mr), nil
+}
+
+func (g *GitlabClient) UndoPaCMergeRequest(repoUrl string, d *gitprovider.MergeRequestData) (string, error) {
+	_, projectPath, err := GetBaseUrlAndProjectPath(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	mrUrl, err := UndoPaCMergeRequest(g, toPaCMergeRequestData(projectPath, d))
+	return mrUrl, refineRateLimitError(err)
+}
+
+func (g *GitlabClient) FindUnmergedPaCMergeRequest(repoUrl string, d *gitprovider.MergeRequestData) (*gitprovider.MergeRequest, error) {
+	_, projectPath, err := GetBaseUrlAndProjectPath(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	mr, err := FindUnmergedOnboardingMergeRequest(g, projectPath, d.BranchName, d.BaseBranchName, d.AuthorName)
+	if err != nil {
+		return nil, refineRateLimitError(err)
+	}
+	if mr == nil {
+		return nil, nil
+	}
+	return toMergeRequest(mr), nil
+}
+
+func (g *GitlabClient) GetMergeRequest(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+	_, projectPath, err := GetBaseUrlAndProjectPath(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	mr, err := g.getMergeRequest(projectPath, int(mrNumber))
+	if err != nil {
+		return nil, refineRateLimitError(err)
+	}
+	return toMergeRequest(mr), nil
+}
+
+func (g *GitlabClient) ReopenMergeRequest(repoUrl string, mrNumber int64) (*gitprovider.MergeRequest, error) {
+	_, projectPath, err := GetBaseUrlAndProjectPath(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	mr, err := g.reopenMergeRequest(projectPath, int(mrNumber))
+	if err != nil {
+		return nil, refineRateLimitError(err)
+	}
+	return toMergeRequest(mr), nil
+}
+
+func (g *GitlabClient) EnablePaCMergeRequestAutoMerge(repoUrl string, mrNumber int64, mergeMethod string) error {
+	_, projectPath, err := GetBaseUrlAndProjectPath(repoUrl)
+	if err != nil {
+		return err
+	}
+
+	var squash bool
+	switch mergeMethod {
+	case gitprovider.MergeMethodMerge, "":
+		squash = false
+	case gitprovider.MergeMethodSquash:
+		squash = true
+	default:
+		return fmt.Errorf("merge method %s is not supported by GitLab per merge request, configure it in the project settings", mergeMethod)
+	}
+	return refineRateLimitError(g.setMergeWhenPipelineSucceeds(projectPath, int(mrNumber), squash))
+}
+
+func (g *GitlabClient) SetupPaCWebhook(repoUrl string, webhookUrl string, webhookSecret string, webhookOptions *gitprovider.WebhookOptions) error {
+	_, projectPath, err := GetBaseUrlAndProjectPath(repoUrl)
+	if err != nil {
+		return err
+	}
+	return refineRateLimitError(SetupPaCWebhook(g, projectPath, webhookUrl, webhookSecret, toPaCWebhookOptions(webhookOptions)))
+}
+
+func (g *GitlabClient) DeletePaCWebhook(repoUrl string, webhookUrl string) error {
+	_, projectPath, err := GetBaseUrlAndProjectPath(repoUrl)
+	if err != nil {
+		return err
+	}
+	return refineRateLimitError(DeletePaCWebhook(g, projectPath, webhookUrl))
+}
+
+func (g *GitlabClient) GetDefaultBranch(repoUrl string) (string, error) {
+	_, projectPath, err := GetBaseUrlAndProjectPath(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	defaultBranch, err := GetDefaultBranch(g, projectPath)
+	return defaultBranch, refineRateLimitError(err)
+}
+
+func (g *GitlabClient) DeleteBranch(repoUrl string, branchName string) (bool, error) {
+	_, projectPath, err := GetBaseUrlAndProjectPath(repoUrl)
+	if err != nil {
+		return false, err
+	}
+
+	err = DeleteBranch(g, projectPath, branchName)
+	if err == nil {
+		return true, nil
+	}
+	if glErrResp, ok := err.(*gitlab.ErrorResponse); ok && glErrResp.Response != nil {
+		if glErrResp.Response.StatusCode == 404 {
+			return false, nil
+		}
+	}
+	return false, refineRateLimitError(err)
+}
+
+func (g *GitlabClient) GetBranchSha(repoUrl string, branchName string) (string, error) {
+	_, projectPath, err := GetBaseUrlAndProjectPath(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	sha, err := GetBranchSHA(g, projectPath, branchName)
+	return sha, refineRateLimitError(err)
+}
+
+func (g *GitlabClient) DownloadFileContent(repoUrl string, branchName string, filePath string) ([]byte, error) {
+	_, projectPath, err := GetBaseUrlAndProjectPath(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+	content, err := g.getFileContent(projectPath, branchName, filePath)
+	return content, refineRateLimitError(err)
+}
+
+func (g *GitlabClient) GetBrowseRepositoryAtShaLink(repoUrl string, sha string) string {
+	return GetBrowseRepositoryAtShaLink(repoUrl, sha)
+}
+
+func (g *GitlabClient) GetConfiguredGitAppName() (string, string, error) {
+	return "", "", fmt.Errorf("GitLab application is not supported")
+}
+
+// toPaCWebhookOptions maps git provider independent webhook events to GitLab project hook events.
+func toMergeRequest(mr *gitlab.MergeRequest) *gitprovider.MergeRequest {
+	var state string
+	switch mr.State {
+	case "merged":
+		state = gitprovider.MergeRequestStateMerged
+	case "closed":
+		state = gitprovider.MergeRequestStateClosed
+	default:
+		// opened or locked
+		state = gitprovider.MergeRequestStateOpen
+	}
+	return &gitprovider.MergeRequest{
+		Number:    int64(mr.IID),
+		WebUrl:    mr.WebURL,
+		Title:     mr.Title,
+		CreatedAt: mr.CreatedAt,
+		State:     state,
+	}
+}
+
+func toPaCWebhookOptions(webhookOptions *gitprovider.WebhookOptions) *PaCWebhookOptions {
+	options := &PaCWebhookOptions{EnableSSLVerification: webhookOptions.SSLVerification}
+	for _, event := range webhookOptions.Events {
+		switch event {
+		case gitprovider.WebhookEventPullRequest:
+			options.MergeRequestsEvents = true
+		case gitprovider.WebhookEventPush:
+			options.PushEvents = true
+		case gitprovider.WebhookEventTagPush:
+			options.TagPushEvents = true
+		case gitprovider.WebhookEventComment:
+			options.NoteEvents = true
+		}
+	}
+	return options
+}
+
+func toPaCMergeRequestData(projectPath string, d *gitprovider.MergeRequestData) *PaCMergeRequestData {
+	var files []File
+	for _, file := range d.Files {
+		files = append(files, File{FullPath: file.FullPath, Content: file.Content})
+	}
+	var deletedFiles []File
+	for _, file := range d.DeletedFiles {
+		deletedFiles = append(deletedFiles, File{FullPath: file.FullPath})
+	}
+	return &PaCMergeRequestData{
+		ProjectPath:   projectPath,
+		CommitMessage: d.CommitMessage,
+		Branch:        d.BranchName,
+		BaseBranch:    d.BaseBranchName,
+		MrTitle:       d.Title,
+		MrText:        d.Text,
+		AuthorName:    d.AuthorName,
+		AuthorEmail:   d.AuthorEmail,
+		Files:         files,
+		DeletedFiles:  deletedFiles,
+		Labels:        d.Labels,
+		Reviewers:     d.Reviewers,
+		Assignees:     d.Assignees,
+		Draft:         d.Draft,
+	}
+}
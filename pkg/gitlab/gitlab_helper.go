@@ -17,23 +17,39 @@ limitations under the License.
 package gitlab
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitrepourl"
 	"github.com/xanzy/go-gitlab"
 )
 
 // Allow mocking for tests
-var EnsurePaCMergeRequest func(g *GitlabClient, d *PaCMergeRequestData) (string, error) = ensurePaCMergeRequest
+var EnsurePaCMergeRequest func(g *GitlabClient, d *PaCMergeRequestData) (*gitlab.MergeRequest, error) = ensurePaCMergeRequest
 var UndoPaCMergeRequest func(g *GitlabClient, d *PaCMergeRequestData) (string, error) = undoPaCMergeRequest
-var SetupPaCWebhook func(g *GitlabClient, projectPath, webhookUrl, webhookSecret string) error = setupPaCWebhook
+var SetupPaCWebhook func(g *GitlabClient, projectPath, webhookUrl, webhookSecret string, webhookOptions *PaCWebhookOptions) error = setupPaCWebhook
 var DeletePaCWebhook func(g *GitlabClient, projectPath, webhookUrl string) error = deletePaCWebhook
 var GetDefaultBranch func(*GitlabClient, string) (string, error) = getDefaultBranch
 var FindUnmergedOnboardingMergeRequest func(*GitlabClient, string, string, string, string) (*gitlab.MergeRequest, error) = findUnmergedOnboardingMergeRequest
 var DeleteBranch func(*GitlabClient, string, string) error = deleteBranch
 
+// GitlabBaseUrlsEnvName is the name of environment variable that contains mapping of self-hosted GitLab hosts
+// to base URLs of the instances in the following format: gitlab.mycompany.com=https://gitlab.mycompany.com/gitlab,...
+// Only gitlab.com and the self-hosted instances listed in the mapping are allowed to receive credentials.
+const GitlabBaseUrlsEnvName = "GITLAB_BASE_URLS"
+
+const gitlabComHost = "gitlab.com"
+
+// defaultRateLimitRetryAfter is used when GitLab doesn't tell when to retry after hitting rate limit
+const defaultRateLimitRetryAfter = time.Minute
+
 type File struct {
 	FullPath string
 	Content  []byte
@@ -49,84 +65,119 @@ type PaCMergeRequestData struct {
 	AuthorName    string
 	AuthorEmail   string
 	Files         []File
+	// DeletedFiles are removed from the branch if it already exists
+	DeletedFiles []File
+	Labels       []string
+	Reviewers    []string
+	Assignees    []string
+	Draft        bool
+}
+
+// PaCWebhookOptions are GitLab project hook settings managed by build-service
+type PaCWebhookOptions struct {
+	EnableSSLVerification bool
+	MergeRequestsEvents   bool
+	PushEvents            bool
+	TagPushEvents         bool
+	NoteEvents            bool
 }
 
-// ensurePaCMergeRequest creates a new merge request and returns its web URL
-func ensurePaCMergeRequest(glclient *GitlabClient, d *PaCMergeRequestData) (string, error) {
+// ensurePaCMergeRequest creates a new merge request or updates existing (if needed) and returns it.
+// If there is no error and the merge request is nil, it means that the MR is not needed (main branch is up to date).
+func ensurePaCMergeRequest(glclient *GitlabClient, d *PaCMergeRequestData) (*gitlab.MergeRequest, error) {
 	// Fallback to the default branch if base branch is not set
 	if d.BaseBranch == "" {
 		baseBranch, err := glclient.getDefaultBranch(d.ProjectPath)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		d.BaseBranch = baseBranch
 	}
 
 	pacConfigurationUpToDate, err := glclient.filesUpToDate(d.ProjectPath, d.BaseBranch, d.Files)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if pacConfigurationUpToDate {
 		// Nothing to do, the configuration is alredy in the main branch of the repository
-		return "", nil
+		return nil, nil
 	}
 
 	mrBranchExists, err := glclient.branchExist(d.ProjectPath, d.Branch)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if mrBranchExists {
 		mrBranchUpToDate, err := glclient.filesUpToDate(d.ProjectPath, d.Branch, d.Files)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		if !mrBranchUpToDate {
 			err := glclient.commitFilesIntoBranch(d.ProjectPath, d.Branch, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files)
 			if err != nil {
-				return "", err
+				return nil, err
+			}
+		}
+
+		// Remove files which are not proposed anymore
+		deletedFiles, err := glclient.filesExist(d.ProjectPath, d.Branch, d.DeletedFiles)
+		if err != nil {
+			return nil, err
+		}
+		if len(deletedFiles) > 0 {
+			err := glclient.addDeleteCommitToBranch(d.ProjectPath, d.Branch, d.AuthorName, d.AuthorEmail, d.CommitMessage, deletedFiles)
+			if err != nil {
+				return nil, err
 			}
 		}
 
 		mr, err := glclient.findMergeRequestByBranches(d.ProjectPath, d.Branch, d.BaseBranch)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		if mr != nil {
 			// Merge request already exists
-			return mr.WebURL, nil
+			reviewerIds, assigneeIds, err := getReviewerAndAssigneeIds(glclient, d)
+			if err != nil {
+				return nil, err
+			}
+			if err := glclient.addMergeRequestMetadata(d.ProjectPath, mr, d.Labels, reviewerIds, assigneeIds); err != nil {
+				return nil, err
+			}
+			return mr, nil
 		}
 
 		diffExists, err := glclient.diffNotEmpty(d.ProjectPath, d.Branch, d.BaseBranch)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		if !diffExists {
 			// This situation occurs if an MR was merged but the branch was not deleted and main is changed after the merge.
 			// Despite the fact that there is actual diff between branches, git treats it as no diff,
 			// because the branch is already "included" in main.
 			if err := glclient.deleteBranch(d.ProjectPath, d.Branch); err != nil {
-				return "", err
+				return nil, err
 			}
 			return ensurePaCMergeRequest(glclient, d)
 		}
 
-		return glclient.createMergeRequestWithinRepository(d.ProjectPath, d.Branch, d.BaseBranch, d.MrTitle, d.MrText)
+		return createPaCMergeRequest(glclient, d)
 
 	} else {
 
 		// Need to create branch and MR with Pipelines as Code configuration
 		err = glclient.createBranch(d.ProjectPath, d.Branch, d.BaseBranch)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		err = glclient.commitFilesIntoBranch(d.ProjectPath, d.Branch, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
-		return glclient.createMergeRequestWithinRepository(d.ProjectPath, d.Branch, d.BaseBranch, d.MrTitle, d.MrText)
+		return createPaCMergeRequest(glclient, d)
 	}
 }
 
@@ -140,7 +191,7 @@ func undoPaCMergeRequest(glclient *GitlabClient, d *PaCMergeRequestData) (string
 		d.BaseBranch = baseBranch
 	}
 
-	files, err := glclient.filesExistInDirectory(d.ProjectPath, d.BaseBranch, ".tekton", d.Files)
+	files, err := glclient.filesExist(d.ProjectPath, d.BaseBranch, d.Files)
 	if err != nil {
 		return "", err
 	}
@@ -172,21 +223,51 @@ func undoPaCMergeRequest(glclient *GitlabClient, d *PaCMergeRequestData) (string
 		return "", err
 	}
 
-	return glclient.createMergeRequestWithinRepository(d.ProjectPath, d.Branch, d.BaseBranch, d.MrTitle, d.MrText)
+	mr, err := createPaCMergeRequest(glclient, d)
+	if err != nil {
+		return "", err
+	}
+	return mr.WebURL, nil
+}
+
+func getReviewerAndAssigneeIds(glclient *GitlabClient, d *PaCMergeRequestData) ([]int, []int, error) {
+	reviewerIds, err := glclient.getUserIds(d.Reviewers)
+	if err != nil {
+		return nil, nil, err
+	}
+	assigneeIds, err := glclient.getUserIds(d.Assignees)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reviewerIds, assigneeIds, nil
+}
+
+// createPaCMergeRequest creates the merge request with configured labels, reviewers and assignees.
+func createPaCMergeRequest(glclient *GitlabClient, d *PaCMergeRequestData) (*gitlab.MergeRequest, error) {
+	reviewerIds, assigneeIds, err := getReviewerAndAssigneeIds(glclient, d)
+	if err != nil {
+		return nil, err
+	}
+	mrTitle := d.MrTitle
+	if d.Draft {
+		// GitLab marks merge requests with the title prefix as draft
+		mrTitle = "Draft: " + mrTitle
+	}
+	return glclient.createMergeRequestWithinRepository(d.ProjectPath, d.Branch, d.BaseBranch, mrTitle, d.MrText, d.Labels, reviewerIds, assigneeIds)
 }
 
-func setupPaCWebhook(glclient *GitlabClient, projectPath, webhookUrl, webhookSecret string) error {
+func setupPaCWebhook(glclient *GitlabClient, projectPath, webhookUrl, webhookSecret string, webhookOptions *PaCWebhookOptions) error {
 	existingWebhook, err := glclient.getWebhookByTargetUrl(projectPath, webhookUrl)
 	if err != nil {
 		return err
 	}
 
 	if existingWebhook == nil {
-		_, err = glclient.createPaCWebhook(projectPath, webhookUrl, webhookSecret)
+		_, err = glclient.createPaCWebhook(projectPath, webhookUrl, webhookSecret, webhookOptions)
 		return err
 	}
 
-	_, err = glclient.updatePaCWebhook(projectPath, existingWebhook.ID, webhookUrl, webhookSecret)
+	_, err = glclient.updatePaCWebhook(projectPath, existingWebhook.ID, webhookUrl, webhookSecret, webhookOptions)
 	return err
 }
 
@@ -217,6 +298,8 @@ func RefineGitHostingServiceError(response *http.Response, originErr error) erro
 		return boerrors.NewBuildOpError(boerrors.EGitLabTokenUnauthorized, originErr)
 	case 403:
 		return boerrors.NewBuildOpError(boerrors.EGitLabTokenInsufficientScope, originErr)
+	case 429:
+		return boerrors.NewRateLimitError(boerrors.EGitLabReachRateLimit, originErr, getRateLimitRetryAt(response))
 	default:
 		return originErr
 	}
@@ -248,10 +331,12 @@ func GetBranchSHA(client *GitlabClient, projectPath, branchName string) (string,
 func findUnmergedOnboardingMergeRequest(
 	glclient *GitlabClient, projectPath, sourceBranch, baseBranch, authorName string) (*gitlab.MergeRequest, error) {
 	opts := &gitlab.ListProjectMergeRequestsOptions{
-		State:          gitlab.String("opened"),
-		AuthorUsername: gitlab.String(authorName),
-		SourceBranch:   gitlab.String(sourceBranch),
-		TargetBranch:   gitlab.String(baseBranch),
+		State:        gitlab.String("opened"),
+		SourceBranch: gitlab.String(sourceBranch),
+		TargetBranch: gitlab.String(baseBranch),
+	}
+	if authorName != "" {
+		opts.AuthorUsername = gitlab.String(authorName)
 	}
 	mrs, resp, err := glclient.client.MergeRequests.ListProjectMergeRequests(projectPath, opts)
 	if err != nil {
@@ -271,12 +356,112 @@ func deleteBranch(glclient *GitlabClient, projectPath, branch string) error {
 }
 
 func GetBrowseRepositoryAtShaLink(repoUrl, sha string) string {
-	repoUrl = strings.TrimSuffix(repoUrl, ".git")
-	gitSourceUrlParts := strings.Split(repoUrl, "/")
-	gitProviderHost := "https://" + gitSourceUrlParts[2]
-	gitlabNamespace := gitSourceUrlParts[3]
-	gitlabProjectName := gitSourceUrlParts[4]
-	projectPath := gitlabNamespace + "/" + gitlabProjectName
-
-	return fmt.Sprintf("%s/%s/-/tree/%s", gitProviderHost, projectPath, sha)
+	baseUrl, projectPath, err := GetBaseUrlAndProjectPath(repoUrl)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%s/%s/-/tree/%s", baseUrl, projectPath, sha)
+}
+
+// GetBaseUrlAndProjectPath returns base URL of the GitLab instance that hosts the given repository
+// and the project path within the instance.
+// Examples:
+//
+//	For https://gitlab.com/namespace/project returns https://gitlab.com and namespace/project
+//	For git@gitlab.mycompany.com:namespace/project.git returns https://gitlab.mycompany.com and namespace/project
+//	For https://gitlab.com/group/subgroup/project returns https://gitlab.com and group/subgroup/project
+//	For https://mycompany.com/gitlab/namespace/project returns https://mycompany.com/gitlab and namespace/project
+//	if mycompany.com=https://mycompany.com/gitlab is configured in GITLAB_BASE_URLS
+func GetBaseUrlAndProjectPath(repoUrl string) (string, string, error) {
+	gitRepoUrl, err := gitrepourl.ParseGitRepoUrl(repoUrl)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse GitLab repository URL: %w", err)
+	}
+	host := gitRepoUrl.Host
+	repoPath := gitRepoUrl.GetRepositoryPath()
+
+	baseUrl := gitRepoUrl.GetProviderUrl()
+	if mappedBaseUrl, isMapped := getBaseUrlsMapping()[host]; isMapped {
+		baseUrl = mappedBaseUrl
+		u, err := url.Parse(mappedBaseUrl)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse GitLab base URL %s configured for %s host: %w", mappedBaseUrl, host, err)
+		}
+		// Strip the sub-path the instance is served under.
+		// Ssh URLs don't contain the sub-path, e.g. git@mycompany.com:namespace/project.git
+		subPath := strings.Trim(u.Path, "/")
+		if subPath != "" && isHttpUrl(repoUrl) {
+			if repoPath != subPath && !strings.HasPrefix(repoPath, subPath+"/") {
+				return "", "", fmt.Errorf("GitLab repository URL %s does not match configured base URL %s", repoUrl, mappedBaseUrl)
+			}
+			repoPath = strings.TrimPrefix(strings.TrimPrefix(repoPath, subPath), "/")
+		}
+	}
+
+	// Project might be located in nested subgroups, so the whole path is the project path,
+	// e.g. group/subgroup/project
+	if !strings.Contains(repoPath, "/") {
+		return "", "", fmt.Errorf("failed to get GitLab project path from repository URL: %s", repoUrl)
+	}
+
+	return baseUrl, repoPath, nil
+}
+
+func isHttpUrl(repoUrl string) bool {
+	lowerRepoUrl := strings.ToLower(repoUrl)
+	return strings.HasPrefix(lowerRepoUrl, "https://") || strings.HasPrefix(lowerRepoUrl, "http://")
+}
+
+// IsAllowedRepositoryUrl checks if credentials may be sent to the GitLab instance that hosts the given repository.
+// Only gitlab.com and self-hosted instances configured in GITLAB_BASE_URLS are allowed,
+// otherwise anyone could obtain the credentials by pointing a component to own server.
+func IsAllowedRepositoryUrl(repoUrl string) bool {
+	gitRepoUrl, err := gitrepourl.ParseGitRepoUrl(repoUrl)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(gitRepoUrl.Host, gitlabComHost) {
+		return true
+	}
+	_, isConfigured := getBaseUrlsMapping()[gitRepoUrl.Host]
+	return isConfigured
+}
+
+// getBaseUrlsMapping returns host to GitLab instance base URL mapping configured via GITLAB_BASE_URLS.
+func getBaseUrlsMapping() map[string]string {
+	mapping := make(map[string]string)
+	for _, hostToBaseUrl := range strings.Split(os.Getenv(GitlabBaseUrlsEnvName), ",") {
+		hostAndBaseUrl := strings.SplitN(strings.TrimSpace(hostToBaseUrl), "=", 2)
+		if len(hostAndBaseUrl) != 2 || hostAndBaseUrl[0] == "" || hostAndBaseUrl[1] == "" {
+			continue
+		}
+		mapping[hostAndBaseUrl[0]] = strings.TrimSuffix(hostAndBaseUrl[1], "/")
+	}
+	return mapping
+}
+
+// getRateLimitRetryAt returns the time when the request that hit GitLab rate limit may be retried.
+// Retry-After header contains the number of seconds to wait, RateLimit-Reset header contains Unix time of the rate limit reset.
+func getRateLimitRetryAt(response *http.Response) time.Time {
+	if retryAfter, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && retryAfter > 0 {
+		return time.Now().Add(time.Duration(retryAfter) * time.Second)
+	}
+	if reset, err := strconv.ParseInt(response.Header.Get("RateLimit-Reset"), 10, 64); err == nil && reset > 0 {
+		return time.Unix(reset, 0)
+	}
+	return time.Now().Add(defaultRateLimitRetryAfter)
+}
+
+// refineRateLimitError converts error of a GitLab request that hit rate limit into an error
+// that carries the time when the operation may be retried. Other errors are returned as is.
+func refineRateLimitError(originErr error) error {
+	var errResp *gitlab.ErrorResponse
+	if errors.As(originErr, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == 429 {
+		if _, isRateLimitError := boerrors.GetRetryAt(originErr); isRateLimitError {
+			return originErr
+		}
+		return RefineGitHostingServiceError(errResp.Response, originErr)
+	}
+	return originErr
 }
@@ -19,26 +19,29 @@ package gitlab
 import (
 	"strings"
 	"testing"
+
+	"github.com/xanzy/go-gitlab"
 )
 
 // THIS FILE IS NOT UNIT TESTS
 // Put your own data below and comment out function override in the test to debug interactions with GitLab
 var (
 	repoUrl     = "https://gitlab.com/user/devfile-sample-go-basic"
+	baseUrl     = "https://gitlab.com"
 	accessToken = "glpat-token"
 )
 
 var (
-	StubEnsurePaCMergeRequest = func(g *GitlabClient, d *PaCMergeRequestData) (string, error) { return "", nil }
+	StubEnsurePaCMergeRequest = func(g *GitlabClient, d *PaCMergeRequestData) (*gitlab.MergeRequest, error) { return nil, nil }
 	StubUndoPaCMergeRequest   = func(g *GitlabClient, d *PaCMergeRequestData) (string, error) { return "", nil }
-	StubSetupPaCWebhook       = func(g *GitlabClient, projectPath, webhookUrl, webhookSecret string) error { return nil }
+	StubSetupPaCWebhook       = func(*GitlabClient, string, string, string, *PaCWebhookOptions) error { return nil }
 	StubDeletePaCWebhook      = func(g *GitlabClient, projectPath, webhookUrl string) error { return nil }
 )
 
 func TestEnsurePaCMergeRequest(t *testing.T) {
 	EnsurePaCMergeRequest = StubEnsurePaCMergeRequest
 
-	glclient, err := NewGitlabClient(accessToken)
+	glclient, err := NewGitlabClient(accessToken, baseUrl)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -63,11 +66,11 @@ func TestEnsurePaCMergeRequest(t *testing.T) {
 		},
 	}
 
-	url, err := EnsurePaCMergeRequest(glclient, mrData)
+	mr, err := EnsurePaCMergeRequest(glclient, mrData)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if url != "" && !strings.HasPrefix(url, "http") {
+	if mr != nil && !strings.HasPrefix(mr.WebURL, "http") {
 		t.Fatal("Merge Request URL must not be empty")
 	}
 }
@@ -75,7 +78,7 @@ func TestEnsurePaCMergeRequest(t *testing.T) {
 func TestUndoPaCMergeRequest(t *testing.T) {
 	UndoPaCMergeRequest = StubUndoPaCMergeRequest
 
-	glclient, err := NewGitlabClient(accessToken)
+	glclient, err := NewGitlabClient(accessToken, baseUrl)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -112,7 +115,7 @@ func TestSetupPaCWebhook(t *testing.T) {
 	targetWebhookUrl := "https://pac.route.my-cluster.net"
 	webhookSecretString := "d01b38971dad59514298d763f288392c08221043"
 
-	glclient, err := NewGitlabClient(accessToken)
+	glclient, err := NewGitlabClient(accessToken, baseUrl)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -120,7 +123,8 @@ func TestSetupPaCWebhook(t *testing.T) {
 	gitSourceUrlParts := strings.Split(repoUrl, "/")
 	projectPath := gitSourceUrlParts[3] + "/" + gitSourceUrlParts[4]
 
-	err = SetupPaCWebhook(glclient, projectPath, targetWebhookUrl, webhookSecretString)
+	webhookOptions := &PaCWebhookOptions{EnableSSLVerification: true, MergeRequestsEvents: true, PushEvents: true, NoteEvents: true}
+	err = SetupPaCWebhook(glclient, projectPath, targetWebhookUrl, webhookSecretString, webhookOptions)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -131,7 +135,7 @@ func TestDeletePaCWebhook(t *testing.T) {
 
 	targetWebhookUrl := "https://pac.route.my-cluster.net"
 
-	glclient, err := NewGitlabClient(accessToken)
+	glclient, err := NewGitlabClient(accessToken, baseUrl)
 	if err != nil {
 		t.Fatal(err)
 	}
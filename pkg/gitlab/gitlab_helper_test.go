@@ -0,0 +1,276 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestGetBaseUrlAndProjectPath(t *testing.T) {
+	tests := []struct {
+		name            string
+		repoUrl         string
+		baseUrlsMapping string
+		wantBaseUrl     string
+		wantProjectPath string
+		wantErr         bool
+	}{
+		{
+			name:            "should handle gitlab.com repository",
+			repoUrl:         "https://gitlab.com/namespace/project",
+			wantBaseUrl:     "https://gitlab.com",
+			wantProjectPath: "namespace/project",
+		},
+		{
+			name:            "should handle repository URL with .git suffix and trailing slash",
+			repoUrl:         "https://gitlab.com/namespace/project.git/",
+			wantBaseUrl:     "https://gitlab.com",
+			wantProjectPath: "namespace/project",
+		},
+		{
+			name:            "should handle project in nested subgroups",
+			repoUrl:         "https://gitlab.com/group/subgroup/subsubgroup/project.git",
+			wantBaseUrl:     "https://gitlab.com",
+			wantProjectPath: "group/subgroup/subsubgroup/project",
+		},
+		{
+			name:            "should handle ssh repository URL of project in nested subgroups",
+			repoUrl:         "git@gitlab.com:group/subgroup/project.git",
+			wantBaseUrl:     "https://gitlab.com",
+			wantProjectPath: "group/subgroup/project",
+		},
+		{
+			name:            "should handle project in nested subgroups of instance hosted under sub-path",
+			repoUrl:         "https://mycompany.com/gitlab/group/subgroup/project",
+			baseUrlsMapping: "mycompany.com=https://mycompany.com/gitlab",
+			wantBaseUrl:     "https://mycompany.com/gitlab",
+			wantProjectPath: "group/subgroup/project",
+		},
+		{
+			name:            "should derive base URL of self-hosted instance from repository URL",
+			repoUrl:         "https://gitlab.mycompany.com/namespace/project",
+			wantBaseUrl:     "https://gitlab.mycompany.com",
+			wantProjectPath: "namespace/project",
+		},
+		{
+			name:            "should keep port of self-hosted instance",
+			repoUrl:         "http://gitlab.mycompany.com:8080/namespace/project",
+			wantBaseUrl:     "http://gitlab.mycompany.com:8080",
+			wantProjectPath: "namespace/project",
+		},
+		{
+			name:            "should handle ssh repository URL",
+			repoUrl:         "git@gitlab.mycompany.com:namespace/project.git",
+			wantBaseUrl:     "https://gitlab.mycompany.com",
+			wantProjectPath: "namespace/project",
+		},
+		{
+			name:            "should handle ssh repository URL with port",
+			repoUrl:         "ssh://git@gitlab.mycompany.com:2222/namespace/project.git",
+			wantBaseUrl:     "https://gitlab.mycompany.com",
+			wantProjectPath: "namespace/project",
+		},
+		{
+			name:            "should use configured base URL of instance hosted under sub-path",
+			repoUrl:         "https://mycompany.com/gitlab/namespace/project",
+			baseUrlsMapping: "gitlab.example.com=https://gitlab.example.com, mycompany.com=https://mycompany.com/gitlab/",
+			wantBaseUrl:     "https://mycompany.com/gitlab",
+			wantProjectPath: "namespace/project",
+		},
+		{
+			name:            "should use configured base URL for ssh repository URL without sub-path",
+			repoUrl:         "git@mycompany.com:namespace/project.git",
+			baseUrlsMapping: "mycompany.com=https://mycompany.com/gitlab",
+			wantBaseUrl:     "https://mycompany.com/gitlab",
+			wantProjectPath: "namespace/project",
+		},
+		{
+			name:            "should fail if repository URL does not match configured sub-path",
+			repoUrl:         "https://mycompany.com/namespace/project",
+			baseUrlsMapping: "mycompany.com=https://mycompany.com/gitlab",
+			wantErr:         true,
+		},
+		{
+			name:    "should fail if project path is missing",
+			repoUrl: "https://gitlab.com/namespace",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(GitlabBaseUrlsEnvName, tt.baseUrlsMapping)
+
+			gotBaseUrl, gotProjectPath, err := GetBaseUrlAndProjectPath(tt.repoUrl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetBaseUrlAndProjectPath(): unexpected error: %v", err)
+			}
+			if gotBaseUrl != tt.wantBaseUrl {
+				t.Errorf("GetBaseUrlAndProjectPath(): got base URL %s, want %s", gotBaseUrl, tt.wantBaseUrl)
+			}
+			if gotProjectPath != tt.wantProjectPath {
+				t.Errorf("GetBaseUrlAndProjectPath(): got project path %s, want %s", gotProjectPath, tt.wantProjectPath)
+			}
+		})
+	}
+}
+
+func TestIsAllowedRepositoryUrl(t *testing.T) {
+	tests := []struct {
+		name            string
+		repoUrl         string
+		baseUrlsMapping string
+		want            bool
+	}{
+		{
+			name:    "should allow gitlab.com",
+			repoUrl: "https://gitlab.com/namespace/project",
+			want:    true,
+		},
+		{
+			name:    "should allow gitlab.com ssh repository URL",
+			repoUrl: "git@gitlab.com:namespace/project.git",
+			want:    true,
+		},
+		{
+			name:    "should not allow self-hosted instance if not configured",
+			repoUrl: "https://gitlab.mycompany.com/namespace/project",
+			want:    false,
+		},
+		{
+			name:            "should allow configured self-hosted instance",
+			repoUrl:         "https://gitlab.mycompany.com/namespace/project",
+			baseUrlsMapping: "gitlab.mycompany.com=https://gitlab.mycompany.com",
+			want:            true,
+		},
+		{
+			name:            "should not allow other self-hosted instance",
+			repoUrl:         "https://gitlab.attacker.com/namespace/project",
+			baseUrlsMapping: "gitlab.mycompany.com=https://gitlab.mycompany.com",
+			want:            false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(GitlabBaseUrlsEnvName, tt.baseUrlsMapping)
+
+			if got := IsAllowedRepositoryUrl(tt.repoUrl); got != tt.want {
+				t.Errorf("IsAllowedRepositoryUrl(%s): got %t, want %t", tt.repoUrl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetBrowseRepositoryAtShaLink(t *testing.T) {
+	t.Setenv(GitlabBaseUrlsEnvName, "mycompany.com=https://mycompany.com/gitlab")
+
+	link := GetBrowseRepositoryAtShaLink("https://mycompany.com/gitlab/namespace/project.git", "abcd")
+	if link != "https://mycompany.com/gitlab/namespace/project/-/tree/abcd" {
+		t.Errorf("GetBrowseRepositoryAtShaLink(): unexpected link: %s", link)
+	}
+
+	link = GetBrowseRepositoryAtShaLink("https://gitlab.com/group/subgroup/project", "abcd")
+	if link != "https://gitlab.com/group/subgroup/project/-/tree/abcd" {
+		t.Errorf("GetBrowseRepositoryAtShaLink(): unexpected link for project in nested subgroups: %s", link)
+	}
+}
+
+func TestRefineRateLimitError(t *testing.T) {
+	resetAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	tests := []struct {
+		name          string
+		statusCode    int
+		header        map[string]string
+		wantRateLimit bool
+		wantRetryAt   time.Time
+	}{
+		{
+			name:          "should use Retry-After header",
+			statusCode:    429,
+			header:        map[string]string{"Retry-After": "3600", "RateLimit-Reset": strconv.FormatInt(resetAt.Add(time.Hour).Unix(), 10)},
+			wantRateLimit: true,
+			wantRetryAt:   time.Now().Add(time.Hour),
+		},
+		{
+			name:          "should use RateLimit-Reset header",
+			statusCode:    429,
+			header:        map[string]string{"RateLimit-Reset": strconv.FormatInt(resetAt.Unix(), 10)},
+			wantRateLimit: true,
+			wantRetryAt:   resetAt,
+		},
+		{
+			name:          "should use default retry delay if no rate limit headers",
+			statusCode:    429,
+			wantRateLimit: true,
+			wantRetryAt:   time.Now().Add(defaultRateLimitRetryAfter),
+		},
+		{
+			name:          "should not change other errors",
+			statusCode:    500,
+			wantRateLimit: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &http.Response{StatusCode: tt.statusCode, Header: http.Header{}}
+			for key, value := range tt.header {
+				response.Header.Set(key, value)
+			}
+			originErr := fmt.Errorf("failed to create merge request: %w", &gitlab.ErrorResponse{Response: response})
+
+			err := refineRateLimitError(originErr)
+			retryAt, isRateLimited := boerrors.GetRetryAt(err)
+			if isRateLimited != tt.wantRateLimit {
+				t.Fatalf("refineRateLimitError(): expected rate limit error: %t, got: %v", tt.wantRateLimit, err)
+			}
+			if !isRateLimited {
+				if err != originErr {
+					t.Errorf("refineRateLimitError(): expected original error, got: %v", err)
+				}
+				return
+			}
+			expectedErr := boerrors.NewBuildOpError(boerrors.EGitLabReachRateLimit, nil)
+			if shortError := err.(*boerrors.BuildOpError).ShortError(); shortError != expectedErr.ShortError() {
+				t.Errorf("refineRateLimitError(): unexpected error: %s", shortError)
+			}
+			if diff := retryAt.Sub(tt.wantRetryAt); diff < -time.Second || diff > time.Second {
+				t.Errorf("refineRateLimitError(): got retry at %s, want %s", retryAt, tt.wantRetryAt)
+			}
+		})
+	}
+}
+
+func TestToPaCWebhookOptions(t *testing.T) {
+	got := toPaCWebhookOptions(&gitprovider.WebhookOptions{SSLVerification: true, Events: gitprovider.DefaultWebhookEvents})
+	want := &PaCWebhookOptions{EnableSSLVerification: true, MergeRequestsEvents: true, PushEvents: true, NoteEvents: true}
+	if *got != *want {
+		t.Errorf("toPaCWebhookOptions(): got %+v, want %+v", got, want)
+	}
+
+	got = toPaCWebhookOptions(&gitprovider.WebhookOptions{Events: []string{gitprovider.WebhookEventTagPush}})
+	want = &PaCWebhookOptions{TagPushEvents: true}
+	if *got != *want {
+		t.Errorf("toPaCWebhookOptions(): got %+v, want %+v", got, want)
+	}
+}
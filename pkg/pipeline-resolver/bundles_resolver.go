@@ -0,0 +1,176 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelineresolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	registrymirrors "github.com/redhat-appstudio/build-service/pkg/registry-mirrors"
+	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	oci "github.com/tektoncd/pipeline/pkg/remote/oci"
+	"k8s.io/utils/lru"
+)
+
+const (
+	// defaultBundleCacheSize is the number of pipeline definitions kept in memory.
+	// Bundles are immutable once referenced by digest, so a definition never needs to be fetched twice.
+	defaultBundleCacheSize = 256
+	// defaultBundleFetchTimeout limits time to resolve a bundle tag and pull the pipeline definition from it.
+	defaultBundleFetchTimeout = 1 * time.Minute
+)
+
+// MirrorsProvider returns registry mirrors to pull bundles from.
+type MirrorsProvider interface {
+	GetRegistryMirrors(ctx context.Context) (*registrymirrors.RegistryMirrors, error)
+}
+
+// bundleCacheKey identifies a pipeline within a bundle image referenced by digest.
+type bundleCacheKey struct {
+	bundle       string
+	pipelineName string
+}
+
+// BundlesResolver fetches pipelines from Tekton bundles.
+// Bundle tags are resolved to digests and parsed pipeline definitions are cached by digest.
+// Mirrors of the bundle are tried first, the bundle itself is the last resort.
+// Credentials are always taken from the namespace of the PipelineRun, namespace param is ignored.
+// Params: bundle, name.
+type BundlesResolver struct {
+	// KeychainProvider gives credentials for the namespace of the PipelineRun, the default keychain is used if not set
+	KeychainProvider KeychainProvider
+	// MirrorsProvider gives registry mirrors, bundles are pulled from their original location only if not set
+	MirrorsProvider MirrorsProvider
+	Timeout         time.Duration
+
+	cache *lru.Cache
+}
+
+// NewBundlesResolver creates a bundles resolver which pulls bundles with credentials and mirrors from the given providers.
+func NewBundlesResolver(keychainProvider KeychainProvider, mirrorsProvider MirrorsProvider) *BundlesResolver {
+	return &BundlesResolver{
+		KeychainProvider: keychainProvider,
+		MirrorsProvider:  mirrorsProvider,
+		Timeout:          defaultBundleFetchTimeout,
+		cache:            lru.New(defaultBundleCacheSize),
+	}
+}
+
+func (r *BundlesResolver) Resolve(ctx context.Context, params map[string]string, namespace string) (*ResolvedPipeline, error) {
+	bundleUri := params["bundle"]
+	pipelineName := params[NameParam]
+	if bundleUri == "" || pipelineName == "" {
+		return nil, fmt.Errorf("bundle and name params are required")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	keychain := authn.DefaultKeychain
+	if r.KeychainProvider != nil {
+		var err error
+		if keychain, err = r.KeychainProvider.GetKeychain(ctx, namespace); err != nil {
+			return nil, fmt.Errorf("failed to get credentials for bundle %s: %w", bundleUri, err)
+		}
+	}
+
+	var registryMirrors *registrymirrors.RegistryMirrors
+	if r.MirrorsProvider != nil {
+		var err error
+		if registryMirrors, err = r.MirrorsProvider.GetRegistryMirrors(ctx); err != nil {
+			return nil, fmt.Errorf("failed to get registry mirrors: %w", err)
+		}
+	}
+
+	// The digest is resolved even for cached definitions, so the registry checks access of the namespace to the bundle
+	var bundleRef name.Digest
+	var bundleLocation string
+	var err error
+	for _, bundleLocation = range append(registryMirrors.GetMirrorReferences(bundleUri), bundleUri) {
+		if bundleRef, err = resolveDigest(ctx, bundleLocation, keychain); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Mirrors have the same content under the same digest, so cache by the canonical reference
+	canonicalBundle, err := PinBundleDigest(bundleUri, bundleRef.DigestStr())
+	if err != nil {
+		return nil, err
+	}
+	cacheKey := bundleCacheKey{bundle: canonicalBundle, pipelineName: pipelineName}
+	if cachedPipelineSpec, ok := r.cache.Get(cacheKey); ok {
+		return &ResolvedPipeline{Spec: cachedPipelineSpec.(*tektonapi.PipelineSpec).DeepCopy(), Digest: bundleRef.DigestStr(), Bundle: bundleLocation}, nil
+	}
+
+	resolver := oci.NewResolver(bundleRef.String(), keychain)
+	obj, _, err := resolver.Get(ctx, "pipeline", pipelineName)
+	if err != nil {
+		return nil, wrapRegistryAuthError(err)
+	}
+	pipelineSpecObj, ok := obj.(tektonapi.PipelineObject)
+	if !ok {
+		return nil, fmt.Errorf("failed to extract pipeline %s from bundle %s", pipelineName, bundleUri)
+	}
+	pipelineSpec := pipelineSpecObj.PipelineSpec()
+	r.cache.Add(cacheKey, pipelineSpec.DeepCopy())
+
+	return &ResolvedPipeline{Spec: &pipelineSpec, Digest: bundleRef.DigestStr(), Bundle: bundleLocation}, nil
+}
+
+// resolveDigest returns reference of the given bundle pinned to its digest.
+func resolveDigest(ctx context.Context, bundleUri string, keychain authn.Keychain) (name.Digest, error) {
+	ref, err := name.ParseReference(bundleUri)
+	if err != nil {
+		return name.Digest{}, fmt.Errorf("failed to parse bundle reference %s: %w", bundleUri, err)
+	}
+
+	descriptor, err := remote.Head(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(keychain))
+	if err != nil {
+		return name.Digest{}, wrapRegistryAuthError(fmt.Errorf("failed to resolve digest of bundle %s: %w", bundleUri, err))
+	}
+	return ref.Context().Digest(descriptor.Digest.String()), nil
+}
+
+// PinBundleDigest returns reference of the given bundle repository pinned to the given digest.
+func PinBundleDigest(bundleUri string, digest string) (string, error) {
+	ref, err := name.ParseReference(bundleUri)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse bundle reference %s: %w", bundleUri, err)
+	}
+	return ref.Context().Digest(digest).String(), nil
+}
+
+// wrapRegistryAuthError returns persistent error if the registry denied access to the bundle.
+func wrapRegistryAuthError(err error) error {
+	var transportError *transport.Error
+	if errors.As(err, &transportError) &&
+		(transportError.StatusCode == http.StatusUnauthorized || transportError.StatusCode == http.StatusForbidden) {
+		return boerrors.NewBuildOpError(boerrors.EPipelineBundleUnauthorized, err)
+	}
+	return err
+}
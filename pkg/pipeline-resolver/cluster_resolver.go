@@ -0,0 +1,68 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelineresolver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ClusterResolver fetches Pipeline objects from the cluster.
+// Pipelines are taken only from the namespace of the PipelineRun or from the shared namespaces,
+// so a component cannot read Pipeline objects of other tenants.
+// Params: name, namespace (the namespace of the PipelineRun by default).
+type ClusterResolver struct {
+	Reader client.Reader
+	// SharedNamespaces are namespaces with Pipeline objects available to any PipelineRun
+	SharedNamespaces []string
+}
+
+func (r *ClusterResolver) Resolve(ctx context.Context, params map[string]string, namespace string) (*ResolvedPipeline, error) {
+	name := params[NameParam]
+	pipelineNamespace := params[NamespaceParam]
+	if name == "" || pipelineNamespace == "" {
+		return nil, fmt.Errorf("name and namespace params are required")
+	}
+	if !r.isNamespaceAllowed(pipelineNamespace, namespace) {
+		return nil, boerrors.NewBuildOpError(boerrors.EPipelineNamespaceNotAllowed,
+			fmt.Errorf("pipeline %s cannot be taken from %s namespace", name, pipelineNamespace))
+	}
+
+	pipeline := &tektonapi.Pipeline{}
+	if err := r.Reader.Get(ctx, types.NamespacedName{Namespace: pipelineNamespace, Name: name}, pipeline); err != nil {
+		return nil, err
+	}
+	return &ResolvedPipeline{Spec: &pipeline.Spec}, nil
+}
+
+// isNamespaceAllowed checks if a PipelineRun in the given namespace may use Pipeline objects from the pipeline namespace.
+func (r *ClusterResolver) isNamespaceAllowed(pipelineNamespace, namespace string) bool {
+	if pipelineNamespace == namespace {
+		return true
+	}
+	for _, sharedNamespace := range r.SharedNamespaces {
+		if pipelineNamespace == sharedNamespace {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelineresolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"k8s.io/utils/lru"
+)
+
+const (
+	// defaultGitCacheSize is the number of pipeline definitions kept in memory.
+	defaultGitCacheSize = 256
+	// defaultGitFetchTimeout limits time to resolve the revision and fetch the pipeline definition from the repository.
+	defaultGitFetchTimeout = 1 * time.Minute
+)
+
+// gitCacheKey identifies a file at a commit, or at an annotated tag object, of a repository.
+type gitCacheKey struct {
+	url        string
+	hash       string
+	pathInRepo string
+}
+
+// GitResolver fetches pipelines from public git repositories, like Tekton git resolver in anonymous mode does.
+// Only the requested revision is fetched, without history, and parsed pipeline definitions are cached by commit.
+// Params: url, revision, pathInRepo.
+type GitResolver struct {
+	Timeout time.Duration
+
+	cache *lru.Cache
+}
+
+// NewGitResolver creates a git resolver with the default timeout and cache size.
+func NewGitResolver() *GitResolver {
+	return &GitResolver{
+		Timeout: defaultGitFetchTimeout,
+		cache:   lru.New(defaultGitCacheSize),
+	}
+}
+
+func (r *GitResolver) Resolve(ctx context.Context, params map[string]string, namespace string) (*ResolvedPipeline, error) {
+	repoUrl := params["url"]
+	revision := params["revision"]
+	pathInRepo := params["pathInRepo"]
+	if repoUrl == "" {
+		// Tekton git resolver API mode (org and repo params) requires the resolver token configuration
+		return nil, fmt.Errorf("url param is required")
+	}
+	if revision == "" || pathInRepo == "" {
+		return nil, fmt.Errorf("revision and pathInRepo params are required")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	// Branches and tags move, so the revision is resolved on every request and the definition is cached by the result
+	hash, referenceName, err := resolveGitRevision(ctx, repoUrl, revision)
+	if err != nil {
+		return nil, err
+	}
+	cacheKey := gitCacheKey{url: repoUrl, hash: hash.String(), pathInRepo: pathInRepo}
+	if cachedPipelineSpec, ok := r.cache.Get(cacheKey); ok {
+		return &ResolvedPipeline{Spec: cachedPipelineSpec.(*tektonapi.PipelineSpec).DeepCopy()}, nil
+	}
+
+	content, err := fetchGitFile(ctx, repoUrl, referenceName, hash, pathInRepo)
+	if err != nil {
+		return nil, err
+	}
+	pipelineSpec, err := parsePipelineSpec(content)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.Add(cacheKey, pipelineSpec.DeepCopy())
+	return &ResolvedPipeline{Spec: pipelineSpec}, nil
+}
+
+// resolveGitRevision returns the object the revision points to and the reference name of the revision.
+// The reference name is empty if the revision is a commit hash.
+func resolveGitRevision(ctx context.Context, repoUrl, revision string) (plumbing.Hash, plumbing.ReferenceName, error) {
+	if plumbing.IsHash(revision) {
+		return plumbing.NewHash(revision), "", nil
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{repoUrl}})
+	references, err := remote.ListContext(ctx, &git.ListOptions{})
+	if err != nil {
+		return plumbing.ZeroHash, "", fmt.Errorf("failed to list references of %s: %w", repoUrl, err)
+	}
+	for _, referenceName := range []plumbing.ReferenceName{plumbing.NewBranchReferenceName(revision), plumbing.NewTagReferenceName(revision)} {
+		for _, reference := range references {
+			if reference.Name() == referenceName {
+				return reference.Hash(), referenceName, nil
+			}
+		}
+	}
+	return plumbing.ZeroHash, "", fmt.Errorf("failed to resolve %s revision of %s", revision, repoUrl)
+}
+
+// fetchGitFile fetches the single revision of the repository without history and returns content of the given file in it.
+func fetchGitFile(ctx context.Context, repoUrl string, referenceName plumbing.ReferenceName, hash plumbing.Hash, pathInRepo string) ([]byte, error) {
+	repository, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := repository.CreateRemote(&gitconfig.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{repoUrl}})
+	if err != nil {
+		return nil, err
+	}
+
+	refSpec := gitconfig.RefSpec(fmt.Sprintf("+%s:%s", referenceName, referenceName))
+	if referenceName == "" {
+		refSpec = gitconfig.RefSpec(fmt.Sprintf("%s:refs/heads/revision", hash))
+	}
+	err = remote.FetchContext(ctx, &git.FetchOptions{RefSpecs: []gitconfig.RefSpec{refSpec}, Depth: 1, Tags: git.NoTags})
+	if errors.Is(err, git.ErrExactSHA1NotSupported) {
+		// The server doesn't allow fetching a commit by hash, fetch all branches to find it
+		err = remote.FetchContext(ctx, &git.FetchOptions{RefSpecs: []gitconfig.RefSpec{"+refs/heads/*:refs/heads/*"}, Tags: git.AllTags})
+	}
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("failed to fetch %s revision of %s: %w", hash, repoUrl, err)
+	}
+
+	commit, err := getGitCommit(repository, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s revision of %s: %w", hash, repoUrl, err)
+	}
+	file, err := commit.File(pathInRepo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in %s: %w", pathInRepo, repoUrl, err)
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s in %s: %w", pathInRepo, repoUrl, err)
+	}
+	return []byte(content), nil
+}
+
+// getGitCommit returns the commit with the given hash or the commit the annotated tag with the given hash points to.
+func getGitCommit(repository *git.Repository, hash plumbing.Hash) (*object.Commit, error) {
+	if tag, err := repository.TagObject(hash); err == nil {
+		return tag.Commit()
+	}
+	return repository.CommitObject(hash)
+}
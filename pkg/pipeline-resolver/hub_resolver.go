@@ -0,0 +1,144 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelineresolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	artifactHubType = "artifact"
+	tektonHubType   = "tekton"
+
+	// Defaults of Tekton hub resolver configuration
+	defaultArtifactHubURL             = "https://artifacthub.io"
+	defaultTektonHubURL               = "https://api.hub.tekton.dev"
+	defaultArtifactHubPipelineCatalog = "tekton-catalog-pipelines"
+	defaultTektonHubCatalog           = "Tekton"
+
+	// defaultHubRequestTimeout limits time to get a pipeline definition from a hub.
+	defaultHubRequestTimeout = 1 * time.Minute
+)
+
+// HubResolver fetches pipelines from Artifact Hub or Tekton Hub.
+// Params: name, version, type (artifact by default), catalog.
+type HubResolver struct {
+	ArtifactHubURL string
+	TektonHubURL   string
+	HttpClient     *http.Client
+}
+
+// NewHubResolver creates a hub resolver for the public Artifact Hub and Tekton Hub instances.
+func NewHubResolver() *HubResolver {
+	return &HubResolver{
+		ArtifactHubURL: defaultArtifactHubURL,
+		TektonHubURL:   defaultTektonHubURL,
+		HttpClient:     &http.Client{Timeout: defaultHubRequestTimeout},
+	}
+}
+
+func (r *HubResolver) Resolve(ctx context.Context, params map[string]string, namespace string) (*ResolvedPipeline, error) {
+	name := params[NameParam]
+	version := params["version"]
+	if name == "" || version == "" {
+		return nil, fmt.Errorf("name and version params are required")
+	}
+	hubType := params["type"]
+	if hubType == "" {
+		hubType = artifactHubType
+	}
+	catalog := params["catalog"]
+
+	var url string
+	var response interface{}
+	var content func() string
+	switch hubType {
+	case artifactHubType:
+		if catalog == "" {
+			catalog = defaultArtifactHubPipelineCatalog
+		}
+		// Artifact Hub follows semantic versioning
+		if len(strings.Split(version, ".")) == 2 {
+			version += ".0"
+		}
+		url = fmt.Sprintf("%s/api/v1/packages/tekton-pipeline/%s/%s/%s", strings.TrimSuffix(r.ArtifactHubURL, "/"), catalog, name, version)
+		artifactHubResponse := &struct {
+			Data struct {
+				YAML string `json:"manifestRaw"`
+			} `json:"data"`
+		}{}
+		response = artifactHubResponse
+		content = func() string { return artifactHubResponse.Data.YAML }
+	case tektonHubType:
+		if catalog == "" {
+			catalog = defaultTektonHubCatalog
+		}
+		// Tekton Hub uses major.minor versions
+		if semVer := strings.Split(version, "."); len(semVer) > 2 {
+			version = strings.Join(semVer[0:2], ".")
+		}
+		url = fmt.Sprintf("%s/v1/resource/%s/pipeline/%s/%s/yaml", strings.TrimSuffix(r.TektonHubURL, "/"), catalog, name, version)
+		tektonHubResponse := &struct {
+			Data struct {
+				YAML string `json:"yaml"`
+			} `json:"data"`
+		}{}
+		response = tektonHubResponse
+		content = func() string { return tektonHubResponse.Data.YAML }
+	default:
+		return nil, fmt.Errorf("hub type '%s' is not supported", hubType)
+	}
+
+	if err := r.fetchHubResource(ctx, url, response); err != nil {
+		return nil, err
+	}
+	pipelineSpec, err := parsePipelineSpec([]byte(content()))
+	if err != nil {
+		return nil, err
+	}
+	return &ResolvedPipeline{Spec: pipelineSpec}, nil
+}
+
+func (r *HubResolver) fetchHubResource(ctx context.Context, url string, response interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.HttpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to request %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get %s: %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response of %s: %w", url, err)
+	}
+	if err := json.Unmarshal(body, response); err != nil {
+		return fmt.Errorf("failed to parse response of %s: %w", url, err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelineresolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// KeychainProvider returns credentials to pull bundles referenced by PipelineRuns in the given namespace.
+type KeychainProvider interface {
+	GetKeychain(ctx context.Context, namespace string) (authn.Keychain, error)
+}
+
+// PullSecretsKeychainProvider provides credentials from Kubernetes image pull secrets,
+// the same way as Tekton does for the service account that runs PipelineRuns.
+// Registries not covered by the pull secrets are accessed with the default keychain.
+type PullSecretsKeychainProvider struct {
+	Reader client.Reader
+	// ServiceAccountName is the name of the service account in the PipelineRun namespace whose image pull secrets are used
+	ServiceAccountName string
+	// GlobalPullSecret is used for all namespaces after the service account pull secrets, ignored if name is empty
+	GlobalPullSecret types.NamespacedName
+}
+
+func (p *PullSecretsKeychainProvider) GetKeychain(ctx context.Context, namespace string) (authn.Keychain, error) {
+	pullSecretKeys := []types.NamespacedName{}
+
+	serviceAccount := &corev1.ServiceAccount{}
+	if err := p.Reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: p.ServiceAccountName}, serviceAccount); err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	for _, pullSecret := range serviceAccount.ImagePullSecrets {
+		pullSecretKeys = append(pullSecretKeys, types.NamespacedName{Namespace: namespace, Name: pullSecret.Name})
+	}
+	if p.GlobalPullSecret.Name != "" {
+		pullSecretKeys = append(pullSecretKeys, p.GlobalPullSecret)
+	}
+
+	pullSecrets := []corev1.Secret{}
+	for _, pullSecretKey := range pullSecretKeys {
+		pullSecret := corev1.Secret{}
+		if err := p.Reader.Get(ctx, pullSecretKey, &pullSecret); err != nil {
+			if errors.IsNotFound(err) {
+				// Ignore missing pull secrets, like kubelet does
+				continue
+			}
+			return nil, err
+		}
+		pullSecrets = append(pullSecrets, pullSecret)
+	}
+
+	pullSecretsKeychain, err := NewPullSecretsKeychain(pullSecrets)
+	if err != nil {
+		return nil, err
+	}
+	return authn.NewMultiKeychain(pullSecretsKeychain, authn.DefaultKeychain), nil
+}
+
+// registryCredentials holds credentials for a registry host or a repository path prefix.
+type registryCredentials struct {
+	location   string
+	authConfig authn.AuthConfig
+}
+
+// pullSecretsKeychain resolves credentials from docker config entries of image pull secrets.
+type pullSecretsKeychain struct {
+	credentials []registryCredentials
+}
+
+// NewPullSecretsKeychain creates a keychain from the given kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg secrets.
+// The most specific entry matching the image repository is used, on equal match the first secret wins.
+func NewPullSecretsKeychain(pullSecrets []corev1.Secret) (authn.Keychain, error) {
+	keychain := &pullSecretsKeychain{}
+	for _, pullSecret := range pullSecrets {
+		auths := map[string]authn.AuthConfig{}
+		switch pullSecret.Type {
+		case corev1.SecretTypeDockerConfigJson:
+			dockerConfig := &struct {
+				Auths map[string]authn.AuthConfig `json:"auths"`
+			}{}
+			if err := json.Unmarshal(pullSecret.Data[corev1.DockerConfigJsonKey], dockerConfig); err != nil {
+				return nil, fmt.Errorf("failed to parse %s pull secret in %s namespace: %w", pullSecret.Name, pullSecret.Namespace, err)
+			}
+			auths = dockerConfig.Auths
+		case corev1.SecretTypeDockercfg:
+			if err := json.Unmarshal(pullSecret.Data[corev1.DockerConfigKey], &auths); err != nil {
+				return nil, fmt.Errorf("failed to parse %s pull secret in %s namespace: %w", pullSecret.Name, pullSecret.Namespace, err)
+			}
+		default:
+			continue
+		}
+
+		for location, authConfig := range auths {
+			keychain.credentials = append(keychain.credentials, registryCredentials{
+				location:   normalizeRegistryLocation(location),
+				authConfig: authConfig,
+			})
+		}
+	}
+	return keychain, nil
+}
+
+func (k *pullSecretsKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	targetLocation := target.String()
+	var matched *registryCredentials
+	for i := range k.credentials {
+		location := k.credentials[i].location
+		if targetLocation != location && !strings.HasPrefix(targetLocation, location+"/") {
+			continue
+		}
+		if matched == nil || len(location) > len(matched.location) {
+			matched = &k.credentials[i]
+		}
+	}
+	if matched == nil {
+		return authn.Anonymous, nil
+	}
+	return authn.FromConfig(matched.authConfig), nil
+}
+
+// normalizeRegistryLocation converts docker config keys, like https://index.docker.io/v1/ or quay.io/org,
+// into the form of registry and repository names used by image references.
+func normalizeRegistryLocation(location string) string {
+	location = strings.TrimPrefix(location, "https://")
+	location = strings.TrimPrefix(location, "http://")
+	location = strings.TrimSuffix(location, "/")
+	location = strings.TrimSuffix(location, "/v1")
+	location = strings.TrimSuffix(location, "/v2")
+
+	host, path, _ := strings.Cut(location, "/")
+	if registry, err := name.NewRegistry(host); err == nil {
+		// Resolves docker.io aliases
+		host = registry.RegistryStr()
+	}
+	if path == "" {
+		return host
+	}
+	return host + "/" + path
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelineresolver
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func getDockerConfigJsonSecret(name, namespace string, auths map[string]string) *corev1.Secret {
+	dockerConfig := `{"auths":{`
+	i := 0
+	for location, credentials := range auths {
+		if i > 0 {
+			dockerConfig += ","
+		}
+		dockerConfig += fmt.Sprintf(`%q:{"auth":%q}`, location, base64.StdEncoding.EncodeToString([]byte(credentials)))
+		i++
+	}
+	dockerConfig += "}}"
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(dockerConfig)},
+	}
+}
+
+// resolveCredentials returns user:password the keychain gives for the given repository, empty for anonymous access
+func resolveCredentials(t *testing.T, keychain authn.Keychain, repository string) string {
+	repo, err := name.NewRepository(repository)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator, err := keychain.Resolve(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authenticator == authn.Anonymous {
+		return ""
+	}
+	authConfig, err := authenticator.Authorization()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authConfig.Username + ":" + authConfig.Password
+}
+
+func TestNewPullSecretsKeychain(t *testing.T) {
+	dockercfgSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "dockercfg", Namespace: "user-ns"},
+		Type:       corev1.SecretTypeDockercfg,
+		Data:       map[string][]byte{corev1.DockerConfigKey: []byte(`{"https://index.docker.io/v1/":{"username":"hub-user","password":"hub-pass"}}`)},
+	}
+	pullSecrets := []corev1.Secret{
+		*getDockerConfigJsonSecret("registry", "user-ns", map[string]string{"quay.io": "quay-user:quay-pass"}),
+		*getDockerConfigJsonSecret("org", "user-ns", map[string]string{"https://quay.io/org/": "org-user:org-pass"}),
+		*getDockerConfigJsonSecret("other", "user-ns", map[string]string{"quay.io": "other-user:other-pass"}),
+		*dockercfgSecret,
+		{ObjectMeta: metav1.ObjectMeta{Name: "opaque", Namespace: "user-ns"}, Type: corev1.SecretTypeOpaque},
+	}
+	keychain, err := NewPullSecretsKeychain(pullSecrets)
+	if err != nil {
+		t.Fatalf("NewPullSecretsKeychain(): unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		repository string
+		want       string
+	}{
+		{repository: "quay.io/org/pipelines", want: "org-user:org-pass"},
+		{repository: "quay.io/organization/pipelines", want: "quay-user:quay-pass"},
+		{repository: "quay.io/pipelines", want: "quay-user:quay-pass"},
+		{repository: "docker.io/org/pipelines", want: "hub-user:hub-pass"},
+		{repository: "registry.io/org/pipelines", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.repository, func(t *testing.T) {
+			if got := resolveCredentials(t, keychain, tt.repository); got != tt.want {
+				t.Errorf("Resolve(): got %s credentials, want %s", got, tt.want)
+			}
+		})
+	}
+
+	invalidSecret := getDockerConfigJsonSecret("invalid", "user-ns", nil)
+	invalidSecret.Data[corev1.DockerConfigJsonKey] = []byte("not a json")
+	if _, err := NewPullSecretsKeychain([]corev1.Secret{*invalidSecret}); err == nil {
+		t.Errorf("NewPullSecretsKeychain(): expected error for invalid pull secret")
+	}
+}
+
+func TestPullSecretsKeychainProvider(t *testing.T) {
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta:       metav1.ObjectMeta{Name: "appstudio-pipeline", Namespace: "user-ns"},
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "missing"}, {Name: "pull-secret"}},
+	}
+	pullSecret := getDockerConfigJsonSecret("pull-secret", "user-ns", map[string]string{"quay.io/org": "user:pass"})
+	globalPullSecret := getDockerConfigJsonSecret("global-pull-secret", "build-service", map[string]string{"quay.io": "global-user:global-pass"})
+	otherNamespacePullSecret := getDockerConfigJsonSecret("pull-secret", "other-ns", map[string]string{"quay.io/org": "other-user:other-pass"})
+
+	provider := &PullSecretsKeychainProvider{
+		Reader:             fake.NewClientBuilder().WithObjects(serviceAccount, pullSecret, globalPullSecret, otherNamespacePullSecret).Build(),
+		ServiceAccountName: "appstudio-pipeline",
+		GlobalPullSecret:   types.NamespacedName{Namespace: "build-service", Name: "global-pull-secret"},
+	}
+
+	keychain, err := provider.GetKeychain(context.Background(), "user-ns")
+	if err != nil {
+		t.Fatalf("GetKeychain(): unexpected error: %v", err)
+	}
+	if got := resolveCredentials(t, keychain, "quay.io/org/pipelines"); got != "user:pass" {
+		t.Errorf("GetKeychain(): got %s credentials from service account pull secrets", got)
+	}
+	if got := resolveCredentials(t, keychain, "quay.io/other-org/pipelines"); got != "global-user:global-pass" {
+		t.Errorf("GetKeychain(): got %s credentials from global pull secret", got)
+	}
+
+	// Namespace without the service account gets the global pull secret only
+	keychain, err = provider.GetKeychain(context.Background(), "another-ns")
+	if err != nil {
+		t.Fatalf("GetKeychain(): unexpected error: %v", err)
+	}
+	if got := resolveCredentials(t, keychain, "quay.io/org/pipelines"); got != "global-user:global-pass" {
+		t.Errorf("GetKeychain(): got %s credentials in namespace without service account", got)
+	}
+
+	provider.GlobalPullSecret = types.NamespacedName{}
+	keychain, err = provider.GetKeychain(context.Background(), "another-ns")
+	if err != nil {
+		t.Fatalf("GetKeychain(): unexpected error: %v", err)
+	}
+	if got := resolveCredentials(t, keychain, "quay.io/org/pipelines"); got != "" {
+		t.Errorf("GetKeychain(): got %s credentials, expected anonymous access", got)
+	}
+}
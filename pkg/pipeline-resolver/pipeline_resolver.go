@@ -0,0 +1,174 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelineresolver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	tektonapiv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	BundlesResolverName = "bundles"
+	GitResolverName     = "git"
+	HubResolverName     = "hub"
+	ClusterResolverName = "cluster"
+
+	// NamespaceParam is added to the resolver params if not set, so resolvers can default to the namespace of the PipelineRun.
+	NamespaceParam = "namespace"
+	// KindParam is the kind of the referenced Tekton resource, resolvers are asked for pipelines only.
+	KindParam = "kind"
+	// NameParam is the name of the referenced Tekton resource
+	NameParam = "name"
+)
+
+// ResolvedPipeline is a pipeline definition fetched by a resolver.
+type ResolvedPipeline struct {
+	Spec *tektonapi.PipelineSpec
+	// Digest is the digest of the bundle image the pipeline was taken from, empty for other sources.
+	Digest string
+	// Bundle is the reference the bundle was pulled from, differs from the requested one if a registry mirror was used.
+	Bundle string
+}
+
+// Resolver fetches pipeline definitions the same way as Tekton resolver with the same name does.
+type Resolver interface {
+	// Resolve returns definition of the pipeline identified by the given Tekton resolver params.
+	// The namespace is the namespace of the PipelineRun which references the pipeline.
+	Resolve(ctx context.Context, params map[string]string, namespace string) (*ResolvedPipeline, error)
+}
+
+// ResolverClient fetches definitions of the pipelines referenced by Tekton pipeline references.
+// Resolvers are looked up by Tekton resolver name, so any resolver can be replaced or added.
+type ResolverClient struct {
+	resolvers map[string]Resolver
+}
+
+// NewResolverClient creates a client with bundles, git, hub and cluster resolvers.
+// The given reader is used by the cluster resolver to get Pipeline objects
+// from the namespace of the PipelineRun or from the shared namespaces.
+// The keychain provider gives credentials to pull bundles, the default keychain is used if it is nil.
+// The mirrors provider gives registry mirrors to pull bundles from, no mirrors are used if it is nil.
+func NewResolverClient(k8sReader client.Reader, sharedNamespaces []string, keychainProvider KeychainProvider, mirrorsProvider MirrorsProvider) *ResolverClient {
+	return &ResolverClient{
+		resolvers: map[string]Resolver{
+			BundlesResolverName: NewBundlesResolver(keychainProvider, mirrorsProvider),
+			GitResolverName:     NewGitResolver(),
+			HubResolverName:     NewHubResolver(),
+			ClusterResolverName: &ClusterResolver{Reader: k8sReader, SharedNamespaces: sharedNamespaces},
+		},
+	}
+}
+
+// RegisterResolver adds or replaces the resolver with the given Tekton resolver name.
+func (c *ResolverClient) RegisterResolver(name string, resolver Resolver) {
+	c.resolvers[name] = resolver
+}
+
+// GetPipeline returns definition of the pipeline referenced by the given pipeline reference.
+// The namespace is the namespace of the PipelineRun which references the pipeline.
+func (c *ResolverClient) GetPipeline(ctx context.Context, pipelineRef *tektonapi.PipelineRef, namespace string) (*ResolvedPipeline, error) {
+	resolverName, params, err := GetResolverParams(pipelineRef)
+	if err != nil {
+		return nil, err
+	}
+	resolver, exists := c.resolvers[resolverName]
+	if !exists {
+		return nil, fmt.Errorf("pipeline resolver '%s' is not supported", resolverName)
+	}
+
+	if kind, exists := params[KindParam]; exists && kind != "pipeline" {
+		return nil, fmt.Errorf("expected pipeline kind in %s resolver reference, got '%s'", resolverName, kind)
+	}
+	params[KindParam] = "pipeline"
+	if params[NamespaceParam] == "" {
+		params[NamespaceParam] = namespace
+	}
+
+	pipeline, err := resolver.Resolve(ctx, params, namespace)
+	if err != nil {
+		if boErr, ok := err.(*boerrors.BuildOpError); ok {
+			// Keep the error as is, so persistent errors are reported to the user
+			return nil, boErr
+		}
+		return nil, fmt.Errorf("failed to get pipeline via %s resolver: %w", resolverName, err)
+	}
+	return pipeline, nil
+}
+
+// GetResolverParams returns Tekton resolver name and params of the given pipeline reference.
+// Deprecated bundle references are returned as bundles resolver references.
+func GetResolverParams(pipelineRef *tektonapi.PipelineRef) (string, map[string]string, error) {
+	if pipelineRef.Bundle != "" {
+		if pipelineRef.Name == "" {
+			return "", nil, fmt.Errorf("name of pipeline in %s bundle is not set", pipelineRef.Bundle)
+		}
+		return BundlesResolverName, map[string]string{
+			"bundle":  pipelineRef.Bundle,
+			NameParam: pipelineRef.Name,
+			KindParam: "pipeline",
+		}, nil
+	}
+
+	if pipelineRef.Resolver == "" {
+		return "", nil, fmt.Errorf("pipeline reference must contain a bundle or a resolver")
+	}
+	params := make(map[string]string, len(pipelineRef.Params))
+	for _, param := range pipelineRef.Params {
+		if param.Value.Type != "" && param.Value.Type != tektonapi.ParamTypeString {
+			return "", nil, fmt.Errorf("%s parameter of %s resolver must be a string", param.Name, pipelineRef.Resolver)
+		}
+		params[param.Name] = param.Value.StringVal
+	}
+	return string(pipelineRef.Resolver), params, nil
+}
+
+// parsePipelineSpec returns spec of the given Pipeline definition in any supported Tekton API version.
+func parsePipelineSpec(content []byte) (*tektonapi.PipelineSpec, error) {
+	typeMeta := &metav1.TypeMeta{}
+	if err := yaml.Unmarshal(content, typeMeta); err != nil {
+		return nil, err
+	}
+	if typeMeta.Kind != "Pipeline" {
+		return nil, fmt.Errorf("expected Pipeline but got '%s' kind", typeMeta.Kind)
+	}
+
+	pipeline := &tektonapi.Pipeline{}
+	switch typeMeta.APIVersion {
+	case tektonapi.SchemeGroupVersion.String():
+		if err := yaml.Unmarshal(content, pipeline); err != nil {
+			return nil, err
+		}
+	case tektonapiv1.SchemeGroupVersion.String():
+		pipelineV1 := &tektonapiv1.Pipeline{}
+		if err := yaml.Unmarshal(content, pipelineV1); err != nil {
+			return nil, err
+		}
+		if err := pipeline.ConvertFrom(context.Background(), pipelineV1); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Pipeline API version '%s'", typeMeta.APIVersion)
+	}
+	return &pipeline.Spec, nil
+}
@@ -0,0 +1,733 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelineresolver
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	registrymirrors "github.com/redhat-appstudio/build-service/pkg/registry-mirrors"
+	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	oci "github.com/tektoncd/pipeline/pkg/remote/oci"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testPipelineV1beta1 = `apiVersion: tekton.dev/v1beta1
+kind: Pipeline
+metadata:
+  name: docker-build
+spec:
+  params:
+  - name: git-url
+    type: string
+  - name: output-image
+    type: string
+  workspaces:
+  - name: workspace
+`
+
+const testPipelineV1 = `apiVersion: tekton.dev/v1
+kind: Pipeline
+metadata:
+  name: docker-build
+spec:
+  params:
+  - name: git-url
+    type: string
+  - name: output-image
+    type: string
+  workspaces:
+  - name: workspace
+`
+
+var testPipelineSpec = &tektonapi.PipelineSpec{
+	Params:     []tektonapi.ParamSpec{{Name: "git-url", Type: "string"}, {Name: "output-image", Type: "string"}},
+	Workspaces: []tektonapi.PipelineWorkspaceDeclaration{{Name: "workspace"}},
+}
+
+type testResolver struct {
+	params map[string]string
+}
+
+func (r *testResolver) Resolve(ctx context.Context, params map[string]string, namespace string) (*ResolvedPipeline, error) {
+	r.params = params
+	return &ResolvedPipeline{Spec: testPipelineSpec}, nil
+}
+
+func TestGetResolverParams(t *testing.T) {
+	tests := []struct {
+		name         string
+		pipelineRef  *tektonapi.PipelineRef
+		wantResolver string
+		wantParams   map[string]string
+		wantErr      bool
+	}{
+		{
+			name:         "should return bundles resolver params for bundle reference",
+			pipelineRef:  &tektonapi.PipelineRef{Name: "docker-build", Bundle: "quay.io/org/pipelines:tag"},
+			wantResolver: "bundles",
+			wantParams:   map[string]string{"bundle": "quay.io/org/pipelines:tag", "name": "docker-build", "kind": "pipeline"},
+		},
+		{
+			name: "should return resolver params",
+			pipelineRef: &tektonapi.PipelineRef{
+				ResolverRef: tektonapi.ResolverRef{
+					Resolver: "git",
+					Params: []tektonapi.Param{
+						{Name: "url", Value: *tektonapi.NewArrayOrString("https://github.com/org/pipelines")},
+						{Name: "revision", Value: *tektonapi.NewArrayOrString("main")},
+						{Name: "pathInRepo", Value: *tektonapi.NewArrayOrString("pipelines/docker-build.yaml")},
+					},
+				},
+			},
+			wantResolver: "git",
+			wantParams:   map[string]string{"url": "https://github.com/org/pipelines", "revision": "main", "pathInRepo": "pipelines/docker-build.yaml"},
+		},
+		{
+			name:        "should fail if pipeline name in bundle is not set",
+			pipelineRef: &tektonapi.PipelineRef{Bundle: "quay.io/org/pipelines:tag"},
+			wantErr:     true,
+		},
+		{
+			name:        "should fail if neither bundle nor resolver is set",
+			pipelineRef: &tektonapi.PipelineRef{Name: "docker-build"},
+			wantErr:     true,
+		},
+		{
+			name: "should fail on array param",
+			pipelineRef: &tektonapi.PipelineRef{
+				ResolverRef: tektonapi.ResolverRef{
+					Resolver: "hub",
+					Params:   []tektonapi.Param{{Name: "name", Value: *tektonapi.NewArrayOrString("a", "b")}},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, params, err := GetResolverParams(tt.pipelineRef)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetResolverParams(): error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if resolver != tt.wantResolver || !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("GetResolverParams(): got %s %v, want %s %v", resolver, params, tt.wantResolver, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestPinBundleDigest(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		name    string
+		bundle  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "should replace tag with digest",
+			bundle: "quay.io/org/pipelines:tag",
+			want:   "quay.io/org/pipelines@" + digest,
+		},
+		{
+			name:   "should replace another digest",
+			bundle: "quay.io/org/pipelines@sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210",
+			want:   "quay.io/org/pipelines@" + digest,
+		},
+		{
+			name:   "should pin bundle without tag",
+			bundle: "quay.io/org/pipelines",
+			want:   "quay.io/org/pipelines@" + digest,
+		},
+		{
+			name:    "should fail on invalid bundle reference",
+			bundle:  "quay.io/org/Pipelines:tag",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PinBundleDigest(tt.bundle, digest)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PinBundleDigest(): error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("PinBundleDigest(): got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePipelineSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *tektonapi.PipelineSpec
+		wantErr bool
+	}{
+		{
+			name:    "should parse v1beta1 pipeline",
+			content: testPipelineV1beta1,
+			want:    testPipelineSpec,
+		},
+		{
+			name:    "should parse v1 pipeline",
+			content: testPipelineV1,
+			want:    testPipelineSpec,
+		},
+		{
+			name:    "should fail on task",
+			content: "apiVersion: tekton.dev/v1beta1\nkind: Task\n",
+			wantErr: true,
+		},
+		{
+			name:    "should fail on unknown API version",
+			content: "apiVersion: tekton.dev/v1alpha1\nkind: Pipeline\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePipelineSpec([]byte(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parsePipelineSpec(): error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePipelineSpec(): got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolverClientGetPipeline(t *testing.T) {
+	resolver := &testResolver{}
+	resolverClient := NewResolverClient(nil, nil, nil, nil)
+	resolverClient.RegisterResolver("bundles", resolver)
+	resolverClient.RegisterResolver("custom", resolver)
+
+	tests := []struct {
+		name        string
+		pipelineRef *tektonapi.PipelineRef
+		wantParams  map[string]string
+		wantErr     bool
+	}{
+		{
+			name:        "should resolve bundle reference via bundles resolver",
+			pipelineRef: &tektonapi.PipelineRef{Name: "docker-build", Bundle: "quay.io/org/pipelines:tag"},
+			wantParams:  map[string]string{"bundle": "quay.io/org/pipelines:tag", "name": "docker-build", "kind": "pipeline", "namespace": "user-ns"},
+		},
+		{
+			name: "should resolve via registered resolver and keep given namespace",
+			pipelineRef: &tektonapi.PipelineRef{
+				ResolverRef: tektonapi.ResolverRef{
+					Resolver: "custom",
+					Params: []tektonapi.Param{
+						{Name: "name", Value: *tektonapi.NewArrayOrString("docker-build")},
+						{Name: "namespace", Value: *tektonapi.NewArrayOrString("build-templates")},
+					},
+				},
+			},
+			wantParams: map[string]string{"name": "docker-build", "kind": "pipeline", "namespace": "build-templates"},
+		},
+		{
+			name: "should fail on unknown resolver",
+			pipelineRef: &tektonapi.PipelineRef{
+				ResolverRef: tektonapi.ResolverRef{Resolver: "unknown"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "should fail on task reference",
+			pipelineRef: &tektonapi.PipelineRef{
+				ResolverRef: tektonapi.ResolverRef{
+					Resolver: "custom",
+					Params:   []tektonapi.Param{{Name: "kind", Value: *tektonapi.NewArrayOrString("task")}},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver.params = nil
+			got, err := resolverClient.GetPipeline(context.Background(), tt.pipelineRef, "user-ns")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetPipeline(): error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Spec != testPipelineSpec {
+				t.Errorf("GetPipeline(): got %#v, want %#v", got.Spec, testPipelineSpec)
+			}
+			if !reflect.DeepEqual(resolver.params, tt.wantParams) {
+				t.Errorf("GetPipeline(): got resolver params %v, want %v", resolver.params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestClusterResolver(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := tektonapi.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	pipeline := &tektonapi.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: "docker-build", Namespace: "build-templates"},
+		Spec:       *testPipelineSpec,
+	}
+	resolver := &ClusterResolver{
+		Reader:           fake.NewClientBuilder().WithScheme(scheme).WithObjects(pipeline).Build(),
+		SharedNamespaces: []string{"build-service", "build-templates"},
+	}
+
+	got, err := resolver.Resolve(context.Background(), map[string]string{"name": "docker-build", "namespace": "build-templates"}, "user-ns")
+	if err != nil {
+		t.Fatalf("Resolve(): unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got.Spec, testPipelineSpec) {
+		t.Errorf("Resolve(): got %#v, want %#v", got.Spec, testPipelineSpec)
+	}
+
+	if _, err := resolver.Resolve(context.Background(), map[string]string{"name": "docker-build", "namespace": "user-ns"}, "user-ns"); err == nil {
+		t.Errorf("Resolve(): expected error for not existing pipeline")
+	}
+
+	// Pipelines of other tenants must not be readable
+	resolver.SharedNamespaces = []string{"build-service"}
+	_, err = resolver.Resolve(context.Background(), map[string]string{"name": "docker-build", "namespace": "build-templates"}, "user-ns")
+	if boErr, ok := err.(*boerrors.BuildOpError); !ok || boErr.ShortError() != boerrors.NewBuildOpError(boerrors.EPipelineNamespaceNotAllowed, nil).ShortError() {
+		t.Errorf("Resolve(): expected EPipelineNamespaceNotAllowed error, got %v", err)
+	}
+	if _, err := resolver.Resolve(context.Background(), map[string]string{"name": "docker-build", "namespace": "build-templates"}, "build-templates"); err != nil {
+		t.Errorf("Resolve(): unexpected error for pipeline in the PipelineRun namespace: %v", err)
+	}
+}
+
+func TestHubResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/packages/tekton-pipeline/tekton-catalog-pipelines/docker-build/0.1.0":
+			fmt.Fprintf(w, `{"data": {"manifestRaw": %q}}`, testPipelineV1beta1)
+		case "/v1/resource/Tekton/pipeline/docker-build/0.1/yaml":
+			fmt.Fprintf(w, `{"data": {"yaml": %q}}`, testPipelineV1)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	resolver := &HubResolver{ArtifactHubURL: server.URL, TektonHubURL: server.URL + "/", HttpClient: server.Client()}
+
+	tests := []struct {
+		name    string
+		params  map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "should get pipeline from artifact hub",
+			params: map[string]string{"name": "docker-build", "version": "0.1"},
+		},
+		{
+			name:   "should get pipeline from tekton hub",
+			params: map[string]string{"name": "docker-build", "version": "0.1.0", "type": "tekton"},
+		},
+		{
+			name:    "should fail if pipeline doesn't exist",
+			params:  map[string]string{"name": "unknown", "version": "0.1"},
+			wantErr: true,
+		},
+		{
+			name:    "should fail if version is not set",
+			params:  map[string]string{"name": "docker-build"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolver.Resolve(context.Background(), tt.params, "user-ns")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Resolve(): error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got.Spec, testPipelineSpec) {
+				t.Errorf("Resolve(): got %#v, want %#v", got.Spec, testPipelineSpec)
+			}
+		})
+	}
+}
+
+func TestGitResolver(t *testing.T) {
+	repoDir := t.TempDir()
+	repository, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repository.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(repoDir, "pipelines"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "pipelines", "docker-build.yaml"), []byte(testPipelineV1), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add("pipelines/docker-build.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	commit, err := worktree.Commit("Add pipeline", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repository.CreateTag("v0.1", commit, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.CreateTag("v0.1-annotated", commit, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		Message: "Release",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := NewGitResolver()
+	for _, revision := range []string{commit.String(), "master", "v0.1", "v0.1-annotated"} {
+		got, err := resolver.Resolve(context.Background(), map[string]string{"url": repoDir, "revision": revision, "pathInRepo": "pipelines/docker-build.yaml"}, "user-ns")
+		if err != nil {
+			t.Fatalf("Resolve(): unexpected error for %s revision: %v", revision, err)
+		}
+		if !reflect.DeepEqual(got.Spec, testPipelineSpec) {
+			t.Errorf("Resolve(): got %#v, want %#v", got.Spec, testPipelineSpec)
+		}
+	}
+
+	// The branch moves, so the new commit must be fetched instead of the cached definition
+	if err := os.WriteFile(filepath.Join(repoDir, "pipelines", "docker-build.yaml"), []byte(strings.Replace(testPipelineV1, "output-image", "image-url", 1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add("pipelines/docker-build.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Commit("Update pipeline", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := resolver.Resolve(context.Background(), map[string]string{"url": repoDir, "revision": "master", "pathInRepo": "pipelines/docker-build.yaml"}, "user-ns")
+	if err != nil {
+		t.Fatalf("Resolve(): unexpected error: %v", err)
+	}
+	if got.Spec.Params[1].Name != "image-url" {
+		t.Errorf("Resolve(): expected the pipeline of the latest commit, got %#v", got.Spec)
+	}
+
+	if _, err := resolver.Resolve(context.Background(), map[string]string{"url": repoDir, "revision": "master", "pathInRepo": "pipelines/unknown.yaml"}, "user-ns"); err == nil {
+		t.Errorf("Resolve(): expected error for not existing file")
+	}
+	if _, err := resolver.Resolve(context.Background(), map[string]string{"url": repoDir, "revision": "unknown", "pathInRepo": "pipelines/docker-build.yaml"}, "user-ns"); err == nil {
+		t.Errorf("Resolve(): expected error for not existing revision")
+	}
+}
+
+// pushTestBundle pushes a Tekton bundle with the given pipeline definition and returns the bundle digest.
+func pushTestBundle(t *testing.T, bundleUri, pipelineName, content string, options ...remote.Option) string {
+	var layerContent bytes.Buffer
+	writer := tar.NewWriter(&layerContent)
+	if err := writer.WriteHeader(&tar.Header{Name: pipelineName, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	layer, err := tarball.LayerFromReader(&layerContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer: layer,
+		Annotations: map[string]string{
+			oci.TitleAnnotation:      pipelineName,
+			oci.KindAnnotation:       "pipeline",
+			oci.APIVersionAnnotation: "v1beta1",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := name.ParseReference(bundleUri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, image, options...); err != nil {
+		t.Fatal(err)
+	}
+	digest, err := image.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return digest.String()
+}
+
+func TestBundlesResolver(t *testing.T) {
+	var blobRequests int32
+	registryHandler := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/blobs/") {
+			atomic.AddInt32(&blobRequests, 1)
+		}
+		registryHandler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	bundleUri := strings.TrimPrefix(server.URL, "http://") + "/org/pipelines:tag"
+	digest := pushTestBundle(t, bundleUri, "docker-build", testPipelineV1beta1)
+
+	resolver := NewBundlesResolver(nil, nil)
+	params := map[string]string{"bundle": bundleUri, "name": "docker-build"}
+
+	got, err := resolver.Resolve(context.Background(), params, "user-ns")
+	if err != nil {
+		t.Fatalf("Resolve(): unexpected error: %v", err)
+	}
+	if got.Digest != digest {
+		t.Errorf("Resolve(): got digest %s, want %s", got.Digest, digest)
+	}
+	if !reflect.DeepEqual(got.Spec, testPipelineSpec) {
+		t.Errorf("Resolve(): got %#v, want %#v", got.Spec, testPipelineSpec)
+	}
+	fetchedBlobs := atomic.LoadInt32(&blobRequests)
+
+	// The same digest must be served from the cache, even if referenced by digest
+	for _, bundle := range []string{bundleUri, strings.TrimSuffix(bundleUri, ":tag") + "@" + digest} {
+		got, err = resolver.Resolve(context.Background(), map[string]string{"bundle": bundle, "name": "docker-build"}, "user-ns")
+		if err != nil {
+			t.Fatalf("Resolve(): unexpected error for %s bundle: %v", bundle, err)
+		}
+		if got.Digest != digest || !reflect.DeepEqual(got.Spec, testPipelineSpec) {
+			t.Errorf("Resolve(): got %s %#v for %s bundle", got.Digest, got.Spec, bundle)
+		}
+	}
+	if atomic.LoadInt32(&blobRequests) != fetchedBlobs {
+		t.Errorf("Resolve(): expected cached pipeline definition to be used")
+	}
+	// Returned definitions must not share the cached one
+	got.Spec.Params = nil
+	if got, _ = resolver.Resolve(context.Background(), params, "user-ns"); !reflect.DeepEqual(got.Spec, testPipelineSpec) {
+		t.Errorf("Resolve(): cached pipeline definition was modified")
+	}
+
+	// Moved tag must be resolved to the new digest
+	updatedPipeline := testPipelineV1beta1 + "  - name: git-auth\n    optional: true\n"
+	updatedDigest := pushTestBundle(t, bundleUri, "docker-build", updatedPipeline)
+	got, err = resolver.Resolve(context.Background(), params, "user-ns")
+	if err != nil {
+		t.Fatalf("Resolve(): unexpected error: %v", err)
+	}
+	if got.Digest != updatedDigest || len(got.Spec.Workspaces) != 2 {
+		t.Errorf("Resolve(): got %s %#v, want definition from %s", got.Digest, got.Spec, updatedDigest)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := resolver.Resolve(ctx, params, "user-ns"); err == nil {
+		t.Errorf("Resolve(): expected error for cancelled context")
+	}
+	if _, err := resolver.Resolve(context.Background(), map[string]string{"bundle": bundleUri, "name": "unknown"}, "user-ns"); err == nil {
+		t.Errorf("Resolve(): expected error for not existing pipeline")
+	}
+}
+
+// testKeychainProvider gives the same keychain for all namespaces and remembers the last requested namespace
+type testKeychainProvider struct {
+	keychain  authn.Keychain
+	namespace string
+}
+
+func (p *testKeychainProvider) GetKeychain(ctx context.Context, namespace string) (authn.Keychain, error) {
+	p.namespace = namespace
+	return p.keychain, nil
+}
+
+func TestBundlesResolverAuthentication(t *testing.T) {
+	registryHandler := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "pass" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		registryHandler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	bundleUri := strings.TrimPrefix(server.URL, "http://") + "/org/pipelines:tag"
+	digest := pushTestBundle(t, bundleUri, "docker-build", testPipelineV1beta1, remote.WithAuth(&authn.Basic{Username: "user", Password: "pass"}))
+	pipelineRef := &tektonapi.PipelineRef{Name: "docker-build", Bundle: bundleUri}
+
+	keychainProvider := &testKeychainProvider{}
+	resolverClient := NewResolverClient(nil, nil, keychainProvider, nil)
+
+	keychainProvider.keychain = authn.NewMultiKeychain()
+	_, err := resolverClient.GetPipeline(context.Background(), pipelineRef, "user-ns")
+	boErr, ok := err.(*boerrors.BuildOpError)
+	if !ok {
+		t.Fatalf("GetPipeline(): expected build operation error, got %v", err)
+	}
+	if boErr.ShortError() != boerrors.NewBuildOpError(boerrors.EPipelineBundleUnauthorized, nil).ShortError() || !boErr.IsPersistent() {
+		t.Errorf("GetPipeline(): unexpected error: %s", boErr.ShortError())
+	}
+
+	pullSecret := getDockerConfigJsonSecret("pull-secret", "user-ns", map[string]string{strings.TrimPrefix(server.URL, "http://"): "user:pass"})
+	keychainProvider.keychain, err = NewPullSecretsKeychain([]corev1.Secret{*pullSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := resolverClient.GetPipeline(context.Background(), pipelineRef, "user-ns")
+	if err != nil {
+		t.Fatalf("GetPipeline(): unexpected error: %v", err)
+	}
+	if got.Digest != digest || !reflect.DeepEqual(got.Spec, testPipelineSpec) {
+		t.Errorf("GetPipeline(): got %s %#v", got.Digest, got.Spec)
+	}
+
+	// Credentials of another namespace must not be used even if the reference asks for them
+	resolverPipelineRef := &tektonapi.PipelineRef{
+		ResolverRef: tektonapi.ResolverRef{
+			Resolver: "bundles",
+			Params: []tektonapi.Param{
+				{Name: "bundle", Value: *tektonapi.NewArrayOrString(bundleUri)},
+				{Name: "name", Value: *tektonapi.NewArrayOrString("docker-build")},
+				{Name: "namespace", Value: *tektonapi.NewArrayOrString("other-ns")},
+			},
+		},
+	}
+	if _, err := resolverClient.GetPipeline(context.Background(), resolverPipelineRef, "user-ns"); err != nil {
+		t.Fatalf("GetPipeline(): unexpected error: %v", err)
+	}
+	if keychainProvider.namespace != "user-ns" {
+		t.Errorf("GetPipeline(): got credentials of %s namespace, want user-ns", keychainProvider.namespace)
+	}
+
+	// Cached definition must not be returned without access to the bundle
+	keychainProvider.keychain = authn.NewMultiKeychain()
+	if _, err := resolverClient.GetPipeline(context.Background(), pipelineRef, "user-ns"); err == nil {
+		t.Errorf("GetPipeline(): expected error for cached definition without access to the bundle")
+	}
+}
+
+// testMirrorsProvider gives the same registry mirrors on every request
+type testMirrorsProvider struct {
+	registryMirrors *registrymirrors.RegistryMirrors
+}
+
+func (p *testMirrorsProvider) GetRegistryMirrors(ctx context.Context) (*registrymirrors.RegistryMirrors, error) {
+	return p.registryMirrors, nil
+}
+
+func TestBundlesResolverMirrors(t *testing.T) {
+	newRegistryServer := func() *httptest.Server {
+		return httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	}
+	sourceServer := newRegistryServer()
+	defer sourceServer.Close()
+	mirrorServer := newRegistryServer()
+	defer mirrorServer.Close()
+	sourceRegistry := strings.TrimPrefix(sourceServer.URL, "http://")
+	mirrorRegistry := strings.TrimPrefix(mirrorServer.URL, "http://")
+
+	bundleUri := sourceRegistry + "/org/pipelines:tag"
+	mirroredBundleUri := mirrorRegistry + "/mirror/org/pipelines:tag"
+	registryMirrors, err := registrymirrors.NewRegistryMirrors([]registrymirrors.ImageMirror{
+		{Source: sourceRegistry, Mirrors: []string{mirrorRegistry + "/mirror"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := NewBundlesResolver(nil, &testMirrorsProvider{registryMirrors: registryMirrors})
+	params := map[string]string{"bundle": bundleUri, "name": "docker-build"}
+
+	// Source bundle is used if the mirror doesn't have it
+	sourceDigest := pushTestBundle(t, bundleUri, "docker-build", testPipelineV1beta1)
+	got, err := resolver.Resolve(context.Background(), params, "user-ns")
+	if err != nil {
+		t.Fatalf("Resolve(): unexpected error: %v", err)
+	}
+	if got.Bundle != bundleUri || got.Digest != sourceDigest || !reflect.DeepEqual(got.Spec, testPipelineSpec) {
+		t.Errorf("Resolve(): got %s %s %#v, want definition from the source bundle", got.Bundle, got.Digest, got.Spec)
+	}
+
+	mirrorDigest := pushTestBundle(t, mirroredBundleUri, "docker-build", testPipelineV1beta1)
+	got, err = resolver.Resolve(context.Background(), params, "user-ns")
+	if err != nil {
+		t.Fatalf("Resolve(): unexpected error: %v", err)
+	}
+	if got.Bundle != mirroredBundleUri || got.Digest != mirrorDigest || !reflect.DeepEqual(got.Spec, testPipelineSpec) {
+		t.Errorf("Resolve(): got %s %s %#v, want definition from the mirror", got.Bundle, got.Digest, got.Spec)
+	}
+
+	// Source registry is not accessed if the bundle is mirrored
+	sourceServer.Close()
+	if _, err := resolver.Resolve(context.Background(), params, "user-ns"); err != nil {
+		t.Errorf("Resolve(): unexpected error for unavailable source registry: %v", err)
+	}
+	if _, err := resolver.Resolve(context.Background(), map[string]string{"bundle": sourceRegistry + "/org/other:tag", "name": "docker-build"}, "user-ns"); err == nil {
+		t.Errorf("Resolve(): expected error for bundle missing in both the mirror and the source registry")
+	}
+}
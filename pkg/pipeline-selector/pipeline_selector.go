@@ -28,19 +28,19 @@ import (
 
 // SelectPipelineForComponent evaluates given list of pipeline selectors aginst specified component
 // to find the build pipeline for the component.
-// The first match is returned.
-func SelectPipelineForComponent(component *appstudiov1alpha1.Component, selectors []buildappstudiov1alpha1.BuildPipelineSelector) (*tektonapi.PipelineRef, []tektonapi.Param, error) {
+// The first match is returned together with the way the pipeline should be referenced from PaC PipelineRuns.
+func SelectPipelineForComponent(component *appstudiov1alpha1.Component, selectors []buildappstudiov1alpha1.BuildPipelineSelector) (*tektonapi.PipelineRef, []tektonapi.Param, *buildappstudiov1alpha1.PipelineReference, error) {
 	selectionParameters, err := getPipelineSelectionParametersForComponent(component)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	for i := range selectors {
-		if buildPipelineRef, buildPipelineAdditionalParams := findMatchingPipeline(selectionParameters, &selectors[i]); buildPipelineRef != nil {
-			return buildPipelineRef, buildPipelineAdditionalParams, nil
+		if buildPipelineRef, buildPipelineAdditionalParams, buildPipelineReference := findMatchingPipeline(selectionParameters, &selectors[i]); buildPipelineRef != nil {
+			return buildPipelineRef, buildPipelineAdditionalParams, buildPipelineReference, nil
 		}
 	}
-	return nil, nil, nil
+	return nil, nil, nil, nil
 }
 
 // getPipelineSelectionParametersForComponent returns build parameters of the given component
@@ -79,7 +79,7 @@ func getPipelineSelectionParametersForComponent(component *appstudiov1alpha1.Com
 
 // findMatchingPipeline evaluates given selectors chain against component parameters.
 // The first match is returned.
-func findMatchingPipeline(selectionParameters *buildappstudiov1alpha1.WhenCondition, selectors *buildappstudiov1alpha1.BuildPipelineSelector) (*tektonapi.PipelineRef, []tektonapi.Param) {
+func findMatchingPipeline(selectionParameters *buildappstudiov1alpha1.WhenCondition, selectors *buildappstudiov1alpha1.BuildPipelineSelector) (*tektonapi.PipelineRef, []tektonapi.Param, *buildappstudiov1alpha1.PipelineReference) {
 	for _, pipelineSelector := range selectors.Spec.Selectors {
 		if pipelineConditionsMatchComponentParameters(&pipelineSelector.WhenConditions, selectionParameters) {
 			var pipelineParams []tektonapi.Param
@@ -89,10 +89,10 @@ func findMatchingPipeline(selectionParameters *buildappstudiov1alpha1.WhenCondit
 					Value: *tektonapi.NewArrayOrString(param.Value),
 				})
 			}
-			return &pipelineSelector.PipelineRef, pipelineParams
+			return &pipelineSelector.PipelineRef, pipelineParams, pipelineSelector.PipelineReference
 		}
 	}
-	return nil, nil
+	return nil, nil, nil
 }
 
 // pipelineConditionsMatchComponentParameters evaluates given pipeline selector against component parameters.
@@ -354,7 +354,7 @@ func TestSelectPipelineForComponent(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			pipelineRef, pipelineParams, err := SelectPipelineForComponent(tt.component, tt.selectors)
+			pipelineRef, pipelineParams, _, err := SelectPipelineForComponent(tt.component, tt.selectors)
 
 			if tt.wantErr {
 				if err == nil {
@@ -576,11 +576,12 @@ func TestGetPipelineSelectionParametersForComponent(t *testing.T) {
 
 func TestFindMatchingPipeline(t *testing.T) {
 	tests := []struct {
-		name                string
-		componentConditions buildappstudiov1alpha1.WhenCondition
-		pipelinesChain      buildappstudiov1alpha1.BuildPipelineSelector
-		wantPipelineRef     *tektonapi.PipelineRef
-		wantPipelineParams  []tektonapi.Param
+		name                  string
+		componentConditions   buildappstudiov1alpha1.WhenCondition
+		pipelinesChain        buildappstudiov1alpha1.BuildPipelineSelector
+		wantPipelineRef       *tektonapi.PipelineRef
+		wantPipelineParams    []tektonapi.Param
+		wantPipelineReference *buildappstudiov1alpha1.PipelineReference
 	}{
 		{
 			name: "should match the only pipeline in chain if conditions are met",
@@ -913,10 +914,63 @@ func TestFindMatchingPipeline(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "should return build pipeline reference",
+			componentConditions: buildappstudiov1alpha1.WhenCondition{
+				Language: "java",
+			},
+			pipelinesChain: buildappstudiov1alpha1.BuildPipelineSelector{
+				Spec: buildappstudiov1alpha1.BuildPipelineSelectorSpec{
+					Selectors: []buildappstudiov1alpha1.PipelineSelector{
+						{
+							PipelineRef: tektonapi.PipelineRef{
+								Name:   "python-build-pipeline",
+								Bundle: "my-bundle",
+							},
+							PipelineReference: &buildappstudiov1alpha1.PipelineReference{
+								Type: buildappstudiov1alpha1.PipelineReferenceBundlesResolver,
+							},
+							WhenConditions: buildappstudiov1alpha1.WhenCondition{
+								Language: "python",
+							},
+						},
+						{
+							PipelineRef: tektonapi.PipelineRef{
+								Name:   "java-build-pipeline",
+								Bundle: "my-bundle",
+							},
+							PipelineReference: &buildappstudiov1alpha1.PipelineReference{
+								Type: buildappstudiov1alpha1.PipelineReferenceGitResolver,
+								Git: &buildappstudiov1alpha1.GitPipelineLocation{
+									URL:        "https://github.com/org/pipelines",
+									Revision:   "main",
+									PathInRepo: "pipelines/java-build.yaml",
+								},
+							},
+							WhenConditions: buildappstudiov1alpha1.WhenCondition{
+								Language: "java",
+							},
+						},
+					},
+				},
+			},
+			wantPipelineRef: &tektonapi.PipelineRef{
+				Name:   "java-build-pipeline",
+				Bundle: "my-bundle",
+			},
+			wantPipelineReference: &buildappstudiov1alpha1.PipelineReference{
+				Type: buildappstudiov1alpha1.PipelineReferenceGitResolver,
+				Git: &buildappstudiov1alpha1.GitPipelineLocation{
+					URL:        "https://github.com/org/pipelines",
+					Revision:   "main",
+					PathInRepo: "pipelines/java-build.yaml",
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			pipelineRef, pipelineParams := findMatchingPipeline(&tt.componentConditions, &tt.pipelinesChain)
+			pipelineRef, pipelineParams, pipelineReference := findMatchingPipeline(&tt.componentConditions, &tt.pipelinesChain)
 
 			if !reflect.DeepEqual(pipelineRef, tt.wantPipelineRef) {
 				t.Errorf("findMatchingPipeline(): pipelineRef got: %v, want: %v", pipelineRef, tt.wantPipelineRef)
@@ -924,6 +978,9 @@ func TestFindMatchingPipeline(t *testing.T) {
 			if !reflect.DeepEqual(pipelineParams, tt.wantPipelineParams) {
 				t.Errorf("findMatchingPipeline(): pipelineParams got: %v, want: %v", pipelineParams, tt.wantPipelineParams)
 			}
+			if !reflect.DeepEqual(pipelineReference, tt.wantPipelineReference) {
+				t.Errorf("findMatchingPipeline(): pipelineReference got: %v, want: %v", pipelineReference, tt.wantPipelineReference)
+			}
 		})
 	}
 }
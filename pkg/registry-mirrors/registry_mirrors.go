@@ -0,0 +1,172 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registrymirrors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ConfigMapName is the name of the config map in build-service namespace with registry mirrors configuration
+	ConfigMapName = "registry-mirrors"
+	// ConfigMapKey is the config map key with the list of mirrors in the format of
+	// ImageDigestMirrorSet imageDigestMirrors field, so the list can be copied from the cluster configuration:
+	//   - source: quay.io/redhat-appstudio-tekton-catalog
+	//     mirrors:
+	//     - registry.example.com/redhat-appstudio-tekton-catalog
+	ConfigMapKey = "mirrors"
+)
+
+// ImageMirror maps a source registry or repository to its mirrors.
+type ImageMirror struct {
+	// Source is a registry host or a repository, images under it are pulled from the mirrors
+	Source string `json:"source"`
+	// Mirrors replace the source part of image references, they are tried in the given order
+	Mirrors []string `json:"mirrors"`
+}
+
+// RegistryMirrors rewrites image references to pull the images from mirrors.
+// Nil RegistryMirrors has no mirrors.
+type RegistryMirrors struct {
+	imageMirrors []ImageMirror
+}
+
+// NewRegistryMirrors validates the given mirrors and normalizes sources, so they match image references.
+func NewRegistryMirrors(imageMirrors []ImageMirror) (*RegistryMirrors, error) {
+	registryMirrors := &RegistryMirrors{}
+	for _, imageMirror := range imageMirrors {
+		source := strings.TrimSuffix(imageMirror.Source, "/")
+		if source == "" || len(imageMirror.Mirrors) == 0 {
+			return nil, fmt.Errorf("source and mirrors must be set")
+		}
+		// Normalize the registry the same way as image references do, e.g. resolve docker.io aliases
+		host, path, _ := strings.Cut(source, "/")
+		registry, err := name.NewRegistry(host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source %s: %w", imageMirror.Source, err)
+		}
+		source = registry.Name()
+		if path != "" {
+			if _, err := name.NewRepository(source + "/" + path); err != nil {
+				return nil, fmt.Errorf("invalid source %s: %w", imageMirror.Source, err)
+			}
+			source += "/" + path
+		}
+
+		mirrors := make([]string, 0, len(imageMirror.Mirrors))
+		for _, mirror := range imageMirror.Mirrors {
+			mirror = strings.TrimSuffix(mirror, "/")
+			if mirror == "" {
+				return nil, fmt.Errorf("empty mirror of %s source", imageMirror.Source)
+			}
+			mirrors = append(mirrors, mirror)
+		}
+		registryMirrors.imageMirrors = append(registryMirrors.imageMirrors, ImageMirror{Source: source, Mirrors: mirrors})
+	}
+	return registryMirrors, nil
+}
+
+// ParseRegistryMirrors parses list of mirrors in the format of ConfigMapKey value.
+func ParseRegistryMirrors(content []byte) (*RegistryMirrors, error) {
+	imageMirrors := []ImageMirror{}
+	if err := yaml.UnmarshalStrict(content, &imageMirrors); err != nil {
+		return nil, err
+	}
+	return NewRegistryMirrors(imageMirrors)
+}
+
+// GetRegistryMirrors reads registry mirrors from the given config map.
+// Empty configuration is returned if the config map doesn't exist.
+func GetRegistryMirrors(ctx context.Context, reader client.Reader, configMapKey types.NamespacedName) (*RegistryMirrors, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, configMapKey, configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return &RegistryMirrors{}, nil
+		}
+		return nil, err
+	}
+	registryMirrors, err := ParseRegistryMirrors([]byte(configMap.Data[ConfigMapKey]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s key of %s config map in %s namespace: %w", ConfigMapKey, configMapKey.Name, configMapKey.Namespace, err)
+	}
+	return registryMirrors, nil
+}
+
+// GetMirrorReferences returns references of the given image in its mirrors, in the order they should be tried.
+// Mirrors of the most specific source are used. Nil is returned if the image is not mirrored.
+func (m *RegistryMirrors) GetMirrorReferences(image string) []string {
+	if m == nil {
+		return nil
+	}
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return nil
+	}
+	repository := ref.Context().Name()
+
+	var matched *ImageMirror
+	for i := range m.imageMirrors {
+		source := m.imageMirrors[i].Source
+		if repository != source && !strings.HasPrefix(repository, source+"/") {
+			continue
+		}
+		if matched == nil || len(source) > len(matched.Source) {
+			matched = &m.imageMirrors[i]
+		}
+	}
+	if matched == nil {
+		return nil
+	}
+
+	identifier := ":" + ref.Identifier()
+	if _, isDigest := ref.(name.Digest); isDigest {
+		identifier = "@" + ref.Identifier()
+	}
+	mirrorReferences := make([]string, 0, len(matched.Mirrors))
+	for _, mirror := range matched.Mirrors {
+		mirrorReferences = append(mirrorReferences, mirror+strings.TrimPrefix(repository, matched.Source)+identifier)
+	}
+	return mirrorReferences
+}
+
+// Rewrite returns reference of the given image in its first mirror, or the image itself if it is not mirrored.
+// Use it for images pulled by the cluster, which cannot fall back to other mirrors.
+func (m *RegistryMirrors) Rewrite(image string) string {
+	if mirrorReferences := m.GetMirrorReferences(image); len(mirrorReferences) > 0 {
+		return mirrorReferences[0]
+	}
+	return image
+}
+
+// ConfigMapProvider reads registry mirrors from the config map on every request, so changes apply without restart.
+type ConfigMapProvider struct {
+	Reader    client.Reader
+	ConfigMap types.NamespacedName
+}
+
+func (p *ConfigMapProvider) GetRegistryMirrors(ctx context.Context) (*RegistryMirrors, error) {
+	return GetRegistryMirrors(ctx, p.Reader, p.ConfigMap)
+}
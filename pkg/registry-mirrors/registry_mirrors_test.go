@@ -0,0 +1,166 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registrymirrors
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testMirrors = `
+- source: quay.io
+  mirrors:
+  - registry.example.com/quay
+- source: quay.io/redhat-appstudio-tekton-catalog/
+  mirrors:
+  - registry.example.com/catalog
+  - backup.example.com/catalog
+- source: docker.io/library
+  mirrors:
+  - registry.example.com/library
+`
+
+func TestParseRegistryMirrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "should parse mirrors", content: testMirrors},
+		{name: "should accept empty list", content: ""},
+		{name: "should reject unknown fields", content: "- source: quay.io\n  mirror: registry.example.com\n", wantErr: true},
+		{name: "should reject source without mirrors", content: "- source: quay.io\n", wantErr: true},
+		{name: "should reject mirrors without source", content: "- mirrors:\n  - registry.example.com\n", wantErr: true},
+		{name: "should reject empty mirror", content: "- source: quay.io\n  mirrors:\n  - ''\n", wantErr: true},
+		{name: "should reject invalid source", content: "- source: quay.io/Org\n  mirrors:\n  - registry.example.com\n", wantErr: true},
+		{name: "should reject invalid content", content: "source: quay.io", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRegistryMirrors([]byte(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRegistryMirrors(): got error %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetMirrorReferences(t *testing.T) {
+	registryMirrors, err := ParseRegistryMirrors([]byte(testMirrors))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		image string
+		want  []string
+	}{
+		{
+			image: "quay.io/org/image:tag",
+			want:  []string{"registry.example.com/quay/org/image:tag"},
+		},
+		{
+			image: "quay.io/redhat-appstudio-tekton-catalog/pipeline-docker-build:devel",
+			want:  []string{"registry.example.com/catalog/pipeline-docker-build:devel", "backup.example.com/catalog/pipeline-docker-build:devel"},
+		},
+		{
+			image: "quay.io/redhat-appstudio-tekton-catalog/pipeline-docker-build@sha256:3e4b8a1f2c6d5e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f7",
+			want: []string{
+				"registry.example.com/catalog/pipeline-docker-build@sha256:3e4b8a1f2c6d5e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f7",
+				"backup.example.com/catalog/pipeline-docker-build@sha256:3e4b8a1f2c6d5e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f7",
+			},
+		},
+		{
+			image: "quay.io/redhat-appstudio-tekton-catalog-other/image",
+			want:  []string{"registry.example.com/quay/redhat-appstudio-tekton-catalog-other/image:latest"},
+		},
+		{
+			image: "alpine:3",
+			want:  []string{"registry.example.com/library/alpine:3"},
+		},
+		{
+			image: "registry.io/org/image:tag",
+			want:  nil,
+		},
+		{
+			image: "invalid reference",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := registryMirrors.GetMirrorReferences(tt.image); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetMirrorReferences(): got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var noMirrors *RegistryMirrors
+	if got := noMirrors.GetMirrorReferences("quay.io/org/image:tag"); got != nil {
+		t.Errorf("GetMirrorReferences(): got %v for nil mirrors", got)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	registryMirrors, err := ParseRegistryMirrors([]byte(testMirrors))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := registryMirrors.Rewrite("quay.io/redhat-appstudio-tekton-catalog/pipeline-docker-build:devel"); got != "registry.example.com/catalog/pipeline-docker-build:devel" {
+		t.Errorf("Rewrite(): got %s for mirrored image", got)
+	}
+	if got := registryMirrors.Rewrite("registry.io/org/image:tag"); got != "registry.io/org/image:tag" {
+		t.Errorf("Rewrite(): got %s for not mirrored image", got)
+	}
+}
+
+func TestGetRegistryMirrors(t *testing.T) {
+	configMapKey := types.NamespacedName{Namespace: "build-service", Name: ConfigMapName}
+	getConfigMap := func(mirrors string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: configMapKey.Name, Namespace: configMapKey.Namespace},
+			Data:       map[string]string{ConfigMapKey: mirrors},
+		}
+	}
+
+	registryMirrors, err := GetRegistryMirrors(context.Background(), fake.NewClientBuilder().WithObjects(getConfigMap(testMirrors)).Build(), configMapKey)
+	if err != nil {
+		t.Fatalf("GetRegistryMirrors(): unexpected error: %v", err)
+	}
+	if got := registryMirrors.Rewrite("quay.io/org/image:tag"); got != "registry.example.com/quay/org/image:tag" {
+		t.Errorf("GetRegistryMirrors(): got %s image from the config map mirrors", got)
+	}
+
+	registryMirrors, err = GetRegistryMirrors(context.Background(), fake.NewClientBuilder().Build(), configMapKey)
+	if err != nil {
+		t.Fatalf("GetRegistryMirrors(): unexpected error for missing config map: %v", err)
+	}
+	if got := registryMirrors.GetMirrorReferences("quay.io/org/image:tag"); got != nil {
+		t.Errorf("GetRegistryMirrors(): got %v mirrors without config map", got)
+	}
+
+	if _, err := GetRegistryMirrors(context.Background(), fake.NewClientBuilder().WithObjects(getConfigMap("invalid")).Build(), configMapKey); err == nil {
+		t.Errorf("GetRegistryMirrors(): expected error for invalid config map")
+	}
+}